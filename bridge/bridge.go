@@ -0,0 +1,327 @@
+// Package bridge provides a component that forwards the lifecycle, health
+// and configuration events of the server to external systems, so that the
+// servers of a fleet can be observed from a single place. The events are
+// forwarded in batches to webhooks, NATS subjects and Kafka topics.
+//
+// The sinks are configured by the "bridge" configuration, e.g.
+//
+//	"bridge": {
+//		"sinks": [
+//			{"type": "webhook", "url": "https://ops.example.com/events"},
+//			{"type": "nats", "url": "nats://nats:4222", "subject": "fleet.lifecycle"},
+//			{"type": "kafka", "url": "http://rest-proxy:8082", "topic": "lifecycle",
+//				"events": ["start_failed", "health_changed", "config_fetched"]}
+//		],
+//		"batch_size": 50,
+//		"batch_interval_ms": 1000
+//	}
+//
+// The Kafka topics are written through a Kafka REST proxy.
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Bridge forwards the events to the sinks.
+type Bridge interface {
+	// Forward forwards the event to the sinks it is selected for.
+	// Components can forward events of their own.
+	Forward(e component.Event)
+}
+
+// DefaultEvents are the types of the events forwarded to a sink if none are
+// configured for it. The other events, e.g. the configuration fetches, are
+// only forwarded if configured.
+var DefaultEvents = []component.EventType{
+	component.EventStarted,
+	component.EventStartFailed,
+	component.EventHealthChanged,
+	component.EventStopping,
+	component.EventStopped,
+}
+
+// Message is the JSON message of an event sent to the sinks.
+type Message struct {
+	Type      component.EventType `json:"type"`
+	Host      string              `json:"host,omitempty"`
+	Group     string              `json:"group"`
+	Component string              `json:"component,omitempty"`
+	Key       string              `json:"key,omitempty"`
+	Healthy   bool                `json:"healthy"`
+	Error     string              `json:"error,omitempty"`
+	Time      time.Time           `json:"time"`
+	RunID     string              `json:"run_id,omitempty"`
+}
+
+type bridge struct {
+	config *configuration
+	ctx    component.Context
+	host   string
+	lock   sync.Mutex
+	sinks  []*sink
+	// started once the sinks are sending, stopped once the server is stopped
+	started bool
+	stopped bool
+}
+
+// configuration defines the configurable parameters of the bridge.
+type configuration struct {
+	config.BaseConfig
+	// Sinks the events are forwarded to, the bridge is disabled if empty
+	Sinks []sinkConfig `json:"sinks"`
+	// Maximum number of messages sent at once to a sink
+	BatchSize int `json:"batch_size"`
+	// Maximum delay of a message before its batch is sent in milliseconds
+	BatchInterval int `json:"batch_interval_ms"`
+	// Maximum number of messages waiting to be sent to a sink
+	QueueSize int `json:"queue_size"`
+	// Number of retries of a failed batch
+	Retries int `json:"retries"`
+	// Delay before the first retry in milliseconds, doubled on each retry
+	RetryDelay int `json:"retry_delay_ms"`
+	// Timeout of the sending of a batch in milliseconds
+	Timeout int `json:"timeout_ms"`
+}
+
+// sinkConfig is the configuration of a sink.
+type sinkConfig struct {
+	// Type of the sink, "webhook", "nats" or "kafka"
+	Type string `json:"type"`
+	// URL of the webhook, of the NATS server or of the Kafka REST proxy
+	URL string `json:"url"`
+	// Subject of the NATS messages
+	Subject string `json:"subject"`
+	// Topic of the Kafka records
+	Topic string `json:"topic"`
+	// Types of the events to forward, DefaultEvents if empty
+	Events []component.EventType `json:"events"`
+}
+
+// sink is a configured sink with its queue of messages.
+type sink struct {
+	sender
+	name   string
+	events []component.EventType
+	queue  chan []byte
+	done   chan struct{}
+}
+
+// New creates a new event bridge.
+func New(ctx component.Context) Bridge {
+	return &bridge{
+		config: &configuration{
+			BaseConfig:    config.BaseConfig{ConfigKey: "bridge"},
+			BatchSize:     50,
+			BatchInterval: 1000,
+			QueueSize:     256,
+			Retries:       3,
+			RetryDelay:    1000,
+			Timeout:       5000,
+		},
+		ctx: ctx,
+	}
+}
+
+func (b *bridge) Config() config.Config {
+	return b.config
+}
+
+func (b *bridge) Configure(ctx component.Context) error {
+	if b.config.BatchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d, must be positive", b.config.BatchSize)
+	}
+	timeout := time.Duration(b.config.Timeout) * time.Millisecond
+	sinks := []*sink{}
+	for i, sc := range b.config.Sinks {
+		s, err := newSender(sc, timeout)
+		if err != nil {
+			return fmt.Errorf("sink %d: %v", i, err)
+		}
+		events := sc.Events
+		if len(events) == 0 {
+			events = DefaultEvents
+		}
+		sinks = append(sinks, &sink{
+			sender: s,
+			name:   s.String(),
+			events: events,
+			queue:  make(chan []byte, b.config.QueueSize),
+			done:   make(chan struct{}),
+		})
+	}
+	b.host, _ = os.Hostname()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.started || b.stopped {
+		// The running sinks are kept
+		return nil
+	}
+	b.sinks = sinks
+	return nil
+}
+
+// Start starts sending the messages to the sinks in the background. Messages
+// are never sent by the caller of Forward, the events forwarded before the
+// start are queued and the events forwarded after the stop are dropped.
+func (b *bridge) Start(ctx component.Context) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.started || b.stopped {
+		return nil
+	}
+	b.started = true
+	for _, s := range b.sinks {
+		go b.run(s)
+	}
+	return nil
+}
+
+// OnEvent forwards the events of the server.
+func (b *bridge) OnEvent(ctx component.Context, e component.Event) {
+	b.Forward(e)
+}
+
+func (b *bridge) Forward(e component.Event) {
+	b.enqueue(e)
+	if e.Type == component.EventStopped {
+		// The server is done, flush the queues before the process exits
+		b.flush()
+	}
+}
+
+// enqueue queues the message of the event to the sinks it is selected for.
+func (b *bridge) enqueue(e component.Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.stopped || len(b.sinks) == 0 {
+		return
+	}
+	msg, err := b.message(e)
+	if err != nil {
+		b.ctx.Log().Error().Error(err).Msg("failed to encode the event")
+		return
+	}
+	for _, s := range b.sinks {
+		if !selected(s.events, e.Type) {
+			continue
+		}
+		select {
+		case s.queue <- msg:
+		default:
+			b.ctx.Log().Warn().Str("sink", s.name).Str("event", string(e.Type)).Msg("event queue is full")
+		}
+	}
+}
+
+// flush closes the queues and waits for the queued messages to be sent if
+// the bridge is started.
+func (b *bridge) flush() {
+	b.lock.Lock()
+	if b.stopped {
+		b.lock.Unlock()
+		return
+	}
+	b.stopped = true
+	for _, s := range b.sinks {
+		close(s.queue)
+	}
+	started, sinks := b.started, b.sinks
+	b.lock.Unlock()
+	if !started {
+		return
+	}
+	for _, s := range sinks {
+		<-s.done
+	}
+}
+
+// run sends the queued messages of the sink in batches until its queue is
+// closed. A batch is sent once it is full or once its first message waited
+// for the batch interval.
+func (b *bridge) run(s *sink) {
+	defer close(s.done)
+	interval := time.Duration(b.config.BatchInterval) * time.Millisecond
+	batch := [][]byte{}
+	var due <-chan time.Time
+	for {
+		select {
+		case msg, ok := <-s.queue:
+			if !ok {
+				b.send(s, batch)
+				return
+			}
+			batch = append(batch, msg)
+			if len(batch) == 1 {
+				due = time.After(interval)
+			}
+			if len(batch) < b.config.BatchSize {
+				continue
+			}
+		case <-due:
+		}
+		b.send(s, batch)
+		batch, due = [][]byte{}, nil
+	}
+}
+
+// send sends the batch to the sink, retrying on failures.
+func (b *bridge) send(s *sink, batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	delay := time.Duration(b.config.RetryDelay) * time.Millisecond
+	var err error
+	for i := 0; i <= b.config.Retries; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = s.send(batch); err == nil {
+			return
+		}
+	}
+	b.ctx.Log().Error().Error(err).Str("sink", s.name).Int("messages", len(batch)).Msg("failed to send the events")
+}
+
+// message encodes the message of the event.
+func (b *bridge) message(e component.Event) ([]byte, error) {
+	m := Message{
+		Type:      e.Type,
+		Host:      b.host,
+		Group:     e.Group,
+		Component: e.Component,
+		Key:       e.Key,
+		Healthy:   e.Healthy,
+		Time:      e.Time,
+		RunID:     e.RunID,
+	}
+	if e.Err != nil {
+		m.Error = e.Err.Error()
+	}
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	if m.RunID == "" {
+		m.RunID = component.RunID(b.ctx)
+	}
+	return json.Marshal(m)
+}
+
+// selected checks if the event type is one of the events.
+func selected(events []component.EventType, t component.EventType) bool {
+	for _, s := range events {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
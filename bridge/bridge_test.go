@@ -0,0 +1,155 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hook struct {
+	lock     sync.Mutex
+	batches  [][]Message
+	failures int
+}
+
+func (h *hook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failures > 0 {
+		h.failures--
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	batch := []Message{}
+	json.NewDecoder(r.Body).Decode(&batch)
+	h.batches = append(h.batches, batch)
+}
+
+// types returns the types of the messages of each batch.
+func (h *hook) types() [][]component.EventType {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	types := [][]component.EventType{}
+	for _, b := range h.batches {
+		t := []component.EventType{}
+		for _, m := range b {
+			t = append(t, m.Type)
+		}
+		types = append(types, t)
+	}
+	return types
+}
+
+func TestBridge(t *testing.T) {
+	Convey("After we create a bridge", t, func() {
+		h := &hook{}
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+		ctx := component.RootContext(zlog.New("bridge.test"))
+		b := New(ctx).(*bridge)
+		So(b.Config().Key(), ShouldEqual, "bridge")
+		b.config.Sinks = []sinkConfig{{Type: "webhook", URL: srv.URL}}
+		b.config.RetryDelay = 1
+
+		Convey("bad configuration should be rejected", func() {
+			b.config.BatchSize = 0
+			So(b.Configure(ctx), ShouldBeError)
+			b.config.BatchSize = 10
+			b.config.Sinks = []sinkConfig{{Type: "webhook", URL: srv.URL}, {Type: "amqp", URL: "amqp://mq"}}
+			err := b.Configure(ctx)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldStartWith, "sink 1: unknown sink type")
+		})
+
+		Convey("the events should be forwarded in batches", func() {
+			b.config.BatchSize = 2
+			So(b.Configure(ctx), ShouldBeNil)
+			b.OnEvent(ctx, component.Event{Type: component.EventStartFailed, Group: "srv/http", Component: "*http.server", Err: fmt.Errorf("bind failed")})
+			// Events before the start are queued
+			So(h.types(), ShouldBeEmpty)
+			So(b.Start(ctx), ShouldBeNil)
+			b.OnEvent(ctx, component.Event{Type: component.EventHealthChanged, Group: "srv"})
+			b.OnEvent(ctx, component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.types(), ShouldResemble, [][]component.EventType{
+				{component.EventStartFailed, component.EventHealthChanged},
+				{component.EventStopped},
+			})
+			m := h.batches[0][0]
+			So(m.Group, ShouldEqual, "srv/http")
+			So(m.Component, ShouldEqual, "*http.server")
+			So(m.Error, ShouldEqual, "bind failed")
+			So(m.RunID, ShouldEqual, component.RunID(ctx))
+			So(m.Time.IsZero(), ShouldBeFalse)
+
+			// Events after the stop are dropped
+			b.Forward(component.Event{Type: component.EventStarted, Group: "srv"})
+			So(h.types(), ShouldHaveLength, 2)
+		})
+
+		Convey("a partial batch should be sent after the batch interval", func() {
+			b.config.BatchInterval = 10
+			So(b.Configure(ctx), ShouldBeNil)
+			So(b.Start(ctx), ShouldBeNil)
+			b.Forward(component.Event{Type: component.EventStarted, Group: "srv"})
+			deadline := time.Now().Add(5 * time.Second)
+			for len(h.types()) == 0 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			So(h.types(), ShouldResemble, [][]component.EventType{{component.EventStarted}})
+			b.Forward(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.types(), ShouldHaveLength, 2)
+		})
+
+		Convey("the events should be filtered for each sink", func() {
+			other := &hook{}
+			srv2 := httptest.NewServer(other)
+			defer srv2.Close()
+			b.config.Sinks = append(b.config.Sinks, sinkConfig{
+				Type:   "webhook",
+				URL:    srv2.URL,
+				Events: []component.EventType{component.EventConfigFetched, component.EventStopped},
+			})
+			So(b.Configure(ctx), ShouldBeNil)
+			So(b.Start(ctx), ShouldBeNil)
+			b.Forward(component.Event{Type: component.EventConfigFetched, Group: "srv", Key: "http"})
+			b.Forward(component.Event{Type: component.EventStarted, Group: "srv"})
+			b.Forward(component.Event{Type: component.EventPanicked, Group: "srv"})
+			b.Forward(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.types(), ShouldResemble, [][]component.EventType{{component.EventStarted, component.EventStopped}})
+			So(other.types(), ShouldResemble, [][]component.EventType{{component.EventConfigFetched, component.EventStopped}})
+			So(other.batches[0][0].Key, ShouldEqual, "http")
+		})
+
+		Convey("failed batches should be retried", func() {
+			b.config.BatchSize = 1
+			So(b.Configure(ctx), ShouldBeNil)
+			So(b.Start(ctx), ShouldBeNil)
+			// The first batch fails all its attempts, the second one
+			// succeeds on its third attempt
+			h.lock.Lock()
+			h.failures = 6
+			h.lock.Unlock()
+			b.Forward(component.Event{Type: component.EventStarted, Group: "srv"})
+			b.Forward(component.Event{Type: component.EventStopping, Group: "srv"})
+			b.Forward(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.types(), ShouldResemble, [][]component.EventType{{component.EventStopping}, {component.EventStopped}})
+		})
+
+		Convey("the bridge should be disabled without sinks", func() {
+			b.config.Sinks = nil
+			So(b.Configure(ctx), ShouldBeNil)
+			So(b.Start(ctx), ShouldBeNil)
+			b.Forward(component.Event{Type: component.EventStarted, Group: "srv"})
+			b.Forward(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.types(), ShouldBeEmpty)
+		})
+	})
+}
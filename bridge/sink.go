@@ -0,0 +1,192 @@
+package bridge
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sender sends the batches of messages to a sink.
+type sender interface {
+	send(batch [][]byte) error
+	String() string
+}
+
+// newSender returns the sender of the sink configuration.
+func newSender(sc sinkConfig, timeout time.Duration) (sender, error) {
+	u, err := url.Parse(sc.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch sc.Type {
+	case "webhook":
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid webhook URL %q", sc.URL)
+		}
+		return &webhook{url: sc.URL, client: &http.Client{Timeout: timeout}}, nil
+	case "nats":
+		if u.Scheme != "nats" || u.Host == "" {
+			return nil, fmt.Errorf("invalid NATS URL %q, must be nats://host:port", sc.URL)
+		}
+		if sc.Subject == "" || strings.ContainsAny(sc.Subject, " \t\r\n") {
+			return nil, fmt.Errorf("invalid NATS subject %q", sc.Subject)
+		}
+		return &nats{url: u, subject: sc.Subject, timeout: timeout}, nil
+	case "kafka":
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("invalid Kafka REST proxy URL %q", sc.URL)
+		}
+		if sc.Topic == "" {
+			return nil, fmt.Errorf("missing Kafka topic")
+		}
+		return &kafka{url: sc.URL, topic: sc.Topic, client: &http.Client{Timeout: timeout}}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q, must be webhook, nats or kafka", sc.Type)
+}
+
+// webhook posts the batches as JSON arrays of messages.
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+func (w *webhook) send(batch [][]byte) error {
+	msgs := make([]json.RawMessage, len(batch))
+	for i, m := range batch {
+		msgs[i] = m
+	}
+	body, err := json.Marshal(msgs)
+	if err != nil {
+		return err
+	}
+	return post(w.client, w.url, "application/json", body)
+}
+
+func (w *webhook) String() string {
+	return "webhook " + w.url
+}
+
+// kafka produces the messages as the records of a topic through the v2 API
+// of a Kafka REST proxy.
+type kafka struct {
+	url    string
+	topic  string
+	client *http.Client
+}
+
+type kafkaRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+func (k *kafka) send(batch [][]byte) error {
+	records := make([]kafkaRecord, len(batch))
+	for i, m := range batch {
+		records[i].Value = m
+	}
+	body, err := json.Marshal(map[string][]kafkaRecord{"records": records})
+	if err != nil {
+		return err
+	}
+	u := strings.TrimSuffix(k.url, "/") + "/topics/" + url.PathEscape(k.topic)
+	return post(k.client, u, "application/vnd.kafka.json.v2+json", body)
+}
+
+func (k *kafka) String() string {
+	return "kafka " + k.url + " " + k.topic
+}
+
+// post posts the body and checks the status of the response.
+func post(client *http.Client, u, contentType string, body []byte) error {
+	resp, err := client.Post(u, contentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nats publishes the messages on a subject with the text protocol of NATS.
+// A connection is made for each batch, the events are not frequent enough to
+// keep one open.
+type nats struct {
+	url     *url.URL
+	subject string
+	timeout time.Duration
+}
+
+// natsConnect is the CONNECT options of the client.
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+}
+
+func (n *nats) send(batch [][]byte) error {
+	conn, err := net.DialTimeout("tcp", n.url.Host, n.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(n.timeout))
+	r := bufio.NewReader(conn)
+	// The server greets the client with its INFO
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+
+	opts := natsConnect{Name: "cube-bridge"}
+	if n.url.User != nil {
+		opts.User = n.url.User.Username()
+		opts.Pass, _ = n.url.User.Password()
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "CONNECT %s\r\n", connect)
+	for _, m := range batch {
+		fmt.Fprintf(buf, "PUB %s %d\r\n%s\r\n", n.subject, len(m), m)
+	}
+	// The PONG tells that the server processed the messages
+	buf.WriteString("PING\r\n")
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (n *nats) String() string {
+	return "nats " + n.url.Host + " " + n.subject
+}
@@ -0,0 +1,131 @@
+package bridge
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// natsServer is a NATS server speaking enough of the protocol to receive
+// the published messages.
+type natsServer struct {
+	l        net.Listener
+	connects chan string
+	pubs     chan string
+	reply    string
+}
+
+func newNATSServer(reply string) *natsServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(err)
+	}
+	s := &natsServer{l: l, connects: make(chan string, 8), pubs: make(chan string, 8), reply: reply}
+	go s.serve()
+	return s
+}
+
+func (s *natsServer) serve() {
+	for {
+		conn, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	io.WriteString(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		switch f[0] {
+		case "CONNECT":
+			s.connects <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+		case "PUB":
+			n, _ := strconv.Atoi(f[2])
+			payload := make([]byte, n+2)
+			io.ReadFull(r, payload)
+			s.pubs <- f[1] + " " + string(payload[:n])
+		case "PING":
+			io.WriteString(conn, s.reply)
+		}
+	}
+}
+
+func TestSinks(t *testing.T) {
+	Convey("Invalid sinks should be rejected", t, func() {
+		for _, sc := range []sinkConfig{
+			{Type: "webhook", URL: "ftp://host"},
+			{Type: "nats", URL: "http://host:4222", Subject: "events"},
+			{Type: "nats", URL: "nats://host:4222"},
+			{Type: "nats", URL: "nats://host:4222", Subject: "fleet events"},
+			{Type: "kafka", URL: "http://proxy:8082"},
+			{Type: "kafka", URL: "kafka://broker:9092", Topic: "events"},
+			{Type: "amqp", URL: "amqp://mq"},
+		} {
+			_, err := newSender(sc, time.Second)
+			So(err, ShouldBeError)
+		}
+	})
+
+	Convey("The Kafka sink should produce the records through the REST proxy", t, func() {
+		var path, contentType string
+		var body map[string][]map[string]map[string]string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+			b, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(b, &body)
+		}))
+		defer srv.Close()
+		s, err := newSender(sinkConfig{Type: "kafka", URL: srv.URL + "/", Topic: "life cycle"}, time.Second)
+		So(err, ShouldBeNil)
+		So(s.send([][]byte{[]byte(`{"type":"started"}`), []byte(`{"type":"stopped"}`)}), ShouldBeNil)
+		So(path, ShouldEqual, "/topics/life cycle")
+		So(contentType, ShouldEqual, "application/vnd.kafka.json.v2+json")
+		So(body["records"], ShouldResemble, []map[string]map[string]string{
+			{"value": {"type": "started"}},
+			{"value": {"type": "stopped"}},
+		})
+	})
+
+	Convey("The NATS sink should publish the messages on the subject", t, func() {
+		srv := newNATSServer("PONG\r\n")
+		defer srv.l.Close()
+		s, err := newSender(sinkConfig{Type: "nats", URL: "nats://ops:secret@" + srv.l.Addr().String(), Subject: "fleet.lifecycle"}, time.Second)
+		So(err, ShouldBeNil)
+		So(s.send([][]byte{[]byte(`{"type":"started"}`), []byte(`{"type":"stopped"}`)}), ShouldBeNil)
+		connect := map[string]interface{}{}
+		So(json.Unmarshal([]byte(<-srv.connects), &connect), ShouldBeNil)
+		So(connect["user"], ShouldEqual, "ops")
+		So(connect["pass"], ShouldEqual, "secret")
+		So(<-srv.pubs, ShouldEqual, `fleet.lifecycle {"type":"started"}`)
+		So(<-srv.pubs, ShouldEqual, `fleet.lifecycle {"type":"stopped"}`)
+
+		Convey("the errors of the server should be returned", func() {
+			srv := newNATSServer("-ERR 'Authorization Violation'\r\n")
+			defer srv.l.Close()
+			s, err := newSender(sinkConfig{Type: "nats", URL: "nats://" + srv.l.Addr().String(), Subject: "fleet.lifecycle"}, time.Second)
+			So(err, ShouldBeNil)
+			err = s.send([][]byte{[]byte(`{}`)})
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldEqual, "NATS error: 'Authorization Violation'")
+		})
+	})
+}
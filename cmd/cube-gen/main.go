@@ -0,0 +1,298 @@
+// Command cube-gen generates the static callers of the constructors of a
+// package, see di.RegisterCaller. The containers invoke the constructors
+// through the generated callers instead of reflection, which speeds up the
+// startup of the server and keeps the constructors in the stack traces.
+//
+// The constructors are the functions of the package passed to the methods
+// adding them to a container or a group, e.g. g.Add(NewServer), and the
+// functions listed with -funcs. Variadic functions and constructors of other
+// packages are left out and still invoked with reflection.
+//
+// Usage:
+//
+//	cube-gen [-o file] [-funcs NewA,NewB] [dir]
+//
+// The callers are written to cube_gen.go in the package directory by default,
+// e.g. with
+//
+//	//go:generate cube-gen
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const diPath = "github.com/anuvu/cube/di"
+
+// registers are the names of the functions, other than the ones starting
+// with Add, that add a function to a container.
+var registers = map[string]bool{
+	"Bind":      true,
+	"Decorate":  true,
+	"Replace":   true,
+	"Invoke":    true,
+	"InvokeCtx": true,
+	"Provide":   true,
+}
+
+func main() {
+	out := flag.String("o", "", "output `file`, cube_gen.go in the package directory by default")
+	funcs := flag.String("funcs", "", "comma separated `names` of other functions to generate callers for")
+	flag.Parse()
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "cube_gen.go")
+	}
+	extra := []string{}
+	for _, f := range strings.Split(*funcs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			extra = append(extra, f)
+		}
+	}
+
+	src, err := generate(dir, filepath.Base(*out), extra)
+	if err == nil {
+		err = ioutil.WriteFile(*out, src, 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cube-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// constructor is a function of the package to generate a caller for.
+type constructor struct {
+	name    string
+	params  []string
+	results []string
+}
+
+// generate returns the source of the callers of the constructors of the
+// package in the directory, skipping the output file.
+func generate(dir, skip string, extra []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != skip
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %s, found %d", dir, len(pkgs))
+	}
+	var pkg *ast.Package
+	for _, p := range pkgs {
+		pkg = p
+	}
+	names := make([]string, 0, len(pkg.Files))
+	for n := range pkg.Files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	decls := map[string]*ast.FuncDecl{}
+	files := map[string]*ast.File{}
+	for _, n := range names {
+		f := pkg.Files[n]
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name != "init" && fd.Name.Name != "main" {
+				decls[fd.Name.Name] = fd
+				files[fd.Name.Name] = f
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	for _, n := range extra {
+		if decls[n] == nil {
+			return nil, fmt.Errorf("function %s not found in package %s", n, pkg.Name)
+		}
+		used[n] = true
+	}
+	for _, n := range names {
+		ast.Inspect(pkg.Files[n], func(n ast.Node) bool {
+			c, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if name := callName(c.Fun); !strings.HasPrefix(name, "Add") && !registers[name] {
+				return true
+			}
+			for _, a := range c.Args {
+				id, ok := a.(*ast.Ident)
+				if !ok || decls[id.Name] == nil || (id.Obj != nil && id.Obj.Kind != ast.Fun) {
+					continue
+				}
+				used[id.Name] = true
+			}
+			return true
+		})
+	}
+	if len(used) == 0 {
+		return nil, fmt.Errorf("no constructors found in package %s", pkg.Name)
+	}
+
+	imports := map[string]string{"di": diPath}
+	ctrs := []constructor{}
+	for n := range used {
+		d := decls[n]
+		if isVariadic(d.Type) {
+			continue
+		}
+		ctr := constructor{name: n}
+		var err error
+		if ctr.params, err = fieldTypes(d.Type.Params, files[n], imports); err != nil {
+			return nil, fmt.Errorf("%s: %v", n, err)
+		}
+		// The results are not converted, their packages are not imported
+		if ctr.results, err = fieldTypes(d.Type.Results, files[n], nil); err != nil {
+			return nil, fmt.Errorf("%s: %v", n, err)
+		}
+		ctrs = append(ctrs, ctr)
+	}
+	sort.Slice(ctrs, func(i, j int) bool { return ctrs[i].name < ctrs[j].name })
+
+	return render(pkg.Name, imports, ctrs)
+}
+
+// callName returns the name of the called function or method.
+func callName(fun ast.Expr) string {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name
+	case *ast.SelectorExpr:
+		return f.Sel.Name
+	case *ast.IndexExpr:
+		// Instantiated generic function, e.g. di.Provide[T]
+		return callName(f.X)
+	}
+	return ""
+}
+
+func isVariadic(ft *ast.FuncType) bool {
+	l := ft.Params.List
+	if len(l) == 0 {
+		return false
+	}
+	_, ok := l[len(l)-1].Type.(*ast.Ellipsis)
+	return ok
+}
+
+// fieldTypes returns the types of the fields, one per parameter or result,
+// and adds the packages they refer to to the imports if not nil.
+func fieldTypes(fl *ast.FieldList, f *ast.File, imports map[string]string) ([]string, error) {
+	ts := []string{}
+	if fl == nil {
+		// No results
+		return ts, nil
+	}
+	for _, field := range fl.List {
+		var err error
+		ast.Inspect(field.Type, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || err != nil || imports == nil {
+				return err == nil
+			}
+			if id, ok := sel.X.(*ast.Ident); ok {
+				err = addImport(id.Name, f, imports)
+			}
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+		n := len(field.Names)
+		if n == 0 {
+			n = 1
+		}
+		for i := 0; i < n; i++ {
+			ts = append(ts, types.ExprString(field.Type))
+		}
+	}
+	return ts, nil
+}
+
+// addImport adds the package imported by the file under the name.
+func addImport(name string, f *ast.File, imports map[string]string) error {
+	for _, spec := range f.Imports {
+		p, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return err
+		}
+		n := importName(p)
+		if spec.Name != nil {
+			n = spec.Name.Name
+		}
+		if n != name {
+			continue
+		}
+		if q, ok := imports[name]; ok && q != p {
+			return fmt.Errorf("package name %s refers to both %s and %s", name, q, p)
+		}
+		imports[name] = p
+		return nil
+	}
+	return fmt.Errorf("no import found for package %s", name)
+}
+
+// importName guesses the name of the package from its path, e.g. yaml for
+// gopkg.in/yaml.v2 or zlog for github.com/anuvu/zlog.
+func importName(p string) string {
+	n := path.Base(p)
+	if i := strings.Index(n, ".v"); i > 0 {
+		n = n[:i]
+	}
+	return strings.TrimPrefix(n, "go-")
+}
+
+func render(pkg string, imports map[string]string, ctrs []constructor) ([]byte, error) {
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by cube-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	names := make([]string, 0, len(imports))
+	for n := range imports {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool { return imports[names[i]] < imports[names[j]] })
+	for _, n := range names {
+		fmt.Fprintf(b, "\t%s %q\n", n, imports[n])
+	}
+	b.WriteString(")\n\nfunc init() {\n")
+	for _, c := range ctrs {
+		fmt.Fprintf(b, "\tdi.RegisterCaller(%s, func(args []interface{}) []interface{} {\n", c.name)
+		args := make([]string, len(c.params))
+		for i, t := range c.params {
+			args[i] = fmt.Sprintf("a%d", i)
+			fmt.Fprintf(b, "\t\ta%d, _ := args[%d].(%s)\n", i, i, t)
+		}
+		rets := make([]string, len(c.results))
+		for i := range c.results {
+			rets[i] = fmt.Sprintf("r%d", i)
+		}
+		callExpr := fmt.Sprintf("%s(%s)", c.name, strings.Join(args, ", "))
+		if len(rets) == 0 {
+			fmt.Fprintf(b, "\t\t%s\n\t\treturn nil\n", callExpr)
+		} else {
+			fmt.Fprintf(b, "\t\t%s := %s\n\t\treturn []interface{}{%s}\n", strings.Join(rets, ", "), callExpr, strings.Join(rets, ", "))
+		}
+		b.WriteString("\t})\n")
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const serverSrc = `package server
+
+import (
+	"net/http"
+
+	"github.com/anuvu/cube/component"
+	zl "github.com/anuvu/zlog"
+)
+
+type Server struct{}
+
+func NewServer(ctx component.Context, l zl.Logger) (*Server, error) { return nil, nil }
+
+func NewMux(a, b int) *http.ServeMux { return nil }
+
+func NewMany(names ...string) *Server { return nil }
+
+func register(s *Server) {}
+
+func helper() {}
+
+func Init(g component.Group) error {
+	g.Add(NewServer)
+	g.(component.Registry).AddToGroup("muxes", NewMux)
+	g.Add(NewMany)
+	return g.Invoke(register)
+}
+`
+
+const serverGen = `// Code generated by cube-gen. DO NOT EDIT.
+
+package server
+
+import (
+	component "github.com/anuvu/cube/component"
+	di "github.com/anuvu/cube/di"
+	zl "github.com/anuvu/zlog"
+)
+
+func init() {
+	di.RegisterCaller(NewMux, func(args []interface{}) []interface{} {
+		a0, _ := args[0].(int)
+		a1, _ := args[1].(int)
+		r0 := NewMux(a0, a1)
+		return []interface{}{r0}
+	})
+	di.RegisterCaller(NewServer, func(args []interface{}) []interface{} {
+		a0, _ := args[0].(component.Context)
+		a1, _ := args[1].(zl.Logger)
+		r0, r1 := NewServer(a0, a1)
+		return []interface{}{r0, r1}
+	})
+	di.RegisterCaller(helper, func(args []interface{}) []interface{} {
+		helper()
+		return nil
+	})
+	di.RegisterCaller(register, func(args []interface{}) []interface{} {
+		a0, _ := args[0].(*Server)
+		register(a0)
+		return nil
+	})
+}
+`
+
+func TestGenerate(t *testing.T) {
+	Convey("After we write a package adding constructors to a group", t, func() {
+		dir, err := ioutil.TempDir("", "cube-gen")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		So(ioutil.WriteFile(filepath.Join(dir, "server.go"), []byte(serverSrc), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "server_test.go"), []byte("package server_test\n"), 0644), ShouldBeNil)
+		So(ioutil.WriteFile(filepath.Join(dir, "cube_gen.go"), []byte("package stale\n"), 0644), ShouldBeNil)
+
+		Convey("the callers of the added functions should be generated", func() {
+			src, err := generate(dir, "cube_gen.go", []string{"helper"})
+			So(err, ShouldBeNil)
+			So(string(src), ShouldEqual, serverGen)
+		})
+
+		Convey("unknown functions should be reported", func() {
+			_, err := generate(dir, "cube_gen.go", []string{"missing"})
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("a package without constructors should be reported", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "server.go"), []byte("package server\n\nfunc f() {}\n"), 0644), ShouldBeNil)
+			_, err := generate(dir, "cube_gen.go", nil)
+			So(err, ShouldNotBeNil)
+		})
+	})
+
+	Convey("The package names should be guessed from the import paths", t, func() {
+		So(importName("gopkg.in/yaml.v2"), ShouldEqual, "yaml")
+		So(importName("github.com/mattn/go-isatty"), ShouldEqual, "isatty")
+		So(importName("net/http"), ShouldEqual, "http")
+	})
+}
@@ -0,0 +1,166 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/di"
+)
+
+// Usage is the resource usage attributed to a component.
+type Usage struct {
+	// Group is the path of the group of the component
+	Group string `json:"group"`
+	// Component is the type of the component
+	Component string `json:"component"`
+	// Goroutines is the number of running goroutines started by the
+	// component with Runner.Go
+	Goroutines int `json:"goroutines"`
+	// Started is the number of goroutines started by the component with
+	// Runner.Go since the server started
+	Started int `json:"started"`
+	// Allocated is the number of bytes allocated by the lifecycle hooks of
+	// the component
+	Allocated uint64 `json:"allocated_bytes"`
+	// Allocs is the number of heap objects allocated by the lifecycle hooks
+	// of the component
+	Allocs uint64 `json:"allocs"`
+}
+
+// accounting is the configuration of the resource accounting stored under
+// the "accounting" key, e.g.
+//
+//	"accounting": {"enabled": true}
+//
+// The accounting is disabled by default. Once enabled, the lifecycle hooks
+// and the goroutines started with Runner.Go run with the "group" and the
+// "component" pprof labels, so that the CPU and goroutine profiles can be
+// filtered by component, and their usage is reported by Group.Usage.
+//
+// The allocations are measured around the lifecycle hooks, which are called
+// one at a time, and include the allocations of the goroutines running at
+// the same time. The allocations of the goroutines of the components are
+// only found in the profiles.
+type accounting struct {
+	Enabled bool `json:"enabled"`
+	lock    sync.Mutex
+	usage   []*usageState
+}
+
+func (a *accounting) Key() config.Key {
+	return "accounting"
+}
+
+// usageState tracks the usage of a component.
+type usageState struct {
+	lock   sync.Mutex
+	usage  Usage
+	labels pprof.LabelSet
+}
+
+// usageOf returns the usage state of the component of the group.
+func (a *accounting) usageOf(group, cmp string) *usageState {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, u := range a.usage {
+		if u.usage.Group == group && u.usage.Component == cmp {
+			return u
+		}
+	}
+	u := &usageState{
+		usage:  Usage{Group: group, Component: cmp},
+		labels: pprof.Labels("group", group, "component", cmp),
+	}
+	a.usage = append(a.usage, u)
+	return u
+}
+
+func (u *usageState) started(delta int) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.usage.Goroutines += delta
+	if delta > 0 {
+		u.usage.Started += delta
+	}
+}
+
+func (u *usageState) allocated(bytes, objects uint64) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.usage.Allocated += bytes
+	u.usage.Allocs += objects
+}
+
+// Usage returns the resource usage of the components of the group and its
+// sub-groups, in the order their lifecycle hooks were first called. It is
+// empty unless the accounting is enabled in the "accounting" configuration.
+func (g *group) Usage() []Usage {
+	path := g.path()
+	g.acct.lock.Lock()
+	defer g.acct.lock.Unlock()
+	usage := []Usage{}
+	for _, u := range g.acct.usage {
+		if u.usage.Group != path && !strings.HasPrefix(u.usage.Group, path+"/") {
+			continue
+		}
+		u.lock.Lock()
+		usage = append(usage, u.usage)
+		u.lock.Unlock()
+	}
+	return usage
+}
+
+// UsageHandler returns an admin handler that reports the resource usage of
+// the components of the group as JSON.
+func UsageHandler(g Inspector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(g.Usage())
+	})
+}
+
+// accounted calls the lifecycle hook of the component with the context of
+// the component, accounting its usage if enabled.
+func (g *group) accounted(cmp interface{}, hook func(ctx *srvCtx) error) error {
+	if !g.acct.Enabled {
+		return hook(g.ctx)
+	}
+	u := g.acct.usageOf(g.path(), componentName(cmp))
+	ctx := g.ctx.accounted(u)
+	var before, after runtime.MemStats
+	var err error
+	runtime.ReadMemStats(&before)
+	pprof.Do(g.ctx.ctx, u.labels, func(context.Context) {
+		err = hook(ctx)
+	})
+	runtime.ReadMemStats(&after)
+	u.allocated(after.TotalAlloc-before.TotalAlloc, after.Mallocs-before.Mallocs)
+	return err
+}
+
+// invokeHook invokes the lifecycle hook function with the context, the
+// Context dependency of the function is the context of the component when
+// the usage is accounted.
+func (g *group) invokeHook(ctx *srvCtx, f interface{}) error {
+	if ctx == g.ctx {
+		return g.c.Invoke(f, nil)
+	}
+	c := di.New(g.c, ctxType)
+	if err := c.Add(func() Context { return ctx }); err != nil {
+		return err
+	}
+	if err := c.Create(nil); err != nil {
+		return err
+	}
+	return c.Invoke(f, nil)
+}
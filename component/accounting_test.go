@@ -0,0 +1,75 @@
+package component
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"runtime/pprof"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hungryCmp struct {
+	buf    [][]byte
+	labels chan string
+}
+
+func (h *hungryCmp) Start(ctx Context) error {
+	for i := 0; i < 16; i++ {
+		h.buf = append(h.buf, make([]byte, 4096))
+	}
+	Go(ctx, func(ctx Context) error {
+		cmp, _ := pprof.Label(ctx.Ctx(), "component")
+		h.labels <- cmp
+		<-ctx.Ctx().Done()
+		return nil
+	})
+	return nil
+}
+
+func TestAccounting(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group with a component starting goroutines", t, func() {
+		root := New("base").(*group)
+		h := &hungryCmp{labels: make(chan string, 1)}
+		So(root.New("app").Add(func() *hungryCmp { return h }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+
+		Convey("the usage should not be accounted by default", func() {
+			os.Args = []string{"accounting.test"}
+			So(root.Configure(), ShouldBeNil)
+			So(root.Start(), ShouldBeNil)
+			So(<-h.labels, ShouldBeEmpty)
+			So(root.Stop(), ShouldBeNil)
+			So(root.Usage(), ShouldBeEmpty)
+		})
+
+		Convey("the usage should be attributed to the component once enabled", func() {
+			os.Args = []string{"accounting.test", "--config.mem", `{"accounting": {"enabled": true}}`}
+			So(root.Configure(), ShouldBeNil)
+			So(root.Start(), ShouldBeNil)
+			So(<-h.labels, ShouldEqual, "*component.hungryCmp")
+
+			usage := root.Usage()
+			So(usage, ShouldHaveLength, 1)
+			So(usage[0].Group, ShouldEqual, "base/app")
+			So(usage[0].Component, ShouldEqual, "*component.hungryCmp")
+			So(usage[0].Goroutines, ShouldEqual, 1)
+			So(usage[0].Started, ShouldEqual, 1)
+			So(usage[0].Allocated, ShouldBeGreaterThanOrEqualTo, 16*4096)
+			So(usage[0].Allocs, ShouldBeGreaterThanOrEqualTo, 16)
+
+			w := httptest.NewRecorder()
+			UsageHandler(root).ServeHTTP(w, httptest.NewRequest("GET", "/usage", nil))
+			reported := []Usage{}
+			So(json.Unmarshal(w.Body.Bytes(), &reported), ShouldBeNil)
+			So(reported, ShouldResemble, usage)
+
+			So(root.Stop(), ShouldBeNil)
+			So(root.Usage()[0].Goroutines, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,144 @@
+package component
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/anuvu/cube/config"
+)
+
+// AddAlternative registers the constructor as the named alternative
+// implementation selected by the config key. The key is a dotted path where
+// the first element is the config store key, e.g. "storage.backend" selects
+// the alternative named by the backend field of the storage configuration.
+//
+// Exactly one alternative per key is activated when the group hierarchy is
+// created. Create fails if the selection is missing from the configuration or
+// does not name a registered alternative.
+func (g *group) AddAlternative(key string, name string, ctr interface{}) error {
+	return g.c.AddAlternative(key, name, ctr)
+}
+
+// hasAlternatives checks if any group in the hierarchy has alternatives.
+func (g *group) hasAlternatives() bool {
+	if len(g.c.Alternatives()) > 0 {
+		return true
+	}
+	for _, child := range g.children {
+		if child.hasAlternatives() {
+			return true
+		}
+	}
+	return false
+}
+
+// selectAlternatives opens the config store ahead of Configure and selects
+// the alternatives of all groups in the hierarchy.
+func (g *group) selectAlternatives() error {
+	// Only the flags known at this point are parsed, the command line is
+	// parsed again by Configure once all components registered their flags.
+	if err := g.cli.Parse(knownArgs(g.cli, os.Args[1:])); err != nil {
+		return err
+	}
+	if err := g.store.Open(); err != nil {
+		return err
+	}
+	defer g.store.Close()
+	return g.selectGroupAlternatives()
+}
+
+func (g *group) selectGroupAlternatives() error {
+	for _, key := range g.c.Alternatives() {
+		sel := newSelection(key)
+		if err := g.store.Get(g.prefixed(sel)); err != nil {
+			return fmt.Errorf("no selection for alternative %s: %v", key, err)
+		}
+		if err := g.c.Select(key, sel.value); err != nil {
+			return err
+		}
+		g.ctx.Log().Info().Str("key", key).Str("alternative", sel.value).Msg("selected alternative")
+	}
+	for _, child := range g.children {
+		if err := child.selectGroupAlternatives(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knownArgs returns the arguments that are flags defined in the flag set
+// along with their values. Unknown flags are dropped.
+func knownArgs(fs *flag.FlagSet, args []string) []string {
+	known := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		hasValue := false
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name = name[:eq]
+			hasValue = true
+		}
+		f := fs.Lookup(name)
+		isBool := false
+		if f != nil {
+			if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+				isBool = bf.IsBoolFlag()
+			}
+		}
+		// Consume the value of the flag if it is a separate argument
+		next := !hasValue && !isBool && i+1 < len(args) && (f != nil || !strings.HasPrefix(args[i+1], "-"))
+		if f != nil {
+			known = append(known, arg)
+			if next {
+				known = append(known, args[i+1])
+			}
+		}
+		if next {
+			i++
+		}
+	}
+	return known
+}
+
+// selection is the configuration object that captures the name of the
+// selected alternative.
+type selection struct {
+	key   config.Key
+	path  []string
+	value string
+}
+
+func newSelection(key string) *selection {
+	parts := strings.Split(key, ".")
+	return &selection{key: config.Key(parts[0]), path: parts[1:]}
+}
+
+func (s *selection) Key() config.Key {
+	return s.key
+}
+
+func (s *selection) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	for _, p := range s.path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s.%s is not an object", s.key, p)
+		}
+		v = m[p]
+	}
+	str, ok := v.(string)
+	if !ok || str == "" {
+		return fmt.Errorf("selection %s must be a non empty string", s.key)
+	}
+	s.value = str
+	return nil
+}
@@ -0,0 +1,89 @@
+package component
+
+import (
+	"flag"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type backend interface {
+	Name() string
+}
+
+type namedBackend string
+
+func (b namedBackend) Name() string { return string(b) }
+
+func TestAlternatives(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	addBackends := func(g Group) {
+		r := g.(Registry)
+		So(r.AddAlternative("storage.backend", "s3", func() backend { return namedBackend("s3") }), ShouldBeNil)
+		So(r.AddAlternative("storage.backend", "fs", func() backend { return namedBackend("fs") }), ShouldBeNil)
+	}
+
+	Convey("After we create a group hierarchy with alternatives", t, func() {
+		Convey("the configured alternative should be created", func() {
+			os.Args = []string{"group.test", "--unknown", "x", "--config.mem", `{"storage": {"backend": "fs"}}`}
+			root := New("root")
+			child := root.New("child")
+			addBackends(child)
+			So(root.Create(), ShouldBeNil)
+			So(child.Invoke(func(b backend) {
+				So(b.Name(), ShouldEqual, "fs")
+			}), ShouldBeNil)
+		})
+
+		Convey("missing selection should fail", func() {
+			os.Args = []string{"group.test", "--config.mem", `{"storage": {}}`}
+			root := New("root")
+			addBackends(root)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("missing config should fail", func() {
+			os.Args = []string{"group.test"}
+			root := New("root")
+			addBackends(root)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("unknown selection should fail", func() {
+			os.Args = []string{"group.test", "--config.mem", `{"storage": {"backend": "gcs"}}`}
+			root := New("root")
+			addBackends(root)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("bad selection should fail", func() {
+			os.Args = []string{"group.test", "--config.mem", `{"storage": "fs"}`}
+			root := New("root")
+			addBackends(root)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("bad store should fail", func() {
+			os.Args = []string{"group.test", "--config.file", "bad_file_name"}
+			root := New("root")
+			addBackends(root)
+			So(root.Create(), ShouldBeError)
+		})
+	})
+}
+
+func TestKnownArgs(t *testing.T) {
+	Convey("Only known flags should be retained", t, func() {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("a", "", "")
+		fs.Bool("b", false, "")
+		So(knownArgs(fs, []string{"-x", "1", "-a", "2", "--b", "-y=3", "--a=4", "-z", "pos"}),
+			ShouldResemble, []string{"-a", "2", "--b", "--a=4"})
+		So(knownArgs(fs, []string{"pos", "-a", "1"}), ShouldBeEmpty)
+		So(knownArgs(fs, []string{"--", "-a", "1"}), ShouldBeEmpty)
+		So(knownArgs(fs, []string{"-x", "-a", "1"}), ShouldResemble, []string{"-a", "1"})
+	})
+}
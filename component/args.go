@@ -0,0 +1,36 @@
+package component
+
+// Args provides the positional command line arguments that remain after the
+// flags are parsed. The first positional argument is treated as the
+// subcommand, so that tools built on cube can act on commands, file paths or
+// resource names passed on the command line.
+//
+// Args is provided by the root group and can be a dependency of any
+// component. The command line is parsed at the beginning of Configure, so the
+// arguments are available in the configure, start and stop hooks and in
+// functions invoked after Configure.
+type Args struct {
+	args []string
+}
+
+// Command returns the subcommand, which is the first positional argument, or
+// an empty string if there are no positional arguments.
+func (a *Args) Command() string {
+	if len(a.args) == 0 {
+		return ""
+	}
+	return a.args[0]
+}
+
+// Args returns the positional arguments following the subcommand.
+func (a *Args) Args() []string {
+	if len(a.args) == 0 {
+		return []string{}
+	}
+	return a.args[1:]
+}
+
+// All returns all the positional arguments including the subcommand.
+func (a *Args) All() []string {
+	return a.args
+}
@@ -0,0 +1,56 @@
+package component
+
+import (
+	"os"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type argsCmp struct {
+	args    *Args
+	command string
+}
+
+func (a *argsCmp) Config() config.Config {
+	return nil
+}
+
+func (a *argsCmp) Configure(ctx Context) error {
+	a.command = a.args.Command()
+	return nil
+}
+
+func TestArgs(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group with a component depending on the args", t, func() {
+		grp := New("base")
+		child := grp.New("child")
+		So(child.Add(func(args *Args) *argsCmp { return &argsCmp{args: args} }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the positional args should be available after configure", func() {
+			os.Args = []string{"args.test", "--config.mem", "{}", "copy", "src", "dst"}
+			So(grp.Configure(), ShouldBeNil)
+			child.Invoke(func(a *argsCmp, args *Args) {
+				So(a.command, ShouldEqual, "copy")
+				So(args.Command(), ShouldEqual, "copy")
+				So(args.Args(), ShouldResemble, []string{"src", "dst"})
+				So(args.All(), ShouldResemble, []string{"copy", "src", "dst"})
+			})
+		})
+
+		Convey("the args should be empty without positional arguments", func() {
+			os.Args = []string{"args.test"}
+			So(grp.Configure(), ShouldBeNil)
+			child.Invoke(func(a *argsCmp, args *Args) {
+				So(a.command, ShouldEqual, "")
+				So(args.Args(), ShouldBeEmpty)
+				So(args.All(), ShouldBeEmpty)
+			})
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package component
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Budget defines the resource limits of a group. A zero value disables the
+// corresponding limit. A group exceeding its budget reports itself unhealthy,
+// giving an early warning before the process runs out of resources.
+type Budget struct {
+	// MaxGoroutines limits the goroutines started by the group using
+	// Runner.Go that are still running.
+	MaxGoroutines int
+
+	// MaxMemory limits the heap memory in use in bytes. Memory is sampled
+	// for the whole process as the runtime does not attribute allocations to
+	// groups, so this is an estimate shared by all groups.
+	MaxMemory uint64
+
+	// MaxConnections limits the sum of the open connections reported by the
+	// components of the group implementing ConnectionHook.
+	MaxConnections int
+}
+
+// ConnectionHook is the interface that provides the number of open connections
+// of the component for budget enforcement.
+type ConnectionHook interface {
+	OpenConnections() int
+}
+
+// SetBudget sets the resource budget of the group.
+func (g *group) SetBudget(b Budget) {
+	g.budget = b
+}
+
+// checkBudget returns an error describing the first limit of the budget that
+// is exceeded.
+func (g *group) checkBudget() error {
+	b := g.budget
+	if b.MaxGoroutines > 0 {
+		if n := g.ctx.tasks.count(); n > b.MaxGoroutines {
+			return fmt.Errorf("%d goroutines exceed the budget of %d", n, b.MaxGoroutines)
+		}
+	}
+	if b.MaxMemory > 0 {
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		if ms.HeapInuse > b.MaxMemory {
+			return fmt.Errorf("%d bytes of memory exceed the budget of %d", ms.HeapInuse, b.MaxMemory)
+		}
+	}
+	if b.MaxConnections > 0 {
+		n := 0
+		for _, h := range g.connHooks {
+			n += h.OpenConnections()
+		}
+		if n > b.MaxConnections {
+			return fmt.Errorf("%d connections exceed the budget of %d", n, b.MaxConnections)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package component
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type connCounter struct {
+	n int
+}
+
+func (c *connCounter) OpenConnections() int { return c.n }
+
+func TestBudget(t *testing.T) {
+	Convey("After we create a group with a budget", t, func() {
+		grp := New("base").(*group)
+		cc := &connCounter{}
+		So(grp.Add(func() *connCounter { return cc }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+		So(grp.IsHealthy(), ShouldBeTrue)
+
+		Convey("goroutines over the budget should degrade health", func() {
+			grp.SetBudget(Budget{MaxGoroutines: 1})
+			release := make(chan struct{})
+			grp.Invoke(func(ctx Context) {
+				for i := 0; i < 2; i++ {
+					Go(ctx, func(Context) error {
+						<-release
+						return nil
+					})
+				}
+			})
+			So(grp.IsHealthy(), ShouldBeFalse)
+			close(release)
+			So(grp.Stop(), ShouldBeNil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+		})
+
+		Convey("connections over the budget should degrade health", func() {
+			grp.SetBudget(Budget{MaxConnections: 2})
+			cc.n = 2
+			So(grp.IsHealthy(), ShouldBeTrue)
+			cc.n = 3
+			So(grp.IsHealthy(), ShouldBeFalse)
+		})
+
+		Convey("memory over the budget should degrade health", func() {
+			grp.SetBudget(Budget{MaxMemory: 1})
+			So(grp.IsHealthy(), ShouldBeFalse)
+			grp.SetBudget(Budget{MaxMemory: 1 << 40})
+			So(grp.IsHealthy(), ShouldBeTrue)
+		})
+	})
+}
@@ -2,6 +2,14 @@ package component
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"runtime/debug"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/anuvu/zlog"
 )
@@ -10,12 +18,79 @@ import (
 //
 // Ctx() returns the underlying go context.
 //
-// Log() returns the group's logger
+// Log() returns the group's logger, which stamps the run ID on its events.
+//
+// The contexts of the groups also implement the Runner and RunIdentifier
+// interfaces, see Go and RunID.
 type Context interface {
 	Ctx() context.Context
 	Log() zlog.Logger
 }
 
+// Runner is implemented by the contexts running goroutines tracked by their
+// group.
+//
+// Go() runs a goroutine tracked by the group. Panics in the goroutine are
+// recovered like Recover does, errors are handled as per the error policy and
+// the group waits for the goroutine to exit when it is stopped. The context passed
+// to the goroutine is cancelled when the group is stopped.
+type Runner interface {
+	Go(f func(ctx Context) error, policy ...ErrorPolicy)
+}
+
+// RunIdentifier is implemented by the contexts identifying the run of the
+// server.
+//
+// RunID() returns the unique identifier of this run of the server.
+type RunIdentifier interface {
+	RunID() string
+}
+
+// Go runs the goroutine with the Runner of the context, see Runner. If the
+// context is not a Runner, the goroutine is not tracked: its panic is
+// recovered and its error is logged.
+func Go(ctx Context, f func(ctx Context) error, policy ...ErrorPolicy) {
+	if r, ok := ctx.(Runner); ok {
+		r.Go(f, policy...)
+		return
+	}
+	go func() {
+		defer Recover(ctx)
+		if err := f(ctx); err != nil {
+			ctx.Log().Error().Error(err).Msg("goroutine failed")
+		}
+	}()
+}
+
+// RunID returns the identifier of the run of the server of the context, empty
+// if the context is not a RunIdentifier.
+func RunID(ctx Context) string {
+	if r, ok := ctx.(RunIdentifier); ok {
+		return r.RunID()
+	}
+	return ""
+}
+
+// RunIDEnv is the environment variable that overrides the generated run ID, so
+// that orchestrators can correlate the run with their own records.
+const RunIDEnv = "CUBE_RUN_ID"
+
+// ErrorPolicy defines how a group handles an error returned by, or a panic
+// raised in, a goroutine started with Runner.Go. A panic also degrades the
+// group as per the "panics" configuration, see Recover.
+type ErrorPolicy int
+
+const (
+	// LogOnError logs the error.
+	LogOnError ErrorPolicy = iota
+
+	// DegradeOnError logs the error and marks the group unhealthy.
+	DegradeOnError
+
+	// ShutdownOnError logs the error and initiates the server shutdown.
+	ShutdownOnError
+)
+
 // Shutdown invokes the shutdown sequence
 type Shutdown func()
 
@@ -26,21 +101,118 @@ func RootContext(log zlog.Logger) Context {
 
 func newContext(p *srvCtx, log zlog.Logger) *srvCtx {
 	c := context.Background()
+	root := (*srvCtx)(nil)
 	if p != nil {
 		c = p.ctx
+		root = p.root
 	}
 	ctx, cancelFunc := context.WithCancel(c)
-	return &srvCtx{
+	sc := &srvCtx{
 		ctx:        ctx,
 		cancelFunc: cancelFunc,
 		log:        log,
+		root:       root,
+		tasks:      &tasks{},
+	}
+	if sc.root == nil {
+		sc.root = sc
+		sc.runID = newRunID()
 	}
+	sc.log = withRunID(log, sc.root.runID)
+	return sc
 }
 
 type srvCtx struct {
 	ctx        context.Context
 	cancelFunc context.CancelFunc
 	log        zlog.Logger
+	root       *srvCtx
+	tasks      *tasks
+	runID      string
+	usage      *usageState
+	onPanic    func(r interface{}, cmp string, err error)
+}
+
+// newRunID returns the run ID from the environment or a new random ID.
+func newRunID() string {
+	if id := os.Getenv(RunIDEnv); id != "" {
+		return id
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// runLogger is a logger that stamps the run ID on its events, so that the
+// logs of the components can be correlated with the run.
+type runLogger struct {
+	zlog.Logger
+	runID string
+}
+
+// withRunID returns the logger stamping the run ID on its events.
+func withRunID(log zlog.Logger, runID string) zlog.Logger {
+	if rl, ok := log.(*runLogger); ok {
+		log = rl.Logger
+	}
+	if log == nil {
+		return nil
+	}
+	return &runLogger{log, runID}
+}
+
+func (l *runLogger) Debug() zlog.Event {
+	return l.Logger.Debug().Str("run_id", l.runID)
+}
+
+func (l *runLogger) Info() zlog.Event {
+	return l.Logger.Info().Str("run_id", l.runID)
+}
+
+func (l *runLogger) Warn() zlog.Event {
+	return l.Logger.Warn().Str("run_id", l.runID)
+}
+
+func (l *runLogger) Error() zlog.Event {
+	return l.Logger.Error().Str("run_id", l.runID)
+}
+
+// derive returns a context derived from sc that has the deadline of ctx and is
+// cancelled with ctx.
+func (sc *srvCtx) derive(ctx context.Context) (*srvCtx, context.CancelFunc) {
+	var c context.Context
+	var cancel context.CancelFunc
+	if d, ok := ctx.Deadline(); ok {
+		c, cancel = context.WithDeadline(sc.ctx, d)
+	} else {
+		c, cancel = context.WithCancel(sc.ctx)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-c.Done():
+		}
+	}()
+	return &srvCtx{
+		ctx:        c,
+		cancelFunc: sc.cancelFunc,
+		log:        sc.log,
+		root:       sc.root,
+		tasks:      sc.tasks,
+		usage:      sc.usage,
+		onPanic:    sc.onPanic,
+	}, cancel
+}
+
+// accounted returns a copy of sc attributing the goroutines it starts to the
+// usage of a component.
+func (sc *srvCtx) accounted(u *usageState) *srvCtx {
+	c := *sc
+	c.usage = u
+	return &c
 }
 
 func (sc *srvCtx) Ctx() context.Context {
@@ -54,3 +226,129 @@ func (sc *srvCtx) Shutdown() {
 func (sc *srvCtx) Log() zlog.Logger {
 	return sc.log
 }
+
+func (sc *srvCtx) RunID() string {
+	return sc.root.runID
+}
+
+func (sc *srvCtx) Go(f func(ctx Context) error, policy ...ErrorPolicy) {
+	p := LogOnError
+	if len(policy) > 0 {
+		p = policy[0]
+	}
+
+	t := sc.tasks
+	gctx := &srvCtx{
+		ctx:        t.context(sc.ctx),
+		cancelFunc: sc.cancelFunc,
+		log:        sc.log,
+		root:       sc.root,
+		tasks:      t,
+		usage:      sc.usage,
+		onPanic:    sc.onPanic,
+	}
+	t.add(1)
+	if sc.usage != nil {
+		sc.usage.started(1)
+	}
+	go func() {
+		defer t.add(-1)
+		panicked := false
+		run := func() (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					panicked = true
+					err = gctx.recovered(r, debug.Stack())
+				}
+			}()
+			return f(gctx)
+		}
+		var err error
+		if u := sc.usage; u != nil {
+			defer u.started(-1)
+			pprof.Do(gctx.ctx, u.labels, func(ctx context.Context) {
+				gctx.ctx = ctx
+				err = run()
+			})
+		} else {
+			err = run()
+		}
+		if panicked {
+			// The panic is already logged and degraded the group
+			if p == ShutdownOnError {
+				sc.root.Shutdown()
+			}
+		} else if err != nil {
+			sc.handleError(err, p)
+		}
+	}()
+}
+
+func (sc *srvCtx) handleError(err error, p ErrorPolicy) {
+	sc.log.Error().Error(err).Msg("goroutine failed")
+	switch p {
+	case DegradeOnError:
+		sc.tasks.degrade(err)
+	case ShutdownOnError:
+		sc.root.Shutdown()
+	}
+}
+
+// tasks tracks the goroutines started by the group.
+type tasks struct {
+	lock   sync.Mutex
+	wg     sync.WaitGroup
+	n      int
+	ctx    context.Context
+	cancel context.CancelFunc
+	err    error
+}
+
+// add adds delta to the running goroutines.
+func (t *tasks) add(delta int) {
+	t.lock.Lock()
+	t.n += delta
+	t.lock.Unlock()
+	t.wg.Add(delta)
+}
+
+// count returns the number of running goroutines.
+func (t *tasks) count() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.n
+}
+
+// context returns the context for new goroutines derived from parent.
+func (t *tasks) context(parent context.Context) context.Context {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.ctx == nil {
+		t.ctx, t.cancel = context.WithCancel(parent)
+	}
+	return t.ctx
+}
+
+// wait cancels the running goroutines and waits for them to exit.
+func (t *tasks) wait() {
+	t.lock.Lock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	t.ctx, t.cancel = nil, nil
+	t.lock.Unlock()
+	t.wg.Wait()
+}
+
+func (t *tasks) degrade(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.err = err
+}
+
+// failure returns the error that degraded the group if any.
+func (t *tasks) failure() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.err
+}
@@ -1,6 +1,8 @@
 package component
 
 import (
+	"errors"
+	"os"
 	"testing"
 	"time"
 
@@ -19,3 +21,91 @@ func TestContext(t *testing.T) {
 		ctx.Shutdown()
 	})
 }
+
+// plainCtx is a context that implements none of the optional interfaces.
+type plainCtx struct {
+	Context
+}
+
+func TestContextGo(t *testing.T) {
+	Convey("After we create a context", t, func() {
+		ctx := RootContext(zlog.New("test")).(*srvCtx)
+
+		Convey("contexts that are not runners should run plain goroutines", func() {
+			plain := plainCtx{ctx}
+			done := make(chan struct{})
+			Go(plain, func(ctx Context) error {
+				defer close(done)
+				panic("plain panic")
+			})
+			<-done
+			So(ctx.tasks.count(), ShouldEqual, 0)
+			So(RunID(plain), ShouldBeEmpty)
+			So(RunID(ctx), ShouldEqual, ctx.RunID())
+		})
+
+		Convey("goroutines should be waited for", func() {
+			done := false
+			Go(ctx, func(ctx Context) error {
+				<-ctx.Ctx().Done()
+				done = true
+				return nil
+			})
+			ctx.tasks.wait()
+			So(done, ShouldBeTrue)
+			So(ctx.tasks.failure(), ShouldBeNil)
+			So(ctx.Ctx().Err(), ShouldBeNil)
+		})
+
+		Convey("panics should be recovered", func() {
+			Go(ctx, func(ctx Context) error { panic("test panic") }, DegradeOnError)
+			ctx.tasks.wait()
+			So(ctx.tasks.failure(), ShouldBeError)
+		})
+
+		Convey("errors should be logged by default", func() {
+			Go(ctx, func(ctx Context) error { return errors.New("test error") })
+			ctx.tasks.wait()
+			So(ctx.tasks.failure(), ShouldBeNil)
+			So(ctx.Ctx().Err(), ShouldBeNil)
+		})
+
+		Convey("errors should shutdown the server on request", func() {
+			child := newContext(ctx, zlog.New("child"))
+			child.Go(func(ctx Context) error {
+				// Nested goroutines are tracked by the same group
+				Go(ctx, func(Context) error { return nil })
+				return errors.New("test error")
+			}, ShutdownOnError)
+			child.tasks.wait()
+			So(ctx.Ctx().Err(), ShouldNotBeNil)
+			So(child.Ctx().Err(), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestRunID(t *testing.T) {
+	Convey("Run IDs should be unique per root context", t, func() {
+		root := RootContext(zlog.New("test")).(*srvCtx)
+		child := newContext(root, zlog.New("child"))
+		So(root.RunID(), ShouldNotBeEmpty)
+		So(child.RunID(), ShouldEqual, root.RunID())
+		So(RunID(RootContext(zlog.New("test"))), ShouldNotEqual, root.RunID())
+
+		Convey("goroutine contexts should share the run ID", func() {
+			id := ""
+			child.Go(func(ctx Context) error {
+				id = RunID(ctx)
+				return nil
+			})
+			child.tasks.wait()
+			So(id, ShouldEqual, root.RunID())
+		})
+
+		Convey("run ID should be overridden by the environment", func() {
+			os.Setenv(RunIDEnv, "test-run")
+			defer os.Unsetenv(RunIDEnv)
+			So(RunID(RootContext(zlog.New("test"))), ShouldEqual, "test-run")
+		})
+	})
+}
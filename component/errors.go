@@ -0,0 +1,111 @@
+package component
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// LifecycleError is returned by the lifecycle methods of a group. It
+// attributes the error to the group and the component that caused it.
+type LifecycleError struct {
+	// Phase is the lifecycle phase, e.g. "configure", "start" or "stop".
+	Phase string
+
+	// Group is the path of the group from the root group, e.g. "server/http".
+	Group string
+
+	// Component is the type of the component, e.g. "*http.server".
+	Component string
+
+	// Err is the error returned by the component or the framework.
+	Err error
+}
+
+func (e *LifecycleError) Error() string {
+	return fmt.Sprintf("%s %s in group %s: %v", e.Phase, e.Component, e.Group, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LifecycleError) Unwrap() error {
+	return e.Err
+}
+
+// StopError aggregates the errors of the components that failed to stop in a
+// group and its sub-groups, in the order in which they were stopped.
+type StopError struct {
+	Errs []error
+}
+
+func (e *StopError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d components failed to stop: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first error.
+func (e *StopError) Unwrap() error {
+	return e.Errs[0]
+}
+
+// appendStopErrors appends the errors of a sub-group to errs.
+func appendStopErrors(errs []error, err error) []error {
+	if se, ok := err.(*StopError); ok {
+		return append(errs, se.Errs...)
+	}
+	return append(errs, err)
+}
+
+// stopError returns the error aggregating errs, nil if there are no errors.
+func stopError(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return &StopError{Errs: errs}
+}
+
+// lifecycleError attributes the error returned in the phase to the
+// component of the group.
+func (g *group) lifecycleError(phase string, cmp interface{}, err error) error {
+	return &LifecycleError{
+		Phase:     phase,
+		Group:     g.path(),
+		Component: componentName(cmp),
+		Err:       err,
+	}
+}
+
+// wrapper is implemented by the hooks registered for the values of a type,
+// they report the value as the component instead of themselves.
+type wrapper interface {
+	wrappedValue() interface{}
+}
+
+// unwrapComponent returns the component of the hook.
+func unwrapComponent(cmp interface{}) interface{} {
+	if w, ok := cmp.(wrapper); ok {
+		return w.wrappedValue()
+	}
+	return cmp
+}
+
+// componentName returns the name of the component of the hook, e.g.
+// "*http.server".
+func componentName(cmp interface{}) string {
+	return reflect.TypeOf(unwrapComponent(cmp)).String()
+}
+
+// path returns the names of the groups from the root to this group joined
+// by "/".
+func (g *group) path() string {
+	names := []string{}
+	for grp := g; grp != nil; grp = grp.parent {
+		names = append([]string{grp.name}, names...)
+	}
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,121 @@
+package component
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type failingCmp struct {
+	phase string
+}
+
+func (f *failingCmp) Config() config.Config { return nil }
+
+func (f *failingCmp) Configure(ctx Context) error { return f.fail("configure") }
+
+func (f *failingCmp) Start(ctx Context) error { return f.fail("start") }
+
+func (f *failingCmp) Stop(ctx Context) error { return f.fail("stop") }
+
+func (f *failingCmp) fail(phase string) error {
+	if f.phase == phase {
+		return errors.New(phase + " failed")
+	}
+	return nil
+}
+
+type failingStop struct{}
+
+func (f *failingStop) Stop(ctx Context) error { return errors.New("stop failed") }
+
+type stopCounter struct {
+	stops *int
+}
+
+func (s *stopCounter) Stop(ctx Context) error {
+	*s.stops++
+	return nil
+}
+
+type otherStopCounter stopCounter
+
+func (s *otherStopCounter) Stop(ctx Context) error {
+	*s.stops++
+	return nil
+}
+
+func TestLifecycleErrors(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"errors.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group hierarchy with a failing component", t, func() {
+		root := New("root")
+		child := root.New("child")
+		f := &failingCmp{}
+		So(child.Add(func() *failingCmp { return f }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+
+		check := func(err error, phase string) {
+			So(err, ShouldNotBeNil)
+			le, ok := err.(*LifecycleError)
+			So(ok, ShouldBeTrue)
+			So(le.Phase, ShouldEqual, phase)
+			So(le.Group, ShouldEqual, "root/child")
+			So(le.Component, ShouldEqual, "*component.failingCmp")
+			So(le.Unwrap().Error(), ShouldEqual, phase+" failed")
+			So(err.Error(), ShouldEqual, phase+" *component.failingCmp in group root/child: "+phase+" failed")
+		}
+
+		Convey("configure errors should be attributed", func() {
+			f.phase = "configure"
+			check(root.Configure(), "configure")
+		})
+
+		Convey("start errors should be attributed", func() {
+			f.phase = "start"
+			check(root.Start(), "start")
+		})
+
+		Convey("stop errors should be attributed", func() {
+			f.phase = "stop"
+			check(root.Stop(), "stop")
+		})
+	})
+
+	Convey("All the stop hooks of a group should be called", t, func() {
+		root := New("root")
+		stops := 0
+		So(root.Add(func() *stopCounter { return &stopCounter{&stops} }), ShouldBeNil)
+		So(root.Add(func() *otherStopCounter { return &otherStopCounter{&stops} }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+		So(root.Stop(), ShouldBeNil)
+		So(stops, ShouldEqual, 2)
+	})
+
+	Convey("The stop errors of a group hierarchy should be aggregated", t, func() {
+		root := New("root").(*group)
+		child := root.New("child")
+		So(root.Add(func() *failingCmp { return &failingCmp{phase: "stop"} }), ShouldBeNil)
+		So(child.Add(func() *failingStop { return &failingStop{} }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() { errs <- root.Wait() }()
+		}
+		So(root.Invoke(func(s Shutdown) { s() }), ShouldBeNil)
+		err := <-errs
+		So(<-errs, ShouldEqual, err)
+		se, ok := err.(*StopError)
+		So(ok, ShouldBeTrue)
+		So(se.Errs, ShouldHaveLength, 2)
+		So(se.Errs[0].(*LifecycleError).Group, ShouldEqual, "root/child")
+		So(se.Errs[1].(*LifecycleError).Group, ShouldEqual, "root")
+		So(err.Error(), ShouldStartWith, "2 components failed to stop: stop *component.failingStop in group root/child")
+	})
+}
@@ -0,0 +1,124 @@
+package component
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/anuvu/cube/config"
+)
+
+// EventType is the type of a lifecycle event of the server.
+type EventType string
+
+// Lifecycle event types emitted by the root group.
+const (
+	// EventStarted is emitted once all the groups are started.
+	EventStarted EventType = "started"
+	// EventStartFailed is emitted when a component fails to start.
+	EventStartFailed EventType = "start_failed"
+	// EventHealthChanged is emitted when the health of the server changes.
+	EventHealthChanged EventType = "health_changed"
+	// EventStopping is emitted when the groups start stopping.
+	EventStopping EventType = "stopping"
+	// EventStopped is emitted once all the groups are stopped.
+	EventStopped EventType = "stopped"
+	// EventConfigFetched is emitted when the configuration of a component is
+	// fetched from the configuration store.
+	EventConfigFetched EventType = "config_fetched"
+	// EventPanicked is emitted when a goroutine of a component panics, see
+	// Recover.
+	EventPanicked EventType = "panicked"
+)
+
+// Event is a lifecycle event of the server.
+type Event struct {
+	// Type of the event
+	Type EventType
+	// Group is the path of the group the event relates to, e.g. "server/http"
+	Group string
+	// Component is the type of the component the event relates to, if any
+	Component string
+	// Key is the configuration key of config events
+	Key string
+	// Healthy is the health of the server for health events
+	Healthy bool
+	// Err is the error that caused the event, if any
+	Err error
+	// Time of the event
+	Time time.Time
+	// RunID is the identifier of the run of the server, see RunIdentifier
+	RunID string
+}
+
+// EventHook is the interface implemented by components to be notified of the
+// lifecycle events of the server. Components of any group in the hierarchy
+// receive the events. Events are delivered synchronously by the lifecycle
+// method that emits them, OnEvent must not block.
+type EventHook interface {
+	OnEvent(ctx Context, e Event)
+}
+
+// emit delivers the event to the event hooks of the group hierarchy.
+func (g *group) emit(e Event) {
+	if e.Group == "" {
+		e.Group = g.path()
+	}
+	e.Time = time.Now()
+	if e.RunID == "" {
+		e.RunID = g.ctx.RunID()
+	}
+	g.emitTo(e)
+}
+
+func (g *group) emitTo(e Event) {
+	for _, h := range g.eventHooks {
+		h.OnEvent(g.ctx, e)
+	}
+	for _, child := range g.children {
+		child.emitTo(e)
+	}
+}
+
+// emitConfig emits the result of fetching the configuration of the component
+// to the whole group hierarchy.
+func (g *group) emitConfig(cmp interface{}, cfg config.Config, err error) {
+	if cfg == nil || cfg.Key().IsNil() {
+		return
+	}
+	root := g
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.emit(Event{
+		Type:      EventConfigFetched,
+		Group:     g.path(),
+		Component: componentName(cmp),
+		Key:       string(cfg.Key()),
+		Err:       err,
+	})
+}
+
+// emitStart emits the result of starting the root group.
+func (g *group) emitStart(err error) {
+	if err == nil {
+		g.emit(Event{Type: EventStarted})
+		return
+	}
+	e := Event{Type: EventStartFailed, Err: err}
+	if le, ok := err.(*LifecycleError); ok {
+		e.Group, e.Component, e.Err = le.Group, le.Component, le.Err
+	}
+	g.emit(e)
+}
+
+// emitHealth emits a health event if the health of the root group changed
+// since the last check.
+func (g *group) emitHealth(healthy bool) {
+	var from, to int32 = 1, 0
+	if !healthy {
+		from, to = 0, 1
+	}
+	if atomic.CompareAndSwapInt32(&g.unhealthy, from, to) {
+		g.emit(Event{Type: EventHealthChanged, Healthy: healthy})
+	}
+}
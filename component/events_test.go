@@ -0,0 +1,100 @@
+package component
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []Event
+}
+
+func (r *eventRecorder) OnEvent(ctx Context, e Event) {
+	r.lock.Lock()
+	r.events = append(r.events, e)
+	r.lock.Unlock()
+}
+
+func (r *eventRecorder) types() []EventType {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	types := []EventType{}
+	for _, e := range r.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+type failingStart struct{}
+
+func (f *failingStart) Start(ctx Context) error {
+	return fmt.Errorf("start error")
+}
+
+func TestEvents(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"events.test"}
+
+	Convey("After we create a group with an event hook in a sub-group", t, func() {
+		base := New("base")
+		rec := &eventRecorder{}
+		f := &flakyCmp{healthy: true}
+		So(base.Add(func() *flakyCmp { return f }), ShouldBeNil)
+		child := base.New("child")
+		So(child.Add(func() *eventRecorder { return rec }), ShouldBeNil)
+
+		Convey("the lifecycle events should be emitted", func() {
+			So(base.Create(), ShouldBeNil)
+			So(base.Configure(), ShouldBeNil)
+			So(base.Start(), ShouldBeNil)
+			So(base.IsHealthy(), ShouldBeTrue)
+			f.set(false, nil)
+			So(base.IsHealthy(), ShouldBeFalse)
+			So(base.IsHealthy(), ShouldBeFalse)
+			f.set(true, nil)
+			So(base.IsHealthy(), ShouldBeTrue)
+			So(base.Stop(), ShouldBeNil)
+			So(rec.types(), ShouldResemble, []EventType{
+				EventStarted, EventHealthChanged, EventHealthChanged, EventStopping, EventStopped,
+			})
+			So(rec.events[0].Group, ShouldEqual, "base")
+			So(rec.events[0].RunID, ShouldEqual, base.(*group).ctx.RunID())
+			So(rec.events[1].Healthy, ShouldBeFalse)
+			So(rec.events[2].Healthy, ShouldBeTrue)
+		})
+
+		Convey("start failures should be attributed to the component", func() {
+			So(child.Add(func() *failingStart { return &failingStart{} }), ShouldBeNil)
+			So(base.Create(), ShouldBeNil)
+			So(base.Configure(), ShouldBeNil)
+			So(base.Start(), ShouldBeError)
+			So(rec.types(), ShouldResemble, []EventType{EventStartFailed})
+			e := rec.events[0]
+			So(e.Group, ShouldEqual, "base/child")
+			So(e.Component, ShouldEqual, "*component.failingStart")
+			So(e.Err, ShouldBeError, "start error")
+		})
+
+		Convey("configuration fetches should be emitted", func() {
+			So(base.Add(func() *prefixCmp {
+				return &prefixCmp{cfg: &prefixCfg{BaseConfig: config.BaseConfig{ConfigKey: "cmp"}}}
+			}), ShouldBeNil)
+			So(base.Create(), ShouldBeNil)
+			os.Args = []string{"events.test", "--config.mem", `{"cmp": {"value": "x"}}`}
+			So(base.Configure(), ShouldBeNil)
+			So(rec.types(), ShouldResemble, []EventType{EventConfigFetched})
+			e := rec.events[0]
+			So(e.Group, ShouldEqual, "base")
+			So(e.Component, ShouldEqual, "*component.prefixCmp")
+			So(e.Key, ShouldEqual, "cmp")
+			So(e.Err, ShouldBeNil)
+		})
+	})
+}
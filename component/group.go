@@ -7,6 +7,9 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/anuvu/cube/config"
 	"github.com/anuvu/cube/di"
@@ -35,6 +38,20 @@ type StopHook interface {
 	Stop(ctx Context) error
 }
 
+// WarmupHook is the interface that provides the warm-up callback for the component.
+// Warmup is called after the group and its sub-groups are started and before the
+// group reports itself ready.
+type WarmupHook interface {
+	Warmup(ctx Context) error
+}
+
+// ReadinessHook is the interface that provides the readiness callback for the
+// component. A component can report itself not ready to take traffic while
+// being healthy.
+type ReadinessHook interface {
+	IsReady(ctx Context) bool
+}
+
 // HealthHook is the interface that provides the health callback for the component.
 type HealthHook interface {
 	IsHealthy(ctx Context) bool
@@ -45,6 +62,17 @@ type ServerShutdown context.CancelFunc
 
 // Group provides and interface to add custom components and
 // sub-groups to this group.
+//
+// Sub-groups are created, configured, started and checked for health and
+// readiness in the order in which they were added to the group, and are
+// stopped in the reverse order.
+//
+// The groups created by New also implement the Registry, Invoker, Inspector
+// and Supervisor interfaces, which callers type-assert, e.g.
+//
+//	if s, ok := g.(component.Supervisor); ok {
+//		s.SetBudget(budget)
+//	}
 type Group interface {
 	Add(ctr interface{}) error
 	Invoke(f interface{}) error
@@ -56,27 +84,99 @@ type Group interface {
 	IsHealthy() bool
 }
 
+// Registry is implemented by the groups supporting the registration of
+// components beyond Add, see di.Container.
+type Registry interface {
+	AddTagged(ctr interface{}, tags ...di.Annotation) error
+	AddDefault(ctr interface{}) error
+	AddNamed(name string, ctr interface{}) error
+	Bind(iface reflect.Type, ctr interface{}) error
+	AddToGroup(group string, ctr interface{}) error
+	AddLazy(ctr interface{}) error
+	AddAlternative(key string, name string, ctr interface{}) error
+	AddInvoke(f interface{}) error
+	Decorate(fn interface{}) error
+	Replace(ctr interface{}) error
+	Remove(t reflect.Type) error
+	Intercept(i di.Interceptor)
+}
+
+// Invoker is implemented by the groups supporting the invocation of
+// functions beyond Invoke.
+type Invoker interface {
+	InvokeResult(f interface{}) ([]interface{}, error)
+	InvokeWith(f interface{}, args ...interface{}) error
+	InvokeTagged(key, value string, f interface{}) error
+	InvokeCtx(ctx context.Context, f interface{}) error
+}
+
+// Inspector is implemented by the groups describing their components.
+type Inspector interface {
+	GraphDOT() string
+	Plan() Plan
+	Usage() []Usage
+	HealthReport() HealthReport
+}
+
+// Supervisor is implemented by the groups supporting the control of the
+// server beyond the lifecycle methods of Group.
+type Supervisor interface {
+	NewE(name string, opts ...Option) (Group, error)
+	SelfCheck(timeout time.Duration) error
+	Wait() error
+	IsReady() bool
+	SetBudget(b Budget)
+	SetConfigDefaults(cfg string)
+}
+
 // Group is a group of components, that have inter-dependencies.
 type group struct {
-	name        string
-	parent      *group
-	children    map[string]*group
-	store       config.Store
-	cli         *flag.FlagSet
-	c           *di.Container
-	ctx         *srvCtx
-	configHooks []ConfigHook
-	startHooks  []StartHook
-	stopHooks   []StopHook
-	healthHooks []HealthHook
+	name         string
+	parent       *group
+	children     []*group
+	store        config.Store
+	ownStore     bool
+	cli          *flag.FlagSet
+	args         *Args
+	c            *di.Container
+	ctx          *srvCtx
+	configHooks  []ConfigHook
+	startHooks   []StartHook
+	stopHooks    []StopHook
+	warmHooks    []WarmupHook
+	readyHooks   []ReadinessHook
+	healthHooks  []HealthHook
+	verHooks     []VersionHook
+	reqHooks     []RequireHook
+	connHooks    []ConnectionHook
+	eventHooks   []EventHook
+	checkHooks   []SelfCheckHook
+	budget       Budget
+	ready        int32
+	unhealthy    int32
+	prefix       string
+	critical     bool
+	invokes      []interface{}
+	defaults     []interface{}
+	typedHooks   []typedHook
+	health       *healthConfig
+	acct         *accounting
+	panics       *panicConfig
+	healthLock   sync.Mutex
+	healthStates []*healthState
+	ownership    Ownership
+	waitOnce     sync.Once
+	waitErr      error
 }
 
 var ctxType = reflect.TypeOf((*Context)(nil)).Elem()
 var shutType = reflect.TypeOf((*Shutdown)(nil)).Elem()
+var lcType = reflect.TypeOf((*Lifecycle)(nil)).Elem()
 
 // New creates a new component group with the specified parent. If the parent is nil
-// this group is the root group.
-func New(name string) Group {
+// this group is the root group. The options customize the root group, e.g.
+// WithStore.
+func New(name string, opts ...Option) Group {
 	grp := newGroup(name, nil)
 
 	// Root container should provide the server shutdown function
@@ -87,8 +187,15 @@ func New(name string) Group {
 	grp.cli = flag.NewFlagSet(name, flag.ContinueOnError)
 	grp.c.Add(func() *flag.FlagSet { return grp.cli })
 
+	// Root container should provide the positional arguments
+	grp.args = &Args{args: []string{}}
+	grp.c.Add(func() *Args { return grp.args })
+
 	// Create the store
 	grp.store = newConfigStore(grp.cli)
+	for _, opt := range opts {
+		opt(grp)
+	}
 	return grp
 }
 
@@ -97,20 +204,28 @@ func newGroup(name string, parent *group) *group {
 	var pctx *srvCtx
 	var cli *flag.FlagSet
 	var store config.Store
+	health := newHealthConfig()
+	acct := &accounting{}
+	panics := &panicConfig{Policy: degradeOnPanic}
+	prefix := ""
 	if parent != nil {
 		pc = parent.c
 		pctx = parent.ctx
 		cli = parent.cli
 		store = parent.store
+		health = parent.health
+		acct = parent.acct
+		panics = parent.panics
+		prefix = parent.prefix
 	}
 
 	log := zlog.New(name)
-	c := di.New(pc, ctxType, shutType)
+	c := di.New(pc, ctxType, shutType, lcType)
 	ctx := newContext(pctx, log)
 	grp := &group{
 		name:        name,
 		parent:      parent,
-		children:    map[string]*group{},
+		children:    []*group{},
 		store:       store,
 		cli:         cli,
 		c:           c,
@@ -118,37 +233,235 @@ func newGroup(name string, parent *group) *group {
 		configHooks: []ConfigHook{},
 		startHooks:  []StartHook{},
 		stopHooks:   []StopHook{},
+		warmHooks:   []WarmupHook{},
+		readyHooks:  []ReadinessHook{},
 		healthHooks: []HealthHook{},
+		verHooks:    []VersionHook{},
+		reqHooks:    []RequireHook{},
+		connHooks:   []ConnectionHook{},
+		prefix:      prefix,
+		critical:    true,
+		health:      health,
+		acct:        acct,
+		panics:      panics,
 	}
+	ctx.onPanic = grp.panicked
+
+	grp.c.Subscribe(grp.logEvent)
 
-	// Provide the Context, Shutdown per group
+	// Provide the Context, Shutdown, Lifecycle per group
 	grp.c.Add(func() Context { return grp.ctx })
 	grp.c.Add(func() Shutdown { return grp.ctx.Shutdown })
+	grp.c.Add(func() Lifecycle { return &lifecycle{grp} })
 
 	return grp
 }
 
+// New creates a sub-group with the name. It panics if the name is already
+// used by another sub-group, use NewE to handle the error. The format of the
+// name is not validated, e.g. the server group is named after the binary.
 func (g *group) New(name string) Group {
+	if err := g.uniqueName(name); err != nil {
+		panic(err)
+	}
 	grp := newGroup(name, g)
-
-	// FIXME: Potential child name collision, check for it.
-	g.children[name] = grp
-
+	g.children = append(g.children, grp)
 	return grp
 }
 
+// NewE creates a sub-group with the name customized by the options. It
+// returns an error if the name is invalid or is already used by another
+// sub-group.
+func (g *group) NewE(name string, opts ...Option) (Group, error) {
+	if err := g.validateName(name); err != nil {
+		return nil, err
+	}
+	grp := newGroup(name, g)
+	for _, opt := range opts {
+		opt(grp)
+	}
+	g.children = append(g.children, grp)
+	return grp, nil
+}
+
 // Add adds a new component constructor to the component group.
 func (g *group) Add(ctr interface{}) error {
 	// add the component constructor to the container
 	return g.c.Add(ctr)
 }
 
+// AddTagged adds a new component constructor to the component group,
+// annotated with metadata tags, see di.Tag.
+func (g *group) AddTagged(ctr interface{}, tags ...di.Annotation) error {
+	return g.c.Add(ctr, tags...)
+}
+
+// AddDefault adds a default component constructor, that is only added to the
+// group if no other constructor provides its components in the group, its
+// ancestors or its sub-groups by the time the group is created, see
+// di.Container.AddDefault. Libraries use it to supply components that the
+// application can override.
+func (g *group) AddDefault(ctr interface{}) error {
+	if t := reflect.TypeOf(ctr); t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("can't add non-function %v", ctr)
+	}
+	g.defaults = append(g.defaults, ctr)
+	return nil
+}
+
+// providedBelow returns true if a sub-group provides one of the values of
+// the constructor.
+func (g *group) providedBelow(ctr interface{}) bool {
+	t := reflect.TypeOf(ctr)
+	for _, child := range g.children {
+		for i := 0; i < t.NumOut(); i++ {
+			if _, ok := child.c.Registration(t.Out(i)); ok {
+				return true
+			}
+		}
+		if child.providedBelow(ctr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddNamed adds a component constructor whose values are bound under the
+// name, see di.Container.AddNamed.
+func (g *group) AddNamed(name string, ctr interface{}) error {
+	return g.c.AddNamed(name, ctr)
+}
+
+// Bind adds a component constructor and binds the interface to the component
+// it produces, see di.Container.Bind.
+func (g *group) Bind(iface reflect.Type, ctr interface{}) error {
+	return g.c.Bind(iface, ctr)
+}
+
+// AddToGroup adds a component constructor whose values are contributed to the
+// value group, see di.Container.AddToGroup.
+func (g *group) AddToGroup(group string, ctr interface{}) error {
+	return g.c.AddToGroup(group, ctr)
+}
+
+// AddLazy adds a component constructor that is only invoked when the component
+// is first used, see di.Container.AddLazy. Lazy components do not take part
+// in the lifecycle of the group, their hooks are never called.
+func (g *group) AddLazy(ctr interface{}) error {
+	return g.c.AddLazy(ctr)
+}
+
+// Decorate registers a decorator of a component of the group, see
+// di.Container.Decorate. The lifecycle hooks of the decorated component are
+// called along with those of the original one.
+func (g *group) Decorate(fn interface{}) error {
+	return g.c.Decorate(fn)
+}
+
+// Replace swaps the constructor of a component of the group, e.g. for a fake
+// in tests, see di.Container.Replace.
+func (g *group) Replace(ctr interface{}) error {
+	return g.c.Replace(ctr)
+}
+
+// Remove removes the constructor of a component of the group, see
+// di.Container.Remove. The lifecycle hooks of a component that was already
+// created are not removed.
+func (g *group) Remove(t reflect.Type) error {
+	return g.c.Remove(t)
+}
+
+// AddInvoke registers a function that is invoked with dependency injection
+// as soon as the components of the group are created, before the sub-groups
+// are created. It returns an error if f is not a function. If the function
+// returns an error, Create returns that error.
+func (g *group) AddInvoke(f interface{}) error {
+	if f == nil || reflect.TypeOf(f).Kind() != reflect.Func {
+		return fmt.Errorf("can't invoke non-function %v", f)
+	}
+	g.invokes = append(g.invokes, f)
+	return nil
+}
+
 // Invoke invokes a function with dependency injection.
 func (g *group) Invoke(f interface{}) error {
 	return g.c.Invoke(f, nil)
 }
 
+// InvokeResult invokes a function with dependency injection and returns the
+// values it returns, without the error returned last, if any.
+func (g *group) InvokeResult(f interface{}) ([]interface{}, error) {
+	return g.c.InvokeResult(f)
+}
+
+// InvokeWith invokes a function with dependency injection, resolving its
+// dependencies from the extra arguments before the group, e.g. to invoke a
+// handler with a request, see di.Container.InvokeWith.
+func (g *group) InvokeWith(f interface{}, args ...interface{}) error {
+	return g.c.InvokeWith(f, nil, args...)
+}
+
+// InvokeTagged invokes a function with dependency injection once for each
+// component of the group tagged with the key and the value, any value if
+// empty, see di.Container.InvokeTagged.
+func (g *group) InvokeTagged(key, value string, f interface{}) error {
+	return g.c.InvokeTagged(key, value, f)
+}
+
+// InvokeCtx invokes a function with dependency injection, bounded by the
+// context. The Context dependency of the function is derived from the group
+// context and is cancelled with ctx, so that the function can observe the
+// deadline of ctx. If ctx is done before the function returns, the derived
+// context is cancelled and the error of ctx is returned without waiting for
+// the function.
+func (g *group) InvokeCtx(ctx context.Context, f interface{}) error {
+	dctx, cancel := g.ctx.derive(ctx)
+	c := di.New(g.c, ctxType)
+	if err := c.Add(func() Context { return dctx }); err != nil {
+		cancel()
+		return err
+	}
+	if err := c.Create(nil); err != nil {
+		cancel()
+		return err
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- c.Invoke(f, nil)
+	}()
+	defer cancel()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Intercept registers an interceptor that can veto the resolution of
+// dependencies in this group and all its sub-groups.
+func (g *group) Intercept(i di.Interceptor) {
+	g.c.Intercept(i)
+}
+
+// GraphDOT returns the dependency graph of the components of the group and its
+// parent groups in the graphviz DOT format, clustered by group.
+func (g *group) GraphDOT() string {
+	names := []string{}
+	for grp := g; grp != nil; grp = grp.parent {
+		names = append([]string{grp.path()}, names...)
+	}
+	return g.c.GraphDOT(names...)
+}
+
 func (g *group) Create() error {
+	if g.parent == nil && g.hasAlternatives() {
+		// root group selects the alternatives before creating any component
+		if err := g.selectAlternatives(); err != nil {
+			return err
+		}
+	}
+
 	g.ctx.Log().Info().Msg("creating group")
 	// g.c.Create will call this function for each value produced by ctr
 	// constructor method we then check if the produced value implements
@@ -157,25 +470,122 @@ func (g *group) Create() error {
 	vf := func(v reflect.Value) error {
 		return g.addLCHooks(v)
 	}
+	for _, ctr := range g.defaults {
+		if g.providedBelow(ctr) {
+			continue
+		}
+		if err := g.c.AddDefault(ctr); err != nil {
+			return err
+		}
+	}
+	g.defaults = nil
 	if err := g.c.Create(vf); err != nil {
 		return err
 	}
+	for _, f := range g.invokes {
+		if err := g.c.Invoke(f, nil); err != nil {
+			return err
+		}
+	}
 
 	for _, child := range g.children {
 		if err := child.Create(); err != nil {
 			return err
 		}
 	}
+
+	if g.parent == nil {
+		// root group verifies the version requirements of the whole hierarchy
+		return g.checkVersions()
+	}
 	return nil
 }
 
+// slowConstruction is the wall time above which a constructor is logged as
+// slow.
+const slowConstruction = time.Second
+
+// logEvent logs the constructors of the group that failed or were slow and
+// the values it rejected, the events of the sub-groups are logged by their
+// own group.
+func (g *group) logEvent(e di.Event) {
+	if e.Container != g.c {
+		return
+	}
+	switch {
+	case e.Kind == di.ConstructionFailed:
+		g.ctx.Log().Warn().Error(e.Err).Str("provider", e.Provider).Str("duration", e.Duration.String()).Msg("constructor failed")
+	case e.Kind == di.ValueRejected:
+		g.ctx.Log().Warn().Error(e.Err).Str("provider", e.Provider).Str("type", e.ID).Msg("value rejected")
+	case e.Kind == di.ValueConstructed && e.Duration > slowConstruction:
+		g.ctx.Log().Warn().Str("provider", e.Provider).Str("type", e.ID).Str("duration", e.Duration.String()).Msg("slow constructor")
+	}
+}
+
+// checkVersions verifies that the version requirements declared by the
+// components in the group hierarchy are met.
+func (g *group) checkVersions() error {
+	versions := map[string]string{FrameworkName: FrameworkVersion}
+	requires := []RequireHook{}
+	var collect func(grp *group) error
+	collect = func(grp *group) error {
+		for _, h := range grp.verHooks {
+			name, ver := h.Version()
+			if v, ok := versions[name]; ok && v != ver {
+				return fmt.Errorf("component %s is present with versions %s and %s", name, v, ver)
+			}
+			versions[name] = ver
+		}
+		requires = append(requires, grp.reqHooks...)
+		for _, child := range grp.children {
+			if err := collect(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := collect(g); err != nil {
+		return err
+	}
+	return checkVersions(versions, requires)
+}
+
 // Configure calls the configure hooks on all components registered for configuration.
+// The hooks are called in the dependency order in which the components were
+// created, the same order used by Start, so a component is configured after
+// the components it depends on. Errors of the components are returned as
+// *LifecycleError.
 func (g *group) Configure() error {
 	if g.parent == nil {
 		// root group parse the cli and initialize the config store
 		if err := g.cli.Parse(os.Args[1:]); err != nil {
 			return err
 		}
+		g.args.args = g.cli.Args()
+		if err := g.store.Open(); err != nil {
+			return err
+		}
+		defer g.store.Close()
+
+		// The health check settings are optional
+		if err := g.store.Get(g.health); err != nil && !config.IsNotFound(err) {
+			return err
+		}
+
+		// The accounting is optional and disabled by default
+		if err := g.store.Get(g.acct); err != nil && !config.IsNotFound(err) {
+			return err
+		}
+
+		// The panics are recovered by default
+		if err := g.store.Get(g.panics); err != nil && !config.IsNotFound(err) {
+			return err
+		}
+		if err := g.panics.check(); err != nil {
+			return err
+		}
+	} else if g.ownStore {
+		// The sub-group reads its configuration from its own store
 		if err := g.store.Open(); err != nil {
 			return err
 		}
@@ -184,12 +594,15 @@ func (g *group) Configure() error {
 
 	g.ctx.Log().Info().Msg("configuring group")
 	for _, h := range g.configHooks {
-		cfg := h.Config()
-		if err := g.store.Get(cfg); err != nil {
-			return err
+		cfg := g.prefixed(h.Config())
+		err := g.store.Get(cfg)
+		g.emitConfig(h, cfg, err)
+		g.warnConfig(h, cfg)
+		if err != nil {
+			return g.lifecycleError("configure", h, err)
 		}
-		if err := h.Configure(g.ctx); err != nil {
-			return err
+		if err := g.accounted(h, func(ctx *srvCtx) error { return h.Configure(ctx) }); err != nil {
+			return g.lifecycleError("configure", h, err)
 		}
 	}
 
@@ -204,16 +617,26 @@ func (g *group) Configure() error {
 
 // Start calls the start hooks on all components registered for startup.
 // If an error occurs on any hook, subsequent start calls are abandoned
-// and a best effort stop is initiated.
+// and a best effort stop is initiated. Errors of the components are returned
+// as *LifecycleError. The root group emits the EventStarted or the
+// EventStartFailed event to the event hooks.
 func (g *group) Start() error {
+	err := g.start()
+	if g.parent == nil {
+		g.emitStart(err)
+	}
+	return err
+}
+
+func (g *group) start() error {
 	g.ctx.Log().Info().Msg("starting group")
 	for _, h := range g.startHooks {
-		if err := g.c.Invoke(h.Start, nil); err != nil {
+		if err := g.accounted(h, func(ctx *srvCtx) error { return g.invokeHook(ctx, h.Start) }); err != nil {
 			// We need to call all stop hooks and ignore errors
 			// as we dont know which components are actually participating
 			// in the stop callbacks
-			defer g.Stop()
-			return err
+			defer g.stop()
+			return g.lifecycleError("start", h, err)
 		}
 	}
 
@@ -223,50 +646,140 @@ func (g *group) Start() error {
 			return err
 		}
 	}
+
+	// Warm up the components before reporting the group ready
+	for _, h := range g.warmHooks {
+		if err := g.accounted(h, func(ctx *srvCtx) error { return g.invokeHook(ctx, h.Warmup) }); err != nil {
+			defer g.stop()
+			return g.lifecycleError("warmup", h, err)
+		}
+	}
+	atomic.StoreInt32(&g.ready, 1)
 	return nil
 }
 
-// Stop calls the stop hooks on all components registered for shutdown.
+// Stop calls the stop hooks on all components registered for shutdown, then
+// the cleanups returned by the constructors of the group, see di.Cleanup. All
+// the stop hooks are called even if some of them fail. The error of a single
+// failed hook is returned as *LifecycleError, the errors of several failed
+// hooks are aggregated in a *StopError. The root group emits the EventStopping and the
+// EventStopped events to the event hooks.
 func (g *group) Stop() error {
-	var e error
+	if g.parent != nil {
+		return g.stop()
+	}
+	g.emit(Event{Type: EventStopping})
+	err := g.stop()
+	g.emit(Event{Type: EventStopped, Err: err})
+	return err
+}
 
-	// Stop all the child groups first
-	for _, child := range g.children {
-		e = child.Stop()
+// Wait blocks until the shutdown of the server is initiated, e.g. by a signal
+// or by calling Shutdown, then stops the group and returns the error of Stop
+// once all the stop hooks completed. Embedders can call Wait from their own
+// run loop instead of calling Stop. Wait can be called from several
+// goroutines, the group is stopped once and they all get the same error.
+func (g *group) Wait() error {
+	<-g.ctx.Ctx().Done()
+	g.waitOnce.Do(func() {
+		g.waitErr = g.Stop()
+	})
+	return g.waitErr
+}
+
+func (g *group) stop() error {
+	errs := []error{}
+	atomic.StoreInt32(&g.ready, 0)
+
+	// Stop all the child groups first, in the reverse order of their creation
+	for i := len(g.children) - 1; i >= 0; i-- {
+		if err := g.children[i].Stop(); err != nil {
+			errs = appendStopErrors(errs, err)
+		}
 	}
 
 	g.ctx.Log().Info().Msg("stopping group")
 
 	// Invoke the stop hooks in the reverse dependency order
-	if len(g.stopHooks) > 0 {
-		for i := len(g.stopHooks) - 1; i == 0; i-- {
-			h := g.stopHooks[i]
-			if err := g.c.Invoke(h.Stop, nil); err != nil {
-				// FIXME: We need to make this multi-error
-				e = err
-			}
+	for i := len(g.stopHooks) - 1; i >= 0; i-- {
+		h := g.stopHooks[i]
+		if err := g.accounted(h, func(ctx *srvCtx) error { return g.invokeHook(ctx, h.Stop) }); err != nil {
+			errs = append(errs, g.lifecycleError("stop", h, err))
 		}
 	}
-	return e
+
+	// Wait for the goroutines started by the components to exit
+	g.ctx.tasks.wait()
+
+	// Release the resources of the components that returned a cleanup
+	g.c.Teardown()
+	return stopError(errs)
 }
 
-// IsHealthy returns true if all components health hooks return true else false
+// IsHealthy returns true if all components health hooks return true else false.
+// The health hooks are called with the timeout and the failure threshold of
+// the "health" configuration. The root group emits the EventHealthChanged
+// event to the event hooks when its health changes.
 func (g *group) IsHealthy() bool {
-	for _, h := range g.healthHooks {
-		if !h.IsHealthy(g.ctx) {
-			return false
+	healthy := g.isHealthy()
+	if g.parent == nil {
+		g.emitHealth(healthy)
+	}
+	return healthy
+}
+
+func (g *group) isHealthy() bool {
+	if g.ctx.tasks.failure() != nil {
+		return false
+	}
+
+	if err := g.checkBudget(); err != nil {
+		g.ctx.Log().Warn().Error(err).Msg("resource budget exceeded")
+		return false
+	}
+
+	// All the components are checked so that the health report is complete
+	healthy := true
+	for i, h := range g.healthHooks {
+		if !g.checkHealth(i, h) {
+			healthy = false
 		}
 	}
 
 	for _, child := range g.children {
 		if !child.IsHealthy() {
+			if !child.critical {
+				g.ctx.Log().Warn().Str("group", child.name).Msg("non-critical group is unhealthy")
+				continue
+			}
+			healthy = false
+		}
+	}
+	return healthy
+}
+
+// IsReady returns true if the group and all its sub-groups are started and
+// warmed up, and are not stopped since, and all components readiness hooks
+// return true.
+func (g *group) IsReady() bool {
+	if atomic.LoadInt32(&g.ready) == 0 {
+		return false
+	}
+	for _, h := range g.readyHooks {
+		if !h.IsReady(g.ctx) {
+			return false
+		}
+	}
+	for _, child := range g.children {
+		if child.critical && !child.IsReady() {
 			return false
 		}
 	}
 	return true
 }
 
-// Add the lifecycle hooks to the group.
+// Add the lifecycle hooks to the group. It is called for each value in the
+// dependency order of the container, which the lifecycle phases rely on.
 func (g *group) addLCHooks(v reflect.Value) error {
 	val := v.Interface()
 	if i, ok := val.(ConfigHook); ok {
@@ -278,12 +791,67 @@ func (g *group) addLCHooks(v reflect.Value) error {
 	if i, ok := val.(StopHook); ok {
 		g.stopHooks = append(g.stopHooks, i)
 	}
+	if i, ok := val.(WarmupHook); ok {
+		g.warmHooks = append(g.warmHooks, i)
+	}
+	if i, ok := val.(ReadinessHook); ok {
+		g.readyHooks = append(g.readyHooks, i)
+	}
 	if i, ok := val.(HealthHook); ok {
 		g.healthHooks = append(g.healthHooks, i)
 	}
+	if i, ok := val.(VersionHook); ok {
+		g.verHooks = append(g.verHooks, i)
+	}
+	if i, ok := val.(RequireHook); ok {
+		g.reqHooks = append(g.reqHooks, i)
+	}
+	if i, ok := val.(ConnectionHook); ok {
+		g.connHooks = append(g.connHooks, i)
+	}
+	if i, ok := val.(EventHook); ok {
+		g.eventHooks = append(g.eventHooks, i)
+	}
+	if i, ok := val.(SelfCheckHook); ok {
+		g.checkHooks = append(g.checkHooks, i)
+	}
+	for _, h := range g.typedHooks {
+		if v.Type() == h.t {
+			g.addLCHooks(reflect.ValueOf(h.hook(v)))
+		}
+	}
 	return nil
 }
 
+// typedHook builds the hook of the values of a type, e.g. for the types that
+// do not implement the hook interfaces.
+type typedHook struct {
+	t    reflect.Type
+	hook func(v reflect.Value) interface{}
+}
+
+// SetConfigDefaults sets the JSON configuration used when no configuration
+// store is given on the command line. Components whose configuration is not
+// found in the defaults keep the values set by their constructor instead of
+// failing to configure. It applies to the whole group hierarchy.
+func (g *group) SetConfigDefaults(cfg string) {
+	if s, ok := g.store.(*cfgStore); ok {
+		s.defaults = cfg
+	}
+}
+
+// warnConfig logs the warnings raised by the store while retrieving the
+// configuration of the component, e.g. its deprecated fields.
+func (g *group) warnConfig(cmp interface{}, cfg config.Config) {
+	w, ok := g.store.(config.Warner)
+	if !ok || cfg == nil || cfg.Key().IsNil() {
+		return
+	}
+	for _, msg := range w.Warnings(cfg.Key()) {
+		g.ctx.Log().Warn().Str("component", componentName(cmp)).Str("key", string(cfg.Key())).Msg(msg)
+	}
+}
+
 func newConfigStore(cli *flag.FlagSet) config.Store {
 	s := &cfgStore{}
 	cli.StringVar(&s.fileCfg, "config.file", "", "file configuration store")
@@ -292,9 +860,11 @@ func newConfigStore(cli *flag.FlagSet) config.Store {
 }
 
 type cfgStore struct {
-	fileCfg string
-	memCfg  string
-	store   config.Store
+	fileCfg  string
+	memCfg   string
+	defaults string
+	lenient  bool
+	store    config.Store
 }
 
 func (s *cfgStore) Open() error {
@@ -309,6 +879,11 @@ func (s *cfgStore) Open() error {
 		r := strings.NewReader(s.memCfg)
 		s.store = config.NewJSONStore(r)
 		return s.store.Open()
+	} else if s.defaults != "" {
+		// Components without configuration keep their defaults
+		s.lenient = true
+		s.store = config.NewJSONStore(strings.NewReader(s.defaults))
+		return s.store.Open()
 	}
 	// No config store
 	return nil
@@ -320,12 +895,23 @@ func (s *cfgStore) Close() {
 	}
 }
 
-func (s *cfgStore) Get(config config.Config) error {
-	if config == nil || config.Key().IsNil() {
+func (s *cfgStore) Warnings(key config.Key) []string {
+	if w, ok := s.store.(config.Warner); ok {
+		return w.Warnings(key)
+	}
+	return nil
+}
+
+func (s *cfgStore) Get(cfg config.Config) error {
+	if cfg == nil || cfg.Key().IsNil() {
 		return nil
 	}
 	if s.store == nil {
-		return fmt.Errorf("%s key not found", config.Key())
+		return &config.NotFoundError{Key: cfg.Key()}
+	}
+	err := s.store.Get(cfg)
+	if s.lenient && config.IsNotFound(err) {
+		return nil
 	}
-	return s.store.Get(config)
+	return err
 }
@@ -1,11 +1,15 @@
 package component
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/di"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -86,6 +90,10 @@ func TestGroup(t *testing.T) {
 		So(grp, ShouldNotBeNil)
 		So(grp.parent, ShouldBeNil)
 		So(grp.ctx, ShouldNotBeNil)
+		So(grp, ShouldImplement, (*Registry)(nil))
+		So(grp, ShouldImplement, (*Invoker)(nil))
+		So(grp, ShouldImplement, (*Inspector)(nil))
+		So(grp, ShouldImplement, (*Supervisor)(nil))
 
 		Convey("we should be able to add a component with no hooks", func() {
 			So(grp.Add(func(ctx Context) *cmp { return &cmp{} }), ShouldBeNil)
@@ -100,6 +108,10 @@ func TestGroup(t *testing.T) {
 				So(s.stopCalled, ShouldBeFalse)
 				So(s.startCalled, ShouldBeFalse)
 			})
+			res, err := grp.InvokeResult(func(s *cmp) bool { return s.startCalled })
+			So(err, ShouldBeNil)
+			So(res, ShouldResemble, []interface{}{false})
+			So(grp.InvokeWith(func(s *cmp, n int) { So(n, ShouldEqual, 7) }, 7), ShouldBeNil)
 		})
 
 		Convey("we should be able to add component with hooks", func() {
@@ -305,3 +317,405 @@ func TestBadCli(t *testing.T) {
 		So(grp.Configure(), ShouldBeError)
 	})
 }
+
+func TestGroupGoroutines(t *testing.T) {
+	Convey("After we create a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("stop should wait for tracked goroutines", func() {
+			stopped := false
+			grp.Invoke(func(ctx Context) {
+				Go(ctx, func(ctx Context) error {
+					<-ctx.Ctx().Done()
+					stopped = true
+					return nil
+				})
+			})
+			So(grp.Stop(), ShouldBeNil)
+			So(stopped, ShouldBeTrue)
+		})
+
+		Convey("failed goroutines should degrade the group health", func() {
+			So(grp.IsHealthy(), ShouldBeTrue)
+			grp.Invoke(func(ctx Context) {
+				Go(ctx, func(ctx Context) error { return fmt.Errorf("failed") }, DegradeOnError)
+			})
+			So(grp.Stop(), ShouldBeNil)
+			So(grp.IsHealthy(), ShouldBeFalse)
+		})
+	})
+}
+
+type warmer struct {
+	fail     bool
+	warmed   bool
+	notReady bool
+}
+
+func (w *warmer) IsReady(ctx Context) bool { return !w.notReady }
+
+func (w *warmer) Warmup(ctx Context) error {
+	if w.fail {
+		return fmt.Errorf("warmup error")
+	}
+	w.warmed = true
+	return nil
+}
+
+func TestGroupWarmup(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"group.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group hierarchy", t, func() {
+		root := New("root").(*group)
+		child := root.New("child").(*group)
+		w := &warmer{}
+		So(child.Add(func() *warmer { return w }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+		So(root.Configure(), ShouldBeNil)
+		So(root.IsReady(), ShouldBeFalse)
+
+		Convey("the group should be ready after warm up", func() {
+			So(root.Start(), ShouldBeNil)
+			So(w.warmed, ShouldBeTrue)
+			So(root.IsReady(), ShouldBeTrue)
+			w.notReady = true
+			So(root.IsReady(), ShouldBeFalse)
+			So(root.Stop(), ShouldBeNil)
+			So(root.IsReady(), ShouldBeFalse)
+		})
+
+		Convey("warm up errors should fail the start", func() {
+			w.fail = true
+			So(root.Start(), ShouldBeError)
+			So(root.IsReady(), ShouldBeFalse)
+			So(child.IsReady(), ShouldBeFalse)
+		})
+	})
+}
+
+type orderCmp struct {
+	name   string
+	events *[]string
+}
+
+func (o *orderCmp) Start(ctx Context) error {
+	*o.events = append(*o.events, "start "+o.name)
+	return nil
+}
+
+func (o *orderCmp) Stop(ctx Context) error {
+	*o.events = append(*o.events, "stop "+o.name)
+	return nil
+}
+
+func TestGroupOrder(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"group.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group with many children", t, func() {
+		root := New("root")
+		events := []string{}
+		names := []string{"c", "a", "d", "b", "e"}
+		for _, name := range names {
+			o := &orderCmp{name, &events}
+			So(root.New(name).Add(func() *orderCmp { return o }), ShouldBeNil)
+		}
+		So(root.Create(), ShouldBeNil)
+		So(root.Configure(), ShouldBeNil)
+
+		Convey("children should start in order and stop in reverse order", func() {
+			So(root.Start(), ShouldBeNil)
+			So(root.Stop(), ShouldBeNil)
+			So(events, ShouldResemble, []string{
+				"start c", "start a", "start d", "start b", "start e",
+				"stop e", "stop b", "stop d", "stop a", "stop c",
+			})
+		})
+	})
+}
+
+type depCfgA struct {
+	events *[]string
+}
+
+func (d *depCfgA) Config() config.Config { return nil }
+
+func (d *depCfgA) Configure(ctx Context) error {
+	*d.events = append(*d.events, "a")
+	return nil
+}
+
+type depCfgB struct {
+	events *[]string
+}
+
+func (d *depCfgB) Config() config.Config { return nil }
+
+func (d *depCfgB) Configure(ctx Context) error {
+	*d.events = append(*d.events, "b")
+	return nil
+}
+
+func TestGroupConfigureOrder(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"group.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we add a component before its dependency", t, func() {
+		grp := New("base")
+		events := []string{}
+		So(grp.Add(func(a *depCfgA) *depCfgB { return &depCfgB{&events} }), ShouldBeNil)
+		So(grp.Add(func() *depCfgA { return &depCfgA{&events} }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the dependency should be configured first", func() {
+			So(grp.Configure(), ShouldBeNil)
+			So(events, ShouldResemble, []string{"a", "b"})
+		})
+	})
+}
+
+func TestGroupAddInvoke(t *testing.T) {
+	Convey("After we register an invocation with a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Add(func() *cmp { return &cmp{} }), ShouldBeNil)
+		So(grp.AddInvoke(10), ShouldBeError)
+		So(grp.AddInvoke(nil), ShouldBeError)
+
+		Convey("it should be invoked on create", func() {
+			var invoked *cmp
+			So(grp.AddInvoke(func(c *cmp) { invoked = c }), ShouldBeNil)
+			So(grp.Create(), ShouldBeNil)
+			So(invoked, ShouldNotBeNil)
+		})
+
+		Convey("its error should fail the create", func() {
+			So(grp.AddInvoke(func(c *cmp) error { return fmt.Errorf("failed") }), ShouldBeNil)
+			So(grp.Create(), ShouldBeError)
+		})
+	})
+}
+
+func TestGroupBind(t *testing.T) {
+	Convey("After we bind an interface in a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Bind(reflect.TypeOf((*StartHook)(nil)).Elem(), newCmpWithHooks), ShouldBeNil)
+
+		Convey("the component should be resolved by its interface", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Invoke(func(h StartHook, c *cmpWithHooks) {
+				So(h, ShouldEqual, c)
+			}), ShouldBeNil)
+			So(grp.startHooks, ShouldHaveLength, 1)
+		})
+	})
+}
+
+func TestGroupAddToGroup(t *testing.T) {
+	Convey("After we contribute components to a value group", t, func() {
+		grp := New("base").(*group)
+		So(grp.AddToGroup("hooks", newCmpWithHooks), ShouldBeNil)
+		So(grp.AddToGroup("hooks", newCmpWithHooks), ShouldBeNil)
+
+		Convey("the consumer should receive all the components", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Invoke(func(p struct {
+				di.In
+				Hooks []*cmpWithHooks `group:"hooks"`
+			}) {
+				So(p.Hooks, ShouldHaveLength, 2)
+			}), ShouldBeNil)
+			So(grp.startHooks, ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestGroupAddLazy(t *testing.T) {
+	Convey("After we add a lazy component to a group", t, func() {
+		grp := New("base").(*group)
+		created := false
+		So(grp.AddLazy(func(ctx Context) *cmpWithHooks {
+			created = true
+			return newCmpWithHooks(ctx)
+		}), ShouldBeNil)
+
+		Convey("it should be created on first use", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(created, ShouldBeFalse)
+			So(grp.Invoke(func(*cmpWithHooks) {}), ShouldBeNil)
+			So(created, ShouldBeTrue)
+			So(grp.startHooks, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestGroupCleanup(t *testing.T) {
+	Convey("After we add a component with a cleanup to a group", t, func() {
+		grp := New("base")
+		cleaned := false
+		So(grp.Add(func() (*cmp, di.Cleanup) {
+			return &cmp{}, func() { cleaned = true }
+		}), ShouldBeNil)
+
+		Convey("it should be cleaned up on stop", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Start(), ShouldBeNil)
+			So(cleaned, ShouldBeFalse)
+			So(grp.Stop(), ShouldBeNil)
+			So(cleaned, ShouldBeTrue)
+		})
+	})
+}
+
+type decoratedCmp struct {
+	*cmpWithHooks
+}
+
+func TestGroupDecorate(t *testing.T) {
+	Convey("After we decorate a component of a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Bind(reflect.TypeOf((*StartHook)(nil)).Elem(), newCmpWithHooks), ShouldBeNil)
+		So(grp.Decorate(func(h StartHook, c *cmpWithHooks) StartHook { return &decoratedCmp{c} }), ShouldBeNil)
+
+		Convey("the decorated component should be resolved", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Invoke(func(h StartHook) {
+				So(h, ShouldHaveSameTypeAs, &decoratedCmp{})
+			}), ShouldBeNil)
+			So(grp.startHooks, ShouldHaveLength, 2)
+		})
+	})
+}
+
+func TestGroupReplace(t *testing.T) {
+	Convey("After we replace a component of a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Add(newCmpWithHooks), ShouldBeNil)
+		fake := &cmpWithHooks{}
+		So(grp.Replace(func() *cmpWithHooks { return fake }), ShouldBeNil)
+
+		Convey("the fake should be used", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Start(), ShouldBeNil)
+			So(fake.startCalled, ShouldBeTrue)
+		})
+	})
+}
+
+func TestGroupRemove(t *testing.T) {
+	Convey("After we remove a component of a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Add(newCmpWithHooks), ShouldBeNil)
+		So(grp.Remove(reflect.TypeOf(&cmpWithHooks{})), ShouldBeNil)
+
+		Convey("it should not be created", func() {
+			So(grp.Create(), ShouldBeNil)
+			So(grp.startHooks, ShouldBeEmpty)
+			So(grp.Remove(reflect.TypeOf(&cmpWithHooks{})), ShouldBeError)
+		})
+	})
+}
+
+func TestGroupGraphDOT(t *testing.T) {
+	Convey("After we add components to a group and a sub-group", t, func() {
+		grp := New("base")
+		So(grp.Add(newCmpWithHooks), ShouldBeNil)
+		child := grp.New("child").(*group)
+		So(child.Add(func(*cmpWithHooks, *testing.T) *cmpWithErrors { return &cmpWithErrors{} }), ShouldBeNil)
+
+		Convey("the graph should be clustered by group", func() {
+			dot := child.GraphDOT()
+			So(dot, ShouldContainSubstring, `label="base";`)
+			So(dot, ShouldContainSubstring, `label="base/child";`)
+			So(dot, ShouldContainSubstring, `"1/github.com/anuvu/cube/component.cmpWithErrors" -> "0/github.com/anuvu/cube/component.cmpWithHooks";`)
+			So(dot, ShouldContainSubstring, `"testing.T" [label="testing.T", style=dashed, color=red];`)
+		})
+	})
+}
+
+func TestGroupInvokeCtx(t *testing.T) {
+	Convey("After we create a group", t, func() {
+		grp := New("base").(*group)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the function should receive a derived context", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			var got Context
+			hasDeadline := false
+			So(grp.InvokeCtx(ctx, func(c Context) {
+				got = c
+				_, hasDeadline = c.Ctx().Deadline()
+			}), ShouldBeNil)
+			So(hasDeadline, ShouldBeTrue)
+			So(got.Ctx().Err(), ShouldNotBeNil)
+			So(grp.InvokeCtx(ctx, func() error { return fmt.Errorf("oops") }), ShouldBeError, "oops")
+		})
+
+		Convey("the function should be cancelled with the context", func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			cancelled, release := make(chan struct{}), make(chan struct{})
+			err := grp.InvokeCtx(ctx, func(c Context) {
+				<-c.Ctx().Done()
+				close(cancelled)
+				<-release
+			})
+			So(err == context.DeadlineExceeded, ShouldBeTrue)
+			<-cancelled
+			close(release)
+			So(grp.ctx.Ctx().Err(), ShouldBeNil)
+		})
+
+		Convey("Wait should stop the group once it is shut down", func() {
+			stopped := make(chan error)
+			go func() { stopped <- grp.Wait() }()
+			So(grp.Invoke(func(s Shutdown) { s() }), ShouldBeNil)
+			So(<-stopped, ShouldBeNil)
+		})
+	})
+}
+
+type defaultCmp struct {
+	name string
+}
+
+func TestAddDefault(t *testing.T) {
+	Convey("After we add a default component to a group", t, func() {
+		root := New("root").(*group)
+		So(root.AddDefault(func() *defaultCmp { return &defaultCmp{"default"} }), ShouldBeNil)
+		So(root.AddDefault(nil), ShouldBeError)
+
+		Convey("the default should be created if no one overrides it", func() {
+			So(root.Create(), ShouldBeNil)
+			So(root.Invoke(func(c *defaultCmp) { So(c.name, ShouldEqual, "default") }), ShouldBeNil)
+		})
+
+		Convey("a sub-group should override the default", func() {
+			child := root.New("app")
+			So(child.Add(func() *defaultCmp { return &defaultCmp{"app"} }), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+			So(child.Invoke(func(c *defaultCmp) { So(c.name, ShouldEqual, "app") }), ShouldBeNil)
+			So(root.Invoke(func(*defaultCmp) {}), ShouldBeError)
+		})
+	})
+}
+
+func TestInvokeTagged(t *testing.T) {
+	Convey("After we add tagged components to a group", t, func() {
+		root := New("root").(*group)
+		So(root.AddTagged(func() *defaultCmp { return &defaultCmp{"db"} }, di.Tag("subsystem", "storage")), ShouldBeNil)
+		So(root.AddTagged(func() *planDB { return &planDB{} }, di.Tag("subsystem", "api")), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+
+		Convey("functions should be invoked over the tagged components", func() {
+			names := []string{}
+			So(root.InvokeTagged("subsystem", "storage", func(c *defaultCmp) { names = append(names, c.name) }), ShouldBeNil)
+			So(names, ShouldResemble, []string{"db"})
+		})
+	})
+}
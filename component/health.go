@@ -0,0 +1,131 @@
+package component
+
+import (
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/config"
+)
+
+// healthSettings are the health check settings of a component.
+type healthSettings struct {
+	// Maximum time to wait for a health hook in milliseconds, no limit if 0
+	Timeout int `json:"timeout_ms"`
+	// Consecutive failures after which the component is reported unhealthy
+	Threshold int `json:"failure_threshold"`
+}
+
+// healthConfig is the configuration of the health checks stored under the
+// "health" key. The settings apply to all the components and can be
+// overridden per component type, e.g.
+//
+//	"health": {
+//		"timeout_ms": 1000,
+//		"failure_threshold": 3,
+//		"components": {"*http.server": {"timeout_ms": 200}}
+//	}
+//
+// The configuration is optional, by default the health hooks have a 5 second
+// timeout and a component is unhealthy as soon as a check fails.
+type healthConfig struct {
+	healthSettings
+	Components map[string]healthSettings `json:"components"`
+}
+
+func newHealthConfig() *healthConfig {
+	return &healthConfig{
+		healthSettings: healthSettings{Timeout: 5000, Threshold: 1},
+		Components:     map[string]healthSettings{},
+	}
+}
+
+func (h *healthConfig) Key() config.Key {
+	return "health"
+}
+
+// settings returns the settings of the component type.
+func (h *healthConfig) settings(cmp string) healthSettings {
+	s := h.healthSettings
+	if o, ok := h.Components[cmp]; ok {
+		if o.Timeout > 0 {
+			s.Timeout = o.Timeout
+		}
+		if o.Threshold > 0 {
+			s.Threshold = o.Threshold
+		}
+	}
+	if s.Threshold < 1 {
+		s.Threshold = 1
+	}
+	return s
+}
+
+// healthState tracks the health checks of a health hook.
+type healthState struct {
+	lock     sync.Mutex
+	failures int
+	running  bool
+	checked  bool
+	healthy  bool
+}
+
+// checkHealth calls the i-th health hook of the group with the timeout of
+// the component. A check that times out fails, and the hook is not called
+// again until the hung call returns. It returns false once the checks failed
+// the threshold of consecutive times.
+func (g *group) checkHealth(i int, h HealthHook) bool {
+	cmp := componentName(h)
+	cfg := g.health.settings(cmp)
+	s := g.healthState(i)
+
+	ok := false
+	s.lock.Lock()
+	running := s.running
+	s.running = true
+	s.lock.Unlock()
+	if running {
+		g.ctx.Log().Warn().Str("component", cmp).Msg("health check is still running")
+	} else if cfg.Timeout <= 0 {
+		ok = h.IsHealthy(g.ctx)
+		s.done()
+	} else {
+		result := make(chan bool, 1)
+		go func() {
+			r := h.IsHealthy(g.ctx)
+			s.done()
+			result <- r
+		}()
+		select {
+		case ok = <-result:
+		case <-time.After(time.Duration(cfg.Timeout) * time.Millisecond):
+			g.ctx.Log().Warn().Str("component", cmp).Msg("health check timed out")
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ok {
+		s.failures = 0
+	} else {
+		s.failures++
+	}
+	s.checked = true
+	s.healthy = s.failures < cfg.Threshold
+	return s.healthy
+}
+
+// healthState returns the state of the i-th health hook of the group.
+func (g *group) healthState(i int) *healthState {
+	g.healthLock.Lock()
+	defer g.healthLock.Unlock()
+	for len(g.healthStates) <= i {
+		g.healthStates = append(g.healthStates, &healthState{})
+	}
+	return g.healthStates[i]
+}
+
+func (s *healthState) done() {
+	s.lock.Lock()
+	s.running = false
+	s.lock.Unlock()
+}
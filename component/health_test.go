@@ -0,0 +1,88 @@
+package component
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type flakyCmp struct {
+	lock    sync.Mutex
+	healthy bool
+	hang    chan struct{}
+}
+
+func (f *flakyCmp) IsHealthy(ctx Context) bool {
+	f.lock.Lock()
+	hang, healthy := f.hang, f.healthy
+	f.lock.Unlock()
+	if hang != nil {
+		<-hang
+	}
+	return healthy
+}
+
+func (f *flakyCmp) set(healthy bool, hang chan struct{}) {
+	f.lock.Lock()
+	f.healthy, f.hang = healthy, hang
+	f.lock.Unlock()
+}
+
+func TestHealth(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group with a health hook", t, func() {
+		grp := New("base")
+		f := &flakyCmp{healthy: true}
+		So(grp.Add(func() *flakyCmp { return f }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the defaults should apply without configuration", func() {
+			os.Args = []string{"health.test"}
+			So(grp.Configure(), ShouldBeNil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			f.set(false, nil)
+			So(grp.IsHealthy(), ShouldBeFalse)
+		})
+
+		Convey("bad configuration should fail", func() {
+			os.Args = []string{"health.test", "--config.mem", `{"health": {"timeout_ms": "x"}}`}
+			So(grp.Configure(), ShouldBeError)
+		})
+
+		Convey("the failure threshold should be applied", func() {
+			os.Args = []string{"health.test", "--config.mem", `{"health": {"failure_threshold": 5, "components": {"*component.flakyCmp": {"failure_threshold": 2}}}}`}
+			So(grp.Configure(), ShouldBeNil)
+			f.set(false, nil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			So(grp.IsHealthy(), ShouldBeFalse)
+			f.set(true, nil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			f.set(false, nil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+		})
+
+		Convey("hung health checks should time out", func() {
+			os.Args = []string{"health.test", "--config.mem", `{"health": {"components": {"*component.flakyCmp": {"timeout_ms": 10}}}}`}
+			So(grp.Configure(), ShouldBeNil)
+			hang := make(chan struct{})
+			f.set(true, hang)
+			So(grp.IsHealthy(), ShouldBeFalse)
+			// The hook is not called again while it is hung
+			So(grp.IsHealthy(), ShouldBeFalse)
+			f.set(true, nil)
+			close(hang)
+			// Wait for the hung call to return
+			healthy := false
+			for i := 0; i < 100 && !healthy; i++ {
+				time.Sleep(time.Millisecond)
+				healthy = grp.IsHealthy()
+			}
+			So(healthy, ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,58 @@
+package component
+
+// Hooks is a set of lifecycle callbacks, any of which may be nil.
+type Hooks struct {
+	OnStart  func(ctx Context) error
+	OnStop   func(ctx Context) error
+	OnHealth func(ctx Context) bool
+}
+
+// Lifecycle lets constructors register start, stop and health callbacks, as
+// an alternative to implementing StartHook, StopHook and HealthHook on the
+// returned type.
+// This is useful for third party types that cannot be given methods.
+//
+// Each group provides its own Lifecycle. The callbacks are called with the
+// start and stop hooks of the group in the order in which they are appended,
+// the start callbacks in dependency order and the stop callbacks in the
+// reverse order.
+type Lifecycle interface {
+	Append(h Hooks)
+}
+
+type lifecycle struct {
+	g *group
+}
+
+func (l *lifecycle) Append(h Hooks) {
+	if h.OnStart != nil {
+		l.g.startHooks = append(l.g.startHooks, startFunc(h.OnStart))
+	}
+	if h.OnStop != nil {
+		l.g.stopHooks = append(l.g.stopHooks, stopFunc(h.OnStop))
+	}
+	if h.OnHealth != nil {
+		l.g.healthHooks = append(l.g.healthHooks, healthFunc(h.OnHealth))
+	}
+}
+
+// startFunc adapts a start callback to a StartHook.
+type startFunc func(ctx Context) error
+
+func (f startFunc) Start(ctx Context) error {
+	return f(ctx)
+}
+
+// stopFunc adapts a stop callback to a StopHook.
+type stopFunc func(ctx Context) error
+
+func (f stopFunc) Stop(ctx Context) error {
+	return f(ctx)
+}
+
+// healthFunc adapts a health callback to a HealthHook.
+type healthFunc func(ctx Context) bool
+
+func (f healthFunc) IsHealthy(ctx Context) bool {
+	return f(ctx)
+}
@@ -0,0 +1,68 @@
+package component
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// thirdParty is a type without lifecycle methods.
+type thirdParty struct {
+	name string
+}
+
+type otherThirdParty thirdParty
+
+func TestLifecycle(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"lifecycle.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create components that append lifecycle hooks", t, func() {
+		grp := New("base")
+		events := []string{}
+		hooks := func(name string) Hooks {
+			return Hooks{
+				OnStart: func(ctx Context) error {
+					events = append(events, "start "+name)
+					return nil
+				},
+				OnStop: func(ctx Context) error {
+					events = append(events, "stop "+name)
+					return nil
+				},
+			}
+		}
+		So(grp.Add(func(lc Lifecycle, t *thirdParty) *otherThirdParty {
+			lc.Append(hooks("b"))
+			lc.Append(Hooks{})
+			return &otherThirdParty{"b"}
+		}), ShouldBeNil)
+		So(grp.Add(func(lc Lifecycle) *thirdParty {
+			lc.Append(hooks("a"))
+			return &thirdParty{"a"}
+		}), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+		So(grp.Configure(), ShouldBeNil)
+
+		Convey("the hooks should be called in dependency order", func() {
+			So(grp.Start(), ShouldBeNil)
+			So(grp.Stop(), ShouldBeNil)
+			So(events, ShouldResemble, []string{"start a", "start b", "stop b", "stop a"})
+		})
+	})
+
+	Convey("A failing start callback should fail the start", t, func() {
+		grp := New("base")
+		So(grp.Add(func(lc Lifecycle) *thirdParty {
+			lc.Append(Hooks{OnStart: func(ctx Context) error { return errors.New("failed") }})
+			return &thirdParty{}
+		}), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+		err := grp.Start()
+		So(err, ShouldBeError)
+		So(err.(*LifecycleError).Unwrap().Error(), ShouldEqual, "failed")
+	})
+}
@@ -0,0 +1,108 @@
+package component
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/zlog"
+)
+
+// Option customizes a group at creation time.
+type Option func(g *group)
+
+// WithLogger overrides the logger of the group, which by default is named
+// after the group. The run ID is stamped on its events.
+func WithLogger(log zlog.Logger) Option {
+	return func(g *group) {
+		g.ctx.log = withRunID(log, g.ctx.RunID())
+	}
+}
+
+// WithConfigPrefix looks up the configuration of the components of the group
+// and its sub-groups under the prefix, e.g. with the prefix "tenant1" the
+// component configured by the "http" key reads the "tenant1.http" key.
+// The prefixed key is a flat top-level key of the configuration, not a
+// nested path, i.e. the component reads
+//
+//	"tenant1.http": {"port": 8080}
+//
+// but not "tenant1": {"http": {"port": 8080}}, for which the "tenant1.http"
+// key is not found. Sub-groups inherit the prefix of their parent by
+// default.
+func WithConfigPrefix(prefix string) Option {
+	return func(g *group) {
+		g.prefix = prefix
+	}
+}
+
+// WithStore makes the group and its sub-groups read the configuration of
+// their components from the store, instead of the store given on the command
+// line, e.g. to inject a configtest.FaultyStore in tests. The store of a
+// sub-group is opened when the sub-group is configured.
+func WithStore(s config.Store) Option {
+	return func(g *group) {
+		g.store = s
+		g.ownStore = g.parent != nil
+	}
+}
+
+// NonCritical marks the group as not critical to the server. A non-critical
+// group that is unhealthy or not ready does not make its parent unhealthy or
+// not ready.
+func NonCritical() Option {
+	return func(g *group) {
+		g.critical = false
+	}
+}
+
+var groupName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateName checks that the name is a valid and unique child group name.
+func (g *group) validateName(name string) error {
+	if !groupName.MatchString(name) {
+		return fmt.Errorf("invalid group name %q, must start with a letter or digit followed by letters, digits, '_', '.' or '-'", name)
+	}
+	return g.uniqueName(name)
+}
+
+// uniqueName checks that the name is not used by another child group.
+func (g *group) uniqueName(name string) error {
+	for _, child := range g.children {
+		if child.name == name {
+			return fmt.Errorf("group %s already has a child group named %s", g.name, name)
+		}
+	}
+	return nil
+}
+
+// prefixed returns the configuration object that is looked up under the
+// config prefix of the group.
+func (g *group) prefixed(cfg config.Config) config.Config {
+	if g.prefix == "" || cfg == nil || cfg.Key().IsNil() {
+		return cfg
+	}
+	return &prefixedConfig{cfg, g.prefix}
+}
+
+// prefixedConfig looks up a configuration object under the flat
+// "prefix.key" key, see WithConfigPrefix.
+type prefixedConfig struct {
+	config.Config
+	prefix string
+}
+
+func (p *prefixedConfig) Key() config.Key {
+	return config.Key(p.prefix + "." + string(p.Config.Key()))
+}
+
+// Unwrap returns the configuration of the component, so that the store
+// migrates it, see config.Migrate.
+func (p *prefixedConfig) Unwrap() config.Config {
+	return p.Config
+}
+
+func (p *prefixedConfig) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, p.Config)
+}
@@ -0,0 +1,135 @@
+package component
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/config/configtest"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type prefixCfg struct {
+	config.BaseConfig
+	Value string `json:"value"`
+}
+
+type prefixCmp struct {
+	cfg *prefixCfg
+}
+
+func (p *prefixCmp) Config() config.Config {
+	return p.cfg
+}
+
+func (p *prefixCmp) Configure(ctx Context) error {
+	return nil
+}
+
+type unhealthyCmp struct{}
+
+func (u *unhealthyCmp) IsHealthy(ctx Context) bool { return false }
+
+func TestOptions(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a root group", t, func() {
+		root := New("root").(*group)
+
+		Convey("invalid or duplicate child names should be rejected", func() {
+			_, err := root.NewE("")
+			So(err, ShouldBeError)
+			_, err = root.NewE("bad name")
+			So(err, ShouldBeError)
+			_, err = root.NewE("-bad")
+			So(err, ShouldBeError)
+
+			child, err := root.NewE("child-1.a_b")
+			So(err, ShouldBeNil)
+			So(child, ShouldNotBeNil)
+			_, err = root.NewE("child-1.a_b")
+			So(err, ShouldBeError)
+			// New does not validate the format of the names
+			So(root.New("__debug_bin"), ShouldNotBeNil)
+			So(root.New("my+server"), ShouldNotBeNil)
+			// but rejects the duplicate names
+			So(func() { root.New("child-1.a_b") }, ShouldPanic)
+			So(func() { root.New("__debug_bin") }, ShouldPanic)
+		})
+
+		Convey("the logger should be overridden", func() {
+			log := zlog.New("custom")
+			child, err := root.NewE("child", WithLogger(log))
+			So(err, ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+			So(child.Invoke(func(ctx Context) {
+				So(ctx.Log().(*runLogger).Logger, ShouldEqual, log)
+			}), ShouldBeNil)
+		})
+
+		Convey("the configuration should be read under the prefix", func() {
+			child, err := root.NewE("tenant", WithConfigPrefix("t1"))
+			So(err, ShouldBeNil)
+			grandChild := child.New("inner")
+			cmp := &prefixCmp{&prefixCfg{BaseConfig: config.BaseConfig{ConfigKey: "cmp"}}}
+			So(grandChild.Add(func() *prefixCmp { return cmp }), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+
+			os.Args = []string{"options.test", "--config.mem", `{"cmp": {"value": "plain"}, "t1.cmp": {"value": "prefixed"}}`}
+			So(root.Configure(), ShouldBeNil)
+			So(cmp.cfg.Value, ShouldEqual, "prefixed")
+		})
+
+		Convey("the prefixed key should not be read as a nested path", func() {
+			child, err := root.NewE("tenant", WithConfigPrefix("t1"))
+			So(err, ShouldBeNil)
+			cmp := &prefixCmp{&prefixCfg{BaseConfig: config.BaseConfig{ConfigKey: "cmp"}}}
+			So(child.Add(func() *prefixCmp { return cmp }), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+
+			os.Args = []string{"options.test", "--config.mem", `{"cmp": {"value": "plain"}, "t1": {"cmp": {"value": "nested"}}}`}
+			err = root.Configure()
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, "t1.cmp key not found")
+			So(cmp.cfg.Value, ShouldBeEmpty)
+		})
+
+		Convey("the configuration should be read from the store of the option", func() {
+			os.Args = []string{"options.test"}
+			store := configtest.NewFaultyStore(config.NewJSONStore(strings.NewReader(`{"cmp": {"value": "stored"}}`)))
+			root := New("root", WithStore(store))
+			cmp := &prefixCmp{&prefixCfg{BaseConfig: config.BaseConfig{ConfigKey: "cmp"}}}
+			So(root.Add(func() *prefixCmp { return cmp }), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+			So(root.Configure(), ShouldBeNil)
+			So(cmp.cfg.Value, ShouldEqual, "stored")
+
+			store.FailGet("cmp", errors.New("get failed"))
+			err := root.Configure()
+			So(err, ShouldHaveSameTypeAs, &LifecycleError{})
+			So(err.Error(), ShouldContainSubstring, "get failed")
+		})
+
+		Convey("a sub-group should open the store of the option", func() {
+			os.Args = []string{"options.test"}
+			store := configtest.NewFaultyStore(config.NewJSONStore(strings.NewReader(`{}`)))
+			_, err := root.NewE("tenant", WithStore(store.FailOpen(errors.New("open failed"))))
+			So(err, ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+			So(root.Configure(), ShouldBeError, "open failed")
+		})
+
+		Convey("a non-critical group should not affect the health of its parent", func() {
+			child, err := root.NewE("optional", NonCritical())
+			So(err, ShouldBeNil)
+			So(child.Add(func() *unhealthyCmp { return &unhealthyCmp{} }), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+			So(child.IsHealthy(), ShouldBeFalse)
+			So(root.IsHealthy(), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,139 @@
+package component
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/anuvu/cube/di"
+)
+
+// Action is a step of the execution plan of a group.
+type Action struct {
+	// Phase of the action, create, invoke, configure, start or warmup
+	Phase string `json:"phase"`
+	// Group is the path of the group of the component
+	Group string `json:"group"`
+	// Component is the value constructed or the function invoked at create,
+	// the type of the component in the other phases
+	Component string `json:"component"`
+	// Provider is the constructor invoked at create
+	Provider string `json:"provider,omitempty"`
+	// Dependencies of the constructor at create
+	Dependencies []PlanDependency `json:"dependencies,omitempty"`
+}
+
+// PlanDependency is a dependency of a constructor and the group providing it.
+type PlanDependency struct {
+	// ID is the identifier of the dependency as in the dependency graph
+	ID string `json:"id"`
+	// Group is the path of the group providing the dependency, empty if no
+	// group provides it
+	Group string `json:"group"`
+}
+
+// Plan is the ordered list of the actions taken by a group to create,
+// configure and start its components and sub-groups.
+type Plan []Action
+
+// String returns the plan with one numbered action per line.
+func (p Plan) String() string {
+	b := &bytes.Buffer{}
+	for i, a := range p {
+		fmt.Fprintf(b, "%d. %s %s %s", i+1, a.Phase, a.Group, a.Component)
+		if a.Provider != "" {
+			fmt.Fprintf(b, " by %s", a.Provider)
+		}
+		deps := []string{}
+		for _, d := range a.Dependencies {
+			from := d.Group
+			if from == "" {
+				from = "missing"
+			}
+			deps = append(deps, fmt.Sprintf("%s (%s)", d.ID, from))
+		}
+		if len(deps) > 0 {
+			fmt.Fprintf(b, " with %s", strings.Join(deps, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// Plan returns the actions the group would take to create, configure and
+// start itself and its sub-groups, in order, without taking them. The
+// constructors invoked at the same level of the dependency graph are listed
+// in no particular order, they may be invoked concurrently by Create.
+//
+// The lifecycle hooks of the components are only known once their values are
+// constructed, the configure, start and warmup actions are listed once the
+// group is created. Before, the plan only lists the constructors, it does
+// not invoke them, but the default constructors and the selection of the
+// alternatives are decided by Create and are not listed.
+func (g *group) Plan() Plan {
+	p := Plan{}
+	g.planCreate(&p)
+	g.planConfigure(&p)
+	g.planStart(&p)
+	return p
+}
+
+func (g *group) planCreate(p *Plan) {
+	path := g.path()
+	for _, level := range g.c.Order() {
+		for _, r := range level {
+			a := Action{Phase: "create", Group: path, Component: r.ID, Provider: r.Provider}
+			for _, d := range r.Dependencies {
+				a.Dependencies = append(a.Dependencies, PlanDependency{d.ID, g.providedBy(d)})
+			}
+			*p = append(*p, a)
+		}
+	}
+	for _, f := range g.invokes {
+		name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+		*p = append(*p, Action{Phase: "invoke", Group: path, Component: name})
+	}
+	for _, child := range g.children {
+		child.planCreate(p)
+	}
+}
+
+// providedBy returns the path of the group providing the dependency, empty
+// if no group provides it.
+func (g *group) providedBy(d di.Dependency) string {
+	if d.Source == di.SourceMissing {
+		return ""
+	}
+	grp := g
+	for i := 0; i < d.Depth && grp.parent != nil; i++ {
+		grp = grp.parent
+	}
+	return grp.path()
+}
+
+func (g *group) planConfigure(p *Plan) {
+	for _, h := range g.configHooks {
+		*p = append(*p, g.planAction("configure", h))
+	}
+	for _, child := range g.children {
+		child.planConfigure(p)
+	}
+}
+
+func (g *group) planStart(p *Plan) {
+	for _, h := range g.startHooks {
+		*p = append(*p, g.planAction("start", h))
+	}
+	for _, child := range g.children {
+		child.planStart(p)
+	}
+	for _, h := range g.warmHooks {
+		*p = append(*p, g.planAction("warmup", h))
+	}
+}
+
+func (g *group) planAction(phase string, cmp interface{}) Action {
+	return Action{Phase: phase, Group: g.path(), Component: componentName(cmp)}
+}
@@ -0,0 +1,76 @@
+package component
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type planDB struct{}
+
+func (d *planDB) Config() config.Config           { return nil }
+func (d *planDB) Configure(ctx Context) error     { return nil }
+func (d *planDB) Start(ctx Context) error         { return nil }
+func (d *planDB) Warmup(ctx Context) error        { return nil }
+func newPlanDB(ctx Context) *planDB               { return &planDB{} }
+func newPlanAPI(db *planDB, ctx Context) *planAPI { return &planAPI{} }
+
+type planAPI struct{}
+
+func (a *planAPI) Start(ctx Context) error { return nil }
+
+func TestPlan(t *testing.T) {
+	Convey("Create a group with a sub-group depending on it", t, func() {
+		root := New("root").(*group)
+		So(root.Add(newPlanDB), ShouldBeNil)
+		api := root.New("api").(*group)
+		So(api.Add(newPlanAPI), ShouldBeNil)
+		So(api.AddInvoke(func(*planAPI) {}), ShouldBeNil)
+
+		actions := func(p Plan, phase string) []Action {
+			as := []Action{}
+			for _, a := range p {
+				if a.Phase == phase {
+					as = append(as, a)
+				}
+			}
+			return as
+		}
+
+		Convey("the plan should list the constructors before creation", func() {
+			p := root.Plan()
+			So(actions(p, "configure"), ShouldBeEmpty)
+			create := actions(p, "create")
+			last := create[len(create)-1]
+			So(last.Group, ShouldEqual, "root/api")
+			So(last.Component, ShouldEqual, "github.com/anuvu/cube/component.planAPI")
+			So(last.Provider, ShouldEqual, "github.com/anuvu/cube/component.newPlanAPI")
+			So(last.Dependencies, ShouldResemble, []PlanDependency{
+				{"github.com/anuvu/cube/component.Context", "root/api"},
+				{"github.com/anuvu/cube/component.planDB", "root"},
+			})
+			invoke := actions(p, "invoke")
+			So(invoke, ShouldHaveLength, 1)
+			So(invoke[0].Group, ShouldEqual, "root/api")
+			So(p[len(p)-1], ShouldResemble, invoke[0])
+		})
+
+		Convey("the plan should list the lifecycle hooks after creation", func() {
+			So(root.Create(), ShouldBeNil)
+			p := root.Plan()
+			So(actions(p, "configure"), ShouldResemble, []Action{
+				{Phase: "configure", Group: "root", Component: "*component.planDB"},
+			})
+			So(actions(p, "start"), ShouldHaveLength, 2)
+			So(p[len(p)-3:], ShouldResemble, Plan{
+				{Phase: "start", Group: "root", Component: "*component.planDB"},
+				{Phase: "start", Group: "root/api", Component: "*component.planAPI"},
+				{Phase: "warmup", Group: "root", Component: "*component.planDB"},
+			})
+			So(p.String(), ShouldContainSubstring, "create root/api github.com/anuvu/cube/component.planAPI by github.com/anuvu/cube/component.newPlanAPI with github.com/anuvu/cube/component.Context (root/api), github.com/anuvu/cube/component.planDB (root)\n")
+			So(strings.HasPrefix(p.String(), "1. create root "), ShouldBeTrue)
+		})
+	})
+}
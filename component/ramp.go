@@ -0,0 +1,49 @@
+package component
+
+import (
+	"sync"
+	"time"
+)
+
+// Ramp ramps a traffic weight linearly from 0 to 1 over a duration. Warmup
+// hooks can begin a ramp once the component is warm, and discovery
+// registration can advertise the current weight so that warmed caches and
+// connection pools are not hit with full traffic instantly.
+type Ramp struct {
+	lock     sync.Mutex
+	duration time.Duration
+	begin    time.Time
+	now      func() time.Time
+}
+
+// NewRamp returns a ramp over the duration.
+func NewRamp(d time.Duration) *Ramp {
+	return &Ramp{duration: d, now: time.Now}
+}
+
+// Begin begins the ramp, calling it again restarts the ramp.
+func (r *Ramp) Begin() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.begin = r.now()
+}
+
+// Weight returns the current weight between 0 and 1. The weight is 0 until
+// the ramp begins.
+func (r *Ramp) Weight() float64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.begin.IsZero() {
+		return 0
+	}
+	elapsed := r.now().Sub(r.begin)
+	if r.duration <= 0 || elapsed >= r.duration {
+		return 1
+	}
+	return float64(elapsed) / float64(r.duration)
+}
+
+// Done returns true if the ramp reached the full weight.
+func (r *Ramp) Done() bool {
+	return r.Weight() >= 1
+}
@@ -0,0 +1,34 @@
+package component
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRamp(t *testing.T) {
+	Convey("After we create a ramp", t, func() {
+		now := time.Now()
+		r := NewRamp(10 * time.Second)
+		r.now = func() time.Time { return now }
+		So(r.Weight(), ShouldEqual, 0)
+		So(r.Done(), ShouldBeFalse)
+
+		Convey("the weight should ramp linearly", func() {
+			r.Begin()
+			So(r.Weight(), ShouldEqual, 0)
+			now = now.Add(5 * time.Second)
+			So(r.Weight(), ShouldEqual, 0.5)
+			now = now.Add(10 * time.Second)
+			So(r.Weight(), ShouldEqual, 1)
+			So(r.Done(), ShouldBeTrue)
+		})
+
+		Convey("zero duration ramps should be done once begun", func() {
+			r = NewRamp(0)
+			r.Begin()
+			So(r.Done(), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package component
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/anuvu/cube/config"
+)
+
+// panicConfig is the configuration of the handling of the panics of the
+// goroutines stored under the "panics" key, e.g.
+//
+//	"panics": {"policy": "crash"}
+//
+// With the default "degrade" policy a panic is recovered, the group of the
+// goroutine is unhealthy from then on and the EventPanicked event is
+// emitted. With the "crash" policy the panic is logged and raised again,
+// which crashes the process, e.g. to get a core dump in development.
+type panicConfig struct {
+	Policy string `json:"policy"`
+}
+
+// Panic policies of the "panics" configuration.
+const (
+	degradeOnPanic = "degrade"
+	crashOnPanic   = "crash"
+)
+
+func (p *panicConfig) Key() config.Key {
+	return "panics"
+}
+
+func (p *panicConfig) check() error {
+	switch p.Policy {
+	case "":
+		p.Policy = degradeOnPanic
+	case degradeOnPanic, crashOnPanic:
+	default:
+		return fmt.Errorf("unknown panic policy %q, must be %s or %s", p.Policy, degradeOnPanic, crashOnPanic)
+	}
+	return nil
+}
+
+// Recover recovers a panic of the goroutine, like the goroutines started
+// with Runner.Go. It must be deferred by the goroutines that are not
+// started with Runner.Go, e.g. the goroutines of a third party library:
+//
+//	go func() {
+//		defer component.Recover(ctx)
+//		...
+//	}()
+//
+// The panic is logged with its stack and, with the default "degrade" policy
+// of the "panics" configuration, the group of the context is unhealthy from
+// then on and the EventPanicked event is emitted to the event hooks. With the
+// "crash" policy the panic is raised again.
+func Recover(ctx Context) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if sc, ok := ctx.(*srvCtx); ok {
+		sc.recovered(r, debug.Stack())
+		return
+	}
+	err := fmt.Errorf("panic: %v", r)
+	ctx.Log().Error().Error(err).Str("stack", string(debug.Stack())).Msg("goroutine panicked")
+}
+
+// recovered handles the recovered panic, it returns the error of the panic.
+func (sc *srvCtx) recovered(r interface{}, stack []byte) error {
+	err := fmt.Errorf("panic: %v", r)
+	cmp := ""
+	if sc.usage != nil {
+		cmp = sc.usage.usage.Component
+	}
+	sc.log.Error().Error(err).Str("component", cmp).Str("stack", string(stack)).Msg("goroutine panicked")
+	if sc.onPanic != nil {
+		sc.onPanic(r, cmp, err)
+	}
+	sc.tasks.degrade(err)
+	return err
+}
+
+// panicked handles the panic of a goroutine of the component of the group as
+// per the panic policy.
+func (g *group) panicked(r interface{}, cmp string, err error) {
+	if g.panics.Policy == crashOnPanic {
+		panic(r)
+	}
+	root := g
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.emit(Event{Type: EventPanicked, Group: g.path(), Component: cmp, Err: err})
+}
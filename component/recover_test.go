@@ -0,0 +1,68 @@
+package component
+
+import (
+	"os"
+	"testing"
+
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type panickingCmp struct {
+	release chan struct{}
+}
+
+func (p *panickingCmp) Start(ctx Context) error {
+	Go(ctx, func(ctx Context) error {
+		<-p.release
+		panic("test panic")
+	})
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("Recover should degrade the context", t, func() {
+		ctx := RootContext(zlog.New("test")).(*srvCtx)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer Recover(ctx)
+			panic("test panic")
+		}()
+		<-done
+		So(ctx.tasks.failure(), ShouldBeError, "panic: test panic")
+	})
+
+	Convey("After we create a group with a panicking goroutine", t, func() {
+		base := New("base")
+		rec := &eventRecorder{}
+		child := base.New("child")
+		So(base.Add(func() *eventRecorder { return rec }), ShouldBeNil)
+		p := &panickingCmp{release: make(chan struct{})}
+		So(child.Add(func() *panickingCmp { return p }), ShouldBeNil)
+		So(base.Create(), ShouldBeNil)
+
+		Convey("the panic should degrade the group and be emitted", func() {
+			os.Args = []string{"recover.test", "--config.mem", `{"accounting": {"enabled": true}}`}
+			So(base.Configure(), ShouldBeNil)
+			So(base.Start(), ShouldBeNil)
+			close(p.release)
+			child.(*group).ctx.tasks.wait()
+			So(base.IsHealthy(), ShouldBeFalse)
+			So(rec.types(), ShouldResemble, []EventType{EventStarted, EventPanicked, EventHealthChanged})
+			e := rec.events[1]
+			So(e.Group, ShouldEqual, "base/child")
+			So(e.Component, ShouldEqual, "*component.panickingCmp")
+			So(e.Err, ShouldBeError, "panic: test panic")
+			So(base.Stop(), ShouldBeNil)
+		})
+
+		Convey("an unknown panic policy should fail the configuration", func() {
+			os.Args = []string{"recover.test", "--config.mem", `{"panics": {"policy": "ignore"}}`}
+			So(base.Configure(), ShouldBeError)
+		})
+	})
+}
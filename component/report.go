@@ -0,0 +1,118 @@
+package component
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Ownership is the on-call metadata of a component, reported with its health
+// so that whoever is paged for an unhealthy component knows where to look.
+type Ownership struct {
+	// Owner of the component, e.g. a person or an alias
+	Owner string `json:"owner,omitempty"`
+	// Team on call for the component
+	Team string `json:"team,omitempty"`
+	// Runbook is the URL of the runbook of the component
+	Runbook string `json:"runbook_url,omitempty"`
+}
+
+// OwnershipHook is the interface that provides the ownership of the component.
+// The fields left empty are inherited from the ownership of the group.
+type OwnershipHook interface {
+	Ownership() Ownership
+}
+
+// WithOwnership sets the default ownership of the components of the group and
+// its sub-groups.
+func WithOwnership(o Ownership) Option {
+	return func(g *group) {
+		g.ownership = o
+	}
+}
+
+// ComponentHealth is the health of a component with a health hook.
+type ComponentHealth struct {
+	Ownership
+	// Group is the path of the group of the component
+	Group string `json:"group"`
+	// Component is the type of the component
+	Component string `json:"component"`
+	// Checked is false until the health hook was called
+	Checked bool `json:"checked"`
+	// Healthy is the result of the last health check
+	Healthy bool `json:"healthy"`
+	// Failures is the number of consecutive failed health checks
+	Failures int `json:"failures"`
+}
+
+// HealthReport is the health of the group and of its components.
+type HealthReport struct {
+	Healthy    bool              `json:"healthy"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// HealthReport checks the health of the group like IsHealthy and reports the
+// health of each component of the group and its sub-groups with their
+// ownership.
+func (g *group) HealthReport() HealthReport {
+	r := HealthReport{Healthy: g.IsHealthy(), Components: []ComponentHealth{}}
+	g.report(&r)
+	return r
+}
+
+func (g *group) report(r *HealthReport) {
+	for i, h := range g.healthHooks {
+		s := g.healthState(i)
+		s.lock.Lock()
+		r.Components = append(r.Components, ComponentHealth{
+			Ownership: g.ownershipOf(h),
+			Group:     g.path(),
+			Component: componentName(h),
+			Checked:   s.checked,
+			Healthy:   s.healthy,
+			Failures:  s.failures,
+		})
+		s.lock.Unlock()
+	}
+	for _, child := range g.children {
+		child.report(r)
+	}
+}
+
+// ownershipOf returns the ownership of the component, completed with the
+// ownership of the group and its parents.
+func (g *group) ownershipOf(cmp interface{}) Ownership {
+	o := Ownership{}
+	if h, ok := unwrapComponent(cmp).(OwnershipHook); ok {
+		o = h.Ownership()
+	}
+	for grp := g; grp != nil; grp = grp.parent {
+		if o.Owner == "" {
+			o.Owner = grp.ownership.Owner
+		}
+		if o.Team == "" {
+			o.Team = grp.ownership.Team
+		}
+		if o.Runbook == "" {
+			o.Runbook = grp.ownership.Runbook
+		}
+	}
+	return o
+}
+
+// HealthHandler returns an admin handler that reports the health report of
+// the group as JSON, with the 503 status if the group is unhealthy.
+func HealthHandler(g Inspector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		report := g.HealthReport()
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
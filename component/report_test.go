@@ -0,0 +1,81 @@
+package component
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type ownedCmp struct {
+	flakyCmp
+}
+
+func (o *ownedCmp) Ownership() Ownership {
+	return Ownership{Owner: "alice", Runbook: "https://runbooks/owned"}
+}
+
+func TestHealthReport(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"report.test"}
+
+	Convey("After we create groups with owned components", t, func() {
+		srv, err := New("base").(*group).NewE("srv", WithOwnership(Ownership{Team: "core", Owner: "bob"}))
+		So(err, ShouldBeNil)
+		base := srv.(*group)
+		f := &flakyCmp{healthy: true}
+		o := &ownedCmp{flakyCmp{healthy: true}}
+		So(base.Add(func() *flakyCmp { return f }), ShouldBeNil)
+		child := base.New("child")
+		So(child.Add(func() *ownedCmp { return o }), ShouldBeNil)
+		So(base.Create(), ShouldBeNil)
+		So(base.Configure(), ShouldBeNil)
+
+		Convey("the report should include the health and the ownership", func() {
+			o.set(false, nil)
+			r := base.HealthReport()
+			So(r.Healthy, ShouldBeFalse)
+			So(r.Components, ShouldResemble, []ComponentHealth{
+				{
+					Ownership: Ownership{Owner: "bob", Team: "core"},
+					Group:     "base/srv",
+					Component: "*component.flakyCmp",
+					Checked:   true,
+					Healthy:   true,
+				},
+				{
+					Ownership: Ownership{Owner: "alice", Team: "core", Runbook: "https://runbooks/owned"},
+					Group:     "base/srv/child",
+					Component: "*component.ownedCmp",
+					Checked:   true,
+					Healthy:   false,
+					Failures:  1,
+				},
+			})
+		})
+
+		Convey("the handler should serve the report", func() {
+			w := httptest.NewRecorder()
+			HealthHandler(base).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			body := map[string]interface{}{}
+			So(json.NewDecoder(w.Body).Decode(&body), ShouldBeNil)
+			So(body["healthy"], ShouldBeTrue)
+			cmps := body["components"].([]interface{})
+			So(cmps[1].(map[string]interface{})["runbook_url"], ShouldEqual, "https://runbooks/owned")
+
+			f.set(false, nil)
+			w = httptest.NewRecorder()
+			HealthHandler(base).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+
+			w = httptest.NewRecorder()
+			HealthHandler(base).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/health", nil))
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		})
+	})
+}
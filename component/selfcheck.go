@@ -0,0 +1,91 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SelfCheckHook is the interface that provides the self-check callback for
+// the component. The self-check verifies that a configured component would
+// be able to start, e.g. that its TLS material loads, that its secret
+// references resolve or that the services it depends on are reachable,
+// without starting it. The context is cancelled at the timeout of the check.
+type SelfCheckHook interface {
+	SelfCheck(ctx Context) error
+}
+
+// CheckError aggregates the errors of the components that failed their
+// self-check in a group and its sub-groups, in the order in which they were
+// checked.
+type CheckError struct {
+	Errs []error
+}
+
+func (e *CheckError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d components failed their self-check: %s", len(e.Errs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first error.
+func (e *CheckError) Unwrap() error {
+	return e.Errs[0]
+}
+
+// SelfCheck calls the self-check hooks of the configured components of the
+// group and its sub-groups, in the order used by Start, each bounded by the
+// timeout, no limit if 0. All the hooks are called even if some of them
+// fail, a hook that does not return by the timeout fails. The error of a
+// single failed hook is returned as *LifecycleError, the errors of several
+// failed hooks are aggregated in a *CheckError.
+func (g *group) SelfCheck(timeout time.Duration) error {
+	errs := g.selfCheck(timeout, []error{})
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return &CheckError{Errs: errs}
+}
+
+func (g *group) selfCheck(timeout time.Duration, errs []error) []error {
+	g.ctx.Log().Info().Msg("checking group")
+	for _, h := range g.checkHooks {
+		if err := g.checkOne(h, timeout); err != nil {
+			g.ctx.Log().Error().Error(err).Msg("self-check failed")
+			errs = append(errs, g.lifecycleError("self-check", h, err))
+		}
+	}
+	for _, child := range g.children {
+		errs = child.selfCheck(timeout, errs)
+	}
+	return errs
+}
+
+// checkOne calls the self-check hook with a context cancelled at the
+// timeout, and returns without waiting for a hook ignoring it.
+func (g *group) checkOne(h SelfCheckHook, timeout time.Duration) error {
+	c, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		c, cancel = context.WithTimeout(c, timeout)
+	}
+	defer cancel()
+	ctx, dcancel := g.ctx.derive(c)
+	defer dcancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- h.SelfCheck(ctx)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-c.Done():
+		return fmt.Errorf("self-check timed out after %v", timeout)
+	}
+}
@@ -0,0 +1,64 @@
+package component
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type checkedCmp struct {
+	err  error
+	hang bool
+}
+
+func (c *checkedCmp) SelfCheck(ctx Context) error {
+	if c.hang {
+		<-ctx.Ctx().Done()
+		time.Sleep(time.Second)
+	}
+	return c.err
+}
+
+type otherCheckedCmp struct {
+	checkedCmp
+}
+
+func TestSelfCheck(t *testing.T) {
+	Convey("After we create a group with components checking themselves", t, func() {
+		root := New("base").(*group)
+		c1 := &checkedCmp{}
+		c2 := &otherCheckedCmp{}
+		So(root.Add(func() *checkedCmp { return c1 }), ShouldBeNil)
+		So(root.New("app").Add(func() *otherCheckedCmp { return c2 }), ShouldBeNil)
+		So(root.Create(), ShouldBeNil)
+
+		Convey("the self-check should pass if all the checks pass", func() {
+			So(root.SelfCheck(time.Second), ShouldBeNil)
+		})
+
+		Convey("a failed check should be attributed to its component", func() {
+			c2.err = fmt.Errorf("unreachable")
+			err := root.SelfCheck(time.Second)
+			So(err, ShouldHaveSameTypeAs, &LifecycleError{})
+			le := err.(*LifecycleError)
+			So(le.Phase, ShouldEqual, "self-check")
+			So(le.Group, ShouldEqual, "base/app")
+			So(le.Err, ShouldEqual, c2.err)
+		})
+
+		Convey("all the checks should run and hung checks should time out", func() {
+			c1.hang = true
+			c2.err = fmt.Errorf("unreachable")
+			start := time.Now()
+			err := root.SelfCheck(50 * time.Millisecond)
+			So(time.Since(start), ShouldBeLessThan, time.Second)
+			So(err, ShouldHaveSameTypeAs, &CheckError{})
+			errs := err.(*CheckError).Errs
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Error(), ShouldContainSubstring, "timed out")
+			So(err.Error(), ShouldStartWith, "2 components failed their self-check")
+		})
+	})
+}
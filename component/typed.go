@@ -0,0 +1,133 @@
+//go:build go1.18
+// +build go1.18
+
+package component
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// onType registers the hook built for the values of type *T of the group.
+func onType[T any](g Group, hook func(t *T) interface{}) error {
+	grp, ok := g.(*group)
+	if !ok {
+		return fmt.Errorf("can't register a typed hook with %T", g)
+	}
+	grp.typedHooks = append(grp.typedHooks, typedHook{
+		t: reflect.TypeOf((*T)(nil)),
+		hook: func(v reflect.Value) interface{} {
+			return hook(v.Interface().(*T))
+		},
+	})
+	return nil
+}
+
+// OnStart registers a start hook for the component of type *T of the group,
+// as if *T implemented StartHook, e.g. for a type the application does not
+// own:
+//
+//	component.OnStart(g, func(ctx component.Context, db *sql.DB) error {
+//		return db.PingContext(ctx.Ctx())
+//	})
+//
+// The typed hooks must be registered before Create, they apply to the
+// components constructed by the constructors of the group, not to the values
+// added with AddValue. The hooks are called in the order of the lifecycle
+// with the other hooks of the group.
+func OnStart[T any](g Group, f func(ctx Context, t *T) error) error {
+	return onType(g, func(t *T) interface{} {
+		return typedStart{wrapped{t}, func(ctx Context) error { return f(ctx, t) }}
+	})
+}
+
+// OnStop registers a stop hook for the component of type *T of the group,
+// see OnStart.
+func OnStop[T any](g Group, f func(ctx Context, t *T) error) error {
+	return onType(g, func(t *T) interface{} {
+		return typedStop{wrapped{t}, func(ctx Context) error { return f(ctx, t) }}
+	})
+}
+
+// OnWarmup registers a warmup hook for the component of type *T of the
+// group, see OnStart.
+func OnWarmup[T any](g Group, f func(ctx Context, t *T) error) error {
+	return onType(g, func(t *T) interface{} {
+		return typedWarmup{wrapped{t}, func(ctx Context) error { return f(ctx, t) }}
+	})
+}
+
+// OnHealth registers a health hook for the component of type *T of the
+// group, see OnStart.
+func OnHealth[T any](g Group, f func(ctx Context, t *T) bool) error {
+	return onType(g, func(t *T) interface{} {
+		return typedHealth{wrapped{t}, func(ctx Context) bool { return f(ctx, t) }}
+	})
+}
+
+// OnReady registers a readiness hook for the component of type *T of the
+// group, see OnStart.
+func OnReady[T any](g Group, f func(ctx Context, t *T) bool) error {
+	return onType(g, func(t *T) interface{} {
+		return typedReady{wrapped{t}, func(ctx Context) bool { return f(ctx, t) }}
+	})
+}
+
+// OnSelfCheck registers a self-check hook for the component of type *T of
+// the group, see OnStart.
+func OnSelfCheck[T any](g Group, f func(ctx Context, t *T) error) error {
+	return onType(g, func(t *T) interface{} {
+		return typedSelfCheck{wrapped{t}, func(ctx Context) error { return f(ctx, t) }}
+	})
+}
+
+// wrapped reports the value of a typed hook as its component.
+type wrapped struct {
+	value interface{}
+}
+
+func (w wrapped) wrappedValue() interface{} {
+	return w.value
+}
+
+type typedStart struct {
+	wrapped
+	f func(Context) error
+}
+
+func (h typedStart) Start(ctx Context) error { return h.f(ctx) }
+
+type typedStop struct {
+	wrapped
+	f func(Context) error
+}
+
+func (h typedStop) Stop(ctx Context) error { return h.f(ctx) }
+
+type typedWarmup struct {
+	wrapped
+	f func(Context) error
+}
+
+func (h typedWarmup) Warmup(ctx Context) error { return h.f(ctx) }
+
+type typedHealth struct {
+	wrapped
+	f func(Context) bool
+}
+
+func (h typedHealth) IsHealthy(ctx Context) bool { return h.f(ctx) }
+
+type typedReady struct {
+	wrapped
+	f func(Context) bool
+}
+
+func (h typedReady) IsReady(ctx Context) bool { return h.f(ctx) }
+
+type typedSelfCheck struct {
+	wrapped
+	f func(Context) error
+}
+
+func (h typedSelfCheck) SelfCheck(ctx Context) error { return h.f(ctx) }
@@ -0,0 +1,65 @@
+//go:build go1.18
+// +build go1.18
+
+package component
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// foreign is a type that does not implement any hook interface.
+type foreign struct {
+	calls []string
+}
+
+func TestTypedHooks(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"typed.test"}
+	defer func() { os.Args = oldArgs }()
+	Convey("After we register typed hooks for a foreign type", t, func() {
+		grp := New("typed").(*group)
+		f := &foreign{}
+		So(grp.Add(func() *foreign { return f }), ShouldBeNil)
+		hook := func(name string) func(Context, *foreign) error {
+			return func(ctx Context, t *foreign) error {
+				if t == f {
+					t.calls = append(t.calls, name)
+				}
+				return nil
+			}
+		}
+		So(OnStart(grp, hook("start")), ShouldBeNil)
+		So(OnWarmup(grp, hook("warmup")), ShouldBeNil)
+		So(OnStop(grp, hook("stop")), ShouldBeNil)
+		So(OnSelfCheck(grp, hook("check")), ShouldBeNil)
+		So(OnHealth(grp, func(ctx Context, t *foreign) bool { return true }), ShouldBeNil)
+		So(OnReady(grp, func(ctx Context, t *foreign) bool { return len(t.calls) > 0 }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the hooks should be called by the lifecycle", func() {
+			So(grp.Configure(), ShouldBeNil)
+			So(grp.SelfCheck(time.Second), ShouldBeNil)
+			So(grp.Start(), ShouldBeNil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			So(grp.IsReady(), ShouldBeTrue)
+			So(grp.Stop(), ShouldBeNil)
+			So(f.calls, ShouldResemble, []string{"check", "start", "warmup", "stop"})
+		})
+
+		Convey("the errors should name the foreign component", func() {
+			g := New("typed")
+			So(g.Add(func() *foreign { return &foreign{} }), ShouldBeNil)
+			So(OnStart(g, func(ctx Context, t *foreign) error { return errors.New("failed") }), ShouldBeNil)
+			So(g.Create(), ShouldBeNil)
+			So(g.Configure(), ShouldBeNil)
+			err := g.Start()
+			So(err, ShouldHaveSameTypeAs, &LifecycleError{})
+			So(err.(*LifecycleError).Component, ShouldEqual, "*component.foreign")
+		})
+	})
+}
@@ -0,0 +1,153 @@
+package component
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FrameworkName is the name used in requirements to constrain the version of
+// the cube framework.
+const FrameworkName = "cube"
+
+// FrameworkVersion is the API version of the cube framework.
+const FrameworkVersion = "0.1.0"
+
+// VersionHook is the interface that provides the API version of the component.
+type VersionHook interface {
+	// Version returns the name and the semantic version of the component API.
+	// Other components refer to this component by name in their requirements.
+	Version() (name string, version string)
+}
+
+// RequireHook is the interface that provides the version requirements of the
+// component.
+type RequireHook interface {
+	// Requires returns the version constraints keyed by component name. The
+	// FrameworkName key constrains the cube framework version.
+	//
+	// Constraints are a comma or space separated list of comparisons that
+	// must all be satisfied, e.g. ">=1.2.0, <2.0.0". The supported operators
+	// are =, !=, >, >=, <, <=, ^ (same major) and ~ (same minor).
+	Requires() map[string]string
+}
+
+// semver is a parsed major.minor.patch version, pre-release and build
+// metadata are ignored.
+type semver [3]int
+
+func parseVersion(v string) (semver, error) {
+	var sv semver
+	s := strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 || parts[0] == "" {
+		return sv, fmt.Errorf("invalid version %q", v)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return sv, fmt.Errorf("invalid version %q", v)
+		}
+		sv[i] = n
+	}
+	return sv, nil
+}
+
+func (v semver) compare(o semver) int {
+	for i := range v {
+		if v[i] != o[i] {
+			if v[i] < o[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v semver) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// satisfies checks if version satisfies all the comparisons in constraint.
+func satisfies(version, constraint string) (bool, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	fields := strings.FieldsFunc(constraint, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(fields) == 0 {
+		return false, fmt.Errorf("empty version constraint")
+	}
+	for _, f := range fields {
+		ok, err := compare(v, f)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func compare(v semver, c string) (bool, error) {
+	op := strings.TrimRight(c, "v0123456789.-+abcdefghijklmnopqrstuvwxyz")
+	cv, err := parseVersion(c[len(op):])
+	if err != nil {
+		return false, err
+	}
+	switch op {
+	case "", "=", "==":
+		return v.compare(cv) == 0, nil
+	case "!=":
+		return v.compare(cv) != 0, nil
+	case ">":
+		return v.compare(cv) > 0, nil
+	case ">=":
+		return v.compare(cv) >= 0, nil
+	case "<":
+		return v.compare(cv) < 0, nil
+	case "<=":
+		return v.compare(cv) <= 0, nil
+	case "^":
+		return v[0] == cv[0] && v.compare(cv) >= 0, nil
+	case "~":
+		return v[0] == cv[0] && v[1] == cv[1] && v.compare(cv) >= 0, nil
+	}
+	return false, fmt.Errorf("invalid version constraint %q", c)
+}
+
+// checkVersions verifies that all the requirements are satisfied by the
+// declared versions.
+func checkVersions(versions map[string]string, requires []RequireHook) error {
+	for _, r := range requires {
+		reqs := r.Requires()
+		names := make([]string, 0, len(reqs))
+		for name := range reqs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			constraint := reqs[name]
+			v, ok := versions[name]
+			if !ok {
+				return fmt.Errorf("%T requires %s %s, but %s is not present", r, name, constraint, name)
+			}
+			ok, err := satisfies(v, constraint)
+			if err != nil {
+				return fmt.Errorf("%T requires %s: %v", r, name, err)
+			}
+			if !ok {
+				return fmt.Errorf("%T requires %s %s, but version %s is present", r, name, constraint, v)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package component
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type versioned struct {
+	name     string
+	version  string
+	requires map[string]string
+}
+
+func (v *versioned) Version() (string, string)   { return v.name, v.version }
+func (v *versioned) Requires() map[string]string { return v.requires }
+
+type otherVersioned struct {
+	versioned
+}
+
+type requirer struct {
+	requires map[string]string
+}
+
+func (r *requirer) Requires() map[string]string { return r.requires }
+
+func TestSatisfies(t *testing.T) {
+	Convey("Version constraints should be evaluated", t, func() {
+		cases := []struct {
+			version    string
+			constraint string
+			ok         bool
+		}{
+			{"1.2.3", "1.2.3", true},
+			{"v1.2.3", "=1.2.3", true},
+			{"1.2.3-rc1", "1.2.3", true},
+			{"1.2", "1.2.0", true},
+			{"1.2.3", "!=1.2.3", false},
+			{"1.2.3", ">1.2.2", true},
+			{"1.2.3", ">=1.3", false},
+			{"1.2.3", "<2", true},
+			{"1.2.3", "<=1.2.2", false},
+			{"1.4.0", "^1.2.0", true},
+			{"2.0.0", "^1.2.0", false},
+			{"1.2.9", "~1.2.3", true},
+			{"1.3.0", "~1.2.3", false},
+			{"1.5.0", ">=1.2.0, <2.0.0", true},
+			{"2.5.0", ">=1.2.0 <2.0.0", false},
+		}
+		for _, c := range cases {
+			ok, err := satisfies(c.version, c.constraint)
+			So(err, ShouldBeNil)
+			So(ok, ShouldEqual, c.ok)
+		}
+
+		for _, bad := range [][2]string{{"x.1", "1.0"}, {"1.0", "=>1.0"}, {"1.0", ""}, {"1.0", ">=a.b"}, {"1.2.3.4", "1"}} {
+			_, err := satisfies(bad[0], bad[1])
+			So(err, ShouldBeError)
+		}
+	})
+}
+
+func TestVersionRequirements(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"group.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group hierarchy", t, func() {
+		root := New("root")
+		child := root.New("child")
+		So(root.Add(func() *versioned { return &versioned{"store", "1.4.2", nil} }), ShouldBeNil)
+
+		Convey("satisfied requirements should create", func() {
+			So(child.Add(func() *requirer {
+				return &requirer{map[string]string{FrameworkName: ">=0.1.0", "store": "^1.2"}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeNil)
+		})
+
+		Convey("incompatible framework version should fail", func() {
+			So(child.Add(func() *requirer {
+				return &requirer{map[string]string{FrameworkName: ">=99.0.0"}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("incompatible component version should fail", func() {
+			So(child.Add(func() *requirer {
+				return &requirer{map[string]string{"store": "~1.3.0"}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("missing component should fail", func() {
+			So(child.Add(func() *requirer {
+				return &requirer{map[string]string{"cache": "1.0.0"}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("bad constraint should fail", func() {
+			So(child.Add(func() *requirer {
+				return &requirer{map[string]string{"store": "?1.0.0"}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeError)
+		})
+
+		Convey("conflicting component versions should fail", func() {
+			So(child.Add(func() *otherVersioned {
+				return &otherVersioned{versioned{"store", "2.0.0", nil}}
+			}), ShouldBeNil)
+			So(root.Create(), ShouldBeError)
+		})
+	})
+}
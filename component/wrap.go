@@ -0,0 +1,52 @@
+package component
+
+import "reflect"
+
+// WrapOption adds a lifecycle callback to a wrapped value.
+type WrapOption func(h *Hooks)
+
+// WithStart calls fn when the group of the wrapped value starts.
+func WithStart(fn func(ctx Context) error) WrapOption {
+	return func(h *Hooks) {
+		h.OnStart = fn
+	}
+}
+
+// WithStop calls fn when the group of the wrapped value stops.
+func WithStop(fn func(ctx Context) error) WrapOption {
+	return func(h *Hooks) {
+		h.OnStop = fn
+	}
+}
+
+// WithHealth calls fn when the health of the group of the wrapped value is
+// checked.
+func WithHealth(fn func(ctx Context) bool) WrapOption {
+	return func(h *Hooks) {
+		h.OnHealth = fn
+	}
+}
+
+// Wrap returns a constructor that provides an existing value, e.g. a *sql.DB
+// created elsewhere, and registers the lifecycle callbacks of the options
+// with the group the constructor is added to. It returns nil if the value is
+// nil, which the group rejects.
+//
+//	g.Add(component.Wrap(db, component.WithStop(func(ctx component.Context) error {
+//		return db.Close()
+//	})))
+func Wrap(value interface{}, opts ...WrapOption) interface{} {
+	if value == nil {
+		return nil
+	}
+	hooks := Hooks{}
+	for _, opt := range opts {
+		opt(&hooks)
+	}
+	val := reflect.ValueOf(value)
+	ft := reflect.FuncOf([]reflect.Type{lcType}, []reflect.Type{val.Type()}, false)
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		args[0].Interface().(Lifecycle).Append(hooks)
+		return []reflect.Value{val}
+	}).Interface()
+}
@@ -0,0 +1,59 @@
+package component
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type external struct {
+	open bool
+}
+
+func TestWrap(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"wrap.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we add a wrapped value to a group", t, func() {
+		grp := New("base")
+		ext := &external{}
+		healthy := true
+		So(grp.Add(Wrap(ext,
+			WithStart(func(ctx Context) error {
+				ext.open = true
+				return nil
+			}),
+			WithStop(func(ctx Context) error {
+				ext.open = false
+				return nil
+			}),
+			WithHealth(func(ctx Context) bool { return healthy }),
+		)), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+		So(grp.Configure(), ShouldBeNil)
+
+		Convey("the value should be provided", func() {
+			So(grp.Invoke(func(e *external) {
+				So(e, ShouldEqual, ext)
+			}), ShouldBeNil)
+		})
+
+		Convey("the value should participate in the lifecycle", func() {
+			So(grp.Start(), ShouldBeNil)
+			So(ext.open, ShouldBeTrue)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			healthy = false
+			So(grp.IsHealthy(), ShouldBeFalse)
+			So(grp.Stop(), ShouldBeNil)
+			So(ext.open, ShouldBeFalse)
+		})
+	})
+
+	Convey("A nil value should be rejected", t, func() {
+		grp := New("base")
+		So(Wrap(nil), ShouldBeNil)
+		So(grp.Add(Wrap(nil)), ShouldBeError)
+	})
+}
@@ -1,5 +1,7 @@
 package config
 
+import "fmt"
+
 // Key uniquely identifies a configuration object in the configuration store.
 type Key string
 
@@ -28,6 +30,22 @@ type Store interface {
 	Get(Config) error
 }
 
+// NotFoundError is returned by Get when the key of the configuration is not
+// found in the store.
+type NotFoundError struct {
+	Key Key
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s key not found", e.Key)
+}
+
+// IsNotFound checks if the error is a NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
 // BaseConfig provides a default implementation for Config interface.
 type BaseConfig struct {
 	ConfigKey Key
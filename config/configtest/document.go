@@ -0,0 +1,86 @@
+package configtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/anuvu/cube/config"
+)
+
+// Document builds a nested configuration document. Values are set using dotted
+// paths where the first element is the component key, e.g. "http.port".
+type Document struct {
+	root map[string]interface{}
+}
+
+// NewDocument returns an empty configuration document.
+func NewDocument() *Document {
+	return &Document{root: map[string]interface{}{}}
+}
+
+// Set sets the value at the dotted path, creating intermediate objects as
+// required. Existing non-object values on the path are replaced.
+func (d *Document) Set(path string, value interface{}) *Document {
+	parts := strings.Split(path, ".")
+	m := d.root
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+	return d
+}
+
+// Component returns a builder scoped to the component key.
+func (d *Document) Component(key config.Key) *Component {
+	return &Component{d, string(key)}
+}
+
+// JSON returns the document encoded as JSON.
+func (d *Document) JSON() []byte {
+	b, err := json.Marshal(d.root)
+	if err != nil {
+		// The document is made of maps and user values, a failure here
+		// is a programming error in the test.
+		panic(err)
+	}
+	return b
+}
+
+// String returns the document encoded as JSON.
+func (d *Document) String() string {
+	return string(d.JSON())
+}
+
+// Reader returns a reader of the JSON encoded document.
+func (d *Document) Reader() io.Reader {
+	return bytes.NewReader(d.JSON())
+}
+
+// Store returns a JSON config store backed by the document.
+func (d *Document) Store() config.Store {
+	return config.NewJSONStore(d.Reader())
+}
+
+// Component is a builder for a single component's configuration.
+type Component struct {
+	doc *Document
+	key string
+}
+
+// Set sets the value at the dotted path relative to the component.
+func (c *Component) Set(path string, value interface{}) *Component {
+	c.doc.Set(c.key+"."+path, value)
+	return c
+}
+
+// Document returns the document the component belongs to.
+func (c *Component) Document() *Document {
+	return c.doc
+}
@@ -0,0 +1,34 @@
+package configtest
+
+import (
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDocument(t *testing.T) {
+	Convey("After we build a document", t, func() {
+		d := NewDocument().Set("logger.file", "/var/log/test.log")
+		d.Component("http").Set("port", 8080).Set("tls.enabled", true)
+
+		Convey("it should be nested by the dotted paths", func() {
+			So(d.String(), ShouldEqual,
+				`{"http":{"port":8080,"tls":{"enabled":true}},"logger":{"file":"/var/log/test.log"}}`)
+		})
+
+		Convey("scalar values on the path should be replaced", func() {
+			d.Set("logger.file.name", "test.log")
+			So(d.String(), ShouldContainSubstring, `"logger":{"file":{"name":"test.log"}}`)
+		})
+
+		Convey("it should be usable as a store", func() {
+			s := d.Store()
+			So(s.Open(), ShouldBeNil)
+			cfg := &httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}
+			So(s.Get(cfg), ShouldBeNil)
+			So(cfg.Port, ShouldEqual, 8080)
+			So(d.Component("http").Document(), ShouldEqual, d)
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package configtest
+
+import (
+	"sync"
+
+	"github.com/anuvu/cube/config"
+)
+
+// FaultyStore wraps a config.Store and fails Open or Get calls as scripted,
+// so that the error paths of components during Configure can be exercised.
+// It is injected in a group with the component.WithStore option.
+type FaultyStore struct {
+	config.Store
+	lock     sync.Mutex
+	openErr  error
+	getErrs  map[config.Key]error
+	afterN   int
+	afterErr error
+	calls    int
+}
+
+// NewFaultyStore returns a store that delegates to s unless a failure is
+// scripted for the call.
+func NewFaultyStore(s config.Store) *FaultyStore {
+	return &FaultyStore{
+		Store:   s,
+		getErrs: map[config.Key]error{},
+	}
+}
+
+// FailOpen makes Open return err.
+func (f *FaultyStore) FailOpen(err error) *FaultyStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.openErr = err
+	return f
+}
+
+// FailGet makes Get return err for the key.
+func (f *FaultyStore) FailGet(key config.Key, err error) *FaultyStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.getErrs[key] = err
+	return f
+}
+
+// FailAfter makes every Open or Get call after the first n calls return err.
+func (f *FaultyStore) FailAfter(n int, err error) *FaultyStore {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.afterN = n
+	f.afterErr = err
+	return f
+}
+
+// Calls returns the number of Open and Get calls made on the store.
+func (f *FaultyStore) Calls() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls
+}
+
+// Open fails if scripted, else opens the underlying store.
+func (f *FaultyStore) Open() error {
+	if err := f.fail(true, ""); err != nil {
+		return err
+	}
+	return f.Store.Open()
+}
+
+// Get fails if scripted for the config key, else retrieves the config from the
+// underlying store.
+func (f *FaultyStore) Get(cfg config.Config) error {
+	var key config.Key
+	if cfg != nil {
+		key = cfg.Key()
+	}
+	if err := f.fail(false, key); err != nil {
+		return err
+	}
+	return f.Store.Get(cfg)
+}
+
+// fail records the call and returns the scripted error if any.
+func (f *FaultyStore) fail(open bool, key config.Key) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls++
+	if f.afterErr != nil && f.calls > f.afterN {
+		return f.afterErr
+	}
+	if open {
+		return f.openErr
+	}
+	return f.getErrs[key]
+}
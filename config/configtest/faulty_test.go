@@ -0,0 +1,46 @@
+package configtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFaultyStore(t *testing.T) {
+	Convey("On a faulty store", t, func() {
+		doc := NewDocument().Set("http.port", 8080).Set("logger.file", "test.log")
+		s := NewFaultyStore(doc.Store())
+		httpCfg := &httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}
+
+		Convey("no scripted failures should delegate", func() {
+			So(s.Open(), ShouldBeNil)
+			So(s.Get(httpCfg), ShouldBeNil)
+			So(httpCfg.Port, ShouldEqual, 8080)
+			So(s.Get(nil), ShouldBeNil)
+			So(s.Calls(), ShouldEqual, 3)
+			s.Close()
+		})
+
+		Convey("open should fail when scripted", func() {
+			s.FailOpen(errors.New("open"))
+			So(s.Open(), ShouldBeError)
+		})
+
+		Convey("get should fail for the selected keys", func() {
+			s.FailGet("http", errors.New("get"))
+			So(s.Open(), ShouldBeNil)
+			So(s.Get(httpCfg), ShouldBeError)
+			So(s.Get(&config.BaseConfig{ConfigKey: "logger"}), ShouldBeNil)
+		})
+
+		Convey("calls should fail after N calls", func() {
+			s.FailAfter(2, errors.New("after"))
+			So(s.Open(), ShouldBeNil)
+			So(s.Get(httpCfg), ShouldBeNil)
+			So(s.Get(httpCfg), ShouldBeError)
+			So(s.Open(), ShouldBeError)
+		})
+	})
+}
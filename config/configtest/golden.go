@@ -0,0 +1,83 @@
+package configtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/anuvu/cube/config"
+)
+
+// UpdateEnv is the environment variable that, when set to a non empty value,
+// makes CompareGolden rewrite the golden files instead of comparing them.
+const UpdateEnv = "CUBE_UPDATE_GOLDEN"
+
+// Dump returns the effective configuration of the config objects as an
+// indented JSON document keyed by the config keys. The fields of the
+// embedded config.BaseConfig are internal and are not dumped, the other
+// fields are sorted by name.
+func Dump(cfgs ...config.Config) ([]byte, error) {
+	internal, err := fields(&config.BaseConfig{})
+	if err != nil {
+		return nil, err
+	}
+	doc := map[config.Key]interface{}{}
+	for _, c := range cfgs {
+		if c == nil || c.Key().IsNil() {
+			continue
+		}
+		f, err := fields(c)
+		if err != nil {
+			// Not a JSON object, e.g. a custom marshaler
+			doc[c.Key()] = c
+			continue
+		}
+		for name := range internal {
+			delete(f, name)
+		}
+		doc[c.Key()] = f
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// CompareGolden compares the effective configuration dump of the config
+// objects with the contents of the golden file at path. It returns an error
+// describing the difference if they do not match.
+func CompareGolden(path string, cfgs ...config.Config) error {
+	got, err := Dump(cfgs...)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv(UpdateEnv) != "" {
+		return ioutil.WriteFile(path, got, 0644)
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(want, got) {
+		return fmt.Errorf("config does not match golden file %s\nwant:\n%s\ngot:\n%s", path, want, got)
+	}
+	return nil
+}
+
+// fields returns the JSON fields of the config object.
+func fields(c config.Config) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	f := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
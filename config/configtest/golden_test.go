@@ -0,0 +1,53 @@
+package configtest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGolden(t *testing.T) {
+	Convey("Compare config with golden files", t, func() {
+		cfg := &httpConfig{config.BaseConfig{ConfigKey: "http"}, 8080}
+
+		Convey("matching config should succeed", func() {
+			So(CompareGolden("testdata/http.golden", cfg, nil, &httpConfig{}), ShouldBeNil)
+		})
+
+		Convey("the internal fields should not be dumped", func() {
+			b, err := Dump(cfg)
+			So(err, ShouldBeNil)
+			So(string(b), ShouldNotContainSubstring, "ConfigKey")
+			dump := map[string]map[string]interface{}{}
+			So(json.Unmarshal(b, &dump), ShouldBeNil)
+			So(dump, ShouldResemble, map[string]map[string]interface{}{"http": {"port": 8080.0}})
+		})
+
+		Convey("mismatching config should fail", func() {
+			cfg.Port = 9090
+			So(CompareGolden("testdata/http.golden", cfg), ShouldBeError)
+		})
+
+		Convey("missing golden file should fail", func() {
+			So(CompareGolden("testdata/missing.golden", cfg), ShouldBeError)
+		})
+
+		Convey("golden files should be updated on request", func() {
+			dir, err := ioutil.TempDir("", "configtest")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			os.Setenv(UpdateEnv, "1")
+			defer os.Unsetenv(UpdateEnv)
+
+			path := filepath.Join(dir, "http.golden")
+			So(CompareGolden(path, cfg), ShouldBeNil)
+			os.Unsetenv(UpdateEnv)
+			So(CompareGolden(path, cfg), ShouldBeNil)
+		})
+	})
+}
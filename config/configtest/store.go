@@ -0,0 +1,155 @@
+// Package configtest provides helpers to unit test the configuration handling
+// of components without building JSON documents by hand.
+package configtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/anuvu/cube/config"
+)
+
+// Store is a mock config.Store. Expectations are registered on the store
+// before it is handed to the code under test, and Verify reports any
+// expectation that was not met.
+type Store struct {
+	lock     sync.Mutex
+	openErr  error
+	openExp  bool
+	opened   int
+	closed   int
+	gets     map[config.Key]*getExpectation
+	getOrder []config.Key
+}
+
+type getExpectation struct {
+	value interface{}
+	err   error
+	calls int
+}
+
+// NewStore returns a new mock store with no expectations.
+func NewStore() *Store {
+	return &Store{
+		gets: map[config.Key]*getExpectation{},
+	}
+}
+
+// ExpectOpen registers an expectation that Open is called. Open returns the
+// provided error.
+func (s *Store) ExpectOpen(err error) *Store {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.openExp = true
+	s.openErr = err
+	return s
+}
+
+// ExpectGet registers an expectation that Get is called for the key. The value
+// is marshaled to JSON and decoded into the config object passed to Get.
+func (s *Store) ExpectGet(key config.Key, value interface{}) *Store {
+	return s.expectGet(key, value, nil)
+}
+
+// ExpectGetError registers an expectation that Get is called for the key and
+// fails with the provided error.
+func (s *Store) ExpectGetError(key config.Key, err error) *Store {
+	return s.expectGet(key, nil, err)
+}
+
+func (s *Store) expectGet(key config.Key, value interface{}, err error) *Store {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.gets[key]; !ok {
+		s.getOrder = append(s.getOrder, key)
+	}
+	s.gets[key] = &getExpectation{value: value, err: err}
+	return s
+}
+
+// Open records the call and returns the error registered with ExpectOpen.
+func (s *Store) Open() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.opened++
+	if !s.openExp {
+		return fmt.Errorf("unexpected call to Open")
+	}
+	return s.openErr
+}
+
+// Close records the call.
+func (s *Store) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed++
+}
+
+// Get populates the config object with the value registered for its key.
+func (s *Store) Get(cfg config.Config) error {
+	if cfg == nil || cfg.Key().IsNil() {
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	exp, ok := s.gets[cfg.Key()]
+	if !ok {
+		return fmt.Errorf("unexpected call to Get for key %s", cfg.Key())
+	}
+	exp.calls++
+	if exp.err != nil {
+		return exp.err
+	}
+	b, err := json.Marshal(exp.value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, cfg)
+}
+
+// Opened returns the number of times Open was called.
+func (s *Store) Opened() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.opened
+}
+
+// Closed returns the number of times Close was called.
+func (s *Store) Closed() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+// Gets returns the number of times Get was called for the key.
+func (s *Store) Gets(key config.Key) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if exp, ok := s.gets[key]; ok {
+		return exp.calls
+	}
+	return 0
+}
+
+// Verify returns an error listing every registered expectation that was not
+// met.
+func (s *Store) Verify() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	missing := []string{}
+	if s.openExp && s.opened == 0 {
+		missing = append(missing, "Open")
+	}
+	for _, k := range s.getOrder {
+		if s.gets[k].calls == 0 {
+			missing = append(missing, fmt.Sprintf("Get(%s)", k))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("expected calls not made: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
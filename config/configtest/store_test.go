@@ -0,0 +1,56 @@
+package configtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/anuvu/cube/config"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type httpConfig struct {
+	config.BaseConfig
+	Port int `json:"port"`
+}
+
+func TestMockStore(t *testing.T) {
+	Convey("On a mock store", t, func() {
+		s := NewStore()
+		So(s, ShouldNotBeNil)
+
+		Convey("unexpected calls should fail", func() {
+			So(s.Open(), ShouldBeError)
+			So(s.Get(&httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}), ShouldBeError)
+			So(s.Get(&httpConfig{}), ShouldBeNil)
+			So(s.Get(nil), ShouldBeNil)
+			So(s.Verify(), ShouldBeNil)
+		})
+
+		Convey("expected calls should succeed", func() {
+			s.ExpectOpen(nil).ExpectGet("http", map[string]int{"port": 8080})
+			So(s.Verify(), ShouldBeError)
+			So(s.Open(), ShouldBeNil)
+			cfg := &httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}
+			So(s.Get(cfg), ShouldBeNil)
+			So(cfg.Port, ShouldEqual, 8080)
+			s.Close()
+			So(s.Opened(), ShouldEqual, 1)
+			So(s.Closed(), ShouldEqual, 1)
+			So(s.Gets("http"), ShouldEqual, 1)
+			So(s.Gets("logger"), ShouldEqual, 0)
+			So(s.Verify(), ShouldBeNil)
+		})
+
+		Convey("expected errors should be returned", func() {
+			s.ExpectOpen(errors.New("open")).ExpectGetError("http", errors.New("get"))
+			So(s.Open(), ShouldBeError)
+			So(s.Get(&httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}), ShouldBeError)
+			So(s.Verify(), ShouldBeNil)
+		})
+
+		Convey("unmarshalable values should fail", func() {
+			s.ExpectGet("http", func() {})
+			So(s.Get(&httpConfig{config.BaseConfig{ConfigKey: "http"}, 0}), ShouldBeError)
+		})
+	})
+}
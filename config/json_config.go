@@ -2,23 +2,29 @@ package config
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
+	"sync"
 )
 
 type jsonStore struct {
-	r  io.Reader
-	kb map[Key][]byte
+	r        io.Reader
+	kb       map[Key][]byte
+	lock     sync.Mutex
+	warnings map[Key][]string
 }
 
 // NewJSONStore returns a config store backed by a JSON stream.
 //
 // The first level keys in the JSON stream match the component names and the
-// values must be decodeable into the types used to retrieve the config.
+// values must be decodeable into the types used to retrieve the config. The
+// values of versioned configurations are migrated to their current version
+// before they are decoded, see Migrate, and the store reports the warnings
+// raised by Migrate as a Warner.
 func NewJSONStore(r io.Reader) Store {
 	return &jsonStore{
-		r:  r,
-		kb: map[Key][]byte{},
+		r:        r,
+		kb:       map[Key][]byte{},
+		warnings: map[Key][]string{},
 	}
 }
 
@@ -51,6 +57,13 @@ func (j *jsonStore) Get(config Config) error {
 
 	name := config.Key()
 	if b, ok := j.kb[name]; ok {
+		b, warnings, err := Migrate(config, b)
+		j.lock.Lock()
+		j.warnings[name] = warnings
+		j.lock.Unlock()
+		if err != nil {
+			return err
+		}
 		if e := json.Unmarshal(b, config); e != nil {
 			// Bad buffer for the current type but lets keep it around
 			// in case the registry is modified with a new type
@@ -59,7 +72,13 @@ func (j *jsonStore) Get(config Config) error {
 		}
 		return nil
 	}
-	return fmt.Errorf("%s key not found", name)
+	return &NotFoundError{name}
+}
+
+func (j *jsonStore) Warnings(key Key) []string {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return j.warnings[key]
 }
 
 type cfgData struct {
@@ -67,6 +86,7 @@ type cfgData struct {
 }
 
 func (d *cfgData) UnmarshalJSON(b []byte) error {
-	d.b = b
+	// The decoder reuses its buffer, so keep a copy of the data.
+	d.b = append([]byte(nil), b...)
 	return nil
 }
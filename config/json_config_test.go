@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -89,5 +90,14 @@ func TestBadJSON(t *testing.T) {
 			So(s.Open(), ShouldBeNil)
 			So(s.Get(&httpConfig{BaseConfig{"http"}, 0}), ShouldBeError)
 		})
+
+		Convey("should return a not found error on missing keys", func() {
+			s := NewJSONStore(strings.NewReader(`{}`))
+			So(s.Open(), ShouldBeNil)
+			err := s.Get(&httpConfig{BaseConfig{"http"}, 0})
+			So(IsNotFound(err), ShouldBeTrue)
+			So(err.Error(), ShouldEqual, "http key not found")
+			So(IsNotFound(errors.New("other")), ShouldBeFalse)
+		})
 	})
 }
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// VersionField is the field of a configuration holding the version of its
+// shape, e.g. {"http": {"config_version": 2, "port": 8080}}. A configuration
+// without the field is at version 1.
+const VersionField = "config_version"
+
+// Versioned is implemented by the configurations whose shape evolves across
+// releases. The configurations found in a store at an older version are
+// migrated to the current version with the registered migrations before they
+// are decoded.
+type Versioned interface {
+	Config
+
+	// Version returns the current version of the shape of the configuration.
+	Version() int
+}
+
+// Migration migrates the decoded JSON document of a configuration from a
+// version to the next one in place, e.g. renaming or converting fields.
+type Migration func(doc map[string]interface{}) error
+
+// Warner is implemented by the stores reporting the warnings raised while
+// retrieving a configuration, e.g. deprecated fields or migrated versions.
+type Warner interface {
+	// Warnings returns the warnings raised by the last Get of the key.
+	Warnings(key Key) []string
+}
+
+type deprecation struct {
+	field string
+	hint  string
+}
+
+var migrations = struct {
+	lock       sync.RWMutex
+	steps      map[Key]map[int]Migration
+	deprecated map[Key][]deprecation
+}{
+	steps:      map[Key]map[int]Migration{},
+	deprecated: map[Key][]deprecation{},
+}
+
+// RegisterMigration registers the migration of the configuration of the key
+// from the version to the next one. It is meant to be called from the init
+// function of the package of the component, it panics if a migration is
+// already registered for the key and the version.
+func RegisterMigration(key Key, from int, m Migration) {
+	migrations.lock.Lock()
+	defer migrations.lock.Unlock()
+	if migrations.steps[key] == nil {
+		migrations.steps[key] = map[int]Migration{}
+	}
+	if _, ok := migrations.steps[key][from]; ok {
+		panic(fmt.Sprintf("migration of %s from version %d is already registered", key, from))
+	}
+	migrations.steps[key][from] = m
+}
+
+// Deprecate registers a deprecated field of the configuration of the key. A
+// warning with the hint, e.g. what to use instead, is raised when a store
+// retrieves a configuration using the field.
+func Deprecate(key Key, field, hint string) {
+	migrations.lock.Lock()
+	defer migrations.lock.Unlock()
+	migrations.deprecated[key] = append(migrations.deprecated[key], deprecation{field, hint})
+}
+
+// Migrate returns the JSON document of the configuration migrated to its
+// current version, and the warnings raised by its deprecated fields and by
+// its migration. Documents of configurations that are not versioned are only
+// checked for deprecated fields. It returns an error if the document is newer
+// than the configuration or if a migration is missing or fails.
+//
+// A configuration wrapping another one, e.g. to look it up under another
+// key, is migrated as the configuration returned by its Unwrap() Config
+// method.
+func Migrate(cfg Config, b []byte) ([]byte, []string, error) {
+	for {
+		u, ok := cfg.(interface{ Unwrap() Config })
+		if !ok {
+			break
+		}
+		cfg = u.Unwrap()
+	}
+	key := cfg.Key()
+	migrations.lock.RLock()
+	steps := migrations.steps[key]
+	deprecated := migrations.deprecated[key]
+	migrations.lock.RUnlock()
+	v, versioned := cfg.(Versioned)
+	if !versioned && len(deprecated) == 0 {
+		return b, nil, nil
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		// Not an object, leave the error to the decoding of the configuration
+		return b, nil, nil
+	}
+	warnings := []string{}
+	for _, d := range deprecated {
+		if _, ok := doc[d.field]; ok {
+			warnings = append(warnings, fmt.Sprintf("%s field %s is deprecated: %s", key, d.field, d.hint))
+		}
+	}
+	if !versioned {
+		return b, warnings, nil
+	}
+
+	from := 1
+	if n, ok := doc[VersionField]; ok {
+		f, isNum := n.(float64)
+		if !isNum || f < 1 || f != float64(int(f)) {
+			return nil, warnings, fmt.Errorf("%s %s must be a positive integer, got %v", key, VersionField, n)
+		}
+		from = int(f)
+	}
+	to := v.Version()
+	if from > to {
+		return nil, warnings, fmt.Errorf("%s configuration version %d is newer than the supported version %d", key, from, to)
+	}
+	if from == to {
+		return b, warnings, nil
+	}
+	for ver := from; ver < to; ver++ {
+		m, ok := steps[ver]
+		if !ok {
+			return nil, warnings, fmt.Errorf("no migration of %s configuration from version %d", key, ver)
+		}
+		if err := m(doc); err != nil {
+			return nil, warnings, fmt.Errorf("migrating %s configuration from version %d: %v", key, ver, err)
+		}
+	}
+	doc[VersionField] = to
+	warnings = append(warnings, fmt.Sprintf("%s configuration migrated from version %d to %d", key, from, to))
+	b, err := json.Marshal(doc)
+	return b, warnings, err
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type dbConfig struct {
+	BaseConfig
+	Hosts   []string `json:"hosts"`
+	Timeout int      `json:"timeout_ms"`
+}
+
+func (d *dbConfig) Version() int {
+	return 3
+}
+
+type wrapped struct {
+	Config
+}
+
+func (w *wrapped) Key() Key                   { return "prefix." + w.Config.Key() }
+func (w *wrapped) Unwrap() Config             { return w.Config }
+func (w *wrapped) UnmarshalJSON([]byte) error { return nil }
+
+func init() {
+	// Version 2 replaced host by the hosts list
+	RegisterMigration("db", 1, func(doc map[string]interface{}) error {
+		if h, ok := doc["host"]; ok {
+			doc["hosts"] = []interface{}{h}
+			delete(doc, "host")
+		}
+		return nil
+	})
+	// Version 3 moved the timeout from seconds to milliseconds
+	RegisterMigration("db", 2, func(doc map[string]interface{}) error {
+		if t, ok := doc["timeout"].(float64); ok {
+			doc["timeout_ms"] = t * 1000
+			delete(doc, "timeout")
+		}
+		return nil
+	})
+	Deprecate("logger", "file", "use the path field")
+}
+
+func TestMigrations(t *testing.T) {
+	Convey("On a json store", t, func() {
+		s := NewJSONStore(strings.NewReader(`{
+			"db": {"host": "db1", "timeout": 2},
+			"new": {"config_version": 4},
+			"bad": {"config_version": "x"},
+			"logger": {"file": "/var/log/test.log"},
+			"current": {"config_version": 3, "hosts": ["db2"]}
+		}`))
+		So(s.Open(), ShouldBeNil)
+		w := s.(Warner)
+
+		Convey("older configurations should be migrated", func() {
+			cfg := &dbConfig{BaseConfig: BaseConfig{"db"}}
+			So(s.Get(cfg), ShouldBeNil)
+			So(cfg.Hosts, ShouldResemble, []string{"db1"})
+			So(cfg.Timeout, ShouldEqual, 2000)
+			So(w.Warnings("db"), ShouldResemble, []string{"db configuration migrated from version 1 to 3"})
+
+			cfg = &dbConfig{BaseConfig: BaseConfig{"current"}}
+			So(s.Get(cfg), ShouldBeNil)
+			So(cfg.Hosts, ShouldResemble, []string{"db2"})
+			So(w.Warnings("current"), ShouldBeEmpty)
+		})
+
+		Convey("invalid versions should be rejected", func() {
+			So(s.Get(&dbConfig{BaseConfig: BaseConfig{"new"}}), ShouldBeError)
+			So(s.Get(&dbConfig{BaseConfig: BaseConfig{"bad"}}), ShouldBeError)
+			_, _, err := Migrate(&dbConfig{BaseConfig: BaseConfig{"cache"}}, []byte(`{}`))
+			So(err, ShouldBeError, fmt.Errorf("no migration of cache configuration from version 1"))
+		})
+
+		Convey("deprecated fields should raise warnings", func() {
+			So(s.Get(&loggerConfig{BaseConfig: BaseConfig{"logger"}}), ShouldBeNil)
+			So(w.Warnings("logger"), ShouldResemble, []string{"logger field file is deprecated: use the path field"})
+		})
+
+		Convey("wrapped configurations should be migrated as the wrapped one", func() {
+			_, warnings, err := Migrate(&wrapped{&loggerConfig{BaseConfig: BaseConfig{"logger"}}}, []byte(`{"file": "x"}`))
+			So(err, ShouldBeNil)
+			So(warnings, ShouldHaveLength, 1)
+		})
+	})
+}
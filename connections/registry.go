@@ -0,0 +1,198 @@
+// Package connections provides a registry component for long-lived
+// connections like websockets, server sent event streams and grpc streams.
+// When the server stops, the registry notifies the registered connections to
+// close and waits for them with per-class deadlines.
+package connections
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Conn is a long-lived connection tracked by the registry.
+type Conn interface {
+	// Notify asks the connection to close gracefully, e.g. by sending a
+	// GOAWAY or a close frame. It must not block.
+	Notify()
+
+	// Close forcefully closes the connection.
+	Close() error
+}
+
+// Registry tracks long-lived connections by class.
+type Registry interface {
+	// Register tracks the connection under the class. The returned function
+	// must be called when the connection is closed.
+	Register(class string, c Conn) (done func())
+
+	// Counts returns the number of open connections per class.
+	Counts() map[string]int
+
+	// Handler returns an admin handler that reports the open connection
+	// counts as JSON.
+	Handler() http.Handler
+}
+
+type entry struct {
+	class string
+	conn  Conn
+}
+
+type registry struct {
+	config  *configuration
+	lock    sync.Mutex
+	conns   map[*entry]struct{}
+	changed chan struct{}
+}
+
+// configuration defines the configurable parameters of the registry.
+type configuration struct {
+	config.BaseConfig
+	// Time to wait for a class of connections to close in milliseconds
+	Deadlines map[string]int `json:"deadlines_ms"`
+	// Time to wait for the classes without a deadline in milliseconds
+	DefaultDeadline int `json:"default_deadline_ms"`
+}
+
+// New creates a new connection registry.
+func New(ctx component.Context) Registry {
+	return &registry{
+		config: &configuration{
+			BaseConfig:      config.BaseConfig{ConfigKey: "connections"},
+			Deadlines:       map[string]int{},
+			DefaultDeadline: 5000,
+		},
+		conns:   map[*entry]struct{}{},
+		changed: make(chan struct{}),
+	}
+}
+
+func (r *registry) Config() config.Config {
+	return r.config
+}
+
+func (r *registry) Configure(ctx component.Context) error {
+	return nil
+}
+
+func (r *registry) Register(class string, c Conn) func() {
+	e := &entry{class, c}
+	r.lock.Lock()
+	r.conns[e] = struct{}{}
+	r.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.lock.Lock()
+			delete(r.conns, e)
+			// wake up the waiters in Stop
+			close(r.changed)
+			r.changed = make(chan struct{})
+			r.lock.Unlock()
+		})
+	}
+}
+
+func (r *registry) Counts() map[string]int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	counts := map[string]int{}
+	for e := range r.conns {
+		counts[e.class]++
+	}
+	return counts
+}
+
+// OpenConnections returns the total number of open connections.
+func (r *registry) OpenConnections() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.conns)
+}
+
+func (r *registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(r.Counts())
+	})
+}
+
+// Stop drains the connections class by class in sorted order. Each class is
+// notified to close and waited for up to its deadline, after which the
+// remaining connections of the class are forcefully closed.
+func (r *registry) Stop(ctx component.Context) error {
+	classes := []string{}
+	for c := range r.Counts() {
+		classes = append(classes, c)
+	}
+	sort.Strings(classes)
+
+	failed := []string{}
+	for _, class := range classes {
+		for _, c := range r.class(class) {
+			c.Notify()
+		}
+		conns := r.wait(class, r.deadline(class))
+		if len(conns) == 0 {
+			continue
+		}
+		ctx.Log().Warn().Str("class", class).Int("count", len(conns)).Msg("forcefully closing connections")
+		for _, c := range conns {
+			c.Close()
+		}
+		failed = append(failed, fmt.Sprintf("%s: %d", class, len(conns)))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("connections did not close before the deadline (%s)", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// wait waits until the connections of the class are closed or the timeout
+// expires, and returns the connections that are still open.
+func (r *registry) wait(class string, timeout time.Duration) []Conn {
+	deadline := time.After(timeout)
+	for {
+		r.lock.Lock()
+		changed := r.changed
+		r.lock.Unlock()
+		conns := r.class(class)
+		if len(conns) == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			return conns
+		}
+	}
+}
+
+func (r *registry) class(class string) []Conn {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	conns := []Conn{}
+	for e := range r.conns {
+		if e.class == class {
+			conns = append(conns, e.conn)
+		}
+	}
+	return conns
+}
+
+func (r *registry) deadline(class string) time.Duration {
+	ms, ok := r.config.Deadlines[class]
+	if !ok {
+		ms = r.config.DefaultDeadline
+	}
+	return time.Duration(ms) * time.Millisecond
+}
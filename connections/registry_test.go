@@ -0,0 +1,97 @@
+package connections
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type conn struct {
+	lock     sync.Mutex
+	notified bool
+	closed   bool
+	onNotify func()
+}
+
+func (c *conn) Notify() {
+	c.lock.Lock()
+	c.notified = true
+	c.lock.Unlock()
+	if c.onNotify != nil {
+		go c.onNotify()
+	}
+}
+
+func (c *conn) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *conn) state() (bool, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.notified, c.closed
+}
+
+func TestRegistry(t *testing.T) {
+	Convey("After we create a connection registry", t, func() {
+		ctx := component.RootContext(zlog.New("connections.test"))
+		r := New(ctx).(*registry)
+		So(r.Config().Key(), ShouldEqual, "connections")
+		So(r.Configure(ctx), ShouldBeNil)
+
+		Convey("connections should be counted by class", func() {
+			done1 := r.Register("websocket", &conn{})
+			r.Register("websocket", &conn{})
+			r.Register("sse", &conn{})
+			So(r.Counts(), ShouldResemble, map[string]int{"websocket": 2, "sse": 1})
+			So(r.OpenConnections(), ShouldEqual, 3)
+
+			done1()
+			done1()
+			So(r.Counts(), ShouldResemble, map[string]int{"websocket": 1, "sse": 1})
+
+			w := httptest.NewRecorder()
+			r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/connections", nil))
+			So(w.Body.String(), ShouldEqual, "{\"sse\":1,\"websocket\":1}\n")
+		})
+
+		Convey("stop should wait for the notified connections to close", func() {
+			c := &conn{}
+			done := r.Register("websocket", c)
+			c.onNotify = done
+			So(r.Stop(ctx), ShouldBeNil)
+			notified, closed := c.state()
+			So(notified, ShouldBeTrue)
+			So(closed, ShouldBeFalse)
+			So(r.OpenConnections(), ShouldEqual, 0)
+		})
+
+		Convey("stop should force close connections after the class deadline", func() {
+			r.config.Deadlines["stream"] = 10
+			stuck := &conn{}
+			r.Register("stream", stuck)
+			good := &conn{}
+			good.onNotify = r.Register("websocket", good)
+
+			err := r.Stop(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "stream: 1")
+			notified, closed := stuck.state()
+			So(notified, ShouldBeTrue)
+			So(closed, ShouldBeTrue)
+			_, closed = good.state()
+			So(closed, ShouldBeFalse)
+		})
+
+		Convey("stop should succeed without connections", func() {
+			So(r.Stop(ctx), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,241 @@
+// Package consumer provides a component that runs consumer loops for queue
+// and file-tailing workers. The worker supplies a Poll and Handle pair and the
+// component manages the loop goroutines, concurrency, backoff on errors,
+// stalled consumer detection and draining on stop.
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// PollFunc polls for the next batch of messages. It should block until
+// messages are available or the context is cancelled.
+type PollFunc func(ctx context.Context) ([]interface{}, error)
+
+// HandleFunc handles a single message.
+type HandleFunc func(ctx context.Context, msg interface{}) error
+
+// Consumer runs the consumer loops.
+type Consumer struct {
+	// 64 bit atomic fields first for alignment on 32 bit platforms.
+	// progress is the last time a loop made progress in unix nanoseconds.
+	progress int64
+	polled   uint64
+	handled  uint64
+	failed   uint64
+	pollErrs uint64
+	running  int32
+	// inflight is the number of messages being handled
+	inflight int32
+	config   *configuration
+	poll     PollFunc
+	handle   HandleFunc
+	// cancel stops the polling, cancelHandle interrupts the in-flight
+	// messages
+	cancel       context.CancelFunc
+	cancelHandle context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// Stats provides the counters of the consumer.
+type Stats struct {
+	Polled       uint64
+	Handled      uint64
+	Failed       uint64
+	PollErrors   uint64
+	LastProgress time.Time
+}
+
+// configuration defines the configurable parameters of the consumer. Durations
+// are in milliseconds.
+type configuration struct {
+	config.BaseConfig
+	// Number of concurrent consumer loops
+	Concurrency int `json:"concurrency"`
+	// Initial backoff after a poll error
+	MinBackoff int `json:"min_backoff_ms"`
+	// Maximum backoff after consecutive poll errors
+	MaxBackoff int `json:"max_backoff_ms"`
+	// Consumer is unhealthy if no loop made progress in this duration while
+	// handling messages
+	StallTimeout int `json:"stall_timeout_ms"`
+	// Maximum time to wait for in-flight messages on stop
+	DrainTimeout int `json:"drain_timeout_ms"`
+}
+
+// New creates a new consumer whose configuration is stored under the key.
+func New(key config.Key, poll PollFunc, handle HandleFunc) *Consumer {
+	return &Consumer{
+		config: &configuration{
+			BaseConfig:   config.BaseConfig{ConfigKey: key},
+			Concurrency:  1,
+			MinBackoff:   100,
+			MaxBackoff:   10000,
+			StallTimeout: 60000,
+			DrainTimeout: 10000,
+		},
+		poll:   poll,
+		handle: handle,
+	}
+}
+
+// Config returns the consumer configuration.
+func (c *Consumer) Config() config.Config {
+	return c.config
+}
+
+// Configure validates the consumer configuration.
+func (c *Consumer) Configure(ctx component.Context) error {
+	cfg := c.config
+	if cfg.Concurrency <= 0 {
+		return fmt.Errorf("consumer %s concurrency must be positive", cfg.Key())
+	}
+	if cfg.MinBackoff <= 0 || cfg.MaxBackoff < cfg.MinBackoff {
+		return fmt.Errorf("consumer %s backoff must be positive and min must not exceed max", cfg.Key())
+	}
+	return nil
+}
+
+// Start starts the consumer loops. The contexts passed to Poll and Handle
+// have the values of the context of the group but are only cancelled by Stop,
+// the context of the group is cancelled as soon as the shutdown is initiated.
+func (c *Consumer) Start(ctx component.Context) error {
+	base := detached{ctx.Ctx()}
+	pctx, cancel := context.WithCancel(base)
+	hctx, cancelHandle := context.WithCancel(base)
+	c.cancel, c.cancelHandle = cancel, cancelHandle
+	c.touch()
+	atomic.StoreInt32(&c.running, 1)
+	for i := 0; i < c.config.Concurrency; i++ {
+		c.wg.Add(1)
+		component.Go(ctx, func(ctx component.Context) error {
+			defer c.wg.Done()
+			c.loop(ctx, pctx, hctx)
+			return nil
+		})
+	}
+	return nil
+}
+
+// Stop stops polling and waits for the in-flight messages to be handled. The
+// context of the in-flight messages is cancelled after the drain timeout.
+func (c *Consumer) Stop(ctx component.Context) error {
+	if !atomic.CompareAndSwapInt32(&c.running, 1, 0) {
+		return nil
+	}
+	c.cancel()
+	defer c.cancelHandle()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-time.After(c.ms(c.config.DrainTimeout)):
+		return fmt.Errorf("consumer %s failed to drain in %dms", c.config.Key(), c.config.DrainTimeout)
+	}
+}
+
+// IsHealthy returns true if the consumer is running and not stalled. The
+// consumer is stalled if no loop made progress in the stall timeout while
+// messages are in flight, an idle consumer waiting for messages in Poll is
+// not stalled.
+func (c *Consumer) IsHealthy(ctx component.Context) bool {
+	if atomic.LoadInt32(&c.running) == 0 {
+		return false
+	}
+	if atomic.LoadInt32(&c.inflight) == 0 {
+		return true
+	}
+	last := time.Unix(0, atomic.LoadInt64(&c.progress))
+	return time.Since(last) < c.ms(c.config.StallTimeout)
+}
+
+// Stats returns the counters of the consumer.
+func (c *Consumer) Stats() Stats {
+	return Stats{
+		Polled:       atomic.LoadUint64(&c.polled),
+		Handled:      atomic.LoadUint64(&c.handled),
+		Failed:       atomic.LoadUint64(&c.failed),
+		PollErrors:   atomic.LoadUint64(&c.pollErrs),
+		LastProgress: time.Unix(0, atomic.LoadInt64(&c.progress)),
+	}
+}
+
+// loop polls and handles messages until the poll context is cancelled. The
+// messages are handled with the handle context so that in-flight messages
+// are not interrupted when the polling stops.
+func (c *Consumer) loop(ctx component.Context, pctx, hctx context.Context) {
+	backoff := c.ms(c.config.MinBackoff)
+	for pctx.Err() == nil {
+		msgs, err := c.poll(pctx)
+		if err != nil {
+			if pctx.Err() != nil {
+				return
+			}
+			atomic.AddUint64(&c.pollErrs, 1)
+			ctx.Log().Error().Str("consumer", string(c.config.Key())).Error(err).Msg("poll failed")
+			select {
+			case <-pctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > c.ms(c.config.MaxBackoff) {
+				backoff = c.ms(c.config.MaxBackoff)
+			}
+			continue
+		}
+		backoff = c.ms(c.config.MinBackoff)
+		c.touch()
+
+		for _, msg := range msgs {
+			atomic.AddUint64(&c.polled, 1)
+			atomic.AddInt32(&c.inflight, 1)
+			err := c.handle(hctx, msg)
+			atomic.AddInt32(&c.inflight, -1)
+			if err != nil {
+				atomic.AddUint64(&c.failed, 1)
+				ctx.Log().Error().Str("consumer", string(c.config.Key())).Error(err).Msg("handle failed")
+			} else {
+				atomic.AddUint64(&c.handled, 1)
+			}
+			c.touch()
+		}
+	}
+}
+
+// detached is a context with the values of its parent that is never
+// cancelled.
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detached) Done() <-chan struct{} {
+	return nil
+}
+
+func (detached) Err() error {
+	return nil
+}
+
+func (c *Consumer) touch() {
+	atomic.StoreInt64(&c.progress, time.Now().UnixNano())
+}
+
+func (c *Consumer) ms(n int) time.Duration {
+	return time.Duration(n) * time.Millisecond
+}
@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type queue struct {
+	lock    sync.Mutex
+	msgs    chan interface{}
+	failing int
+	seen    []interface{}
+}
+
+func (q *queue) Poll(ctx context.Context) ([]interface{}, error) {
+	q.lock.Lock()
+	if q.failing > 0 {
+		q.failing--
+		q.lock.Unlock()
+		return nil, errors.New("poll error")
+	}
+	q.lock.Unlock()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case m := <-q.msgs:
+		return []interface{}{m}, nil
+	}
+}
+
+func (q *queue) Handle(ctx context.Context, msg interface{}) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.seen = append(q.seen, msg)
+	if msg == "bad" {
+		return errors.New("bad message")
+	}
+	return nil
+}
+
+func (q *queue) Seen() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.seen)
+}
+
+func TestConsumer(t *testing.T) {
+	Convey("After we create a consumer", t, func() {
+		ctx := component.RootContext(zlog.New("consumer.test"))
+		q := &queue{msgs: make(chan interface{})}
+		c := New("orders", q.Poll, q.Handle)
+		So(c.Config().Key(), ShouldEqual, "orders")
+		c.config.MinBackoff = 1
+		c.config.MaxBackoff = 2
+		So(c.Configure(ctx), ShouldBeNil)
+		So(c.IsHealthy(ctx), ShouldBeFalse)
+
+		Convey("it should handle messages", func() {
+			q.failing = 3
+			So(c.Start(ctx), ShouldBeNil)
+			So(c.IsHealthy(ctx), ShouldBeTrue)
+			q.msgs <- "good"
+			q.msgs <- "bad"
+			q.msgs <- "good"
+			for q.Seen() < 3 {
+				time.Sleep(time.Millisecond)
+			}
+			So(c.Stop(ctx), ShouldBeNil)
+			So(c.Stop(ctx), ShouldBeNil)
+			So(c.IsHealthy(ctx), ShouldBeFalse)
+
+			s := c.Stats()
+			So(s.Polled, ShouldEqual, 3)
+			So(s.Handled, ShouldEqual, 2)
+			So(s.Failed, ShouldEqual, 1)
+			So(s.PollErrors, ShouldEqual, 3)
+		})
+
+		Convey("it should be healthy when idle", func() {
+			c.config.StallTimeout = 1
+			So(c.Start(ctx), ShouldBeNil)
+			time.Sleep(5 * time.Millisecond)
+			So(c.IsHealthy(ctx), ShouldBeTrue)
+			So(c.Stop(ctx), ShouldBeNil)
+		})
+
+		Convey("it should be unhealthy when stalled", func() {
+			block := make(chan struct{})
+			c.handle = func(ctx context.Context, msg interface{}) error {
+				<-block
+				return nil
+			}
+			c.config.StallTimeout = 1
+			So(c.Start(ctx), ShouldBeNil)
+			q.msgs <- "slow"
+			time.Sleep(5 * time.Millisecond)
+			So(c.IsHealthy(ctx), ShouldBeFalse)
+			close(block)
+			So(c.Stop(ctx), ShouldBeNil)
+		})
+
+		Convey("it should fail to stop if the messages do not drain", func() {
+			block := make(chan struct{})
+			defer close(block)
+			c.handle = func(ctx context.Context, msg interface{}) error {
+				<-block
+				return nil
+			}
+			c.config.DrainTimeout = 1
+			So(c.Start(ctx), ShouldBeNil)
+			q.msgs <- "slow"
+			So(c.Stop(ctx), ShouldBeError)
+		})
+
+		Convey("it should handle messages until it is stopped after the shutdown", func() {
+			errs := make(chan error, 1)
+			c.handle = func(ctx context.Context, msg interface{}) error {
+				errs <- ctx.Err()
+				<-ctx.Done()
+				return nil
+			}
+			c.config.DrainTimeout = 1
+			So(c.Start(ctx), ShouldBeNil)
+			ctx.(interface{ Shutdown() }).Shutdown()
+			select {
+			case q.msgs <- "late":
+			case <-time.After(time.Second):
+			}
+			var err error = context.Canceled
+			select {
+			case err = <-errs:
+			case <-time.After(time.Second):
+			}
+			So(err, ShouldBeNil)
+			So(c.Stop(ctx), ShouldBeError)
+			// The in-flight message is interrupted after the drain timeout
+			c.wg.Wait()
+		})
+
+		Convey("bad configuration should fail", func() {
+			c.config.Concurrency = 0
+			So(c.Configure(ctx), ShouldBeError)
+			c.config.Concurrency = 1
+			c.config.MaxBackoff = 0
+			So(c.Configure(ctx), ShouldBeError)
+		})
+	})
+}
@@ -1,27 +1,82 @@
 package cube
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/exitcode"
 	"github.com/anuvu/cube/signal"
+	"github.com/anuvu/zlog"
 )
 
 // ServerInit provides the server initialization function type.
-// This function is called to customize server initialization.
+// This function is called to customize server initialization. Components
+// added by this function can depend on *component.Args to act on the
+// subcommand and the positional arguments passed on the command line.
 type ServerInit func(g component.Group) error
 
 // Main is the entrypoint of the server that can be customized by providing a
 // ServerInit function. Developers can create custom components and component
 // groups in this function.
 //
+// The --plan flag prints the constructors the server would invoke to create
+// its components, in order, see component.Plan, and exits without invoking
+// them. The default and the alternative constructors are only chosen when
+// the server is created, they are not listed.
+//
+// The --self-check flag creates and configures the server, then calls the
+// self-check hooks of the components, see component.SelfCheckHook, and exits
+// without starting them, with the self-check exit code if a check fails. The
+// --self-check.timeout flag bounds each check, 5 seconds by default. It is
+// meant to be run as a deployment canary.
+//
 // By default a signal handler is installed to handle SIGINT and SIGTERM for
 // graceful shutdown of the server.
+//
+// The --stop.timeout flag bounds the time to stop the server once its
+// shutdown is initiated, the server fails with the stop timeout exit code if
+// the components are not stopped in time. There is no limit by default.
+//
+// If the server fails, the error is logged and the process exits with the exit
+// code of the error category defined in the cube exitcode package.
 func Main(initF ServerInit) {
+	if err := Run(initF); err != nil {
+		name := filepath.Base(os.Args[0])
+		code := exitcode.ExitCode(err)
+		zlog.New(name).Error().
+			Str("category", exitcode.CategoryOf(err).String()).
+			Str("exit_code", strconv.Itoa(code)).
+			Error(err).
+			Msg("server failed")
+		exit(code)
+	}
+}
+
+// exit terminates the process, replaced in tests.
+var exit = os.Exit
+
+// stdout is where the plan is printed, replaced in tests.
+var stdout io.Writer = os.Stdout
+
+// Run runs the server like Main, but returns the error instead of exiting the
+// process. The returned error is categorized using the cube exitcode package.
+func Run(initF ServerInit) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = exitcode.PanicError(r)
+		}
+	}()
+
 	name := filepath.Base(os.Args[0])
-	base := component.New(name + "-core")
+	base := component.New(name + "-core").(server)
 	base.Add(signal.New)
 
 	// Install the signal handler
@@ -30,33 +85,134 @@ func Main(initF ServerInit) {
 
 	// Initialize all the server components
 	if err := initF(srvGrp); err != nil {
-		panic(err)
+		return exitcode.DependencyError(err)
+	}
+
+	// Print the plan instead of running the server if requested, before any
+	// constructor is invoked. The flag is registered below for the usage but
+	// the command line is only parsed by Configure
+	if planRequested(os.Args[1:]) {
+		fmt.Fprint(stdout, base.Plan())
+		return nil
 	}
 
 	// Create the groups
 	if err := base.Create(); err != nil {
-		panic(err)
+		return exitcode.DependencyError(err)
+	}
+
+	flags := &runFlags{}
+	var ctx component.Context
+	if err := base.Invoke(func(cli *flag.FlagSet, c component.Context) error {
+		ctx = c
+		return flags.register(cli)
+	}); err != nil {
+		return exitcode.DependencyError(err)
 	}
 
 	// Configure the server
 	if err := base.Configure(); err != nil {
-		panic(err)
+		return exitcode.ConfigError(err)
+	}
+
+	// Check the server instead of starting it if requested
+	if flags.selfCheck {
+		return exitcode.SelfCheckError(base.SelfCheck(flags.checkTimeout))
 	}
 
 	// Start the server
 	if err := base.Start(); err != nil {
-		panic(err)
+		return exitcode.StartError(err)
 	}
 
-	// Wait for shutdown sequence to be initiated by someone
-	base.Invoke(func(ctx component.Context) {
-		<-ctx.Ctx().Done()
+	// Wait for shutdown sequence to be initiated by someone, then stop all
+	// the components and exit
+	return wait(base, ctx, flags.stopTimeout)
+}
+
+// runFlags are the command line flags of Run.
+type runFlags struct {
+	plan         bool
+	selfCheck    bool
+	checkTimeout time.Duration
+	stopTimeout  time.Duration
+}
+
+// register registers the flags on the command line of the components. The
+// command line is shared with the components, which register their flags
+// when they are constructed, it returns an error if a flag is already
+// registered by a component.
+func (f *runFlags) register(cli *flag.FlagSet) error {
+	fs := flag.NewFlagSet(cli.Name(), flag.ContinueOnError)
+	fs.BoolVar(&f.plan, "plan", false, "print the constructors of the components and exit without invoking them")
+	fs.BoolVar(&f.selfCheck, "self-check", false, "configure the server, run the self-checks of the components and exit")
+	fs.DurationVar(&f.checkTimeout, "self-check.timeout", 5*time.Second, "timeout of each self-check")
+	fs.DurationVar(&f.stopTimeout, "stop.timeout", 0, "maximum time to stop the server once the shutdown is initiated, no limit if 0")
+
+	var err error
+	fs.VisitAll(func(fl *flag.Flag) {
+		if err == nil && cli.Lookup(fl.Name) != nil {
+			err = fmt.Errorf("flag --%s of the server is also registered by a component", fl.Name)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fs.VisitAll(func(fl *flag.Flag) {
+		cli.Var(fl.Value, fl.Name, fl.Usage)
 	})
+	return nil
+}
+
+// server is the root group of the server, the groups created by
+// component.New implement the optional interfaces used by Run.
+type server interface {
+	component.Group
+	component.Inspector
+	component.Supervisor
+}
+
+// wait waits for the shutdown of the server and for the group to stop. It
+// returns a stop timeout error if the group is not stopped within the timeout
+// once the shutdown is initiated, without waiting for the stop hooks still
+// running.
+func wait(g server, ctx component.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		return exitcode.StopError(g.Wait())
+	}
+	<-ctx.Ctx().Done()
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Wait()
+	}()
+	select {
+	case err := <-done:
+		return exitcode.StopError(err)
+	case <-time.After(timeout):
+		return exitcode.StopTimeoutError(fmt.Errorf("server not stopped within %v", timeout))
+	}
+}
 
-	// Stop all the components and exit
-	if err := base.Stop(); err != nil {
-		panic(err)
+// planRequested returns true if the plan flag is set in the arguments. The
+// arguments after a "--" terminator are positional.
+func planRequested(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			return false
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "plan" {
+			return true
+		}
+		if strings.HasPrefix(name, "plan=") {
+			v, err := strconv.ParseBool(strings.TrimPrefix(name, "plan="))
+			return err == nil && v
+		}
 	}
+	return false
 }
 
 type shutDownHandler struct {
@@ -58,6 +58,10 @@ func ExampleMain() {
 	os.Args = []string{"cube.test"}
 	defer func() { os.Args = oldArgs }()
 
+	// Fix the run ID stamped on the logs
+	os.Setenv(component.RunIDEnv, "example")
+	defer os.Unsetenv(component.RunIDEnv)
+
 	cube.Main(func(g component.Group) error {
 		g.Add(newDummy)
 		g.Add(newKiller)
@@ -65,17 +69,17 @@ func ExampleMain() {
 	})
 
 	// Output:
-	// {"level":"info","name":"cube.test-core","message":"creating group"}
-	// {"level":"info","name":"cube.test","message":"creating group"}
-	// {"level":"info","name":"cube.test","message":"dummy object created"}
-	// {"level":"info","name":"cube.test","message":"killer object created"}
-	// {"level":"info","name":"cube.test-core","message":"configuring group"}
-	// {"level":"info","name":"cube.test","message":"configuring group"}
-	// {"level":"info","name":"cube.test-core","message":"starting group"}
-	// {"level":"info","name":"cube.test","message":"starting group"}
-	// {"level":"info","name":"cube.test","message":"dummy object started"}
-	// {"level":"info","name":"cube.test","message":"Killing the server"}
-	// {"level":"info","name":"cube.test","message":"stopping group"}
-	// {"level":"info","name":"cube.test","message":"dummy object stopped"}
-	// {"level":"info","name":"cube.test-core","message":"stopping group"}
+	// {"level":"info","name":"cube.test-core","run_id":"example","message":"creating group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"creating group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"dummy object created"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"killer object created"}
+	// {"level":"info","name":"cube.test-core","run_id":"example","message":"configuring group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"configuring group"}
+	// {"level":"info","name":"cube.test-core","run_id":"example","message":"starting group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"starting group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"dummy object started"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"Killing the server"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"stopping group"}
+	// {"level":"info","name":"cube.test","run_id":"example","message":"dummy object stopped"}
+	// {"level":"info","name":"cube.test-core","run_id":"example","message":"stopping group"}
 }
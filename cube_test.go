@@ -1,13 +1,16 @@
 package cube
 
 import (
+	"bytes"
 	"errors"
+	"flag"
 	"os"
 	"syscall"
 	"testing"
 
 	"github.com/anuvu/cube/component"
 	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/exitcode"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -45,44 +48,141 @@ func (d *stoptester) Stop(ctx component.Context) error {
 	return errors.New("bad stop")
 }
 
-func TestCubePanics(t *testing.T) {
+type slowStop struct {
+	release chan struct{}
+	stopped chan struct{}
+}
+
+func (s *slowStop) Stop(ctx component.Context) error {
+	<-s.release
+	return nil
+}
+
+func (s *slowStop) OnEvent(ctx component.Context, e component.Event) {
+	if e.Type == component.EventStopped {
+		s.stopped <- struct{}{}
+	}
+}
+
+type selfChecker struct {
+	calls int
+	err   error
+}
+
+func (s *selfChecker) SelfCheck(ctx component.Context) error {
+	s.calls++
+	return s.err
+}
+
+// mainExitCode runs Main and returns the exit code it exits with.
+func mainExitCode(initF ServerInit) (code int) {
+	oldExit := exit
+	defer func() {
+		exit = oldExit
+		if r := recover(); r != nil {
+			code = r.(int)
+		}
+	}()
+	exit = func(code int) { panic(code) }
+	Main(initF)
+	return 0
+}
+
+func TestCubeErrors(t *testing.T) {
 	// Replace os.Args for test case
 	oldArgs := os.Args
 	os.Args = []string{"cube.test"}
 	defer func() { os.Args = oldArgs }()
 
-	Convey("cube main should panic on create error", t, func() {
+	Convey("cube main should exit on create error", t, func() {
 		initFunc := func(g component.Group) error {
 			return g.Add(func(bool) int { return 0 })
 		}
-		So(func() { Main(initFunc) }, ShouldPanic)
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Dependency)
+		So(mainExitCode(initFunc), ShouldEqual, exitcode.Dependency.ExitCode())
+	})
+
+	Convey("cube main should exit on init error", t, func() {
+		initFunc := func(g component.Group) error { return g.Add(nil) }
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Dependency)
 	})
 
-	Convey("cube main should panic on config error", t, func() {
+	Convey("cube main should exit if a component registers a flag of the server", t, func() {
+		initFunc := func(g component.Group) error {
+			return g.Add(func(cli *flag.FlagSet) *tester {
+				cli.Bool("self-check", false, "component flag")
+				return &tester{}
+			})
+		}
+		err := Run(initFunc)
+		So(exitcode.CategoryOf(err), ShouldEqual, exitcode.Dependency)
+		So(err.Error(), ShouldContainSubstring, "--self-check")
+	})
+
+	Convey("cube main should exit on config error", t, func() {
 		initFunc := func(g component.Group) error { return g.Add(newBadConfig) }
-		So(func() { Main(initFunc) }, ShouldPanic)
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Config)
+		So(mainExitCode(initFunc), ShouldEqual, exitcode.Config.ExitCode())
 	})
 
-	Convey("cube main should panic dependencies are not met", t, func() {
+	Convey("cube main should exit dependencies are not met", t, func() {
 		initFunc := func(g component.Group) error { return g.Add(func(i *int) {}) }
-		So(func() { Main(initFunc) }, ShouldPanic)
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Dependency)
 	})
 
-	Convey("cube main should panic on start errors", t, func() {
+	Convey("cube main should exit on start errors", t, func() {
 		initFunc := func(g component.Group) error {
 			g.Add(newtest)
 			return nil
 		}
-		So(func() { Main(initFunc) }, ShouldPanic)
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Start)
+		So(mainExitCode(initFunc), ShouldEqual, exitcode.Start.ExitCode())
 	})
 
-	Convey("cube main should panic on stop errors", t, func() {
+	Convey("cube main should exit on stop errors", t, func() {
 		initFunc := func(g component.Group) error {
 			g.Add(func() *stoptester { return &stoptester{} })
 			g.Add(func(s *stoptester, k component.ServerShutdown) int { k(); return 0 })
 			return nil
 		}
-		So(func() { Main(initFunc) }, ShouldPanic)
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Stop)
+	})
+
+	Convey("cube main should exit on stop timeouts", t, func() {
+		os.Args = []string{"cube.test", "--stop.timeout", "10ms"}
+		defer func() { os.Args = []string{"cube.test"} }()
+		release := make(chan struct{})
+		stopped := make(chan struct{}, 2)
+		initFunc := func(g component.Group) error {
+			g.Add(func() *slowStop { return &slowStop{release, stopped} })
+			g.Add(func(s *slowStop, k component.ServerShutdown) int { k(); return 0 })
+			return nil
+		}
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.StopTimeout)
+		So(mainExitCode(initFunc), ShouldEqual, 6)
+		// Let the servers stop before the next tests
+		close(release)
+		<-stopped
+		<-stopped
+	})
+
+	Convey("cube main should exit on panics", t, func() {
+		initFunc := func(g component.Group) error { panic("init panic") }
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.Panic)
+		So(mainExitCode(initFunc), ShouldEqual, exitcode.Panic.ExitCode())
+	})
+
+	Convey("cube should run binaries with any file name", t, func() {
+		os.Args = []string{"__debug_bin"}
+		defer func() { os.Args = []string{"cube.test"} }()
+		initFunc := func(g component.Group) error {
+			g.Add(func(s *shutDownHandler) int {
+				s.shut(syscall.SIGTERM)
+				return 0
+			})
+			return nil
+		}
+		So(Run(initFunc), ShouldBeNil)
 	})
 
 	Convey("calling shutdown handler should stop server", t, func() {
@@ -93,6 +193,56 @@ func TestCubePanics(t *testing.T) {
 			})
 			return nil
 		}
-		So(func() { Main(initFunc) }, ShouldNotPanic)
+		So(Run(initFunc), ShouldBeNil)
+		So(mainExitCode(initFunc), ShouldEqual, 0)
+	})
+}
+
+func TestPlanFlag(t *testing.T) {
+	oldArgs := os.Args
+	oldStdout := stdout
+	defer func() {
+		os.Args = oldArgs
+		stdout = oldStdout
+	}()
+
+	Convey("cube should print the plan without starting the server", t, func() {
+		b := &bytes.Buffer{}
+		stdout = b
+		os.Args = []string{"cube.test", "--plan"}
+		constructed := false
+		initFunc := func(g component.Group) error {
+			return g.Add(func() *tester {
+				constructed = true
+				return &tester{}
+			})
+		}
+		So(Run(initFunc), ShouldBeNil)
+		So(b.String(), ShouldContainSubstring, "create cube.test-core/cube.test github.com/anuvu/cube.tester by github.com/anuvu/cube.TestPlanFlag.")
+		// The constructors should not be invoked
+		So(constructed, ShouldBeFalse)
+		So(b.String(), ShouldNotContainSubstring, "start ")
+	})
+
+	Convey("cube should check the server without starting it", t, func() {
+		os.Args = []string{"cube.test", "--self-check", "--self-check.timeout", "1s"}
+		checked := &selfChecker{}
+		initFunc := func(g component.Group) error {
+			g.Add(newtest)
+			return g.Add(func() *selfChecker { return checked })
+		}
+		So(Run(initFunc), ShouldBeNil)
+		So(checked.calls, ShouldEqual, 1)
+
+		checked.err = errors.New("unreachable")
+		So(exitcode.CategoryOf(Run(initFunc)), ShouldEqual, exitcode.SelfCheck)
+	})
+
+	Convey("the plan flag should be found in the arguments", t, func() {
+		So(planRequested([]string{"-plan"}), ShouldBeTrue)
+		So(planRequested([]string{"serve", "--plan=true"}), ShouldBeTrue)
+		So(planRequested([]string{"--plan=false"}), ShouldBeFalse)
+		So(planRequested([]string{"--", "--plan"}), ShouldBeFalse)
+		So(planRequested([]string{"plan"}), ShouldBeFalse)
 	})
 }
@@ -0,0 +1,40 @@
+// Package devdefaults provides the defaults to run a server during early
+// development without any flag or configuration file, e.g. with go run.
+//
+//	cube.Main(func(g component.Group) error {
+//		if err := devdefaults.Install(g); err != nil {
+//			return err
+//		}
+//		return g.Add(newService)
+//	})
+//
+// The defaults only apply when no configuration store is given on the
+// command line, so the same binary runs with a real configuration later.
+package devdefaults
+
+import (
+	"fmt"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/http"
+)
+
+// Config is the in-memory configuration used when no configuration store is
+// given on the command line. The HTTP server listens on an ephemeral port.
+const Config = `{"http": {"port": 0}}`
+
+// Install sets the development configuration defaults of the group hierarchy
+// and adds an HTTP server to the group, along with its http.BoundAddr.
+// Components whose configuration is missing from the defaults keep the values
+// set by their constructor.
+func Install(g component.Group) error {
+	s, ok := g.(component.Supervisor)
+	if !ok {
+		return fmt.Errorf("group %T does not support configuration defaults", g)
+	}
+	s.SetConfigDefaults(Config)
+	if err := g.Add(http.New); err != nil {
+		return err
+	}
+	return g.Add(http.NewBoundAddr)
+}
@@ -0,0 +1,58 @@
+package devdefaults
+
+import (
+	"os"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/http"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type service struct {
+	config *svcConfig
+}
+
+type svcConfig struct {
+	config.BaseConfig
+	Name string `json:"name"`
+}
+
+func (s *service) Config() config.Config {
+	return s.config
+}
+
+func (s *service) Configure(ctx component.Context) error {
+	return nil
+}
+
+func TestInstall(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we install the development defaults", t, func() {
+		grp := component.New("dev")
+		So(Install(grp), ShouldBeNil)
+		svc := &service{&svcConfig{config.BaseConfig{ConfigKey: "service"}, "default"}}
+		So(grp.Add(func() *service { return svc }), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the server should run without any flag", func() {
+			os.Args = []string{"dev.test"}
+			So(grp.Configure(), ShouldBeNil)
+			So(svc.config.Name, ShouldEqual, "default")
+			So(grp.Start(), ShouldBeNil)
+			So(grp.Invoke(func(a http.BoundAddr) {
+				So(a.Addr(), ShouldNotBeNil)
+			}), ShouldBeNil)
+			So(grp.IsHealthy(), ShouldBeTrue)
+			So(grp.Stop(), ShouldBeNil)
+		})
+
+		Convey("the configuration on the command line should be strict", func() {
+			os.Args = []string{"dev.test", "--config.mem", `{"http": {"port": 0}}`}
+			So(grp.Configure(), ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,103 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// alternative is a set of named constructors of which exactly one is added
+// to the container.
+type alternative struct {
+	ctrs     map[string]interface{}
+	selected string
+}
+
+// AddAlternative registers the constructor under the name in the alternative
+// group. Alternatives are not part of the dependency graph until one of them
+// is selected using Select. Create fails if any alternative group has no
+// selection. It returns an error if the name is already registered in the
+// group.
+func (c *Container) AddAlternative(group, name string, ctr interface{}) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	if err := checkFunc(ctr, reflect.TypeOf(ctr)); err != nil {
+		return err
+	}
+	if c.alts == nil {
+		c.alts = map[string]*alternative{}
+	}
+	alt, ok := c.alts[group]
+	if !ok {
+		alt = &alternative{ctrs: map[string]interface{}{}}
+		c.alts[group] = alt
+	}
+	if _, ok := alt.ctrs[name]; ok {
+		return fmt.Errorf("alternative %s is ambiguous for %s", name, group)
+	}
+	alt.ctrs[name] = ctr
+	return nil
+}
+
+// Select adds the constructor of the named alternative of the group to the
+// container. It returns an error if the alternative is unknown or another
+// alternative is already selected.
+func (c *Container) Select(group, name string) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	alt, ok := c.alts[group]
+	if !ok {
+		return fmt.Errorf("unknown alternative group %s", group)
+	}
+	ctr, ok := alt.ctrs[name]
+	if !ok {
+		return fmt.Errorf("unknown alternative %q for %s, must be one of %v", name, group, alt.names())
+	}
+	if alt.selected != "" {
+		if alt.selected == name {
+			return nil
+		}
+		return fmt.Errorf("alternative %s is already selected for %s", alt.selected, group)
+	}
+	if _, err := c.add(ctr, "", ""); err != nil {
+		return err
+	}
+	alt.selected = name
+	return nil
+}
+
+// Alternatives returns the names of the alternative groups registered in this
+// container in sorted order.
+func (c *Container) Alternatives() []string {
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	return c.alternatives()
+}
+
+func (c *Container) alternatives() []string {
+	groups := make([]string, 0, len(c.alts))
+	for g := range c.alts {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// checkAlternatives verifies that every alternative group has a selection.
+func (c *Container) checkAlternatives() error {
+	for _, g := range c.alternatives() {
+		if alt := c.alts[g]; alt.selected == "" {
+			return fmt.Errorf("no alternative selected for %s, must be one of %v", g, alt.names())
+		}
+	}
+	return nil
+}
+
+func (a *alternative) names() []string {
+	names := make([]string, 0, len(a.ctrs))
+	for n := range a.ctrs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,39 @@
+package di
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAlternatives(t *testing.T) {
+	Convey("Create a container with alternatives", t, func() {
+		c := New(nil)
+		So(c.AddAlternative("storage", "s3", func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.AddAlternative("storage", "fs", func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+		So(c.Alternatives(), ShouldResemble, []string{"storage"})
+
+		Convey("bad alternatives should be rejected", func() {
+			So(c.AddAlternative("storage", "s3", func() *testS1 { return &testS1{} }), ShouldBeError)
+			So(c.AddAlternative("storage", "nil", nil), ShouldBeError)
+		})
+
+		Convey("create should fail without a selection", func() {
+			So(c.Create(nil), ShouldBeError)
+		})
+
+		Convey("unknown selections should fail", func() {
+			So(c.Select("storage", "gcs"), ShouldBeError)
+			So(c.Select("cache", "s3"), ShouldBeError)
+		})
+
+		Convey("create should use the selected alternative", func() {
+			So(c.Select("storage", "fs"), ShouldBeNil)
+			So(c.Select("storage", "fs"), ShouldBeNil)
+			So(c.Select("storage", "s3"), ShouldBeError)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS2) {}, nil), ShouldBeNil)
+		})
+	})
+}
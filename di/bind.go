@@ -0,0 +1,63 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Bind adds the constructor to the container and binds the interface type to
+// the first concrete type it produces that implements the interface.
+// Constructors can then depend on the interface, and the dependency is
+// resolved with the value of the concrete type. The concrete type remains
+// available to the constructors that depend on it.
+//
+// The interface type is usually obtained from a nil pointer, e.g.
+//
+//	c.Bind(reflect.TypeOf((*Store)(nil)).Elem(), NewMemoryStore)
+//
+// It returns an error if the type is not an interface, if the constructor
+// does not produce an implementation of it, or if the interface is already
+// provided in the container.
+func (c *Container) Bind(iface reflect.Type, ctr interface{}) error {
+	if iface == nil || iface.Kind() != reflect.Interface {
+		return fmt.Errorf("can't bind to non-interface type %v", iface)
+	}
+	ctrType := reflect.TypeOf(ctr)
+	if err := checkFunc(ctr, ctrType); err != nil {
+		return err
+	}
+	var impl reflect.Type
+	for i := 0; i < ctrType.NumOut() && impl == nil; i++ {
+		if t := ctrType.Out(i); !t.Implements(_errType) && t.Implements(iface) {
+			impl = t
+		}
+	}
+	if impl == nil {
+		return fmt.Errorf("constructor %v does not produce an implementation of %v", ctrType, iface)
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	if c.dag.GetValue(iface) != nil {
+		return fmt.Errorf("constructor for type %v is already present", iface)
+	}
+	if _, err := c.add(ctr, "", ""); err != nil {
+		return err
+	}
+
+	// The binding is a constructor converting the concrete value to the
+	// interface, so that the graph orders it after the implementation.
+	binder := reflect.MakeFunc(
+		reflect.FuncOf([]reflect.Type{impl}, []reflect.Type{iface}, false),
+		func(args []reflect.Value) []reflect.Value {
+			return []reflect.Value{args[0].Convert(iface)}
+		},
+	)
+	if _, err := c.add(binder.Interface(), "", ""); err != nil {
+		return err
+	}
+	if c.binds == nil {
+		c.binds = map[Key]bool{}
+	}
+	c.binds[iface] = true
+	return nil
+}
@@ -0,0 +1,49 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type testStore interface {
+	Get() string
+}
+
+type testMemStore struct{}
+
+func (s *testMemStore) Get() string { return "mem" }
+
+var testStoreType = reflect.TypeOf((*testStore)(nil)).Elem()
+
+func TestBind(t *testing.T) {
+	Convey("Create a container with a binding", t, func() {
+		c := New(nil)
+		So(c.Add(func(s testStore) *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.Bind(testStoreType, func() *testMemStore { return &testMemStore{} }), ShouldBeNil)
+
+		Convey("bad bindings should be rejected", func() {
+			So(c.Bind(reflect.TypeOf(testS1{}), func() *testS2 { return &testS2{} }), ShouldBeError)
+			So(c.Bind(testStoreType, func() *testS2 { return &testS2{} }), ShouldBeError)
+			So(c.Bind(testStoreType, nil), ShouldBeError)
+			So(c.Bind(testStoreType, func() *testMemStore { return &testMemStore{} }), ShouldBeError)
+		})
+
+		Convey("the interface should resolve to the implementation", func() {
+			processed := []string{}
+			So(c.Create(func(v reflect.Value) error {
+				processed = append(processed, fmt.Sprint(v.Type()))
+				return nil
+			}), ShouldBeNil)
+			So(processed, ShouldResemble, []string{"*di.testMemStore", "*di.testS1"})
+			var impl *testMemStore
+			So(c.Invoke(func(s testStore, m *testMemStore) {
+				So(s.Get(), ShouldEqual, "mem")
+				impl = m
+			}, nil), ShouldBeNil)
+			So(impl, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,48 @@
+package di
+
+import "reflect"
+
+// Cleanup releases the resources of the values produced by a constructor. A
+// constructor can return a Cleanup along with its values, e.g.
+//
+//	func NewFile(cfg *Config) (*os.File, di.Cleanup, error) {
+//		f, err := os.Open(cfg.Path)
+//		if err != nil {
+//			return nil, nil, err
+//		}
+//		return f, func() { f.Close() }, nil
+//	}
+//
+// The cleanups of the constructors invoked by Create are called by Teardown
+// in the reverse order of their construction, so a value is cleaned up
+// before its dependencies. The cleanups of transient and scoped
+// constructors are called when their scope is closed.
+type Cleanup func()
+
+var cleanupType = reflect.TypeOf(Cleanup(nil))
+
+// Teardown calls the cleanups returned by the constructors of the container
+// in the reverse order of their construction. Each cleanup is called once.
+func (c *Container) Teardown() {
+	c.lock.Lock()
+	cleanups := c.cleanups
+	c.cleanups = nil
+	c.lock.Unlock()
+	runCleanups(cleanups)
+}
+
+// addCleanup records the cleanup returned by a constructor.
+func (c *Container) addCleanup(v reflect.Value) {
+	if f := v.Interface().(Cleanup); f != nil {
+		c.lock.Lock()
+		c.cleanups = append(c.cleanups, f)
+		c.lock.Unlock()
+	}
+}
+
+// runCleanups calls the cleanups in the reverse order.
+func runCleanups(cleanups []Cleanup) {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+}
@@ -0,0 +1,58 @@
+package di
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCleanup(t *testing.T) {
+	Convey("Create a container with cleanups", t, func() {
+		c := New(nil)
+		cleaned := []string{}
+		cleanup := func(name string) Cleanup {
+			return func() { cleaned = append(cleaned, name) }
+		}
+		So(c.Add(func(*testS1) (*testS2, Cleanup) { return &testS2{}, cleanup("s2") }), ShouldBeNil)
+		So(c.Add(func() (*testS1, Cleanup, error) { return &testS1{}, cleanup("s1"), nil }), ShouldBeNil)
+		So(c.Add(func() (*pool, Cleanup) { return &pool{}, nil }), ShouldBeNil)
+
+		Convey("constructors must produce more than a cleanup", func() {
+			So(c.Add(func() Cleanup { return nil }), ShouldBeError)
+		})
+
+		Convey("teardown should clean up in the reverse order", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS2, *pool) {}, nil), ShouldBeNil)
+			So(cleaned, ShouldBeEmpty)
+			c.Teardown()
+			So(cleaned, ShouldResemble, []string{"s2", "s1"})
+			c.Teardown()
+			So(cleaned, ShouldHaveLength, 2)
+		})
+
+		Convey("failed constructors should not be cleaned up", func() {
+			So(c.Add(func(*testS2) (*testS3, Cleanup, error) {
+				return nil, cleanup("s3"), fmt.Errorf("failed")
+			}), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError)
+			c.Teardown()
+			So(cleaned, ShouldResemble, []string{"s2", "s1"})
+		})
+
+		Convey("scoped values should be cleaned up with their scope", func() {
+			So(c.AddScoped(func() (*testS3, Cleanup) { return &testS3{}, cleanup("s3") }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS3) {
+				So(cleaned, ShouldBeEmpty)
+			}, nil), ShouldBeNil)
+			So(cleaned, ShouldResemble, []string{"s3"})
+			s := c.NewScope()
+			So(s.Invoke(func(*testS3) {}, nil), ShouldBeNil)
+			So(s.Invoke(func(*testS3) {}, nil), ShouldBeNil)
+			s.Close()
+			So(cleaned, ShouldResemble, []string{"s3", "s3"})
+		})
+	})
+}
@@ -0,0 +1,93 @@
+package di
+
+// Clone returns a copy of the container with the same constructors,
+// dependency graph, bindings, decorators, alternatives, interceptors and
+// hooks, but without any constructed value, e.g. to create the same topology
+// again in a test or a canary. The clone is chained to the parent of the
+// container, the values of the ancestors are shared.
+//
+// Changes made to the clone or to the container after the clone is taken are
+// not visible in the other one. Lazy, transient and scoped values are
+// constructed again by the clone, and the cleanups are not copied.
+func (c *Container) Clone() *Container {
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+
+	cc := New(c.parent, c.dupes...)
+	vertices := c.dag.Sort()
+	for _, v := range vertices {
+		cc.dag.AddVertex(v.Key, v.Value)
+	}
+	for _, v := range vertices {
+		if deps := c.dag.Dependencies(v.Key); len(deps) > 0 {
+			cc.dag.AddDependencies(v.Key, deps...)
+		}
+	}
+
+	cc.parallel = c.parallel
+	cc.interceptors = append([]Interceptor(nil), c.interceptors...)
+	cc.hooks = append([]Hooks(nil), c.hooks...)
+	if c.alts != nil {
+		cc.alts = map[string]*alternative{}
+		for g, alt := range c.alts {
+			ctrs := make(map[string]interface{}, len(alt.ctrs))
+			for n, ctr := range alt.ctrs {
+				ctrs[n] = ctr
+			}
+			cc.alts[g] = &alternative{ctrs, alt.selected}
+		}
+	}
+	if c.binds != nil {
+		cc.binds = make(map[Key]bool, len(c.binds))
+		for k, b := range c.binds {
+			cc.binds[k] = b
+		}
+	}
+	if c.outs != nil {
+		cc.outs = make(map[Key][]Key, len(c.outs))
+		for k, outs := range c.outs {
+			cc.outs[k] = outs
+		}
+	}
+	if c.members != nil {
+		cc.members = make(map[groupKey]int, len(c.members))
+		for k, n := range c.members {
+			cc.members[k] = n
+		}
+	}
+	if c.lazy != nil {
+		// The keys of a constructor share their lazy value
+		cc.lazy = make(map[Key]*lazyValue, len(c.lazy))
+		lazy := map[*lazyValue]*lazyValue{}
+		for k, l := range c.lazy {
+			if lazy[l] == nil {
+				lazy[l] = &lazyValue{}
+			}
+			cc.lazy[k] = lazy[l]
+		}
+	}
+	if c.scopes != nil {
+		cc.scopes = make(map[Key]*scopedCtr, len(c.scopes))
+		scopes := map[*scopedCtr]*scopedCtr{}
+		for k, sc := range c.scopes {
+			if scopes[sc] == nil {
+				scopes[sc] = &scopedCtr{sc.lifetime, sc.ctr, sc.keys, cc}
+			}
+			cc.scopes[k] = scopes[sc]
+		}
+	}
+	if c.tags != nil {
+		cc.tags = make(map[Key]map[string]string, len(c.tags))
+		for k, tags := range c.tags {
+			cc.tags[k] = tags
+		}
+	}
+	if c.decorators != nil {
+		cc.decorators = make(map[Key][]interface{}, len(c.decorators))
+		for k, fns := range c.decorators {
+			cc.decorators[k] = append([]interface{}(nil), fns...)
+		}
+	}
+	cc.defaults = append([]defaultCtr(nil), c.defaults...)
+	return cc
+}
@@ -0,0 +1,80 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type cloneStore interface {
+	Get() string
+}
+
+type cloneMem struct {
+	name string
+}
+
+func (m *cloneMem) Get() string { return m.name }
+
+func TestClone(t *testing.T) {
+	Convey("After we add constructors to a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(p.Create(nil), ShouldBeNil)
+
+		calls := 0
+		c := New(p)
+		So(c.Add(func(*testS1) *testS2 {
+			calls++
+			return &testS2{}
+		}), ShouldBeNil)
+		So(c.Bind(reflect.TypeOf((*cloneStore)(nil)).Elem(), func() *cloneMem { return &cloneMem{"mem"} }), ShouldBeNil)
+		So(c.Decorate(func(s *testS2) *testS2 { return s }), ShouldBeNil)
+		So(c.AddToGroup("pools", func() *pool { return &pool{"a"} }), ShouldBeNil)
+		So(c.AddLazy(func() *testS3 { return &testS3{} }), ShouldBeNil)
+		So(c.AddScoped(func() *pool { return &pool{"scoped"} }), ShouldBeNil)
+		So(c.AddAlternative("name", "a", func() string { return "a" }), ShouldBeNil)
+		So(c.Select("name", "a"), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+
+		cc := c.Clone()
+
+		Convey("the clone should have the same graph without the values", func() {
+			So(cc.Describe(), ShouldResemble, c.Describe())
+			r, ok := cc.Registration(reflect.TypeOf(&testS2{}))
+			So(ok, ShouldBeTrue)
+			So(r.Constructed, ShouldBeFalse)
+			So(cc.Alternatives(), ShouldResemble, []string{"name"})
+		})
+
+		Convey("the clone should construct its own values", func() {
+			So(cc.Create(nil), ShouldBeNil)
+			So(calls, ShouldEqual, 2)
+			var s1, cs1 *testS1
+			var m, cm *cloneMem
+			So(c.Invoke(func(a *testS1, b *cloneMem) { s1, m = a, b }, nil), ShouldBeNil)
+			So(cc.Invoke(func(a *testS1, b *cloneMem, s cloneStore, n string, in struct {
+				In
+				Pools []*pool `group:"pools"`
+			}, _ *testS3, sp *pool) {
+				cs1, cm = a, b
+				So(s.Get(), ShouldEqual, "mem")
+				So(n, ShouldEqual, "a")
+				So(in.Pools, ShouldHaveLength, 1)
+				So(sp.name, ShouldEqual, "scoped")
+			}, nil), ShouldBeNil)
+			So(cs1, ShouldEqual, s1)
+			So(cm, ShouldNotPointTo, m)
+		})
+
+		Convey("the changes to the clone should not be visible in the container", func() {
+			So(cc.Remove(reflect.TypeOf(&testS3{})), ShouldBeNil)
+			So(cc.AddNamed("x", func() *pool { return &pool{"x"} }), ShouldBeNil)
+			_, ok := c.Registration(reflect.TypeOf(&testS3{}))
+			So(ok, ShouldBeTrue)
+			_, ok = c.Registration(reflect.TypeOf(&pool{}), "x")
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
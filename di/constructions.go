@@ -0,0 +1,53 @@
+package di
+
+import (
+	"time"
+)
+
+// Construction records the invocation of a constructor by Create, e.g. to
+// find the constructors slowing the startup down.
+type Construction struct {
+	// Provider is the fully qualified name of the constructor
+	Provider string
+	// Types are the identifiers of the values produced by the constructor
+	Types []string
+	// Level is the level of the constructor in the dependency graph, the
+	// constructors of a level are invoked concurrently if the parallel
+	// construction is enabled, see SetParallel
+	Level int
+	// Start is the time the constructor was invoked at
+	Start time.Time
+	// Duration is the wall time of the constructor
+	Duration time.Duration
+	// Err is the error of the constructor or of the processing of its
+	// values, nil if it succeeded
+	Err error
+}
+
+// record records the construction of the level and its outcome.
+func (c *Container) record(x *construction, level int, err error) {
+	types := make([]string, len(x.keys))
+	for i, k := range x.keys {
+		types[i] = keyID(k)
+	}
+	c.lock.Lock()
+	c.ctrStats = append(c.ctrStats, Construction{
+		Provider: funcName(x.ctr),
+		Types:    types,
+		Level:    level,
+		Start:    x.start,
+		Duration: x.duration,
+		Err:      err,
+	})
+	c.lock.Unlock()
+}
+
+// Constructions returns the constructors invoked by the calls to Create of
+// the container, not of its ancestors, in the order in which their values
+// were cached. Lazy, transient and scoped values are constructed on demand
+// and are not recorded.
+func (c *Container) Constructions() []Construction {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]Construction{}, c.ctrStats...)
+}
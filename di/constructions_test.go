@@ -0,0 +1,46 @@
+package di
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConstructions(t *testing.T) {
+	Convey("Create a container with constructors", t, func() {
+		c := New(nil)
+		So(c.Add(func() *testS1 { time.Sleep(10 * time.Millisecond); return &testS1{} }), ShouldBeNil)
+		So(c.Add(func(*testS1) (*testS2, *testS3) { return &testS2{}, &testS3{} }), ShouldBeNil)
+		So(c.AddLazy(func() *pool { return &pool{} }), ShouldBeNil)
+		So(c.Constructions(), ShouldBeEmpty)
+
+		Convey("the constructors invoked by create should be recorded", func() {
+			So(c.Create(nil), ShouldBeNil)
+			ctrs := c.Constructions()
+			So(len(ctrs), ShouldEqual, 2)
+			So(ctrs[0].Types, ShouldResemble, []string{"github.com/anuvu/cube/di.testS1"})
+			So(ctrs[0].Level, ShouldEqual, 0)
+			So(ctrs[0].Duration, ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+			So(ctrs[0].Err, ShouldBeNil)
+			So(ctrs[0].Provider, ShouldStartWith, "github.com/anuvu/cube/di.TestConstructions")
+			So(ctrs[1].Types, ShouldResemble, []string{"github.com/anuvu/cube/di.testS2", "github.com/anuvu/cube/di.testS3"})
+			So(ctrs[1].Level, ShouldEqual, 1)
+			So(ctrs[1].Start, ShouldHappenOnOrAfter, ctrs[0].Start.Add(ctrs[0].Duration))
+		})
+
+		Convey("failed constructors should be recorded", func() {
+			So(c.Add(func() (*testMemStore, error) { return nil, errors.New("no store") }), ShouldBeNil)
+			So(c.Create(nil), ShouldNotBeNil)
+			failed := 0
+			for _, x := range c.Constructions() {
+				if x.Err != nil {
+					failed++
+					So(x.Err.Error(), ShouldEqual, "no store")
+				}
+			}
+			So(failed, ShouldEqual, 1)
+		})
+	})
+}
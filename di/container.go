@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 )
 
 // Container provides dependency injection for components. Each container keeps
@@ -20,11 +21,38 @@ import (
 // By adding the components in their order of dependency into a container and
 // by chaining these containers we can build the complete static dependency
 // graph of a process.
+//
+// A container is safe for concurrent use: functions can be invoked from many
+// goroutines while constructors are added to the container. Constructors
+// added after Create are only invoked by the next Create, or on first use if
+// they are lazy.
 type Container struct {
-	parent   *Container
-	objTable map[reflect.Type]reflect.Value
-	dupes    []reflect.Type
-	dag      Graph
+	parent       *Container
+	objTable     map[Key]reflect.Value
+	dupes        []reflect.Type
+	dag          Graph
+	interceptors []Interceptor
+	hooks        []Hooks
+	alts         map[string]*alternative
+	binds        map[Key]bool
+	outs         map[Key][]Key
+	members      map[groupKey]int
+	lazy         map[Key]*lazyValue
+	scopes       map[Key]*scopedCtr
+	cleanups     []Cleanup
+	decorators   map[Key][]interface{}
+	defaults     []defaultCtr
+	tags         map[Key]map[string]string
+	ctrStats     []Construction
+	listeners    []subscription
+	nextSub      int
+	parallel     bool
+	// lock guards the object table, the cleanups, the construction
+	// statistics and the listeners
+	lock sync.RWMutex
+	// graphLock guards the dependency graph and the registrations, it is
+	// never held while calling a constructor and is taken before lock
+	graphLock sync.RWMutex
 }
 
 // New creates a new container chained to a parent container, if parent
@@ -32,7 +60,7 @@ type Container struct {
 func New(p *Container, dupes ...reflect.Type) *Container {
 	return &Container{
 		parent:   p,
-		objTable: map[reflect.Type]reflect.Value{},
+		objTable: map[Key]reflect.Value{},
 		dupes:    dupes,
 		dag:      NewDAG(),
 	}
@@ -50,7 +78,61 @@ type ValueProcessor func(reflect.Value) error
 // error, that error is returned to the caller of Invoke.
 //
 // Note the any return values from the invoked function are not cached the container.
+//
+// Each Invoke resolves the scoped dependencies in a new scope, see AddScoped.
+// The scope is closed when the function returns.
 func (c *Container) Invoke(fx interface{}, vp ValueProcessor) error {
+	s := &Scope{c: c}
+	defer s.Close()
+	return c.invoke(fx, vp, s)
+}
+
+// InvokeWith invokes the function like Invoke, resolving its dependencies
+// from the extra arguments before the container, e.g. a context.Context or
+// a request. The arguments are only visible to this invocation: they resolve
+// the parameters of the function and of the transient and scoped
+// constructors invoked for it, but are never cached in the container. An
+// argument resolves the parameters of its own type first, then of the
+// interfaces it implements, e.g. a *http.Request resolves a *http.Request
+// parameter and a context.Context parameter is resolved by a context. Named
+// and value group dependencies are not resolved from the arguments.
+func (c *Container) InvokeWith(fx interface{}, vp ValueProcessor, args ...interface{}) error {
+	s := &Scope{c: c}
+	defer s.Close()
+	for _, a := range args {
+		if a == nil {
+			return errors.New("can't invoke with an untyped nil argument")
+		}
+		s.args = append(s.args, reflect.ValueOf(a))
+	}
+	return c.invoke(fx, vp, s)
+}
+
+// InvokeResult invokes the function like Invoke and returns the values it
+// returns, without the error returned last, if any. It lets the caller query
+// the graph without a value processor, e.g.
+//
+//	res, err := c.InvokeResult(func(db *DB) int { return db.Size() })
+//	size := res[0].(int)
+func (c *Container) InvokeResult(fx interface{}) ([]interface{}, error) {
+	results := []interface{}{}
+	err := c.Invoke(fx, func(v reflect.Value) error {
+		results = append(results, v.Interface())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	f := reflect.TypeOf(fx)
+	if n := f.NumOut(); n > 0 && f.Out(n-1).Implements(_errType) {
+		results = results[:n-1]
+	}
+	return results, nil
+}
+
+// invoke invokes the function resolving the scoped dependencies in the scope.
+// Scoped dependencies can't be resolved if the scope is nil.
+func (c *Container) invoke(fx interface{}, vp ValueProcessor, s *Scope) error {
 	// Check for function type
 	f := reflect.TypeOf(fx)
 	if e := checkFunc(fx, f); e != nil {
@@ -58,13 +140,13 @@ func (c *Container) Invoke(fx interface{}, vp ValueProcessor) error {
 	}
 
 	// Build the arguments list
-	args, err := c.buildArgs(f)
+	args, err := c.buildArgs(fx, f, s)
 	if err != nil {
 		return err
 	}
 
 	// Call the function
-	returned := reflect.ValueOf(fx).Call(args)
+	returned := call(fx, args)
 
 	// Check for errors
 	if err := checkError(returned); err != nil {
@@ -90,58 +172,184 @@ func (c *Container) Invoke(fx interface{}, vp ValueProcessor) error {
 // are cached in this container against their types and can be used for subsequent
 // dependency calculations.
 //
+// A constructor producing several values, either as positional results or as
+// the fields of a result struct, is invoked once.
+//
+// The dependency graph is split in levels, each level only depending on the
+// previous ones. The constructors of a level are invoked one at a time unless
+// parallel construction is enabled, see SetParallel. Their values are always
+// processed and cached in the dependency order, one constructor at a time.
+//
 // If the type of the value is produced by the constructor is already present in this
 // container or its ancestors, the value is rejected and create returns an error.
 //
 // If a value processor is provided, Create calls the value processor function on all returned
 // values of each constructor. This can used to cache/use the values outside the container.
+//
+// Create returns an error if an alternative group registered with the container has no
+// selection.
+//
+// Create first adds the default constructors whose values are not provided
+// by another constructor, see AddDefault. The wall time and the outcome of
+// each constructor it invokes are recorded, see Constructions.
 func (c *Container) Create(vp ValueProcessor) error {
-	vals := []reflect.Value{}
-	resProc := func(v reflect.Value) error {
-		t := baseType(v.Type())
-		if _, err := c.get(t); err == nil {
-			return fmt.Errorf("type %v is already present", v.Type())
+	if err := c.addDefaults(); err != nil {
+		return err
+	}
+	plan, err := c.plan()
+	if err != nil {
+		return err
+	}
+
+	c.graphLock.RLock()
+	parallel := c.parallel
+	c.graphLock.RUnlock()
+	for level, ctrs := range plan {
+		// The constructors of a level are independent, they are invoked
+		// concurrently if enabled and their values are processed in the
+		// dependency order
+		if parallel {
+			c.constructAll(ctrs)
 		}
-		if vp != nil {
-			// Call the value processor passed by the caller of Add
-			if err := vp(v); err != nil {
+		for i, x := range ctrs {
+			if !parallel {
+				x.run(c)
+			}
+			err := c.cache(x, vp)
+			c.record(x, level, err)
+			if err != nil && parallel {
+				// Release the values of the other constructors
+				for _, y := range ctrs[i+1:] {
+					c.record(y, level, y.err)
+					for _, v := range y.cleanups {
+						c.addCleanup(v)
+					}
+				}
+			}
+			if err != nil {
 				return err
 			}
 		}
-		vals = append(vals, v)
-		return nil
 	}
 
-	for _, n := range c.dag.Sort() {
-		ctr := n.Value
-		if ctr == nil {
-			// This dependency MUST be provided by the parent hierarchy, else
-			// invoke will fail with a dependency not met error
-			continue
-		}
+	return nil
+}
 
-		// Invoke this constructor with our own result processor
-		vals = []reflect.Value{}
-		if err := c.Invoke(ctr, resProc); err != nil {
-			return err
-		}
-		// Cache all the values produced by this invocation.
-		for _, v := range vals {
-			t := baseType(v.Type())
-			c.objTable[t] = v
-		}
+// plan returns the constructions of Create grouped by the levels of the
+// dependency graph.
+func (c *Container) plan() ([][]*construction, error) {
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	if err := c.checkAlternatives(); err != nil {
+		return nil, err
+	}
+	if err := c.checkDecorators(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	plan := [][]*construction{}
+	for _, level := range c.levels() {
+		ctrs := []*construction{}
+		claimed := map[Key]bool{}
+		for _, n := range level {
+			if n.Value == nil {
+				// This dependency MUST be provided by the parent hierarchy, else
+				// invoke will fail with a dependency not met error
+				continue
+			}
+			c.lock.RLock()
+			_, constructed := c.objTable[n.Key]
+			c.lock.RUnlock()
+			if constructed || claimed[n.Key] {
+				// The constructor produces several values and was already
+				// invoked for another one
+				continue
+			}
+			if _, ok := c.lazy[n.Key]; ok {
+				// Lazy values are constructed on first use
+				continue
+			}
+			if _, ok := c.scopes[n.Key]; ok {
+				// Transient and scoped values are constructed by Invoke
+				continue
+			}
+			keys := c.outs[n.Key]
+			for _, k := range keys {
+				claimed[k] = true
+			}
+			ctrs = append(ctrs, &construction{ctr: n.Value, keys: keys, bound: c.binds[n.Key]})
+		}
+		plan = append(plan, ctrs)
+	}
+	return plan, nil
 }
 
 // buildArgs builds the arguments required by the constructor by looking
-// up the object table.
-func (c *Container) buildArgs(ctrType reflect.Type) ([]reflect.Value, error) {
+// up the object table, and constructing the transient and scoped
+// dependencies in the scope.
+func (c *Container) buildArgs(ctr interface{}, ctrType reflect.Type, s *Scope) ([]reflect.Value, error) {
 	n := numArgs(ctrType)
 	vals := make([]reflect.Value, 0, n)
+	fn := ""
+	resolve := func(t reflect.Type, k Key, optional bool) (reflect.Value, error) {
+		if c.hasInterceptors() {
+			if fn == "" {
+				fn = funcName(ctr)
+			}
+			if err := c.intercept(fn, t); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		if _, ok := k.(reflect.Type); ok {
+			if v, ok := s.arg(t); ok {
+				return v, nil
+			}
+		}
+		if gk, ok := k.(groupKey); ok {
+			// Groups resolve to all their contributions, possibly none, the
+			// interceptors apply to the type of the members
+			if c.hasInterceptors() {
+				if err := c.intercept(fn, t.Elem()); err != nil {
+					return reflect.Value{}, err
+				}
+			}
+			return c.groupSlice(t, gk), nil
+		}
+		var v reflect.Value
+		var err error
+		if sc := c.scoped(k); sc != nil {
+			v, err = sc.resolve(k, s)
+		} else {
+			v, err = c.get(k)
+		}
+		if _, ok := err.(*NotFoundError); ok && k == Key(t) && getterTarget(t) != nil {
+			// Getters that are not provided are synthesized, the
+			// interceptors apply to the type they resolve
+			if c.hasInterceptors() {
+				if err := c.intercept(fn, getterTarget(t)); err != nil {
+					return reflect.Value{}, err
+				}
+			}
+			return c.getter(t), nil
+		}
+		if err != nil && optional {
+			// Missing optional dependencies resolve to the zero value
+			return reflect.Zero(t), nil
+		}
+		if nf, ok := err.(*NotFoundError); ok {
+			return v, c.notFound(nf.Key)
+		}
+		return v, err
+	}
 	for i := 0; i < n; i++ {
-		v, err := c.get(ctrType.In(i))
+		t := ctrType.In(i)
+		var v reflect.Value
+		var err error
+		if isIn(t) {
+			v, err = buildIn(t, resolve)
+		} else {
+			v, err = resolve(t, t, false)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -152,13 +360,111 @@ func (c *Container) buildArgs(ctrType reflect.Type) ([]reflect.Value, error) {
 
 // Add adds the component's constructor to the container. It returns an error if another
 // constructor is already producing this component. Add guarantees that the constructor
-// does not have cyclic dependencies to produce the components. It returns an error
-// if it detects cyclic dependencies.
-func (c *Container) Add(ctr interface{}) error {
+// does not have cyclic dependencies to produce the components. It returns a
+// *CycleError listing the types forming the cycle if it detects cyclic
+// dependencies.
+//
+// The constructor can be annotated with metadata tags, e.g.
+//
+//	c.Add(NewStore, di.Tag("subsystem", "storage"))
+//
+// to query its values later, see Tagged and InvokeTagged.
+//
+// A constructor depending on a getter of type func() T or func() (T, error)
+// that no constructor provides receives a getter resolving T from the
+// container when it is called, rather than when the constructor is invoked.
+// The getter does not make the constructor depend on T, which breaks the
+// cycles of the components that only need each other once they are created,
+// e.g.
+//
+//	func NewClient(server func() *Server) *Client
+//	func NewServer(c *Client) *Server
+//
+// A getter only resolves the singleton and lazy values. The error of a
+// func() (T, error) getter is a *NotFoundError if T is not provided, or the
+// error of its lazy construction, a func() T getter panics with the error.
+func (c *Container) Add(ctr interface{}, tags ...Annotation) error {
+	if err := checkTags(tags); err != nil {
+		return err
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	keys, err := c.add(ctr, "", "")
+	if err != nil {
+		return err
+	}
+	c.tag(keys, tags)
+	return nil
+}
+
+// add adds the constructor binding its values under the name, or
+// contributing them to the group, if not empty. It returns the keys of the
+// values produced by the constructor.
+func (c *Container) add(ctr interface{}, name, group string) ([]Key, error) {
+	dependencies, outs, err := c.signature(ctr, name, group)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add all the output parameters to the graph as producers
+	for i, k := range outs {
+		if c.dag.AddVertex(k, ctr) != nil {
+			// This may be out of order dependency, lets access the vertex and see if
+			// there is already constructor set.
+			v := c.dag.GetValue(k)
+			if v != nil {
+				// Before returning this error remove the vertices that are already
+				// added as part of this constructor
+				for _, added := range outs[:i] {
+					c.dag.RemoveVertex(added)
+				}
+				return nil, fmt.Errorf("constructor for type %v is already present", k)
+			} // set the out of order dependency, now the provider is set!
+			c.dag.SetValue(k, ctr)
+		}
+		if c.outs == nil {
+			c.outs = map[Key][]Key{}
+		}
+		c.outs[k] = outs
+
+		if mk, ok := k.(memberKey); ok {
+			// The group depends on its contributions
+			c.dag.AddVertex(mk.groupKey, nil)
+			if err := c.dag.AddDependencies(mk.groupKey, mk); err != nil {
+				return nil, err
+			}
+		}
+
+		// Add all the dependencies as edges to this vertex
+		for _, d := range dependencies {
+			// Add the dependency to the graph so that the dependency for this constructor
+			// is captured. We can ignore the error, it simply means someone else is also dependent
+			// on the same type or the dependencies provider is already present in the graph.
+			// If it is not present, this makes a forward reference for the provider to be registered
+			// our of order
+			c.dag.AddVertex(d, nil)
+
+			// As the dependency vertex is already added if this fails it means that this is a
+			// cyclic dependency, the *CycleError lists the types forming the cycle
+			if err := c.dag.AddDependencies(k, d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, k := range outs {
+		c.emit(VertexAdded, k, ctr, 0, nil)
+	}
+	return outs, nil
+}
+
+// signature returns the keys of the dependencies of the constructor and of
+// the values it produces.
+func (c *Container) signature(ctr interface{}, name, group string) ([]Key, []Key, error) {
 	// Verify that this infact is a function
 	ctrType := reflect.TypeOf(ctr)
 	if err := checkFunc(ctr, ctrType); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	nOut := ctrType.NumOut()
@@ -167,59 +473,42 @@ func (c *Container) Add(ctr interface{}) error {
 		nOut--
 	}
 	if nOut <= 0 {
-		return fmt.Errorf("Constructor function must construct something other than errors")
+		return nil, nil, fmt.Errorf("Constructor function must construct something other than errors")
 	}
 
 	// Compute all the arguments to the constructor as dependencies
 	n := numArgs(ctrType)
-	dependencies := make([]reflect.Type, 0, n)
+	dependencies := make([]Key, 0, n)
 	for i := 0; i < n; i++ {
-		t := baseType(ctrType.In(i))
-		if t.Implements(_errType) {
-			return fmt.Errorf("constructor cannot depend on error type")
+		keys, err := paramKeys(ctrType.In(i))
+		if err != nil {
+			return nil, nil, err
 		}
-		dependencies = append(dependencies, t)
+		for _, k := range keys {
+			if keyType(k).Implements(_errType) {
+				return nil, nil, fmt.Errorf("constructor cannot depend on error type")
+			}
+		}
+		dependencies = append(dependencies, keys...)
 	}
 
-	// Add all the output parameters to the graph as producers
+	// Compute all the values produced by the constructor
+	outs := []Key{}
 	for i := 0; i < nOut; i++ {
-		t := baseType(ctrType.Out(i))
-		if !t.Implements(_errType) {
-			if c.dag.AddVertex(t, ctr) != nil {
-				// This may be out of order dependency, lets access the vertex and see if
-				// there is already constructor set.
-				v := c.dag.GetValue(t)
-				if v != nil {
-					// Before returning this error remove the vertices that are already
-					// added as part of this constructor
-					for addIndex := 0; addIndex < i; addIndex++ {
-						t := baseType(ctrType.Out(addIndex))
-						c.dag.RemoveVertex(t)
-					}
-					return fmt.Errorf("constructor for type %v is already present", t)
-				} // set the out of order dependency, now the provider is set!
-				c.dag.SetValue(t, ctr)
-			}
-
-			// Add all the dependencies as edges to this vertex
-			for _, d := range dependencies {
-				// Add the dependency to the graph so that the dependency for this constructor
-				// is captured. We can ignore the error, it simply means someone else is also dependent
-				// on the same type or the dependencies provider is already present in the graph.
-				// If it is not present, this makes a forward reference for the provider to be registered
-				// our of order
-				c.dag.AddVertex(d, nil)
-
-				// As the dependency vertex is already added if this fails it means that this is a
-				// cyclic dependency
-				if c.dag.AddDependencies(t, d) != nil {
-					return fmt.Errorf("dependency %v to produce %v is cyclic", d, t)
-				}
-			}
+		t := ctrType.Out(i)
+		if baseType(t).Implements(_errType) || t == cleanupType {
+			continue
+		}
+		keys, err := c.resultKeys(t, name, group)
+		if err != nil {
+			return nil, nil, err
 		}
+		outs = append(outs, keys...)
 	}
-
-	return nil
+	if len(outs) == 0 {
+		return nil, nil, fmt.Errorf("constructor function must construct something other than cleanups")
+	}
+	return dependencies, outs, nil
 }
 
 func numArgs(ctrType reflect.Type) int {
@@ -231,7 +520,7 @@ func numArgs(ctrType reflect.Type) int {
 	return n
 }
 
-func (c *Container) checkParent(in reflect.Type) bool {
+func (c *Container) checkParent(in Key) bool {
 	if c.parent != nil {
 		for _, dup := range c.dupes {
 			if in == dup {
@@ -243,10 +532,29 @@ func (c *Container) checkParent(in reflect.Type) bool {
 	return false
 }
 
+// rlockChain read locks the registrations of the container and of its
+// ancestors, from the root, and returns the function releasing them.
+func (c *Container) rlockChain() func() {
+	if c.parent == nil {
+		c.graphLock.RLock()
+		return c.graphLock.RUnlock
+	}
+	unlock := c.parent.rlockChain()
+	c.graphLock.RLock()
+	return func() {
+		c.graphLock.RUnlock()
+		unlock()
+	}
+}
+
 // get finds a object required by buildArgs. It looks up the parent
 // container first for the object and then the object table of this
 // container.
-func (c *Container) get(in reflect.Type) (reflect.Value, error) {
+func (c *Container) get(in Key) (reflect.Value, error) {
+	if t, ok := in.(reflect.Type); ok {
+		in = baseType(t)
+	}
+
 	// Always find the value in the parent type first.
 	if c.checkParent(in) {
 		v, err := c.parent.get(in)
@@ -258,11 +566,12 @@ func (c *Container) get(in reflect.Type) (reflect.Value, error) {
 	}
 
 	// Check in this container for the value
-	in = baseType(in)
-	v, ok := c.objTable[in]
-
+	v, ok, err := c.lookup(in)
+	if err != nil {
+		return v, err
+	}
 	if !ok {
-		return v, fmt.Errorf("dependency for type %v not found", in)
+		return v, &NotFoundError{Key: in}
 	}
 
 	// Found Value!
@@ -2,7 +2,9 @@ package di
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -39,6 +41,23 @@ func TestContainer(t *testing.T) {
 			So(c.Create(nil), ShouldBeNil)
 			So(c.Invoke(func(t *testS1) {}, nil), ShouldBeNil)
 		})
+		Convey("can invoke a function returning results", func() {
+			s1 := &testS1{}
+			So(c.AddValue(s1), ShouldBeNil)
+			res, err := c.InvokeResult(func(s *testS1) (*testS1, int, error) { return s, 7, nil })
+			So(err, ShouldBeNil)
+			So(res, ShouldHaveLength, 2)
+			So(res[0], ShouldEqual, s1)
+			So(res[1], ShouldEqual, 7)
+			res, err = c.InvokeResult(func(*testS1) (int, error) { return 0, errors.New("error") })
+			So(err, ShouldBeError)
+			So(res, ShouldBeNil)
+			res, err = c.InvokeResult(func(*testS2) int { return 0 })
+			So(err, ShouldBeError)
+			res, err = c.InvokeResult(func() {})
+			So(err, ShouldBeNil)
+			So(res, ShouldBeEmpty)
+		})
 		Convey("cannot create constructor with bad dependencies", func() {
 			So(c.Add(func(c *Container) *Container { return nil }), ShouldBeError)
 			So(c.Add(func(e error) int { return 0 }), ShouldBeError)
@@ -90,5 +109,64 @@ func TestContainer(t *testing.T) {
 			So(badC.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
 			So(badC.Create(nil), ShouldBeError)
 		})
+
+		Convey("Cyclic dependencies should report the full cycle", func() {
+			So(c.Add(func(*testS3) *testS1 { return &testS1{} }), ShouldBeNil)
+			So(c.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+			err := c.Add(func(*testS2) *testS3 { return &testS3{} })
+			So(err, ShouldBeError, "cyclic dependency di.testS3 -> di.testS2 -> di.testS1 -> di.testS3")
+			So(err, ShouldHaveSameTypeAs, &CycleError{})
+		})
+	})
+}
+
+func TestConcurrentContainer(t *testing.T) {
+	Convey("Functions should be invoked while the container is extended", t, func() {
+		p := New(nil)
+		So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(p.Create(nil), ShouldBeNil)
+		c := New(p)
+		So(c.AddToGroup("pools", func() *pool { return &pool{"a"} }), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 100)
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					errs <- c.Invoke(func(s1 *testS1, in struct {
+						In
+						Pools []*pool `group:"pools"`
+						S2    *testS2 `optional:"true"`
+					}) {
+					}, nil)
+					c.Invoke(func(*testS3) {}, nil)
+					c.Registrations()
+					c.GraphDOT()
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(errs)
+		}()
+		for i := 0; i < 20; i++ {
+			So(c.AddNamed(fmt.Sprintf("pool%d", i), func() *pool { return &pool{} }), ShouldBeNil)
+			So(c.AddToGroup("pools", func() *pool { return &pool{} }), ShouldBeNil)
+			c.Intercept(func(string, reflect.Type) error { return nil })
+		}
+		So(c.AddLazy(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+		for err := range errs {
+			So(err, ShouldBeNil)
+		}
+		So(c.Invoke(func(in struct {
+			In
+			Pools []*pool `group:"pools"`
+		}) {
+			So(in.Pools, ShouldHaveLength, 21)
+		}, nil), ShouldBeNil)
 	})
 }
@@ -2,6 +2,8 @@ package di
 
 import (
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/twmb/algoimpl/go/graph"
 )
@@ -35,6 +37,11 @@ type Graph interface {
 	// Adding an dependency that creates a cycle in the graph is not allowed.
 	AddDependencies(Key, ...Key) error
 
+	// RemoveDependencies removes the dependencies between a given vertex and the
+	// provided list of dependency vertices. This returns an error if either the
+	// vertex or a dependency is not present in the graph.
+	RemoveDependencies(Key, ...Key) error
+
 	// GetValue returns the value of the vertex specified by the key. It returns nil if the
 	// vertex is not present in the graph.
 	GetValue(Key) Value
@@ -43,6 +50,23 @@ type Graph interface {
 	// the vertex is not present in the graph
 	SetValue(Key, Value) error
 
+	// Dependencies returns the keys of the vertices that the vertex specified by
+	// the key depends on. It returns nil if the vertex is not present in the graph.
+	Dependencies(Key) []Key
+
+	// Dependents returns the keys of the vertices that depend on the vertex
+	// specified by the key. It returns nil if the vertex is not present in the
+	// graph.
+	Dependents(Key) []Key
+
+	// Vertices returns the keys of all the vertices of the graph, in no
+	// particular order.
+	Vertices() []Key
+
+	// Edges returns all the dependencies between the vertices of the graph,
+	// in no particular order.
+	Edges() []Edge
+
 	// Sort returns all the vertex entries in the dependency order. Vertices are ordered in
 	// such a way that a vertex's dependencies will always preseed itself.
 	Sort() []Vertex
@@ -51,8 +75,8 @@ type Graph interface {
 // NewDAG creates a new DAG.
 func NewDAG() Graph {
 	return &dag{
-		graph.New(graph.Directed),
-		make(map[Key]graph.Node, 0),
+		graph:    graph.New(graph.Directed),
+		vertices: make(map[Key]graph.Node, 0),
 	}
 }
 
@@ -62,12 +86,21 @@ type Vertex struct {
 	Value Value
 }
 
+// Edge is a dependency between two vertices of the graph, the vertex of the
+// Dependent key depends on the vertex of the Dependency key.
+type Edge struct {
+	Dependent  Key
+	Dependency Key
+}
+
 // dag is a Graph which has an internal graph.Graph inside itself handling
 // nodes creation and making edges. It also has a map of all vertices which associates
 // the key to a graph.Node that is holding their corresponding component.
 type dag struct {
 	graph    *graph.Graph
 	vertices map[Key]graph.Node
+	// sortLock serializes the sorts which mark the nodes of the graph
+	sortLock sync.Mutex
 }
 
 func (dg *dag) AddVertex(key Key, val Value) error {
@@ -104,6 +137,21 @@ func (dg *dag) AddDependencies(vertex Key, dependencies ...Key) error {
 	return nil
 }
 
+func (dg *dag) RemoveDependencies(vertex Key, dependencies ...Key) error {
+	srcObj, ok := dg.vertices[vertex]
+	if !ok {
+		return fmt.Errorf("key %s does not exist", vertex)
+	}
+	for _, dep := range dependencies {
+		dstObj, ok := dg.vertices[dep]
+		if !ok {
+			return fmt.Errorf("key %s does not exist", dep)
+		}
+		dg.graph.RemoveEdge(dstObj, srcObj)
+	}
+	return nil
+}
+
 // adds a single dependency to the graph
 func (dg *dag) addDep(srcObj graph.Node, node Key, dependency Key) error {
 	dstObj, ok := dg.vertices[dependency]
@@ -112,19 +160,63 @@ func (dg *dag) addDep(srcObj graph.Node, node Key, dependency Key) error {
 	}
 
 	if node == dependency {
-		return fmt.Errorf("edge to self is not allowed")
+		return &CycleError{Path: []Key{node, node}}
 	}
 
 	dg.graph.MakeEdge(dstObj, srcObj)
 
 	if !isAcyclic(dg) {
 		dg.graph.RemoveEdge(dstObj, srcObj)
-		return fmt.Errorf("edge from %s to %s makes a cycle", node, dependency)
+		return &CycleError{Path: dg.cycle(node, dependency)}
 	}
 	return nil
 
 }
 
+// CycleError is returned when adding a dependency would make a cycle in the
+// graph.
+type CycleError struct {
+	// Path is the chain of keys forming the cycle, each key depending on the
+	// next one. The first and the last keys are the same.
+	Path []Key
+}
+
+func (e *CycleError) Error() string {
+	keys := make([]string, len(e.Path))
+	for i, k := range e.Path {
+		keys[i] = fmt.Sprint(k)
+	}
+	return "cyclic dependency " + strings.Join(keys, " -> ")
+}
+
+// cycle returns the cycle that a dependency of node on dependency would make,
+// starting and ending with node. It finds the shortest chain of dependents
+// from node to dependency, which the new dependency closes.
+func (dg *dag) cycle(node Key, dependency Key) []Key {
+	prev := map[Key]Key{}
+	queue := []Key{node}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		if k == dependency {
+			break
+		}
+		n := dg.vertices[k]
+		for _, m := range dg.graph.Neighbors(n) {
+			d := (*m.Value).(*Vertex).Key
+			if _, ok := prev[d]; !ok && d != node {
+				prev[d] = k
+				queue = append(queue, d)
+			}
+		}
+	}
+	path := []Key{node}
+	for k, ok := dependency, true; ok && k != node; k, ok = prev[k] {
+		path = append(path, k)
+	}
+	return append(path, node)
+}
+
 // Return the vertex by its key if exists else return nil.
 func (dg *dag) GetValue(v Key) Value {
 	if o, ok := dg.vertices[v]; ok {
@@ -144,11 +236,61 @@ func (dg *dag) SetValue(v Key, val Value) error {
 	return fmt.Errorf("key %s does not exist", v)
 }
 
+func (dg *dag) Dependencies(v Key) []Key {
+	o, ok := dg.vertices[v]
+	if !ok {
+		return nil
+	}
+	target := (*o.Value).(*Vertex)
+	deps := []Key{}
+	// Edges point from a dependency to its dependents
+	for k, n := range dg.vertices {
+		for _, m := range dg.graph.Neighbors(n) {
+			if (*m.Value).(*Vertex) == target {
+				deps = append(deps, k)
+			}
+		}
+	}
+	return deps
+}
+
+func (dg *dag) Dependents(v Key) []Key {
+	n, ok := dg.vertices[v]
+	if !ok {
+		return nil
+	}
+	deps := []Key{}
+	for _, m := range dg.graph.Neighbors(n) {
+		deps = append(deps, (*m.Value).(*Vertex).Key)
+	}
+	return deps
+}
+
+func (dg *dag) Vertices() []Key {
+	keys := make([]Key, 0, len(dg.vertices))
+	for k := range dg.vertices {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (dg *dag) Edges() []Edge {
+	edges := []Edge{}
+	for k, n := range dg.vertices {
+		for _, m := range dg.graph.Neighbors(n) {
+			edges = append(edges, Edge{Dependent: (*m.Value).(*Vertex).Key, Dependency: k})
+		}
+	}
+	return edges
+}
+
 // A sorted traversal of this graph will guarantee the
 // dependency order. This means A (node) depends on B (dependency) then
 // the sorted traversal will always return B before A.
 func (dg *dag) Sort() []Vertex {
+	dg.sortLock.Lock()
 	sorted := dg.graph.TopologicalSort()
+	dg.sortLock.Unlock()
 	nodes := make([]Vertex, 0, len(sorted))
 	for _, n := range sorted {
 		vp := (*n.Value).(*Vertex)
@@ -27,8 +27,10 @@ func TestDag(t *testing.T) {
 		So(dag.AddDependencies("does_not_exist", "shirt"), ShouldBeError)
 
 		// Assert that cycles cannot happen
-		So(dag.AddDependencies("shirt", "shirt"), ShouldBeError)
-		So(dag.AddDependencies("shirt", "jacket"), ShouldBeError)
+		So(dag.AddDependencies("shirt", "shirt"), ShouldBeError, "cyclic dependency shirt -> shirt")
+		err := dag.AddDependencies("shirt", "jacket")
+		So(err, ShouldBeError, "cyclic dependency shirt -> jacket -> tie -> shirt")
+		So(err.(*CycleError).Path, ShouldResemble, []Key{"shirt", "jacket", "tie", "shirt"})
 
 		// Check if the vertex can be retrieved
 		So(dag.GetValue("shirt"), ShouldEqual, 1)
@@ -39,6 +41,25 @@ func TestDag(t *testing.T) {
 		So(dag.GetValue("shirt"), ShouldEqual, 9)
 		So(dag.SetValue("unknown_key", 99), ShouldNotBeNil)
 
+		// Check the dependencies of a vertex
+		So(dag.Dependencies("jacket"), ShouldHaveLength, 2)
+		So(dag.Dependencies("jacket"), ShouldContain, "tie")
+		So(dag.Dependencies("jacket"), ShouldContain, "belt")
+		So(dag.Dependencies("pants"), ShouldBeEmpty)
+		So(dag.Dependencies("unknown_key"), ShouldBeNil)
+
+		// Check the dependents of a vertex
+		So(dag.Dependents("tie"), ShouldResemble, []Key{"jacket"})
+		So(dag.Dependents("jacket"), ShouldBeEmpty)
+		So(dag.Dependents("unknown_key"), ShouldBeNil)
+
+		// List the vertices and the edges
+		So(dag.Vertices(), ShouldHaveLength, 5)
+		So(dag.Vertices(), ShouldContain, "pants")
+		So(dag.Edges(), ShouldHaveLength, 4)
+		So(dag.Edges(), ShouldContain, Edge{Dependent: "jacket", Dependency: "belt"})
+		So(dag.Edges(), ShouldContain, Edge{Dependent: "belt", Dependency: "pants"})
+
 		expectedSortedNodes := []Vertex{
 			{"pants", 4},
 			{"belt", 3},
@@ -49,6 +70,12 @@ func TestDag(t *testing.T) {
 		sortedNodes := dag.Sort()
 		So(sortedNodes, ShouldResemble, expectedSortedNodes)
 
+		// Remove a dependency
+		So(dag.RemoveDependencies("jacket", "belt"), ShouldBeNil)
+		So(dag.Dependencies("jacket"), ShouldResemble, []Key{"tie"})
+		So(dag.RemoveDependencies("jacket", "unknown_key"), ShouldBeError)
+		So(dag.RemoveDependencies("unknown_key", "tie"), ShouldBeError)
+
 		// Add and remove a vertex
 		So(dag.AddVertex("hat", 100), ShouldBeNil)
 		So(dag.RemoveVertex("hat"), ShouldBeNil)
@@ -0,0 +1,129 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Decorate registers a decorator of the values of a type provided by a
+// constructor of the container. The decorator takes the value, along with
+// any other dependencies, and returns the value to use in its place, e.g. to
+// wrap a store with a caching layer.
+//
+//	c.Decorate(func(s Store, m *Metrics) Store { return &meteredStore{s, m} })
+//
+// The decorated type is the first result of the decorator, which can also
+// return an error. The decorators of a type are applied in the order of
+// their registration as soon as the value is constructed, before any
+// dependent constructor is invoked. Named, grouped, lazy, transient and
+// scoped values can't be decorated.
+func (c *Container) Decorate(fn interface{}) error {
+	fnType := reflect.TypeOf(fn)
+	if err := checkFunc(fn, fnType); err != nil {
+		return err
+	}
+	nOut := fnType.NumOut()
+	if nOut == 2 && fnType.Out(1).Implements(_errType) {
+		nOut--
+	}
+	if nOut != 1 || fnType.Out(0).Implements(_errType) {
+		return fmt.Errorf("decorator %v must return the decorated type and an optional error", fnType)
+	}
+	out := fnType.Out(0)
+	k := Key(baseType(out))
+	decorated := false
+	deps := []Key{}
+	for i := 0; i < numArgs(fnType); i++ {
+		in := fnType.In(i)
+		if in == out {
+			decorated = true
+			continue
+		}
+		keys, err := paramKeys(in)
+		if err != nil {
+			return err
+		}
+		deps = append(deps, keys...)
+	}
+	if !decorated {
+		return fmt.Errorf("decorator %v must take the decorated type %v", fnType, out)
+	}
+
+	// The value is decorated before its dependents are constructed, so it
+	// depends on the dependencies of the decorator
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	c.dag.AddVertex(k, nil)
+	for _, d := range deps {
+		c.dag.AddVertex(d, nil)
+		if err := c.dag.AddDependencies(k, d); err != nil {
+			return err
+		}
+	}
+	if c.decorators == nil {
+		c.decorators = map[Key][]interface{}{}
+	}
+	c.decorators[k] = append(c.decorators[k], fn)
+	return nil
+}
+
+// checkDecorators verifies that the decorated types are provided by the
+// singleton constructors of the container.
+func (c *Container) checkDecorators() error {
+	for k := range c.decorators {
+		if c.dag.GetValue(k) == nil {
+			return fmt.Errorf("no constructor to decorate %v", k)
+		}
+		_, lazy := c.lazy[k]
+		_, scoped := c.scopes[k]
+		if lazy || scoped {
+			return fmt.Errorf("type %v is not a singleton and can't be decorated", k)
+		}
+	}
+	return nil
+}
+
+// decorate applies the decorators of the key to its value in the object
+// table. The decorated values are passed to the value processor, unless the
+// decorator returned its argument.
+func (c *Container) decorate(k Key, vp ValueProcessor) error {
+	c.graphLock.RLock()
+	decorators := c.decorators[k]
+	c.graphLock.RUnlock()
+	for _, fn := range decorators {
+		c.lock.RLock()
+		orig := c.objTable[k]
+		c.lock.RUnlock()
+		var v reflect.Value
+		err := c.invoke(fn, func(r reflect.Value) error {
+			if !v.IsValid() {
+				v = r
+			}
+			return nil
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if vp != nil && !sameValue(v, orig) {
+			if err := vp(v); err != nil {
+				return err
+			}
+		}
+		c.lock.Lock()
+		c.objTable[k] = v
+		c.lock.Unlock()
+	}
+	return nil
+}
+
+// sameValue checks if the values hold the same object.
+func sameValue(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	if b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	return a.IsValid() && b.IsValid() && a.Type() == b.Type() && a.Type().Comparable() &&
+		a.Interface() == b.Interface()
+}
@@ -0,0 +1,81 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type cachedStore struct {
+	testStore
+	prefix string
+}
+
+func (s *cachedStore) Get() string { return s.prefix + s.testStore.Get() }
+
+func TestDecorate(t *testing.T) {
+	Convey("Create a container with a decorator", t, func() {
+		c := New(nil)
+		So(c.Bind(testStoreType, func() *testMemStore { return &testMemStore{} }), ShouldBeNil)
+		So(c.Decorate(func(s testStore, p *pool) testStore { return &cachedStore{s, p.name} }), ShouldBeNil)
+		var got string
+		So(c.Add(func(s testStore) *testS2 {
+			got = s.Get()
+			return &testS2{}
+		}), ShouldBeNil)
+		So(c.Add(func() *pool { return &pool{"cached-"} }), ShouldBeNil)
+
+		Convey("bad decorators should be rejected", func() {
+			So(c.Decorate(nil), ShouldBeError)
+			So(c.Decorate(func(testStore) {}), ShouldBeError)
+			So(c.Decorate(func(testStore) error { return nil }), ShouldBeError)
+			So(c.Decorate(func(*pool) testStore { return nil }), ShouldBeError)
+			So(c.Decorate(func(s testStore, _ *testS2) testStore { return s }), ShouldBeError)
+		})
+
+		Convey("undecorated types should fail the create", func() {
+			So(c.Decorate(func(s *testS3) *testS3 { return s }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError)
+		})
+
+		Convey("lazy types should fail the create", func() {
+			So(c.AddLazy(func() *testS3 { return &testS3{} }), ShouldBeNil)
+			So(c.Decorate(func(s *testS3) *testS3 { return s }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError)
+		})
+
+		Convey("dependents should receive the decorated value", func() {
+			So(c.Decorate(func(s testStore) (testStore, error) { return &cachedStore{s, "again-"}, nil }), ShouldBeNil)
+			processed := []string{}
+			So(c.Create(func(v reflect.Value) error {
+				processed = append(processed, fmt.Sprint(v.Type()))
+				return nil
+			}), ShouldBeNil)
+			So(got, ShouldEqual, "again-cached-mem")
+			So(processed, ShouldContain, "di.testStore")
+			So(c.Invoke(func(s testStore, m *testMemStore) {
+				So(s.Get(), ShouldEqual, "again-cached-mem")
+				So(m.Get(), ShouldEqual, "mem")
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("decorator errors should fail the create", func() {
+			So(c.Decorate(func(s testStore) (testStore, error) { return nil, fmt.Errorf("failed") }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError, "failed")
+		})
+
+		Convey("decorators returning their argument should not be processed again", func() {
+			So(c.Decorate(func(s *testMemStore) *testMemStore { return s }), ShouldBeNil)
+			processed := 0
+			So(c.Create(func(v reflect.Value) error {
+				if _, ok := v.Interface().(*testMemStore); ok {
+					processed++
+				}
+				return nil
+			}), ShouldBeNil)
+			So(processed, ShouldEqual, 1)
+		})
+	})
+}
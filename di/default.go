@@ -0,0 +1,56 @@
+package di
+
+// defaultCtr is a constructor added unless its values are provided by
+// another constructor.
+type defaultCtr struct {
+	ctr  interface{}
+	keys []Key
+}
+
+// AddDefault adds the constructor to the container like Add, unless the
+// container or one of its ancestors provides one of its values by the time
+// the container is created, e.g. so that a library can supply a default
+// implementation the application can override. The decision is taken by
+// Create, the default constructor is not part of the dependency graph
+// before. It returns an error if the constructor is not valid.
+func (c *Container) AddDefault(ctr interface{}) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	_, keys, err := c.signature(ctr, "", "")
+	if err != nil {
+		return err
+	}
+	c.defaults = append(c.defaults, defaultCtr{ctr, keys})
+	return nil
+}
+
+// addDefaults adds the default constructors whose values are not provided
+// by the container or its ancestors.
+func (c *Container) addDefaults() error {
+	c.graphLock.Lock()
+	defaults := c.defaults
+	c.defaults = nil
+	c.graphLock.Unlock()
+
+	for _, d := range defaults {
+		unlock := c.rlockChain()
+		provided := false
+		for _, k := range d.keys {
+			if c.provider(k) != nil {
+				provided = true
+				break
+			}
+		}
+		unlock()
+		if provided {
+			continue
+		}
+		c.graphLock.Lock()
+		_, err := c.add(d.ctr, "", "")
+		c.graphLock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package di
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAddDefault(t *testing.T) {
+	Convey("Create a container chained to a parent with a default", t, func() {
+		p := New(nil)
+		c := New(p)
+		So(c.AddDefault(func() *pool { return &pool{"default"} }), ShouldBeNil)
+		So(c.Add(func(*pool) *testS1 { return &testS1{} }), ShouldBeNil)
+
+		Convey("the default should be used if nothing else provides the type", func() {
+			So(p.Create(nil), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p *pool, _ *testS1) { So(p.name, ShouldEqual, "default") }, nil), ShouldBeNil)
+		})
+
+		Convey("the default should be overridden by the container", func() {
+			So(c.Add(func() *pool { return &pool{"app"} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p *pool) { So(p.name, ShouldEqual, "app") }, nil), ShouldBeNil)
+		})
+
+		Convey("the default should be overridden by the ancestors", func() {
+			So(p.Add(func() *pool { return &pool{"parent"} }), ShouldBeNil)
+			So(p.Create(nil), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p *pool) { So(p.name, ShouldEqual, "parent") }, nil), ShouldBeNil)
+		})
+
+		Convey("bad defaults should be rejected", func() {
+			So(c.AddDefault(nil), ShouldBeError)
+			So(c.AddDefault(func() error { return nil }), ShouldBeError)
+		})
+	})
+}
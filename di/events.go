@@ -0,0 +1,105 @@
+package di
+
+import (
+	"reflect"
+	"time"
+)
+
+// EventKind is the kind of an event of a container.
+type EventKind string
+
+// Kinds of the events emitted by the containers.
+const (
+	// VertexAdded is emitted for each value of a constructor added to the
+	// dependency graph.
+	VertexAdded EventKind = "vertex_added"
+	// ValueConstructed is emitted for each value constructed by Create or
+	// on first use of a lazy value, transient and scoped values are not
+	// reported.
+	ValueConstructed EventKind = "value_constructed"
+	// ConstructionFailed is emitted when a constructor returns an error or
+	// its dependencies can't be resolved.
+	ConstructionFailed EventKind = "construction_failed"
+	// ValueRejected is emitted when a constructed value is not cached,
+	// because its type is already present or the value processor of
+	// Create returned an error.
+	ValueRejected EventKind = "value_rejected"
+)
+
+// Event is an event of a container.
+type Event struct {
+	// Kind of the event
+	Kind EventKind
+	// Container emitting the event
+	Container *Container
+	// ID is the identifier of the value, the first value of the constructor
+	// for the failed constructions
+	ID string
+	// Type of the value, the element type for pointers
+	Type reflect.Type
+	// Provider is the fully qualified name of the constructor
+	Provider string
+	// Duration is the wall time of the constructor for the constructions
+	Duration time.Duration
+	// Err is the error of the failed constructions and rejected values
+	Err error
+	// Time of the event
+	Time time.Time
+}
+
+// Listener receives the events of a container.
+type Listener func(e Event)
+
+type subscription struct {
+	id int
+	l  Listener
+}
+
+// Subscribe registers a listener receiving the events of the container and
+// of its descendant containers, and returns a function removing it. The
+// listeners are called synchronously, possibly while the container is
+// locked, they must not block nor call the container.
+func (c *Container) Subscribe(l Listener) func() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.nextSub++
+	id := c.nextSub
+	c.listeners = append(c.listeners, subscription{id, l})
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		for i, s := range c.listeners {
+			if s.id == id {
+				c.listeners = append(c.listeners[:i:i], c.listeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// emit delivers the event of the key to the listeners of the container and
+// of its ancestors.
+func (c *Container) emit(kind EventKind, k Key, ctr interface{}, d time.Duration, err error) {
+	var subs []subscription
+	for p := c; p != nil; p = p.parent {
+		p.lock.RLock()
+		subs = append(subs, p.listeners...)
+		p.lock.RUnlock()
+	}
+	if len(subs) == 0 {
+		return
+	}
+	e := Event{
+		Kind:      kind,
+		Container: c,
+		ID:        keyID(k),
+		Type:      keyType(k),
+		Provider:  funcName(ctr),
+		Duration:  d,
+		Err:       err,
+		Time:      time.Now(),
+	}
+	for _, s := range subs {
+		s.l(e)
+	}
+}
@@ -0,0 +1,65 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestEvents(t *testing.T) {
+	Convey("Create a container with listeners", t, func() {
+		p := New(nil)
+		c := New(p)
+		lock := sync.Mutex{}
+		events := []string{}
+		listen := func(prefix string) Listener {
+			return func(e Event) {
+				lock.Lock()
+				defer lock.Unlock()
+				So(e.Time.IsZero(), ShouldBeFalse)
+				events = append(events, fmt.Sprint(prefix, " ", e.Kind, " ", e.Type, " ", e.Err))
+			}
+		}
+		unsubscribe := p.Subscribe(listen("parent"))
+		c.Subscribe(listen("child"))
+
+		Convey("the listeners should receive the events of the container and its descendants", func() {
+			So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(events, ShouldResemble, []string{
+				"child vertex_added di.testS1 <nil>",
+				"parent vertex_added di.testS1 <nil>",
+				"child value_constructed di.testS1 <nil>",
+				"parent value_constructed di.testS1 <nil>",
+			})
+
+			events = []string{}
+			unsubscribe()
+			So(p.Add(func() *testS2 { return &testS2{} }), ShouldBeNil)
+			So(c.AddLazy(func(*testS2) *testS3 { return &testS3{} }), ShouldBeNil)
+			So(c.Invoke(func(*testS3) {}, nil), ShouldNotBeNil)
+			So(len(events), ShouldEqual, 2)
+			So(events[0], ShouldEqual, "child vertex_added di.testS3 <nil>")
+			So(events[1], ShouldStartWith, "child construction_failed di.testS3 dependency for type di.testS2 not found")
+		})
+
+		Convey("failed constructions and rejected values should be reported", func() {
+			So(p.AddValue(&testS1{}), ShouldBeNil)
+			So(c.Add(func() (*testS2, error) { return nil, errors.New("failed") }), ShouldBeNil)
+			events = []string{}
+			So(c.Create(nil), ShouldNotBeNil)
+			So(events, ShouldContain, "child construction_failed di.testS2 failed")
+
+			c = New(p)
+			c.Subscribe(listen("child"))
+			So(c.Add(func() *testS3 { return &testS3{} }), ShouldBeNil)
+			events = []string{}
+			So(c.Create(func(v reflect.Value) error { return errors.New("rejected") }), ShouldNotBeNil)
+			So(events, ShouldContain, "child value_rejected di.testS3 rejected")
+		})
+	})
+}
@@ -0,0 +1,308 @@
+package di
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// DOTOptions controls the export of the dependency graph in DOT format.
+type DOTOptions struct {
+	// Cluster groups the vertices by their Go package.
+	Cluster bool
+
+	// Root limits the export to the subtree of the root type, that is the root
+	// type and its transitive dependencies. The complete graph is exported if
+	// the root is nil.
+	Root reflect.Type
+}
+
+// WriteDOT writes the dependency graph of the container in the graphviz DOT
+// format. Edges point from a type to its dependencies. Types that are
+// dependencies but are not produced by this container, and hence must be
+// provided by its ancestors, are drawn dashed.
+func (c *Container) WriteDOT(w io.Writer, opts DOTOptions) error {
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	keys, err := c.graphKeys(opts.Root)
+	if err != nil {
+		return err
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "digraph dependencies {")
+	if opts.Cluster {
+		pkgs := []string{}
+		clusters := map[string][]Key{}
+		for _, k := range keys {
+			pkg := keyType(k).PkgPath()
+			if _, ok := clusters[pkg]; !ok {
+				pkgs = append(pkgs, pkg)
+			}
+			clusters[pkg] = append(clusters[pkg], k)
+		}
+		sort.Strings(pkgs)
+		for i, pkg := range pkgs {
+			if pkg == "" {
+				// Unnamed and builtin types have no package
+				for _, k := range clusters[pkg] {
+					c.writeDOTVertex(buf, "\t", k)
+				}
+				continue
+			}
+			fmt.Fprintf(buf, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, pkg)
+			for _, k := range clusters[pkg] {
+				c.writeDOTVertex(buf, "\t\t", k)
+			}
+			fmt.Fprintln(buf, "\t}")
+		}
+	} else {
+		for _, k := range keys {
+			c.writeDOTVertex(buf, "\t", k)
+		}
+	}
+
+	included := map[Key]bool{}
+	for _, k := range keys {
+		included[k] = true
+	}
+	for _, k := range keys {
+		for _, d := range c.dependencies(k) {
+			if included[d] {
+				fmt.Fprintf(buf, "\t%q -> %q;\n", keyID(k), keyID(d))
+			}
+		}
+	}
+	fmt.Fprintln(buf, "}")
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// GraphDOT returns the dependency graph of the container and its ancestors in
+// the graphviz DOT format. The types produced by each container are clustered
+// from the root container down to this container, and the dependencies point
+// to the container that resolves them. Dependencies that no container
+// produces are drawn dashed in red. The clusters are labeled with the labels
+// in the order of the containers from the root, "container N" by default.
+func (c *Container) GraphDOT(labels ...string) string {
+	defer c.rlockChain()()
+	chain := []*Container{}
+	for p := c; p != nil; p = p.parent {
+		chain = append([]*Container{p}, chain...)
+	}
+	index := map[*Container]int{}
+	for i, cc := range chain {
+		index[cc] = i
+	}
+	vertex := func(cc *Container, k Key) string {
+		return fmt.Sprintf("%d/%s", index[cc], keyID(k))
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "digraph dependencies {")
+	keys := make([][]Key, len(chain))
+	for i, cc := range chain {
+		keys[i], _ = cc.graphKeys(nil)
+		label := fmt.Sprintf("container %d", i)
+		if i < len(labels) {
+			label = labels[i]
+		}
+		fmt.Fprintf(buf, "\tsubgraph cluster_%d {\n\t\tlabel=%q;\n", i, label)
+		for _, k := range keys[i] {
+			if _, ok := k.(groupKey); ok || cc.dag.GetValue(k) != nil {
+				fmt.Fprintf(buf, "\t\t%q [label=%q];\n", vertex(cc, k), dotLabel(k))
+			}
+		}
+		fmt.Fprintln(buf, "\t}")
+	}
+
+	missing := []Key{}
+	seen := map[Key]bool{}
+	for i, cc := range chain {
+		for _, k := range keys[i] {
+			if _, ok := k.(groupKey); !ok && cc.dag.GetValue(k) == nil {
+				continue
+			}
+			for _, d := range cc.dependencies(k) {
+				to := cc
+				if _, ok := d.(groupKey); !ok {
+					to = cc.provider(d)
+				}
+				if to != nil {
+					fmt.Fprintf(buf, "\t%q -> %q;\n", vertex(cc, k), vertex(to, d))
+					continue
+				}
+				if !seen[d] {
+					seen[d] = true
+					missing = append(missing, d)
+				}
+				fmt.Fprintf(buf, "\t%q -> %q;\n", vertex(cc, k), keyID(d))
+			}
+		}
+	}
+	sortKeys(missing)
+	for _, k := range missing {
+		fmt.Fprintf(buf, "\t%q [label=%q, style=dashed, color=red];\n", keyID(k), dotLabel(k))
+	}
+	fmt.Fprintln(buf, "}")
+	return buf.String()
+}
+
+// provider returns the container resolving the key with the parent first
+// delegation, nil if no container produces the key.
+func (c *Container) provider(k Key) *Container {
+	if c.checkParent(k) {
+		if p := c.parent.provider(k); p != nil {
+			return p
+		}
+	}
+	if c.dag.GetValue(k) != nil {
+		return c
+	}
+	return nil
+}
+
+// GraphNode describes a type in the dependency graph of a container.
+type GraphNode struct {
+	// Type is the fully qualified name of the type.
+	Type string `json:"type"`
+
+	// Provider is the fully qualified name of the constructor producing the
+	// type. It is empty if the type must be provided by an ancestor container.
+	Provider string `json:"provider,omitempty"`
+
+	// Dependencies are the fully qualified names of the types this type
+	// depends on.
+	Dependencies []string `json:"dependencies"`
+}
+
+// Describe returns the dependency graph of the container in a canonical form.
+// The nodes are sorted by type and the dependencies of each node are sorted,
+// so that the graphs of two builds can be compared.
+func (c *Container) Describe() []GraphNode {
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	keys, _ := c.graphKeys(nil)
+	nodes := make([]GraphNode, 0, len(keys))
+	for _, k := range keys {
+		n := GraphNode{Type: keyID(k), Dependencies: []string{}}
+		if ctr := c.dag.GetValue(k); ctr != nil {
+			n.Provider = funcName(ctr)
+		}
+		for _, d := range c.dependencies(k) {
+			n.Dependencies = append(n.Dependencies, keyID(d))
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// WriteJSON writes the canonical form of the dependency graph returned by
+// Describe as indented JSON. Tools can diff the output between commits to
+// detect unexpected changes in the wiring.
+func (c *Container) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(c.Describe(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func (c *Container) writeDOTVertex(buf *bytes.Buffer, indent string, k Key) {
+	style := ""
+	if _, ok := k.(groupKey); !ok && c.dag.GetValue(k) == nil {
+		style = ", style=dashed"
+	}
+	fmt.Fprintf(buf, "%s%q [label=%q%s];\n", indent, keyID(k), dotLabel(k), style)
+}
+
+// dotLabel returns the label of the vertex of the key in DOT graphs.
+func dotLabel(k Key) string {
+	label := keyType(k).String()
+	switch k := k.(type) {
+	case namedKey:
+		label = fmt.Sprintf("%s %q", label, k.name)
+	case groupKey:
+		label = fmt.Sprintf("[]%s group %q", label, k.group)
+	case memberKey:
+		label = fmt.Sprintf("%s group %q #%d", label, k.group, k.index)
+	}
+	return label
+}
+
+// graphKeys returns the keys in the dependency graph sorted by their
+// identifiers. If root is not nil only the subtree of the root type is
+// returned.
+func (c *Container) graphKeys(root reflect.Type) ([]Key, error) {
+	all := map[Key]bool{}
+	for _, v := range c.dag.Sort() {
+		all[v.Key] = true
+	}
+
+	keys := []Key{}
+	if root == nil {
+		for k := range all {
+			keys = append(keys, k)
+		}
+	} else {
+		r := Key(baseType(root))
+		if !all[r] {
+			return nil, fmt.Errorf("type %v is not in the dependency graph", r)
+		}
+		seen := map[Key]bool{r: true}
+		queue := []Key{r}
+		for len(queue) > 0 {
+			k := queue[0]
+			queue = queue[1:]
+			keys = append(keys, k)
+			for _, d := range c.dependencies(k) {
+				if !seen[d] {
+					seen[d] = true
+					queue = append(queue, d)
+				}
+			}
+		}
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+// dependencies returns the dependencies of the key in the dependency graph
+// sorted by their identifiers.
+func (c *Container) dependencies(k Key) []Key {
+	deps := c.dag.Dependencies(k)
+	sortKeys(deps)
+	return deps
+}
+
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keyID(keys[i]) < keyID(keys[j])
+	})
+}
+
+// keyID returns the fully qualified name of the type of the key followed by
+// the name of the binding or the value group if any, e.g.
+// "github.com/anuvu/cube/config.Store", "database/sql.DB[primary]" or
+// "net/http.Handler{handlers}#0".
+func keyID(k Key) string {
+	t := keyType(k)
+	id := t.String()
+	if t.PkgPath() != "" && t.Name() != "" {
+		id = t.PkgPath() + "." + t.Name()
+	}
+	switch k := k.(type) {
+	case namedKey:
+		id += "[" + k.name + "]"
+	case groupKey:
+		id += "{" + k.group + "}"
+	case memberKey:
+		id += fmt.Sprintf("{%s}#%d", k.group, k.index)
+	}
+	return id
+}
@@ -0,0 +1,136 @@
+package di
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWriteDOT(t *testing.T) {
+	Convey("Create a container with a dependency chain", t, func() {
+		c := New(nil)
+		So(c.Add(func(int) *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+		So(c.Add(func(*testS2, *testS1) *testS3 { return &testS3{} }), ShouldBeNil)
+		buf := &bytes.Buffer{}
+
+		Convey("the complete graph should be exported", func() {
+			So(c.WriteDOT(buf, DOTOptions{}), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `digraph dependencies {
+	"github.com/anuvu/cube/di.testS1" [label="di.testS1"];
+	"github.com/anuvu/cube/di.testS2" [label="di.testS2"];
+	"github.com/anuvu/cube/di.testS3" [label="di.testS3"];
+	"int" [label="int", style=dashed];
+	"github.com/anuvu/cube/di.testS1" -> "int";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.testS1";
+	"github.com/anuvu/cube/di.testS3" -> "github.com/anuvu/cube/di.testS1";
+	"github.com/anuvu/cube/di.testS3" -> "github.com/anuvu/cube/di.testS2";
+}
+`)
+		})
+
+		Convey("the vertices should be clustered by package", func() {
+			So(c.WriteDOT(buf, DOTOptions{Cluster: true}), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `digraph dependencies {
+	"int" [label="int", style=dashed];
+	subgraph cluster_1 {
+		label="github.com/anuvu/cube/di";
+		"github.com/anuvu/cube/di.testS1" [label="di.testS1"];
+		"github.com/anuvu/cube/di.testS2" [label="di.testS2"];
+		"github.com/anuvu/cube/di.testS3" [label="di.testS3"];
+	}
+	"github.com/anuvu/cube/di.testS1" -> "int";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.testS1";
+	"github.com/anuvu/cube/di.testS3" -> "github.com/anuvu/cube/di.testS1";
+	"github.com/anuvu/cube/di.testS3" -> "github.com/anuvu/cube/di.testS2";
+}
+`)
+		})
+
+		Convey("the export should be filtered to a subtree", func() {
+			So(c.WriteDOT(buf, DOTOptions{Root: reflect.TypeOf(&testS2{})}), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `digraph dependencies {
+	"github.com/anuvu/cube/di.testS1" [label="di.testS1"];
+	"github.com/anuvu/cube/di.testS2" [label="di.testS2"];
+	"int" [label="int", style=dashed];
+	"github.com/anuvu/cube/di.testS1" -> "int";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.testS1";
+}
+`)
+			So(c.WriteDOT(buf, DOTOptions{Root: reflect.TypeOf("")}), ShouldBeError)
+		})
+	})
+}
+
+func TestGraphDOT(t *testing.T) {
+	Convey("Create a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		c := New(p)
+		So(c.Add(func(*testS1, int) *testS2 { return &testS2{} }), ShouldBeNil)
+
+		Convey("the graph should include the parent and the missing types", func() {
+			So(c.GraphDOT(), ShouldEqual, `digraph dependencies {
+	subgraph cluster_0 {
+		label="container 0";
+		"0/github.com/anuvu/cube/di.testS1" [label="di.testS1"];
+	}
+	subgraph cluster_1 {
+		label="container 1";
+		"1/github.com/anuvu/cube/di.testS2" [label="di.testS2"];
+	}
+	"1/github.com/anuvu/cube/di.testS2" -> "0/github.com/anuvu/cube/di.testS1";
+	"1/github.com/anuvu/cube/di.testS2" -> "int";
+	"int" [label="int", style=dashed, color=red];
+}
+`)
+		})
+	})
+}
+
+func newExportS1() *testS1 { return &testS1{} }
+
+func newExportS2(*testS1, int) *testS2 { return &testS2{} }
+
+func TestWriteJSON(t *testing.T) {
+	Convey("Create a container with named constructors", t, func() {
+		c := New(nil)
+		So(c.Add(newExportS2), ShouldBeNil)
+		So(c.Add(newExportS1), ShouldBeNil)
+
+		Convey("the graph should be described in canonical order", func() {
+			So(c.Describe(), ShouldResemble, []GraphNode{
+				{"github.com/anuvu/cube/di.testS1", "github.com/anuvu/cube/di.newExportS1", []string{}},
+				{"github.com/anuvu/cube/di.testS2", "github.com/anuvu/cube/di.newExportS2", []string{"github.com/anuvu/cube/di.testS1", "int"}},
+				{"int", "", []string{}},
+			})
+		})
+
+		Convey("the graph should be written as JSON", func() {
+			buf := &bytes.Buffer{}
+			So(c.WriteJSON(buf), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `[
+  {
+    "type": "github.com/anuvu/cube/di.testS1",
+    "provider": "github.com/anuvu/cube/di.newExportS1",
+    "dependencies": []
+  },
+  {
+    "type": "github.com/anuvu/cube/di.testS2",
+    "provider": "github.com/anuvu/cube/di.newExportS2",
+    "dependencies": [
+      "github.com/anuvu/cube/di.testS1",
+      "int"
+    ]
+  },
+  {
+    "type": "int",
+    "dependencies": []
+  }
+]
+`)
+		})
+	})
+}
@@ -0,0 +1,43 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// getterTarget returns the type resolved by a lazy getter of type func() T
+// or func() (T, error), nil if the type is not a getter, see Add.
+func getterTarget(t reflect.Type) reflect.Type {
+	if t.Kind() != reflect.Func || t.NumIn() != 0 {
+		return nil
+	}
+	switch {
+	case t.NumOut() == 1 && t.Out(0) != _errType:
+	case t.NumOut() == 2 && t.Out(1) == _errType:
+	default:
+		return nil
+	}
+	return t.Out(0)
+}
+
+// getter returns the lazy getter of type t of the container, see
+// getterTarget.
+func (c *Container) getter(t reflect.Type) reflect.Value {
+	target := getterTarget(t)
+	return reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+		v, err := c.get(target)
+		if nf, ok := err.(*NotFoundError); ok {
+			err = c.notFound(nf.Key)
+		}
+		if t.NumOut() == 1 {
+			if err != nil {
+				panic(fmt.Sprintf("lazy getter %v failed: %v", t, err))
+			}
+			return []reflect.Value{v}
+		}
+		if err != nil {
+			return []reflect.Value{reflect.Zero(target), reflect.ValueOf(&err).Elem()}
+		}
+		return []reflect.Value{v, reflect.Zero(_errType)}
+	})
+}
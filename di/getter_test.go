@@ -0,0 +1,84 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type getterClient struct {
+	server func() *getterServer
+}
+
+type getterServer struct {
+	client *getterClient
+}
+
+func TestGetter(t *testing.T) {
+	Convey("Create a container with components needing each other", t, func() {
+		c := New(nil)
+		So(c.Add(func(s func() *getterServer) *getterClient {
+			return &getterClient{server: s}
+		}), ShouldBeNil)
+		So(c.Add(func(c *getterClient) *getterServer {
+			return &getterServer{client: c}
+		}), ShouldBeNil)
+		So(c.Validate(), ShouldBeNil)
+
+		Convey("the getter should resolve the value when it is called", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(cl *getterClient, s *getterServer) {
+				So(cl.server(), ShouldEqual, s)
+				So(s.client, ShouldEqual, cl)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("a getter with an error should resolve lazy values", func() {
+			So(c.AddLazy(func() (*testS1, error) { return nil, errors.New("lazy error") }), ShouldBeNil)
+			So(c.AddLazy(func() *testS2 { return &testS2{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(g1 func() (*testS1, error), g2 func() (*testS2, error)) {
+				_, err := g1()
+				So(err, ShouldBeError, "lazy error")
+				s2, err := g2()
+				So(err, ShouldBeNil)
+				So(s2, ShouldNotBeNil)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("a missing value should fail the call, not the construction", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(g func() (*testS3, error), p func() *testS3) {
+				_, err := g()
+				So(err, ShouldHaveSameTypeAs, &NotFoundError{})
+				So(func() { p() }, ShouldPanic)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("the interceptors should apply to the type of a getter", func() {
+			c.Intercept(Restrict(reflect.TypeOf(&getterServer{}), "github.com/anuvu/cube/http"))
+			So(c.Create(nil), ShouldBeError)
+			So(c.Invoke(func(func() (*getterServer, error)) {}, nil), ShouldBeError)
+		})
+
+		Convey("a provided getter should be used", func() {
+			s := &testS3{}
+			So(c.Add(func() func() *testS3 { return func() *testS3 { return s } }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(g func() *testS3) {
+				So(g(), ShouldEqual, s)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("validation should require the value of a getter", func() {
+			So(c.Add(func(func() *testS3) *testS1 { return &testS1{} }), ShouldBeNil)
+			err := c.Validate()
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+			errs := err.(*ValidationError).Errs
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].(*UnresolvedError).Err.Key, ShouldEqual, Key(reflect.TypeOf(testS3{})))
+		})
+	})
+}
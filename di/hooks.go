@@ -0,0 +1,79 @@
+package di
+
+import (
+	"reflect"
+)
+
+// Hooks are called around each invocation of a constructor by the container,
+// by Create and when the lazy, transient and scoped values are constructed,
+// e.g. to trace, time or log the build of the container. The constructor is
+// identified by its fully qualified name and the types it constructs, the
+// element type for pointers. The hooks must be safe for concurrent use as
+// independent constructors are invoked concurrently when the parallel
+// construction is enabled, see SetParallel.
+type Hooks struct {
+	// Before is called before the constructor is invoked.
+	Before func(fn string, types []reflect.Type)
+
+	// After is called once the constructor returned, with the values it
+	// constructed or with its error.
+	After func(fn string, types []reflect.Type, values []interface{}, err error)
+}
+
+// AddHooks registers hooks with the container. Hooks registered with a
+// container also apply to all its descendant containers, the hooks of the
+// ancestors are called first.
+func (c *Container) AddHooks(h Hooks) {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	c.hooks = append(c.hooks, h)
+}
+
+// allHooks returns the hooks of the container hierarchy, the hooks of the
+// root container first.
+func (c *Container) allHooks() []Hooks {
+	hooks := []Hooks{}
+	for ; c != nil; c = c.parent {
+		c.graphLock.RLock()
+		hooks = append(append([]Hooks{}, c.hooks...), hooks...)
+		c.graphLock.RUnlock()
+	}
+	return hooks
+}
+
+// invokeCtr invokes the constructor of the keys like invoke and calls the
+// hooks of the container hierarchy around it.
+func (c *Container) invokeCtr(ctr interface{}, keys []Key, vp ValueProcessor, s *Scope) error {
+	hooks := c.allHooks()
+	if len(hooks) == 0 {
+		return c.invoke(ctr, vp, s)
+	}
+	fn := funcName(ctr)
+	types := make([]reflect.Type, len(keys))
+	for i, k := range keys {
+		types[i] = keyType(k)
+	}
+	for _, h := range hooks {
+		if h.Before != nil {
+			h.Before(fn, types)
+		}
+	}
+	values := []interface{}{}
+	err := c.invoke(ctr, func(v reflect.Value) error {
+		if !baseType(v.Type()).Implements(_errType) && v.Type() != cleanupType {
+			for _, r := range results(v) {
+				values = append(values, r.Interface())
+			}
+		}
+		return vp(v)
+	}, s)
+	if err != nil {
+		values = nil
+	}
+	for _, h := range hooks {
+		if h.After != nil {
+			h.After(fn, types, values, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHooks(t *testing.T) {
+	Convey("Create a container with construction hooks", t, func() {
+		p := New(nil)
+		c := New(p)
+		lock := sync.Mutex{}
+		events := []string{}
+		record := func(prefix string) Hooks {
+			return Hooks{
+				Before: func(fn string, types []reflect.Type) {
+					lock.Lock()
+					defer lock.Unlock()
+					events = append(events, fmt.Sprint(prefix, " before ", types))
+				},
+				After: func(fn string, types []reflect.Type, values []interface{}, err error) {
+					lock.Lock()
+					defer lock.Unlock()
+					events = append(events, fmt.Sprint(prefix, " after ", types, " ", len(values), " ", err))
+				},
+			}
+		}
+		p.AddHooks(record("parent"))
+		c.AddHooks(record("child"))
+		c.AddHooks(Hooks{})
+
+		Convey("the hooks should be called around the constructors of create", func() {
+			So(c.Add(func() (*testS1, *testS2, Cleanup) { return &testS1{}, &testS2{}, func() {} }), ShouldBeNil)
+			So(c.Add(func(*testS1) (*testS3, error) { return nil, errors.New("failed") }), ShouldBeNil)
+			So(c.Create(nil), ShouldNotBeNil)
+			So(events, ShouldResemble, []string{
+				"parent before [di.testS1 di.testS2]",
+				"child before [di.testS1 di.testS2]",
+				"parent after [di.testS1 di.testS2] 2 <nil>",
+				"child after [di.testS1 di.testS2] 2 <nil>",
+				"parent before [di.testS3]",
+				"child before [di.testS3]",
+				"parent after [di.testS3] 0 failed",
+				"child after [di.testS3] 0 failed",
+			})
+		})
+
+		Convey("the hooks should be called around the lazy and transient constructors", func() {
+			So(c.AddLazy(func() *testS1 { return &testS1{} }), ShouldBeNil)
+			So(c.AddTransient(func() *testS2 { return &testS2{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(events, ShouldBeEmpty)
+			So(c.Invoke(func(*testS1, *testS2) {}, nil), ShouldBeNil)
+			So(c.Invoke(func(*testS1, *testS2) {}, nil), ShouldBeNil)
+			So(len(events), ShouldEqual, 12)
+		})
+
+		Convey("the hooks of a child should not apply to its parent", func() {
+			So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+			So(p.Create(nil), ShouldBeNil)
+			So(events, ShouldResemble, []string{"parent before [di.testS1]", "parent after [di.testS1] 1 <nil>"})
+		})
+	})
+}
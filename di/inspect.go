@@ -0,0 +1,192 @@
+package di
+
+import (
+	"reflect"
+)
+
+// Source tells where a dependency is resolved from.
+type Source int
+
+const (
+	// SourceMissing means no container produces the dependency.
+	SourceMissing Source = iota
+	// SourceLocal means the container itself produces the dependency.
+	SourceLocal
+	// SourceAncestor means an ancestor container produces the dependency.
+	SourceAncestor
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceLocal:
+		return "local"
+	case SourceAncestor:
+		return "ancestor"
+	}
+	return "missing"
+}
+
+// Registration describes a type registered in a container.
+type Registration struct {
+	// ID is the fully qualified name of the type followed by the name of the
+	// binding or the value group if any, as in the exported graphs.
+	ID string
+	// Type is the registered type, the element type for pointers which are
+	// registered by their element type.
+	Type reflect.Type
+	// Name is the name of the binding of the type, if any.
+	Name string
+	// Group is the value group the type is a member of, if any.
+	Group string
+	// Provider is the fully qualified name of the constructor of the type.
+	Provider string
+	// Constructed is true once the value of the type is constructed.
+	Constructed bool
+	// Dependencies are the direct dependencies of the type sorted by their
+	// identifiers.
+	Dependencies []Dependency
+	// Tags are the metadata tags of the constructor, nil if it has none.
+	Tags map[string]string
+}
+
+// Dependency describes a direct dependency of a registered type.
+type Dependency struct {
+	// ID is the identifier of the dependency.
+	ID string
+	// Type is the type of the dependency, the element type for value groups.
+	Type reflect.Type
+	// Name is the name of the binding of the dependency, if any.
+	Name string
+	// Group is the value group of the dependency, if any.
+	Group string
+	// Source tells which container resolves the dependency. Value groups
+	// collect the members of the container and of its ancestors and are
+	// always resolved locally.
+	Source Source
+	// Depth is the number of containers between the container and the
+	// ancestor resolving the dependency, 0 unless the source is an ancestor.
+	Depth int
+}
+
+// Registrations returns the types registered in the container, not in its
+// ancestors, sorted by their identifiers.
+func (c *Container) Registrations() []Registration {
+	defer c.rlockChain()()
+	keys, _ := c.graphKeys(nil)
+	regs := []Registration{}
+	for _, k := range keys {
+		if r, ok := c.registration(k); ok {
+			regs = append(regs, r)
+		}
+	}
+	return regs
+}
+
+// Registration returns the registration of the type in the container, or of
+// the type bound to the name if a name is given. It returns false if the
+// container does not register the type.
+func (c *Container) Registration(t reflect.Type, name ...string) (Registration, bool) {
+	var k Key = baseType(t)
+	if len(name) > 0 && name[0] != "" {
+		k = namedKey{baseType(t), name[0]}
+	}
+	defer c.rlockChain()()
+	return c.registration(k)
+}
+
+// Order returns the registrations of the values constructed by Create grouped
+// by the levels in which their constructors are invoked, the constructors of
+// a level only depend on the values of the previous levels and are invoked
+// concurrently if the parallel construction is enabled. A constructor producing several values is listed once, by the
+// first value it produces. Lazy, transient and scoped values are constructed
+// on demand and are not listed.
+func (c *Container) Order() [][]Registration {
+	defer c.rlockChain()()
+	order := [][]Registration{}
+	for _, level := range c.levels() {
+		regs := []Registration{}
+		claimed := map[Key]bool{}
+		for _, n := range level {
+			if n.Value == nil || claimed[n.Key] {
+				continue
+			}
+			if _, ok := c.lazy[n.Key]; ok {
+				continue
+			}
+			if _, ok := c.scopes[n.Key]; ok {
+				continue
+			}
+			for _, k := range c.outs[n.Key] {
+				claimed[k] = true
+			}
+			if r, ok := c.registration(n.Key); ok {
+				regs = append(regs, r)
+			}
+		}
+		if len(regs) > 0 {
+			order = append(order, regs)
+		}
+	}
+	return order
+}
+
+func (c *Container) registration(k Key) (Registration, bool) {
+	ctr := c.dag.GetValue(k)
+	if ctr == nil {
+		return Registration{}, false
+	}
+	c.lock.RLock()
+	_, constructed := c.objTable[k]
+	c.lock.RUnlock()
+	r := Registration{
+		ID:           keyID(k),
+		Type:         keyType(k),
+		Provider:     funcName(ctr),
+		Constructed:  constructed,
+		Dependencies: []Dependency{},
+	}
+	if tags := c.tags[k]; tags != nil {
+		r.Tags = make(map[string]string, len(tags))
+		for n, v := range tags {
+			r.Tags[n] = v
+		}
+	}
+	r.Name, r.Group = keyNames(k)
+	for _, d := range c.dependencies(k) {
+		r.Dependencies = append(r.Dependencies, c.dependency(d))
+	}
+	return r, true
+}
+
+func (c *Container) dependency(k Key) Dependency {
+	d := Dependency{ID: keyID(k), Type: keyType(k), Source: SourceLocal}
+	d.Name, d.Group = keyNames(k)
+	if _, ok := k.(groupKey); ok {
+		return d
+	}
+	p := c.provider(k)
+	if p == nil {
+		d.Source = SourceMissing
+		return d
+	}
+	for a := c; a != p; a = a.parent {
+		d.Depth++
+	}
+	if d.Depth > 0 {
+		d.Source = SourceAncestor
+	}
+	return d
+}
+
+// keyNames returns the name of the binding and the value group of the key.
+func keyNames(k Key) (name, group string) {
+	switch k := k.(type) {
+	case namedKey:
+		return k.name, ""
+	case groupKey:
+		return "", k.group
+	case memberKey:
+		return "", k.group
+	}
+	return "", ""
+}
@@ -0,0 +1,94 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type inspectIn struct {
+	In
+	S1    *testS1
+	N     int
+	Pools []*pool `group:"pools"`
+}
+
+func newInspectS2(inspectIn) *testS2 { return &testS2{} }
+
+func TestRegistrations(t *testing.T) {
+	Convey("Create a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.Add(newExportS1), ShouldBeNil)
+		c := New(p)
+		So(c.Add(newInspectS2), ShouldBeNil)
+		So(c.AddNamed("replica", func() *pool { return &pool{"replica"} }), ShouldBeNil)
+		So(c.AddToGroup("pools", func() *pool { return &pool{"a"} }), ShouldBeNil)
+
+		Convey("the registered types should be enumerated", func() {
+			regs := c.Registrations()
+			So(regs, ShouldHaveLength, 3)
+			So(regs[0].ID, ShouldEqual, "github.com/anuvu/cube/di.pool[replica]")
+			So(regs[0].Name, ShouldEqual, "replica")
+			So(regs[1].Group, ShouldEqual, "pools")
+			So(regs[2].Type, ShouldEqual, reflect.TypeOf(testS2{}))
+			So(regs[2].Provider, ShouldEqual, "github.com/anuvu/cube/di.newInspectS2")
+			So(regs[2].Constructed, ShouldBeFalse)
+			So(p.Registrations(), ShouldHaveLength, 1)
+		})
+
+		Convey("the dependencies should tell where they are resolved from", func() {
+			r, ok := c.Registration(reflect.TypeOf(&testS2{}))
+			So(ok, ShouldBeTrue)
+			deps := map[string]Dependency{}
+			for _, d := range r.Dependencies {
+				deps[d.ID] = d
+			}
+			So(deps["github.com/anuvu/cube/di.testS1"].Source, ShouldEqual, SourceAncestor)
+			So(deps["github.com/anuvu/cube/di.testS1"].Depth, ShouldEqual, 1)
+			So(deps["int"].Source, ShouldEqual, SourceMissing)
+			So(deps["int"].Source.String(), ShouldEqual, "missing")
+			So(deps["github.com/anuvu/cube/di.pool{pools}"].Source, ShouldEqual, SourceLocal)
+		})
+
+		Convey("the lookup should honor the names and the construction", func() {
+			_, ok := c.Registration(reflect.TypeOf(&pool{}))
+			So(ok, ShouldBeFalse)
+			r, ok := c.Registration(reflect.TypeOf(&pool{}), "replica")
+			So(ok, ShouldBeTrue)
+			So(r.Dependencies, ShouldBeEmpty)
+			So(p.Create(nil), ShouldBeNil)
+			r, _ = p.Registration(reflect.TypeOf(&testS1{}))
+			So(r.Constructed, ShouldBeTrue)
+		})
+	})
+}
+
+func TestOrder(t *testing.T) {
+	Convey("Create a container with dependent constructors", t, func() {
+		c := New(nil)
+		So(c.Add(func(*testS1) (*testS2, *testS3) { return &testS2{}, &testS3{} }), ShouldBeNil)
+		So(c.Add(newExportS1), ShouldBeNil)
+		So(c.Add(func() int { return 1 }), ShouldBeNil)
+		So(c.AddLazy(func(*testS2) string { return "lazy" }), ShouldBeNil)
+
+		Convey("the constructors should be listed in the order of construction", func() {
+			order := c.Order()
+			So(order, ShouldHaveLength, 2)
+			So(order[0], ShouldHaveLength, 2)
+			ids := []string{order[0][0].ID, order[0][1].ID}
+			So(ids, ShouldContain, "github.com/anuvu/cube/di.testS1")
+			So(ids, ShouldContain, "int")
+			So(order[1], ShouldHaveLength, 1)
+			So(order[1][0].Dependencies, ShouldHaveLength, 1)
+			So(order[1][0].Dependencies[0].ID, ShouldEqual, "github.com/anuvu/cube/di.testS1")
+		})
+
+		Convey("the constructed values should still be listed", func() {
+			So(c.Create(nil), ShouldBeNil)
+			order := c.Order()
+			So(order, ShouldHaveLength, 2)
+			So(order[1][0].Constructed, ShouldBeTrue)
+		})
+	})
+}
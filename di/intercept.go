@@ -0,0 +1,87 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Interceptor is called before a dependency of a function is resolved by the
+// container. The function is identified by its fully qualified name, e.g.
+// "github.com/anuvu/cube/http.New". If the interceptor returns an error the
+// resolution is vetoed and the error is returned to the caller.
+type Interceptor func(fn string, dep reflect.Type) error
+
+// Intercept registers an interceptor with the container. Interceptors
+// registered with a container also apply to all its descendant containers.
+func (c *Container) Intercept(i Interceptor) {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	c.interceptors = append(c.interceptors, i)
+}
+
+// Restrict returns an interceptor that allows only functions defined in the
+// listed packages, or their sub-packages, to depend on the type t.
+func Restrict(t reflect.Type, pkgs ...string) Interceptor {
+	t = baseType(t)
+	return func(fn string, dep reflect.Type) error {
+		if baseType(dep) != t {
+			return nil
+		}
+		pkg := funcPackage(fn)
+		for _, p := range pkgs {
+			if pkg == p || strings.HasPrefix(pkg, p+"/") {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not allowed to depend on type %v", fn, t)
+	}
+}
+
+// intercept runs the interceptors of the container hierarchy for the
+// dependency of the function.
+func (c *Container) intercept(fn string, dep reflect.Type) error {
+	for ; c != nil; c = c.parent {
+		c.graphLock.RLock()
+		interceptors := c.interceptors
+		c.graphLock.RUnlock()
+		for _, i := range interceptors {
+			if err := i(fn, dep); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasInterceptors checks if any interceptors are registered in the container
+// hierarchy.
+func (c *Container) hasInterceptors() bool {
+	for ; c != nil; c = c.parent {
+		c.graphLock.RLock()
+		n := len(c.interceptors)
+		c.graphLock.RUnlock()
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// funcName returns the fully qualified name of the function.
+func funcName(fx interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fx).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// funcPackage returns the package path of a fully qualified function name.
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
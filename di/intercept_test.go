@@ -0,0 +1,47 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIntercept(t *testing.T) {
+	Convey("Create a container hierarchy", t, func() {
+		c := New(nil)
+		cc := New(c)
+		s1Type := reflect.TypeOf(&testS1{})
+		So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+
+		Convey("allowed packages should resolve the type", func() {
+			c.Intercept(Restrict(s1Type, "github.com/anuvu/cube"))
+			So(cc.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(cc.Create(nil), ShouldBeNil)
+			So(cc.Invoke(func(*testS2) {}, nil), ShouldBeNil)
+		})
+
+		Convey("other packages should be vetoed", func() {
+			c.Intercept(Restrict(s1Type, "github.com/anuvu/cube/http"))
+			So(cc.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(cc.Create(nil), ShouldBeError)
+			So(cc.Invoke(func(*testS1) {}, nil), ShouldBeError)
+			So(c.Invoke(func(*testS1) {}, nil), ShouldBeError)
+		})
+
+		Convey("unrestricted types should resolve", func() {
+			cc.Intercept(Restrict(reflect.TypeOf(testS3{}), "github.com/anuvu/cube/http"))
+			So(cc.Invoke(func(*testS1) {}, nil), ShouldBeNil)
+			So(c.Invoke(func(*testS1) {}, nil), ShouldBeNil)
+		})
+	})
+
+	Convey("Package paths should be extracted from function names", t, func() {
+		So(funcPackage("github.com/anuvu/cube/http.New"), ShouldEqual, "github.com/anuvu/cube/http")
+		So(funcPackage("github.com/anuvu/cube/http.(*server).Start"), ShouldEqual, "github.com/anuvu/cube/http")
+		So(funcPackage("github.com/anuvu/cube.Main.func1"), ShouldEqual, "github.com/anuvu/cube")
+		So(funcPackage("main.main"), ShouldEqual, "main")
+		So(funcPackage("main"), ShouldEqual, "main")
+	})
+}
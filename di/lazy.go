@@ -0,0 +1,88 @@
+package di
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// lazyValue tracks the construction of the values of a lazy constructor.
+type lazyValue struct {
+	once sync.Once
+	err  error
+}
+
+// AddLazy adds the constructor to the container like Add, but its values are
+// constructed on first use rather than by Create. A lazy value is
+// constructed once, the first time it is resolved as a dependency of an
+// invoked function or of another constructor. If the construction fails,
+// the error is returned by every resolution of the value.
+//
+// The values of lazy constructors are not passed to the value processor of
+// Create. A snapshot constructs the lazy values that are not constructed
+// before it is taken on their first use in the snapshot.
+func (c *Container) AddLazy(ctr interface{}) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	keys, err := c.add(ctr, "", "")
+	if err != nil {
+		return err
+	}
+	if c.lazy == nil {
+		c.lazy = map[Key]*lazyValue{}
+	}
+	l := &lazyValue{}
+	for _, k := range keys {
+		c.lazy[k] = l
+	}
+	return nil
+}
+
+// lookup finds the value of the key in the object table of the container,
+// constructing it if it is lazy.
+func (c *Container) lookup(k Key) (reflect.Value, bool, error) {
+	c.graphLock.RLock()
+	l, lazy := c.lazy[k]
+	c.graphLock.RUnlock()
+	if lazy {
+		l.once.Do(func() { l.err = c.construct(k) })
+	}
+	c.lock.RLock()
+	v, ok := c.objTable[k]
+	c.lock.RUnlock()
+	if lazy && !ok {
+		return v, true, l.err
+	}
+	return v, ok, nil
+}
+
+// construct invokes the lazy constructor of the key and caches its values.
+func (c *Container) construct(k Key) error {
+	c.graphLock.RLock()
+	ctr, keys := c.dag.GetValue(k), c.outs[k]
+	c.graphLock.RUnlock()
+	vals := []reflect.Value{}
+	start := time.Now()
+	err := c.invokeCtr(ctr, keys, func(v reflect.Value) error {
+		if v.Type() == cleanupType {
+			c.addCleanup(v)
+		} else if !baseType(v.Type()).Implements(_errType) {
+			vals = append(vals, results(v)...)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		c.emit(ConstructionFailed, keys[0], ctr, time.Since(start), err)
+		return err
+	}
+	d := time.Since(start)
+	c.lock.Lock()
+	for i, key := range keys {
+		c.objTable[key] = vals[i]
+	}
+	c.lock.Unlock()
+	for _, key := range keys {
+		c.emit(ValueConstructed, key, ctr, d, nil)
+	}
+	return nil
+}
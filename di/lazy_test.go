@@ -0,0 +1,73 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLazy(t *testing.T) {
+	Convey("Create a container with lazy constructors", t, func() {
+		c := New(nil)
+		s1, s2 := 0, 0
+		So(c.AddLazy(func() *testS1 {
+			s1++
+			return &testS1{}
+		}), ShouldBeNil)
+		So(c.AddLazy(func(*testS1) (*testS2, *testS3) {
+			s2++
+			return &testS2{}, &testS3{}
+		}), ShouldBeNil)
+
+		Convey("bad constructors should be rejected", func() {
+			So(c.AddLazy(nil), ShouldBeError)
+			So(c.AddLazy(func() *testS1 { return nil }), ShouldBeError)
+		})
+
+		Convey("values should be constructed on first use", func() {
+			processed := 0
+			So(c.Create(func(reflect.Value) error {
+				processed++
+				return nil
+			}), ShouldBeNil)
+			So(s1+s2, ShouldEqual, 0)
+
+			wg := sync.WaitGroup{}
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.Invoke(func(*testS2, *testS3) {}, nil)
+				}()
+			}
+			wg.Wait()
+			So(s1, ShouldEqual, 1)
+			So(s2, ShouldEqual, 1)
+			So(processed, ShouldEqual, 0)
+		})
+
+		Convey("eager constructors should construct their lazy dependencies", func() {
+			So(c.Add(func(*testS1) *pool { return &pool{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(s1, ShouldEqual, 1)
+			So(s2, ShouldEqual, 0)
+		})
+
+		Convey("construction errors should be returned on every use", func() {
+			cc := New(c)
+			calls := 0
+			So(cc.AddLazy(func(*testS2) (*pool, error) {
+				calls++
+				return nil, fmt.Errorf("failed")
+			}), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(cc.Create(nil), ShouldBeNil)
+			So(cc.Invoke(func(*pool) {}, nil), ShouldBeError, "failed")
+			So(cc.Invoke(func(*pool) {}, nil), ShouldBeError, "failed")
+			So(calls, ShouldEqual, 1)
+		})
+	})
+}
@@ -0,0 +1,248 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// In marks a struct as a parameter struct when embedded in it. A constructor
+// or an invoked function can take a parameter struct in place of positional
+// parameters, each exported field of the struct is then resolved as a
+// dependency. A field with a `name:"..."` tag is resolved from the values
+// bound under that name with AddNamed. A field with an `optional:"true"` tag
+// is set to its zero value if the dependency is not provided, instead of
+// failing the resolution. A slice field with a `group:"..."` tag is resolved
+// with all the values contributed to that value group, see AddToGroup.
+//
+//	type params struct {
+//		di.In
+//		Primary *sql.DB   `name:"primary"`
+//		Replica *sql.DB   `name:"replica" optional:"true"`
+//		Metrics *Registry `optional:"true"`
+//	}
+type In struct{}
+
+var inType = reflect.TypeOf(In{})
+
+// Out marks a struct as a result struct when embedded in it. A constructor
+// can return a result struct in place of positional results, each exported
+// field of the struct is then provided as a value of its own. A field with a
+// `name:"..."` tag is bound under that name, see AddNamed. A field with a
+// `group:"..."` tag is contributed to that value group, see AddToGroup.
+//
+//	type result struct {
+//		di.Out
+//		Primary *sql.DB `name:"primary"`
+//		Replica *sql.DB `name:"replica"`
+//	}
+type Out struct{}
+
+var outType = reflect.TypeOf(Out{})
+
+// namedKey identifies a value bound under a name.
+type namedKey struct {
+	t    reflect.Type
+	name string
+}
+
+func (k namedKey) String() string {
+	return fmt.Sprintf("%v named %q", k.t, k.name)
+}
+
+// AddNamed adds the constructor to the container and binds the values it
+// produces under the name. Many constructors can produce the same type under
+// distinct names. The named values are only resolved by the fields of
+// parameter structs tagged with the name, see In.
+func (c *Container) AddNamed(name string, ctr interface{}) error {
+	if name == "" {
+		return fmt.Errorf("name of the constructor must not be empty")
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	_, err := c.add(ctr, name, "")
+	return err
+}
+
+// outKey returns the key of a value of the type produced by a constructor
+// added with the name.
+func outKey(t reflect.Type, name string) Key {
+	if name == "" {
+		return t
+	}
+	return namedKey{t, name}
+}
+
+// keyType returns the type of the values identified by the key.
+func keyType(k Key) reflect.Type {
+	switch k := k.(type) {
+	case namedKey:
+		return k.t
+	case groupKey:
+		return k.t
+	case memberKey:
+		return k.t
+	}
+	return k.(reflect.Type)
+}
+
+// isIn checks if the type is a parameter struct.
+func isIn(t reflect.Type) bool {
+	return embeds(t, inType)
+}
+
+// isOut checks if the type is a result struct.
+func isOut(t reflect.Type) bool {
+	return embeds(t, outType)
+}
+
+// embeds checks if the type is a struct embedding the marker type.
+func embeds(t reflect.Type, marker reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// inFields returns the fields of a parameter struct that are resolved as
+// dependencies. It returns an error if any of the fields is unexported.
+func inFields(t reflect.Type) ([]reflect.StructField, error) {
+	fields, err := structFields(t, inType, "parameter")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.Tag.Get("group") == "" {
+			continue
+		}
+		if f.Type.Kind() != reflect.Slice || f.Tag.Get("name") != "" {
+			return nil, fmt.Errorf("field %s of parameter struct %v must be an unnamed slice to receive a group", f.Name, t)
+		}
+	}
+	return fields, nil
+}
+
+// outFields returns the fields of a result struct that are provided as
+// values. It returns an error if any of the fields is unexported.
+func outFields(t reflect.Type) ([]reflect.StructField, error) {
+	fields, err := structFields(t, outType, "result")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.Tag.Get("group") != "" && f.Tag.Get("name") != "" {
+			return nil, fmt.Errorf("field %s of result struct %v can't be both named and grouped", f.Name, t)
+		}
+	}
+	return fields, nil
+}
+
+// structFields returns the fields of the struct other than the marker.
+func structFields(t reflect.Type, marker reflect.Type, kind string) ([]reflect.StructField, error) {
+	fields := []reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type == marker {
+			continue
+		}
+		if f.PkgPath != "" {
+			return nil, fmt.Errorf("field %s of %s struct %v must be exported", f.Name, kind, t)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// fieldKey returns the key of the dependency of a parameter struct field.
+func fieldKey(f reflect.StructField) Key {
+	if g := f.Tag.Get("group"); g != "" {
+		return groupKey{baseType(f.Type.Elem()), g}
+	}
+	return outKey(baseType(f.Type), f.Tag.Get("name"))
+}
+
+// resultKeys returns the keys of the values of a result produced by a
+// constructor added with the name or to the group.
+func (c *Container) resultKeys(t reflect.Type, name, group string) ([]Key, error) {
+	if !isOut(t) {
+		if group != "" {
+			return []Key{c.member(baseType(t), group)}, nil
+		}
+		return []Key{outKey(baseType(t), name)}, nil
+	}
+	fields, err := outFields(t)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("result struct %v must have fields", t)
+	}
+	keys := make([]Key, 0, len(fields))
+	for _, f := range fields {
+		n, g := f.Tag.Get("name"), f.Tag.Get("group")
+		if n == "" && g == "" {
+			n, g = name, group
+		}
+		if g != "" {
+			keys = append(keys, c.member(baseType(f.Type), g))
+		} else {
+			keys = append(keys, outKey(baseType(f.Type), n))
+		}
+	}
+	return keys, nil
+}
+
+// results returns the values of a result produced by a constructor.
+func results(v reflect.Value) []reflect.Value {
+	if !isOut(v.Type()) {
+		return []reflect.Value{v}
+	}
+	fields, _ := outFields(v.Type())
+	vals := make([]reflect.Value, 0, len(fields))
+	for _, f := range fields {
+		vals = append(vals, v.FieldByIndex(f.Index))
+	}
+	return vals
+}
+
+// paramKeys returns the keys of the dependencies of a parameter.
+func paramKeys(t reflect.Type) ([]Key, error) {
+	if !isIn(t) {
+		return []Key{baseType(t)}, nil
+	}
+	fields, err := inFields(t)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]Key, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, fieldKey(f))
+	}
+	return keys, nil
+}
+
+// isOptional checks if the parameter struct field is an optional dependency.
+func isOptional(f reflect.StructField) bool {
+	return f.Tag.Get("optional") == "true"
+}
+
+// buildIn builds a parameter struct resolving each field with resolve.
+func buildIn(t reflect.Type, resolve func(reflect.Type, Key, bool) (reflect.Value, error)) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	fields, err := inFields(t)
+	if err != nil {
+		return v, err
+	}
+	for _, f := range fields {
+		fv, err := resolve(f.Type, fieldKey(f), isOptional(f))
+		if err != nil {
+			return v, err
+		}
+		v.FieldByIndex(f.Index).Set(fv)
+	}
+	return v, nil
+}
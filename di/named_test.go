@@ -0,0 +1,201 @@
+package di
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type pool struct {
+	name string
+}
+
+type poolParams struct {
+	In
+	Primary *pool `name:"primary"`
+	Replica *pool `name:"replica"`
+	S1      *testS1
+}
+
+func TestNamed(t *testing.T) {
+	Convey("Create a container with named bindings", t, func() {
+		c := New(nil)
+		So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.AddNamed("primary", func(*testS1) *pool { return &pool{"primary"} }), ShouldBeNil)
+		So(c.AddNamed("replica", func() *pool { return &pool{"replica"} }), ShouldBeNil)
+
+		Convey("bad bindings should be rejected", func() {
+			So(c.AddNamed("primary", func() *pool { return &pool{} }), ShouldBeError)
+			So(c.AddNamed("", func() *pool { return &pool{} }), ShouldBeError)
+			So(c.Add(func(struct {
+				In
+				p *pool
+			}) *testS2 {
+				return nil
+			}), ShouldBeError)
+		})
+
+		Convey("the named values should be resolved by parameter structs", func() {
+			So(c.Add(func(p poolParams) *testS2 {
+				So(p.Primary.name, ShouldEqual, "primary")
+				So(p.Replica.name, ShouldEqual, "replica")
+				So(p.S1, ShouldNotBeNil)
+				return &testS2{}
+			}), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p poolParams, s2 *testS2) {
+				So(p.Primary.name, ShouldEqual, "primary")
+			}, nil), ShouldBeNil)
+
+			// Named values are not resolved by type alone
+			So(c.Invoke(func(*pool) {}, nil), ShouldBeError)
+		})
+
+		Convey("the named values should be resolved from the parent", func() {
+			So(c.Create(nil), ShouldBeNil)
+			cc := New(c)
+			So(cc.Add(func(p poolParams) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(cc.Create(nil), ShouldBeNil)
+			So(cc.Invoke(func(*testS2) {}, nil), ShouldBeNil)
+		})
+
+		Convey("missing named values should fail", func() {
+			So(c.Create(nil), ShouldBeNil)
+			err := c.Invoke(func(struct {
+				In
+				P *pool `name:"backup"`
+			}) {
+			}, nil)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldContainSubstring, `di.pool named "backup"`)
+		})
+
+		Convey("the named bindings should be exported", func() {
+			So(c.Add(func(p poolParams) *testS2 { return &testS2{} }), ShouldBeNil)
+			buf := &bytes.Buffer{}
+			So(c.WriteDOT(buf, DOTOptions{Root: reflect.TypeOf(&testS2{})}), ShouldBeNil)
+			So(buf.String(), ShouldEqual, `digraph dependencies {
+	"github.com/anuvu/cube/di.pool[primary]" [label="di.pool \"primary\""];
+	"github.com/anuvu/cube/di.pool[replica]" [label="di.pool \"replica\""];
+	"github.com/anuvu/cube/di.testS1" [label="di.testS1"];
+	"github.com/anuvu/cube/di.testS2" [label="di.testS2"];
+	"github.com/anuvu/cube/di.pool[primary]" -> "github.com/anuvu/cube/di.testS1";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.pool[primary]";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.pool[replica]";
+	"github.com/anuvu/cube/di.testS2" -> "github.com/anuvu/cube/di.testS1";
+}
+`)
+		})
+	})
+}
+
+type optionalParams struct {
+	In
+	S1 *testS1 `optional:"true"`
+	S3 *testS3 `optional:"true"`
+	P  *pool   `name:"backup" optional:"true"`
+}
+
+func TestOptional(t *testing.T) {
+	Convey("Create a container with optional dependencies", t, func() {
+		c := New(nil)
+		So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		var params optionalParams
+		So(c.Add(func(p optionalParams) *testS2 {
+			params = p
+			return &testS2{}
+		}), ShouldBeNil)
+
+		Convey("missing optional dependencies should get the zero value", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(params.S1, ShouldNotBeNil)
+			So(params.S3, ShouldBeNil)
+			So(params.P, ShouldBeNil)
+		})
+
+		Convey("optional dependencies provided later should be resolved", func() {
+			So(c.Add(func() *testS3 { return &testS3{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(params.S3, ShouldNotBeNil)
+		})
+
+		Convey("vetoed optional dependencies should still fail", func() {
+			c.Intercept(Restrict(reflect.TypeOf(&testS3{}), "github.com/anuvu/cube/http"))
+			So(c.Create(nil), ShouldBeError)
+		})
+	})
+}
+
+type poolResult struct {
+	Out
+	Primary *pool `name:"primary"`
+	Replica *pool `name:"replica"`
+	S1      *testS1
+}
+
+func TestResult(t *testing.T) {
+	Convey("Create a container with a result struct", t, func() {
+		c := New(nil)
+		calls := 0
+		So(c.Add(func() poolResult {
+			calls++
+			return poolResult{Primary: &pool{"primary"}, Replica: &pool{"replica"}, S1: &testS1{}}
+		}), ShouldBeNil)
+
+		Convey("bad result structs should be rejected", func() {
+			So(c.Add(func() struct{ Out } { return struct{ Out }{} }), ShouldBeError)
+			So(c.Add(func() struct {
+				Out
+				s *testS2
+			} {
+				return struct {
+					Out
+					s *testS2
+				}{}
+			}), ShouldBeError)
+			So(c.AddNamed("primary", func() *pool { return &pool{} }), ShouldBeError)
+			So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeError)
+		})
+
+		Convey("each field should be provided once", func() {
+			processed := 0
+			So(c.Add(func(p poolParams) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(c.Create(func(reflect.Value) error {
+				processed++
+				return nil
+			}), ShouldBeNil)
+			So(calls, ShouldEqual, 1)
+			So(processed, ShouldEqual, 4)
+			So(c.Invoke(func(p poolParams) {
+				So(p.Primary.name, ShouldEqual, "primary")
+				So(p.Replica.name, ShouldEqual, "replica")
+				So(p.S1, ShouldNotBeNil)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("the name of the constructor should apply to untagged fields", func() {
+			So(c.AddNamed("backup", func() struct {
+				Out
+				P *pool
+				R *pool `name:"restore"`
+			} {
+				return struct {
+					Out
+					P *pool
+					R *pool `name:"restore"`
+				}{P: &pool{"backup"}, R: &pool{"restore"}}
+			}), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p struct {
+				In
+				B *pool `name:"backup"`
+				R *pool `name:"restore"`
+			}) {
+				So(p.B.name, ShouldEqual, "backup")
+				So(p.R.name, ShouldEqual, "restore")
+			}, nil), ShouldBeNil)
+		})
+	})
+}
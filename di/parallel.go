@@ -0,0 +1,146 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// construction is the invocation of a constructor by Create.
+type construction struct {
+	ctr      interface{}
+	keys     []Key
+	bound    bool
+	vals     []reflect.Value
+	cleanups []reflect.Value
+	err      error
+	start    time.Time
+	duration time.Duration
+}
+
+// levels returns the vertices of the dependency graph grouped by their depth,
+// the vertices of a level only depend on the vertices of the previous levels.
+// The vertices of a level are in the order of the topological sort.
+func (c *Container) levels() [][]Vertex {
+	depth := map[Key]int{}
+	levels := [][]Vertex{}
+	for _, n := range c.dag.Sort() {
+		l := 0
+		for _, d := range c.dag.Dependencies(n.Key) {
+			if depth[d]+1 > l {
+				l = depth[d] + 1
+			}
+		}
+		depth[n.Key] = l
+		for len(levels) <= l {
+			levels = append(levels, []Vertex{})
+		}
+		levels[l] = append(levels[l], n)
+	}
+	return levels
+}
+
+// SetParallel enables or disables the parallel construction of the
+// container, disabled by default. When enabled, Create invokes the
+// constructors that do not depend on each other, directly or transitively,
+// concurrently. The constructors must then be safe to run concurrently, e.g.
+// they must not register flags on a shared flag set.
+func (c *Container) SetParallel(enabled bool) {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	c.parallel = enabled
+}
+
+// constructAll invokes the independent constructors concurrently and waits
+// for all of them to return.
+func (c *Container) constructAll(ctrs []*construction) {
+	if len(ctrs) == 1 {
+		ctrs[0].run(c)
+		return
+	}
+	wg := sync.WaitGroup{}
+	for _, x := range ctrs {
+		wg.Add(1)
+		go func(x *construction) {
+			defer wg.Done()
+			x.run(c)
+		}(x)
+	}
+	wg.Wait()
+}
+
+// run invokes the constructor and collects the values it produced. A panic of
+// the constructor is returned as its error.
+func (x *construction) run(c *Container) {
+	x.start = time.Now()
+	defer func() {
+		x.duration = time.Since(x.start)
+		if r := recover(); r != nil {
+			x.err = fmt.Errorf("constructor %s panicked: %v", funcName(x.ctr), r)
+		}
+	}()
+	x.err = c.invokeCtr(x.ctr, x.keys, func(v reflect.Value) error {
+		if baseType(v.Type()).Implements(_errType) {
+			// Errors are not values of the container
+			return nil
+		}
+		if v.Type() == cleanupType {
+			x.cleanups = append(x.cleanups, v)
+			return nil
+		}
+		x.vals = append(x.vals, results(v)...)
+		return nil
+	}, nil)
+}
+
+// cache caches the values produced by the constructor, after calling the
+// value processor, and applies their decorators.
+func (c *Container) cache(x *construction, vp ValueProcessor) error {
+	for _, v := range x.cleanups {
+		c.addCleanup(v)
+	}
+	if x.err != nil {
+		c.emit(ConstructionFailed, x.keys[0], x.ctr, x.duration, x.err)
+		return x.err
+	}
+	for i, k := range x.keys {
+		if _, ok := k.(memberKey); ok {
+			// Many values can be contributed to a group
+			continue
+		}
+		if _, err := c.get(k); err == nil {
+			if nk, ok := k.(namedKey); ok {
+				err = fmt.Errorf("type %v named %q is already present", x.vals[i].Type(), nk.name)
+			} else {
+				err = fmt.Errorf("type %v is already present", x.vals[i].Type())
+			}
+			c.emit(ValueRejected, k, x.ctr, x.duration, err)
+			return err
+		}
+	}
+	if vp != nil && !x.bound {
+		// Call the value processor passed by the caller of Add, the values
+		// of bindings were already processed as their concrete type
+		for i, v := range x.vals {
+			if err := vp(v); err != nil {
+				c.emit(ValueRejected, x.keys[i], x.ctr, x.duration, err)
+				return err
+			}
+		}
+	}
+	c.lock.Lock()
+	for i, v := range x.vals {
+		c.objTable[x.keys[i]] = v
+	}
+	c.lock.Unlock()
+	for _, k := range x.keys {
+		c.emit(ValueConstructed, k, x.ctr, x.duration, nil)
+	}
+	for _, k := range x.keys {
+		if err := c.decorate(k, vp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParallelCreate(t *testing.T) {
+	Convey("Create a container with independent slow constructors", t, func() {
+		c := New(nil)
+		slow := func(v interface{}) interface{} {
+			val := reflect.ValueOf(v)
+			ft := reflect.FuncOf(nil, []reflect.Type{val.Type()}, false)
+			return reflect.MakeFunc(ft, func([]reflect.Value) []reflect.Value {
+				time.Sleep(100 * time.Millisecond)
+				return []reflect.Value{val}
+			}).Interface()
+		}
+		So(c.Add(slow(&testS1{})), ShouldBeNil)
+		So(c.Add(slow(&testS2{})), ShouldBeNil)
+		So(c.Add(func(*testS1, *testS2) *testS3 { return &testS3{} }), ShouldBeNil)
+
+		Convey("the constructors should be invoked one at a time by default", func() {
+			start := time.Now()
+			So(c.Create(nil), ShouldBeNil)
+			So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 200*time.Millisecond)
+		})
+
+		Convey("the independent constructors should be invoked concurrently if enabled", func() {
+			c.SetParallel(true)
+			types := []string{}
+			start := time.Now()
+			So(c.Create(func(v reflect.Value) error {
+				types = append(types, v.Type().String())
+				return nil
+			}), ShouldBeNil)
+			So(time.Since(start), ShouldBeLessThan, 190*time.Millisecond)
+			So(types, ShouldHaveLength, 3)
+			So(types[2], ShouldEqual, "*di.testS3")
+		})
+
+		Convey("the first error of a level should be returned", func() {
+			c.SetParallel(true)
+			So(c.Add(func() (*pool, error) { return nil, fmt.Errorf("pool error") }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError, "pool error")
+		})
+
+		Convey("the panic of a constructor should be returned as an error", func() {
+			c.SetParallel(true)
+			So(c.Add(func() *pool { panic("no pool") }), ShouldBeNil)
+			err := c.Create(nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "panicked: no pool")
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package di
+
+import (
+	"reflect"
+)
+
+// Dependents returns the registrations of the values of the container that
+// depend on the type, or on the type bound to the name if a name is given,
+// directly or transitively, e.g. the values to restart after the value of
+// the type is replaced. The registrations are sorted in the dependency
+// order, a value comes after its dependencies. The dependents in the child
+// containers are not listed.
+func (c *Container) Dependents(t reflect.Type, name ...string) []Registration {
+	k := queryKey(t, name)
+	defer c.rlockChain()()
+	return c.closure([]Key{k}, k, false)
+}
+
+// Impact returns the registrations of the values of the container that
+// could no longer be constructed if the constructor of the type, or of the
+// type bound to the name if a name is given, was removed, see Remove. They
+// are the other values of the constructor and the values depending on them,
+// directly or transitively, through dependencies that are not optional.
+// Value groups don't require their members, their consumers are not listed.
+// The registrations are sorted in the dependency order.
+func (c *Container) Impact(t reflect.Type, name ...string) []Registration {
+	k := queryKey(t, name)
+	defer c.rlockChain()()
+	removed := c.outs[k]
+	if len(removed) == 0 {
+		removed = []Key{k}
+	}
+	return c.closure(removed, k, true)
+}
+
+// queryKey returns the key of the type bound to the name, if any.
+func queryKey(t reflect.Type, name []string) Key {
+	if len(name) > 0 && name[0] != "" {
+		return namedKey{baseType(t), name[0]}
+	}
+	return baseType(t)
+}
+
+// closure returns the registrations of the keys and of their transitive
+// dependents, but the excluded key, in the dependency order. Only the
+// dependents requiring the keys are followed if required is true.
+func (c *Container) closure(keys []Key, exclude Key, required bool) []Registration {
+	found := map[Key]bool{}
+	queue := []Key{}
+	visit := func(k Key) {
+		if !found[k] {
+			found[k] = true
+			queue = append(queue, k)
+		}
+	}
+	for _, k := range keys {
+		visit(k)
+	}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		for _, d := range c.dag.Dependents(k) {
+			if _, group := d.(groupKey); group && required {
+				continue
+			}
+			if required && !c.requires(d, k) {
+				continue
+			}
+			visit(d)
+			// The other values of the constructor are constructed with it
+			for _, o := range c.outs[d] {
+				visit(o)
+			}
+		}
+	}
+
+	regs := []Registration{}
+	for _, v := range c.dag.Sort() {
+		if !found[v.Key] || v.Key == exclude {
+			continue
+		}
+		if r, ok := c.registration(v.Key); ok {
+			regs = append(regs, r)
+		}
+	}
+	return regs
+}
+
+// requires checks if the constructor of the key requires the dependency,
+// i.e. the dependency is not optional.
+func (c *Container) requires(k, dependency Key) bool {
+	ctr := c.dag.GetValue(k)
+	if ctr == nil {
+		return false
+	}
+	for _, r := range requiredKeys(reflect.TypeOf(ctr)) {
+		if r == dependency {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type optionalS3 struct {
+	In
+	S3 *testS3 `optional:"true"`
+}
+
+func registrationIDs(regs []Registration) []string {
+	ids := []string{}
+	for _, r := range regs {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	Convey("Create a container with a dependency graph", t, func() {
+		c := New(nil)
+		So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(c.Add(func(*testS1) (*testS2, *testS3) { return &testS2{}, &testS3{} }), ShouldBeNil)
+		So(c.Add(func(optionalS3) *pool { return &pool{} }), ShouldBeNil)
+		So(c.Add(func(*pool) int { return 0 }), ShouldBeNil)
+		So(c.AddToGroup("pools", func(*testS2) *pool { return &pool{"a"} }), ShouldBeNil)
+		So(c.Add(func(poolGroup) string { return "" }), ShouldBeNil)
+
+		s1 := "github.com/anuvu/cube/di.testS1"
+		s2 := "github.com/anuvu/cube/di.testS2"
+		s3 := "github.com/anuvu/cube/di.testS3"
+		p := "github.com/anuvu/cube/di.pool"
+		member := "github.com/anuvu/cube/di.pool{pools}#0"
+
+		Convey("the dependents should be transitive and sorted", func() {
+			ids := registrationIDs(c.Dependents(reflect.TypeOf(&testS1{})))
+			So(ids, ShouldHaveLength, 6)
+			for _, id := range []string{s2, s3, p, "int", member, "string"} {
+				So(ids, ShouldContain, id)
+			}
+			So(ids[0], ShouldBeIn, s2, s3)
+			So(ids[len(ids)-1], ShouldBeIn, "int", "string")
+			So(c.Dependents(reflect.TypeOf(0)), ShouldBeEmpty)
+			So(c.Dependents(reflect.TypeOf(&testS1{}), "unknown"), ShouldBeEmpty)
+		})
+
+		Convey("the impact should only follow the required dependencies", func() {
+			ids := registrationIDs(c.Impact(reflect.TypeOf(&testS2{})))
+			So(ids, ShouldHaveLength, 2)
+			So(ids, ShouldContain, s3)
+			So(ids, ShouldContain, member)
+			So(ids, ShouldNotContain, s1)
+			So(registrationIDs(c.Impact(reflect.TypeOf(&pool{}))), ShouldResemble, []string{"int"})
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Remove removes the constructor producing the type from the container,
+// along with the values it produced, e.g. to reconfigure a container or to
+// build a test fixture incrementally. All the values of the constructor are
+// removed, not only the one of the type.
+//
+// It returns an error if no constructor of the container produces the type,
+// or if other constructors or decorators still depend on its values. Named
+// and grouped values can't be removed.
+//
+// A container does not know its child containers, only the constructors of
+// the container are checked. A value still required by a child container is
+// removed, and the child fails to resolve it. Validate the child containers
+// after a removal to report their constructors missing the value.
+func (c *Container) Remove(t reflect.Type) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	k := Key(baseType(t))
+	ctr := c.dag.GetValue(k)
+	if ctr == nil {
+		return fmt.Errorf("no constructor for type %v to remove", k)
+	}
+	keys := c.outs[k]
+	removed := map[Key]bool{}
+	for _, o := range keys {
+		removed[o] = true
+	}
+
+	// The values can only be removed if nothing else depends on them
+	deps := map[Key]bool{}
+	for _, v := range c.dag.Sort() {
+		for _, d := range c.dependencies(v.Key) {
+			if !removed[d] {
+				if removed[v.Key] {
+					deps[d] = true
+				}
+				continue
+			}
+			if !removed[v.Key] {
+				return fmt.Errorf("type %v is still required by %v", d, v.Key)
+			}
+		}
+	}
+	for _, o := range keys {
+		if _, ok := c.decorators[o]; ok {
+			return fmt.Errorf("type %v is still decorated", o)
+		}
+	}
+
+	for _, o := range keys {
+		c.dag.RemoveVertex(o)
+		c.lock.Lock()
+		delete(c.objTable, o)
+		c.lock.Unlock()
+		delete(c.outs, o)
+		delete(c.lazy, o)
+		delete(c.scopes, o)
+		delete(c.binds, o)
+		delete(c.tags, o)
+	}
+
+	// Remove the dependencies that were only referenced by the constructor
+	for d := range deps {
+		if c.dag.GetValue(d) == nil && !c.required(d) {
+			c.dag.RemoveVertex(d)
+		}
+	}
+	return nil
+}
+
+// required checks if any vertex of the graph depends on the key.
+func (c *Container) required(k Key) bool {
+	for _, v := range c.dag.Sort() {
+		for _, d := range c.dag.Dependencies(v.Key) {
+			if d == k {
+				return true
+			}
+		}
+	}
+	return false
+}
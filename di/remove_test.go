@@ -0,0 +1,55 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRemove(t *testing.T) {
+	Convey("Create a container with dependent constructors", t, func() {
+		c := New(nil)
+		So(c.Add(func(*testS1) (*testS2, *testS3) { return &testS2{}, &testS3{} }), ShouldBeNil)
+		So(c.Add(func(*pool) *testS1 { return &testS1{} }), ShouldBeNil)
+
+		Convey("required types should not be removed", func() {
+			So(c.Remove(reflect.TypeOf(&testS1{})), ShouldBeError)
+			So(c.Remove(reflect.TypeOf(&pool{})), ShouldBeError)
+			So(c.Decorate(func(s *testS2) *testS2 { return s }), ShouldBeNil)
+			So(c.Remove(reflect.TypeOf(&testS3{})), ShouldBeError)
+		})
+
+		Convey("removed constructors should leave the graph", func() {
+			So(c.Remove(reflect.TypeOf(&testS3{})), ShouldBeNil)
+			So(c.Describe(), ShouldHaveLength, 2)
+			So(c.Remove(reflect.TypeOf(&testS1{})), ShouldBeNil)
+			So(c.Describe(), ShouldBeEmpty)
+			So(c.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+		})
+
+		Convey("values required by child containers should be removed", func() {
+			child := New(c)
+			So(child.Add(func(*testS3) int { return 0 }), ShouldBeNil)
+			So(child.Validate(), ShouldBeNil)
+			So(c.Remove(reflect.TypeOf(&testS3{})), ShouldBeNil)
+
+			// The child should report its constructor missing the value
+			err := child.Validate()
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+			errs := err.(*ValidationError).Errs
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].(*UnresolvedError).Err.Key, ShouldEqual, Key(reflect.TypeOf(testS3{})))
+		})
+
+		Convey("removed values should not be resolved", func() {
+			So(c.Add(func() *pool { return &pool{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Remove(reflect.TypeOf(&testS2{})), ShouldBeNil)
+			So(c.Invoke(func(*testS3) {}, nil), ShouldBeError)
+			So(c.Invoke(func(*testS1) {}, nil), ShouldBeNil)
+			So(c.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,88 @@
+package di
+
+import "fmt"
+
+// Replace swaps the constructors of the values produced by the constructor
+// for the constructor, e.g. to substitute a fake in a test of an otherwise
+// production dependency graph. The constructor must produce all the values
+// of the constructors it replaces, and must be a plain constructor: named
+// and grouped values can't be replaced. The replacement is a singleton even
+// if the replaced constructors were lazy, transient, scoped or bindings.
+//
+// It returns an error if a value is not provided by a constructor of the
+// container, if it is already constructed or if the dependencies of the
+// constructor are cyclic. The container is unchanged on error.
+func (c *Container) Replace(ctr interface{}) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	deps, outs, err := c.signature(ctr, "", "")
+	if err != nil {
+		return err
+	}
+	produced := map[Key]bool{}
+	for _, k := range outs {
+		produced[k] = true
+	}
+
+	// The dependencies of the replaced constructors by produced key
+	old := map[Key][]Key{}
+	for _, k := range outs {
+		if _, ok := k.(memberKey); ok {
+			return fmt.Errorf("type %v of a group can't be replaced", keyType(k))
+		}
+		prev := c.dag.GetValue(k)
+		if prev == nil {
+			return fmt.Errorf("no constructor for type %v to replace", k)
+		}
+		c.lock.RLock()
+		_, constructed := c.objTable[k]
+		c.lock.RUnlock()
+		if constructed {
+			return fmt.Errorf("type %v is already constructed", k)
+		}
+		for _, o := range c.outs[k] {
+			if !produced[o] {
+				return fmt.Errorf("constructor for type %v must also produce %v", k, o)
+			}
+		}
+		old[k] = c.dag.Dependencies(k)
+	}
+
+	// Move the edges of the replaced constructors to the constructor, the
+	// dependencies missing from the graph are added as forward references
+	added := []Key{}
+	for _, d := range deps {
+		if c.dag.AddVertex(d, nil) == nil {
+			added = append(added, d)
+		}
+	}
+	for _, k := range outs {
+		c.dag.RemoveDependencies(k, old[k]...)
+	}
+	for _, k := range outs {
+		if err := c.dag.AddDependencies(k, deps...); err != nil {
+			c.restore(old, added)
+			return err
+		}
+	}
+	for _, k := range outs {
+		c.dag.SetValue(k, ctr)
+		c.outs[k] = outs
+		delete(c.lazy, k)
+		delete(c.scopes, k)
+		delete(c.binds, k)
+	}
+	return nil
+}
+
+// restore restores the edges of the replaced constructors and removes the
+// vertices added for the constructor after a failed replacement.
+func (c *Container) restore(old map[Key][]Key, added []Key) {
+	for k, prevDeps := range old {
+		c.dag.RemoveDependencies(k, c.dag.Dependencies(k)...)
+		c.dag.AddDependencies(k, prevDeps...)
+	}
+	for _, d := range added {
+		c.dag.RemoveVertex(d)
+	}
+}
@@ -0,0 +1,71 @@
+package di
+
+import (
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReplace(t *testing.T) {
+	Convey("Create a container with a production graph", t, func() {
+		c := New(nil)
+		So(c.Add(func() *pool { return &pool{"prod"} }), ShouldBeNil)
+		So(c.Add(func(*pool) (*testS1, *testS3) { return &testS1{}, &testS3{} }), ShouldBeNil)
+		var got *pool
+		So(c.Add(func(p *pool, _ *testS1) *testS2 {
+			got = p
+			return &testS2{}
+		}), ShouldBeNil)
+
+		Convey("bad replacements should be rejected", func() {
+			So(c.Replace(nil), ShouldBeError)
+			So(c.Replace(func() *cachedStore { return nil }), ShouldBeError)
+			So(c.Replace(func() *testS1 { return nil }), ShouldBeError)
+			So(c.Replace(func(*testS2) *pool { return nil }), ShouldBeError)
+			So(c.Create(nil), ShouldBeNil)
+			So(got.name, ShouldEqual, "prod")
+			So(c.Replace(func() *pool { return nil }), ShouldBeError)
+		})
+
+		Convey("a failed replacement should leave the graph unchanged", func() {
+			before := c.Describe()
+			So(c.Replace(func(*testStore, *testS2) (*testS1, *testS3) { return nil, nil }), ShouldBeError)
+			So(c.Describe(), ShouldResemble, before)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS1, *testS3) {}, nil), ShouldBeNil)
+			So(got.name, ShouldEqual, "prod")
+		})
+
+		Convey("the replacement should be used by the dependents", func() {
+			So(c.Replace(func() *pool { return &pool{"fake"} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(got.name, ShouldEqual, "fake")
+		})
+
+		Convey("the dependencies of the replacement should be ordered", func() {
+			So(c.Replace(func() (*testS1, *testS3) { return &testS1{}, &testS3{} }), ShouldBeNil)
+			So(c.Add(func() *testStore { return nil }), ShouldBeNil)
+			So(c.Replace(func(*testS2) *pool { return &pool{"cyclic"} }), ShouldBeError)
+			So(c.Replace(func(*testStore) *pool { return &pool{"late"} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(got.name, ShouldEqual, "late")
+		})
+
+		Convey("bindings should be replaced by plain constructors", func() {
+			So(c.Bind(testStoreType, func() *testMemStore { return &testMemStore{} }), ShouldBeNil)
+			So(c.Replace(func() testStore { return &cachedStore{&testMemStore{}, "fake-"} }), ShouldBeNil)
+			processed := 0
+			So(c.Create(func(v reflect.Value) error {
+				if v.Type() == testStoreType {
+					processed++
+				}
+				return nil
+			}), ShouldBeNil)
+			So(processed, ShouldEqual, 1)
+			So(c.Invoke(func(s testStore) {
+				So(s.Get(), ShouldEqual, "fake-mem")
+			}, nil), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,171 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// lifetime is the lifetime of the values of a constructor.
+type lifetime int
+
+const (
+	// transient values are constructed each time they are resolved
+	transient lifetime = iota
+	// scoped values are constructed once per scope
+	scoped
+)
+
+func (l lifetime) String() string {
+	if l == transient {
+		return "transient"
+	}
+	return "scoped"
+}
+
+// scopedCtr is a constructor whose values are not cached in the container.
+type scopedCtr struct {
+	lifetime lifetime
+	ctr      interface{}
+	keys     []Key
+	c        *Container
+}
+
+// Scope caches the values of scoped constructors, e.g. for the duration of a
+// request. A scope must not be used by many goroutines at the same time.
+type Scope struct {
+	c        *Container
+	values   map[Key]reflect.Value
+	cleanups []Cleanup
+	args     []reflect.Value
+}
+
+// AddTransient adds the constructor to the container like Add, but its values
+// are not cached, they are constructed each time they are resolved. Transient
+// values are only resolved by Invoke, singletons constructed by Create can't
+// depend on them.
+func (c *Container) AddTransient(ctr interface{}) error {
+	return c.addScoped(ctr, transient)
+}
+
+// AddScoped adds the constructor to the container like Add, but its values
+// are cached per scope rather than in the container. Each Invoke resolves
+// the values in a new scope, so they are constructed once per Invoke and
+// shared by all the dependencies of the invoked function. Scope.Invoke
+// shares the values across the invocations of the scope, e.g. to construct
+// a transaction once per request. Scoped values are only resolved by
+// Invoke, singletons constructed by Create can't depend on them.
+func (c *Container) AddScoped(ctr interface{}) error {
+	return c.addScoped(ctr, scoped)
+}
+
+func (c *Container) addScoped(ctr interface{}, l lifetime) error {
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	keys, err := c.add(ctr, "", "")
+	if err != nil {
+		return err
+	}
+	if c.scopes == nil {
+		c.scopes = map[Key]*scopedCtr{}
+	}
+	sc := &scopedCtr{l, ctr, keys, c}
+	for _, k := range keys {
+		c.scopes[k] = sc
+	}
+	return nil
+}
+
+// NewScope creates a new scope to invoke functions with the container.
+func (c *Container) NewScope() *Scope {
+	return &Scope{c: c}
+}
+
+// Invoke a function evaluating its dependencies using the container of the
+// scope. Scoped dependencies are resolved from the scope. It otherwise
+// behaves like Container.Invoke.
+func (s *Scope) Invoke(fx interface{}, vp ValueProcessor) error {
+	return s.c.invoke(fx, vp, s)
+}
+
+// Close calls the cleanups returned by the transient and scoped constructors
+// invoked in the scope, in the reverse order of their construction.
+func (s *Scope) Close() {
+	runCleanups(s.cleanups)
+	s.cleanups = nil
+}
+
+// arg returns the extra argument of the invocation assignable to the type,
+// preferring an argument of the same type, see InvokeWith.
+func (s *Scope) arg(t reflect.Type) (reflect.Value, bool) {
+	if s == nil {
+		return reflect.Value{}, false
+	}
+	for _, v := range s.args {
+		if v.Type() == t {
+			return v, true
+		}
+	}
+	for _, v := range s.args {
+		if v.Type().AssignableTo(t) {
+			return v, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// scoped returns the scoped constructor of the key in the container
+// hierarchy, nil if the key is not scoped.
+func (c *Container) scoped(k Key) *scopedCtr {
+	if t, ok := k.(reflect.Type); ok {
+		k = baseType(t)
+	}
+	if c.checkParent(k) {
+		if sc := c.parent.scoped(k); sc != nil {
+			return sc
+		}
+	}
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	return c.scopes[k]
+}
+
+// resolve returns the value of the key, constructing it in the scope unless
+// it is already cached by the scope.
+func (sc *scopedCtr) resolve(k Key, s *Scope) (reflect.Value, error) {
+	if t, ok := k.(reflect.Type); ok {
+		k = baseType(t)
+	}
+	if s == nil {
+		return reflect.Value{}, fmt.Errorf("dependency for type %v is %v and can only be resolved by invoke", k, sc.lifetime)
+	}
+	if v, ok := s.values[k]; ok {
+		return v, nil
+	}
+	vals := []reflect.Value{}
+	err := sc.c.invokeCtr(sc.ctr, sc.keys, func(v reflect.Value) error {
+		if v.Type() == cleanupType {
+			if f := v.Interface().(Cleanup); f != nil {
+				s.cleanups = append(s.cleanups, f)
+			}
+		} else if !baseType(v.Type()).Implements(_errType) {
+			vals = append(vals, results(v)...)
+		}
+		return nil
+	}, s)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	var v reflect.Value
+	for i, key := range sc.keys {
+		if key == k {
+			v = vals[i]
+		}
+		if sc.lifetime == scoped {
+			if s.values == nil {
+				s.values = map[Key]reflect.Value{}
+			}
+			s.values[key] = vals[i]
+		}
+	}
+	return v, nil
+}
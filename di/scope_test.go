@@ -0,0 +1,103 @@
+package di
+
+import (
+	"context"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestScopes(t *testing.T) {
+	Convey("Create a container with transient and scoped constructors", t, func() {
+		c := New(nil)
+		s1, s2 := 0, 0
+		So(c.Add(func() *pool { return &pool{} }), ShouldBeNil)
+		So(c.AddScoped(func(*pool) *testS1 {
+			s1++
+			return &testS1{}
+		}), ShouldBeNil)
+		So(c.AddTransient(func(*testS1) *testS2 {
+			s2++
+			return &testS2{}
+		}), ShouldBeNil)
+
+		Convey("bad constructors should be rejected", func() {
+			So(c.AddScoped(nil), ShouldBeError)
+			So(c.AddTransient(func() *testS1 { return nil }), ShouldBeError)
+		})
+
+		Convey("create should not construct them", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(s1+s2, ShouldEqual, 0)
+		})
+
+		Convey("singletons should not depend on them", func() {
+			So(c.Add(func(*testS2) *testS3 { return &testS3{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeError)
+		})
+
+		Convey("each invoke should have its own scope", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p struct {
+				In
+				S1 *testS1
+				S2 *testS2
+			}, x *testS2) {
+			}, nil), ShouldBeNil)
+			So(s1, ShouldEqual, 1)
+			So(s2, ShouldEqual, 2)
+			So(c.Invoke(func(*testS1) {}, nil), ShouldBeNil)
+			So(s1, ShouldEqual, 2)
+		})
+
+		Convey("a scope should share the scoped values across invokes", func() {
+			So(c.Create(nil), ShouldBeNil)
+			cc := New(c)
+			So(cc.Create(nil), ShouldBeNil)
+			s := cc.NewScope()
+			So(s.Invoke(func(*testS1) {}, nil), ShouldBeNil)
+			So(s.Invoke(func(*testS1, *testS2) {}, nil), ShouldBeNil)
+			So(s1, ShouldEqual, 1)
+			So(s2, ShouldEqual, 1)
+		})
+	})
+}
+
+type request struct {
+	path string
+}
+
+func TestInvokeWith(t *testing.T) {
+	Convey("Create a container with a constructor scoped to requests", t, func() {
+		c := New(nil)
+		shared := &pool{"shared"}
+		So(c.AddValue(shared), ShouldBeNil)
+		So(c.AddScoped(func(r *request, p *pool) *testS1 {
+			So(r.path, ShouldEqual, "/orders")
+			return &testS1{}
+		}), ShouldBeNil)
+
+		Convey("the arguments should resolve the dependencies of the invocation", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req := &request{"/orders"}
+			So(c.InvokeWith(func(r *request, ctx context.Context, p *pool, s *testS1) {
+				So(r, ShouldEqual, req)
+				So(ctx.Done(), ShouldNotBeNil)
+				So(p, ShouldEqual, shared)
+			}, nil, req, ctx), ShouldBeNil)
+		})
+
+		Convey("the arguments should take precedence over the container", func() {
+			local := &pool{"local"}
+			So(c.InvokeWith(func(p *pool) { So(p, ShouldEqual, local) }, nil, local), ShouldBeNil)
+			So(c.Invoke(func(p *pool) { So(p, ShouldEqual, shared) }, nil), ShouldBeNil)
+		})
+
+		Convey("the arguments should not be visible to other invocations", func() {
+			So(c.Invoke(func(*request) {}, nil), ShouldBeError)
+			So(c.InvokeWith(func(*testS1) {}, nil), ShouldBeError)
+			So(c.InvokeWith(func(*request) {}, nil, nil), ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package di
+
+import "reflect"
+
+// Snapshot is an immutable view of a created container and its ancestors. A
+// snapshot has no Add or Create, its object tables are only written by the
+// lazy constructions, so it can be used by Invoke on hot paths from many
+// goroutines.
+// The resolutions only take the read locks of the snapshot, they never wait
+// for the changes made to the container.
+//
+// Changes made to the container after the snapshot is taken are not visible
+// in the snapshot. Take a new snapshot and swap it in to publish them. The
+// lazy values that are not constructed when the snapshot is taken are
+// constructed by the snapshot on first use, independently of the container.
+type Snapshot struct {
+	c *Container
+}
+
+// Snapshot takes an immutable snapshot of the container and its ancestors.
+// It should be taken after Create completes.
+func (c *Container) Snapshot() *Snapshot {
+	return &Snapshot{c.freeze()}
+}
+
+// freeze copies the object tables, the lazy and scoped constructors, the
+// decorators, the hooks and the interceptors of the container hierarchy into
+// a new hierarchy whose dependency graphs only hold the lazy constructors.
+func (c *Container) freeze() *Container {
+	var parent *Container
+	if c.parent != nil {
+		parent = c.parent.freeze()
+	}
+	c.lock.RLock()
+	objTable := make(map[Key]reflect.Value, len(c.objTable))
+	for t, v := range c.objTable {
+		objTable[t] = v
+	}
+	c.lock.RUnlock()
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	members := make(map[groupKey]int, len(c.members))
+	for k, n := range c.members {
+		members[k] = n
+	}
+	frozen := &Container{
+		parent:       parent,
+		objTable:     objTable,
+		dag:          NewDAG(),
+		members:      members,
+		scopes:       make(map[Key]*scopedCtr, len(c.scopes)),
+		dupes:        append([]reflect.Type(nil), c.dupes...),
+		interceptors: append([]Interceptor(nil), c.interceptors...),
+		hooks:        append([]Hooks(nil), c.hooks...),
+	}
+	// The scoped constructors resolve their dependencies from the snapshot,
+	// the keys of a constructor share its copy
+	copies := map[*scopedCtr]*scopedCtr{}
+	for k, sc := range c.scopes {
+		cp, ok := copies[sc]
+		if !ok {
+			cp = &scopedCtr{lifetime: sc.lifetime, ctr: sc.ctr, keys: sc.keys, c: frozen}
+			copies[sc] = cp
+		}
+		frozen.scopes[k] = cp
+	}
+	// The lazy values already constructed are in the object table, the keys
+	// of a constructor share its lazy value
+	lazy := map[*lazyValue]*lazyValue{}
+	for k, l := range c.lazy {
+		if _, ok := objTable[k]; ok {
+			continue
+		}
+		if frozen.lazy == nil {
+			frozen.lazy = map[Key]*lazyValue{}
+			frozen.outs = map[Key][]Key{}
+		}
+		if lazy[l] == nil {
+			lazy[l] = &lazyValue{}
+		}
+		frozen.lazy[k] = lazy[l]
+		frozen.outs[k] = c.outs[k]
+		frozen.dag.AddVertex(k, c.dag.GetValue(k))
+	}
+	if c.decorators != nil {
+		frozen.decorators = make(map[Key][]interface{}, len(c.decorators))
+		for k, fns := range c.decorators {
+			frozen.decorators[k] = append([]interface{}(nil), fns...)
+		}
+	}
+	return frozen
+}
+
+// Invoke a function evaluating its dependencies using the snapshot. It
+// behaves like Container.Invoke.
+func (s *Snapshot) Invoke(fx interface{}, vp ValueProcessor) error {
+	return s.c.Invoke(fx, vp)
+}
@@ -0,0 +1,102 @@
+package di
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSnapshot(t *testing.T) {
+	Convey("Create a container hierarchy and take a snapshot", t, func() {
+		p := New(nil)
+		s1 := &testS1{}
+		So(p.Add(func() *testS1 { return s1 }), ShouldBeNil)
+		So(p.Create(nil), ShouldBeNil)
+		c := New(p)
+		So(c.Add(func(*testS1) *testS2 { return &testS2{} }), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+		snap := c.Snapshot()
+
+		Convey("the snapshot should resolve the values of the hierarchy", func() {
+			So(snap.Invoke(func(s *testS1, _ *testS2) {
+				So(s, ShouldEqual, s1)
+			}, nil), ShouldBeNil)
+			So(snap.Invoke(func(*testS3) {}, nil), ShouldBeError)
+		})
+
+		Convey("the snapshot should be safe for concurrent invokes", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					snap.Invoke(func(*testS1, *testS2) {}, nil)
+				}()
+			}
+			wg.Wait()
+		})
+
+		Convey("scoped values should be resolved while the container changes", func() {
+			So(c.AddScoped(func(*testS2) *pool { return &pool{} }), ShouldBeNil)
+			snap := c.Snapshot()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 50; i++ {
+					c.AddScoped(func() *testS3 { return &testS3{} })
+					c.Remove(reflect.TypeOf(testS3{}))
+				}
+			}()
+			for i := 0; i < 50; i++ {
+				So(snap.Invoke(func(*pool) {}, nil), ShouldBeNil)
+			}
+			<-done
+			So(snap.Invoke(func(*testS3) {}, nil), ShouldBeError)
+
+			// The scoped constructors resolve their dependencies from the
+			// snapshot
+			So(c.Remove(reflect.TypeOf(pool{})), ShouldBeNil)
+			So(c.Remove(reflect.TypeOf(testS2{})), ShouldBeNil)
+			So(snap.Invoke(func(*pool) {}, nil), ShouldBeNil)
+		})
+
+		Convey("lazy and decorated values should be resolved by the snapshot", func() {
+			p := New(nil)
+			So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+			decorated := &testS1{}
+			So(p.Decorate(func(*testS1) *testS1 { return decorated }), ShouldBeNil)
+			So(p.Create(nil), ShouldBeNil)
+			c := New(p)
+			calls, s3Calls := 0, 0
+			So(c.AddLazy(func(*testS1) *testS2 { calls++; return &testS2{} }), ShouldBeNil)
+			So(c.AddLazy(func() *testS3 { s3Calls++; return &testS3{} }), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS3) {}, nil), ShouldBeNil)
+			snap := c.Snapshot()
+			So(snap.c.parent.decorators, ShouldHaveLength, 1)
+
+			for i := 0; i < 2; i++ {
+				So(snap.Invoke(func(s1 *testS1, _ *testS2) {
+					So(s1, ShouldEqual, decorated)
+				}, nil), ShouldBeNil)
+			}
+			So(calls, ShouldEqual, 1)
+			// The lazy value constructed by the snapshot is not visible in
+			// the container, the value constructed before is shared
+			So(c.objTable, ShouldNotContainKey, reflect.TypeOf(testS2{}))
+			So(c.Invoke(func(*testS2) {}, nil), ShouldBeNil)
+			So(calls, ShouldEqual, 2)
+			So(snap.Invoke(func(*testS3) {}, nil), ShouldBeNil)
+			So(s3Calls, ShouldEqual, 1)
+		})
+
+		Convey("later changes should not be visible in the snapshot", func() {
+			c.objTable[reflect.TypeOf(testS3{})] = reflect.ValueOf(&testS3{})
+			So(c.Invoke(func(*testS3) {}, nil), ShouldBeNil)
+			So(snap.Invoke(func(*testS3) {}, nil), ShouldBeError)
+			So(c.Snapshot().Invoke(func(*testS3) {}, nil), ShouldBeNil)
+		})
+	})
+}
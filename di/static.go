@@ -0,0 +1,66 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Caller calls a function with its arguments and returns its results without
+// reflection. The arguments are in the order of the parameters of the
+// function and a nil argument is the zero value of its parameter.
+//
+// Callers are generated by the cube-gen tool, see cmd/cube-gen, which emits a
+// static caller for each constructor added to the container in a package.
+type Caller func(args []interface{}) []interface{}
+
+var callers = struct {
+	sync.RWMutex
+	m map[uintptr]Caller
+}{m: map[uintptr]Caller{}}
+
+// RegisterCaller registers the static caller of the function. The containers
+// invoke the function through its caller instead of reflect.Value.Call, which
+// is faster and keeps the function in the stack traces. Functions without a
+// caller are still invoked with reflection.
+//
+// The function must be declared at the package level, closures and method
+// values can't be told apart by their code and must not be registered.
+// RegisterCaller panics if fx is not a function.
+func RegisterCaller(fx interface{}, call Caller) {
+	v := reflect.ValueOf(fx)
+	if v.Kind() != reflect.Func || call == nil {
+		panic(fmt.Sprintf("di: invalid static caller for %T", fx))
+	}
+	callers.Lock()
+	defer callers.Unlock()
+	callers.m[v.Pointer()] = call
+}
+
+// call calls the function with its static caller if one is registered, else
+// with reflection.
+func call(fx interface{}, args []reflect.Value) []reflect.Value {
+	v := reflect.ValueOf(fx)
+	callers.RLock()
+	c := callers.m[v.Pointer()]
+	callers.RUnlock()
+	if c == nil {
+		return v.Call(args)
+	}
+
+	in := make([]interface{}, len(args))
+	for i, a := range args {
+		in[i] = a.Interface()
+	}
+	out := c(in)
+	t := v.Type()
+	returned := make([]reflect.Value, t.NumOut())
+	for i := range returned {
+		// Nil interfaces, e.g. errors, are lost in the conversion
+		returned[i] = reflect.New(t.Out(i)).Elem()
+		if out[i] != nil {
+			returned[i].Set(reflect.ValueOf(out[i]))
+		}
+	}
+	return returned
+}
@@ -0,0 +1,81 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type staticS struct {
+	s1 *testS1
+	s  fmt.Stringer
+}
+
+var staticCalls int32
+
+func newStaticS1() *testS1 { return &testS1{} }
+
+func newStaticS(s1 *testS1, s fmt.Stringer) (*staticS, error) {
+	return &staticS{s1, s}, nil
+}
+
+func newStaticFail() (*testS2, error) {
+	return nil, errors.New("static failure")
+}
+
+func init() {
+	RegisterCaller(newStaticS, func(args []interface{}) []interface{} {
+		atomic.AddInt32(&staticCalls, 1)
+		a0, _ := args[0].(*testS1)
+		a1, _ := args[1].(fmt.Stringer)
+		r0, r1 := newStaticS(a0, a1)
+		return []interface{}{r0, r1}
+	})
+	RegisterCaller(newStaticFail, func(args []interface{}) []interface{} {
+		atomic.AddInt32(&staticCalls, 1)
+		r0, r1 := newStaticFail()
+		return []interface{}{r0, r1}
+	})
+}
+
+func TestStaticCaller(t *testing.T) {
+	Convey("After we add constructors with static callers", t, func() {
+		atomic.StoreInt32(&staticCalls, 0)
+		c := New(nil)
+		So(c.Add(newStaticS1), ShouldBeNil)
+		So(c.Add(func() fmt.Stringer { return nil }), ShouldBeNil)
+
+		Convey("the constructors should be invoked through their callers", func() {
+			So(c.Add(newStaticS), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(atomic.LoadInt32(&staticCalls), ShouldEqual, 1)
+			So(c.Invoke(func(s *staticS, s1 *testS1) {
+				So(s.s1, ShouldEqual, s1)
+				So(s.s, ShouldBeNil)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("the functions without callers should be invoked with reflection", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(s1 *testS1) (*staticS, error) {
+				return newStaticS(s1, nil)
+			}, nil), ShouldBeNil)
+			So(atomic.LoadInt32(&staticCalls), ShouldEqual, 0)
+		})
+
+		Convey("the errors returned through the callers should be reported", func() {
+			So(c.Add(newStaticFail), ShouldBeNil)
+			err := c.Create(nil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "static failure")
+			So(atomic.LoadInt32(&staticCalls), ShouldEqual, 1)
+		})
+	})
+
+	Convey("Registering a caller for a value that is not a function should panic", t, func() {
+		So(func() { RegisterCaller(1, func([]interface{}) []interface{} { return nil }) }, ShouldPanic)
+	})
+}
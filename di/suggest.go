@@ -0,0 +1,91 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxRegistered is the maximum number of registered types listed by a
+// NotFoundError.
+const maxRegistered = 10
+
+// NotFoundError is returned when a dependency is not provided by the
+// container or its ancestors. Dependencies of constructors and invoked
+// functions list the close matches and the registered types, to help finding
+// a missing or misnamed binding.
+type NotFoundError struct {
+	// Key of the dependency
+	Key Key
+	// Suggestions are the registered types close to the dependency, e.g. the
+	// same type bound under a name or a type implementing the interface
+	Suggestions []string
+	// Registered are the types registered in the container and its ancestors
+	Registered []string
+}
+
+func (e *NotFoundError) Error() string {
+	msg := fmt.Sprintf("dependency for type %v not found", e.Key)
+	if len(e.Suggestions) > 0 {
+		msg += ", did you mean " + strings.Join(e.Suggestions, " or ") + "?"
+	}
+	if len(e.Registered) > 0 {
+		reg := e.Registered
+		more := ""
+		if len(reg) > maxRegistered {
+			more = fmt.Sprintf(" and %d more", len(reg)-maxRegistered)
+			reg = reg[:maxRegistered]
+		}
+		msg += " registered types: " + strings.Join(reg, ", ") + more
+	}
+	return msg
+}
+
+// notFound returns the error of the missing dependency with the close matches
+// among the types registered in the container and its ancestors.
+func (c *Container) notFound(k Key) *NotFoundError {
+	e := &NotFoundError{Key: k, Suggestions: []string{}, Registered: []string{}}
+	t := keyType(k)
+	name, _ := keyNames(k)
+	seen := map[Key]bool{}
+	defer c.rlockChain()()
+	for p := c; p != nil; p = p.parent {
+		keys, _ := p.graphKeys(nil)
+		for _, r := range keys {
+			if p.dag.GetValue(r) == nil || seen[r] {
+				continue
+			}
+			seen[r] = true
+			e.Registered = append(e.Registered, fmt.Sprint(r))
+			if s := suggestion(t, name, r); s != "" {
+				e.Suggestions = append(e.Suggestions, s)
+			}
+		}
+	}
+	return e
+}
+
+// suggestion describes the registered key r if it is close to the type t
+// bound under the name, or returns an empty string.
+func suggestion(t reflect.Type, name string, r Key) string {
+	rt := keyType(r)
+	rname, rgroup := keyNames(r)
+	switch {
+	case rt == t && rgroup != "":
+		return fmt.Sprintf("%v contributed to group %q", rt, rgroup)
+	case rt == t && rname != name:
+		if rname == "" {
+			return fmt.Sprintf("%v without a name", rt)
+		}
+		return fmt.Sprintf("%v named %q", rt, rname)
+	case rt != t && rt.Name() != "" && rt.Name() == t.Name():
+		// Same type name in another package
+		return fmt.Sprint(r)
+	case t.Kind() == reflect.Interface && rt != t:
+		// Pointers are registered by their element type
+		if rt.Implements(t) || reflect.PtrTo(rt).Implements(t) {
+			return fmt.Sprintf("%v which implements %v, see Bind", r, t)
+		}
+	}
+	return ""
+}
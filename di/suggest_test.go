@@ -0,0 +1,57 @@
+package di
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type suggester interface {
+	Suggest()
+}
+
+type suggestImpl struct{}
+
+func (s *suggestImpl) Suggest() {}
+
+func TestSuggestions(t *testing.T) {
+	Convey("Create a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.AddNamed("primary", func() *pool { return &pool{"primary"} }), ShouldBeNil)
+		c := New(p)
+		So(c.Add(func() *suggestImpl { return &suggestImpl{} }), ShouldBeNil)
+		So(p.Create(nil), ShouldBeNil)
+		So(c.Create(nil), ShouldBeNil)
+
+		Convey("missing dependencies should suggest the named bindings", func() {
+			err := c.Invoke(func(*pool) {}, nil)
+			So(err, ShouldHaveSameTypeAs, &NotFoundError{})
+			So(err.(*NotFoundError).Suggestions, ShouldResemble, []string{`di.pool named "primary"`})
+			So(err.Error(), ShouldStartWith, `dependency for type di.pool not found, did you mean di.pool named "primary"?`)
+			So(err.Error(), ShouldContainSubstring, "registered types: ")
+		})
+
+		Convey("missing interfaces should suggest the implementations", func() {
+			err := c.Invoke(func(suggester) {}, nil)
+			So(err.(*NotFoundError).Suggestions, ShouldResemble, []string{"di.suggestImpl which implements di.suggester, see Bind"})
+		})
+
+		Convey("the registered types should be listed", func() {
+			err := c.Invoke(func(*testS1) {}, nil)
+			nf := err.(*NotFoundError)
+			So(nf.Suggestions, ShouldBeEmpty)
+			So(nf.Registered, ShouldContain, "di.suggestImpl")
+			So(nf.Registered, ShouldContain, `di.pool named "primary"`)
+			nf.Registered = make([]string, 12)
+			So(nf.Error(), ShouldEndWith, " and 2 more")
+		})
+
+		Convey("missing optional dependencies should not fail", func() {
+			So(c.Invoke(func(struct {
+				In
+				S1 *testS1 `optional:"true"`
+			}) {
+			}, nil), ShouldBeNil)
+		})
+	})
+}
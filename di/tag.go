@@ -0,0 +1,125 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Annotation is a metadata tag of a constructor, see Tag.
+type Annotation struct {
+	Key   string
+	Value string
+}
+
+// Tag returns the metadata tag annotating a constructor with the value of
+// the key, e.g. the subsystem it belongs to:
+//
+//	c.Add(NewStore, di.Tag("subsystem", "storage"))
+func Tag(key, value string) Annotation {
+	return Annotation{key, value}
+}
+
+func checkTags(tags []Annotation) error {
+	for _, t := range tags {
+		if t.Key == "" {
+			return errors.New("can't tag a constructor with an empty key")
+		}
+	}
+	return nil
+}
+
+// tag annotates the values of a constructor with the tags, the last value
+// of a key wins.
+func (c *Container) tag(keys []Key, tags []Annotation) {
+	if len(tags) == 0 {
+		return
+	}
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	if c.tags == nil {
+		c.tags = map[Key]map[string]string{}
+	}
+	for _, k := range keys {
+		c.tags[k] = m
+	}
+}
+
+// taggedKeys returns the keys of the values of the container tagged with
+// the key and the value, any value if empty, in the dependency order.
+func (c *Container) taggedKeys(key, value string) []Key {
+	keys := []Key{}
+	for _, v := range c.dag.Sort() {
+		tags, ok := c.tags[v.Key]
+		if !ok || v.Value == nil {
+			continue
+		}
+		if tv, ok := tags[key]; ok && (value == "" || tv == value) {
+			keys = append(keys, v.Key)
+		}
+	}
+	return keys
+}
+
+// Tagged returns the registrations of the values of the container, not of
+// its ancestors, whose constructor is tagged with the key and the value, or
+// with any value of the key if the value is empty. The registrations are
+// sorted in the dependency order, a value comes after its dependencies.
+func (c *Container) Tagged(key, value string) []Registration {
+	defer c.rlockChain()()
+	regs := []Registration{}
+	for _, k := range c.taggedKeys(key, value) {
+		if r, ok := c.registration(k); ok {
+			regs = append(regs, r)
+		}
+	}
+	return regs
+}
+
+// InvokeTagged invokes the function once for each value of the container
+// tagged with the key and the value, see Tagged, in the dependency order, e.g.
+//
+//	c.InvokeTagged("subsystem", "storage", func(s Starter) error {
+//		return s.Start()
+//	})
+//
+// The first parameter of the function receives the value, the values that
+// are not assignable to it are skipped. The other parameters are resolved
+// like InvokeWith with the value as argument. Lazy values are constructed
+// for the invocation, transient and scoped values are skipped. InvokeTagged
+// stops at the first error returned by the function.
+func (c *Container) InvokeTagged(key, value string, fx interface{}) error {
+	f := reflect.TypeOf(fx)
+	if err := checkFunc(fx, f); err != nil {
+		return err
+	}
+	if f.NumIn() == 0 {
+		return fmt.Errorf("can't invoke %v over tagged values without parameters", f)
+	}
+	in := f.In(0)
+
+	c.graphLock.RLock()
+	keys := []Key{}
+	for _, k := range c.taggedKeys(key, value) {
+		if _, ok := c.scopes[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	c.graphLock.RUnlock()
+
+	for _, k := range keys {
+		v, err := c.get(k)
+		if err != nil {
+			return err
+		}
+		if !v.Type().AssignableTo(in) {
+			continue
+		}
+		if err := c.InvokeWith(fx, nil, v.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type taggedStore struct {
+	name string
+}
+
+func (s *taggedStore) Get() string { return s.name }
+
+func TestTag(t *testing.T) {
+	Convey("Create a container with tagged constructors", t, func() {
+		c := New(nil)
+		So(c.Add(func() *testMemStore { return &testMemStore{} }, Tag("subsystem", "storage")), ShouldBeNil)
+		So(c.Add(func(*testMemStore) *taggedStore { return &taggedStore{"disk"} },
+			Tag("subsystem", "storage"), Tag("tier", "cold")), ShouldBeNil)
+		So(c.Add(func() *testS1 { return &testS1{} }, Tag("subsystem", "api")), ShouldBeNil)
+		So(c.Add(func() *testS2 { return &testS2{} }), ShouldBeNil)
+
+		Convey("tags should be validated", func() {
+			So(c.Add(func() *testS3 { return &testS3{} }, Tag("", "x")), ShouldNotBeNil)
+			_, ok := c.Registration(reflect.TypeOf(&testS3{}))
+			So(ok, ShouldBeFalse)
+		})
+
+		Convey("registrations should carry their tags", func() {
+			r, _ := c.Registration(reflect.TypeOf(&taggedStore{}))
+			So(r.Tags, ShouldResemble, map[string]string{"subsystem": "storage", "tier": "cold"})
+			r, _ = c.Registration(reflect.TypeOf(&testS2{}))
+			So(r.Tags, ShouldBeNil)
+		})
+
+		Convey("tagged registrations should be queried in dependency order", func() {
+			regs := c.Tagged("subsystem", "storage")
+			So(len(regs), ShouldEqual, 2)
+			So(regs[0].Type, ShouldEqual, reflect.TypeOf(testMemStore{}))
+			So(regs[1].Type, ShouldEqual, reflect.TypeOf(taggedStore{}))
+			So(len(c.Tagged("subsystem", "")), ShouldEqual, 3)
+			So(len(c.Tagged("tier", "hot")), ShouldEqual, 0)
+			So(len(c.Tagged("missing", "")), ShouldEqual, 0)
+		})
+
+		Convey("functions should be invoked over the tagged values", func() {
+			So(c.Create(nil), ShouldBeNil)
+			names := []string{}
+			err := c.InvokeTagged("subsystem", "", func(s testStore, s2 *testS2) {
+				So(s2, ShouldNotBeNil)
+				names = append(names, s.Get())
+			})
+			So(err, ShouldBeNil)
+			So(names, ShouldResemble, []string{"mem", "disk"})
+
+			err = c.InvokeTagged("subsystem", "storage", func(s testStore) error {
+				return errors.New(s.Get())
+			})
+			So(err.Error(), ShouldEqual, "mem")
+			So(c.InvokeTagged("subsystem", "storage", func() {}), ShouldNotBeNil)
+			So(c.InvokeTagged("subsystem", "storage", 1), ShouldNotBeNil)
+		})
+
+		Convey("clones should keep the tags and removed values should lose them", func() {
+			cc := c.Clone()
+			So(len(cc.Tagged("subsystem", "api")), ShouldEqual, 1)
+			So(c.Remove(reflect.TypeOf(&testS1{})), ShouldBeNil)
+			So(c.tags, ShouldNotContainKey, reflect.TypeOf(testS1{}))
+			So(len(c.Tagged("subsystem", "api")), ShouldEqual, 0)
+			So(len(cc.Tagged("subsystem", "api")), ShouldEqual, 1)
+		})
+	})
+}
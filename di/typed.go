@@ -0,0 +1,51 @@
+//go:build go1.18
+// +build go1.18
+
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// typeOf returns the type T, including interface types.
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Provide adds the constructor of T to the container. Unlike Add it checks
+// that the first value produced by the constructor is of type T, so that the
+// type provided is explicit at the call site, e.g.
+//
+//	di.Provide[*sql.DB](c, newDB)
+func Provide[T any](c *Container, ctr interface{}) error {
+	t := typeOf[T]()
+	ft := reflect.TypeOf(ctr)
+	if ft == nil || ft.Kind() != reflect.Func || ft.NumOut() == 0 || ft.Out(0) != t {
+		return fmt.Errorf("constructor %v must produce %v", ft, t)
+	}
+	return c.Add(ctr)
+}
+
+// Supply adds a constructor providing the value to the container.
+func Supply[T any](c *Container, v T) error {
+	return c.Add(func() T { return v })
+}
+
+// Resolve returns the value of type T from the container or its ancestors,
+// constructing the lazy, transient and scoped values as Invoke does.
+func Resolve[T any](c *Container) (T, error) {
+	var v T
+	err := c.Invoke(func(r T) { v = r }, nil)
+	return v, err
+}
+
+// MustResolve returns the value of type T like Resolve, it panics if the
+// value can't be resolved.
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -0,0 +1,31 @@
+//go:build go1.18
+// +build go1.18
+
+package di
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTyped(t *testing.T) {
+	Convey("Create a container with typed constructors", t, func() {
+		c := New(nil)
+		So(Provide[*testS1](c, func() *testS1 { return &testS1{} }), ShouldBeNil)
+		So(Supply[suggester](c, &suggestImpl{}), ShouldBeNil)
+		So(Provide[*testS2](c, func() *testS3 { return &testS3{} }), ShouldBeError)
+		So(Provide[*testS2](c, nil), ShouldBeError)
+		So(c.Create(nil), ShouldBeNil)
+
+		Convey("the values should be resolved by their type", func() {
+			s1, err := Resolve[*testS1](c)
+			So(err, ShouldBeNil)
+			So(s1, ShouldNotBeNil)
+			So(MustResolve[suggester](c), ShouldHaveSameTypeAs, &suggestImpl{})
+			_, err = Resolve[*testS3](c)
+			So(err, ShouldBeError)
+			So(func() { MustResolve[*testS3](c) }, ShouldPanic)
+		})
+	})
+}
@@ -0,0 +1,160 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists the problems found by Validate, e.g. the missing
+// dependencies of the constructors.
+type ValidationError struct {
+	Errs []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid dependency graph: %s", strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the first error.
+func (e *ValidationError) Unwrap() error {
+	return e.Errs[0]
+}
+
+// UnresolvedError is the error of a constructor whose dependency is not
+// provided by the container or its ancestors.
+type UnresolvedError struct {
+	// Provider is the fully qualified name of the constructor
+	Provider string
+	// Err describes the missing dependency
+	Err *NotFoundError
+}
+
+func (e *UnresolvedError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the error of the missing dependency.
+func (e *UnresolvedError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks that the dependencies of the constructors and of the
+// decorators registered with the container are provided by the container or
+// its ancestors, without invoking any constructor, e.g. to catch a missing
+// wiring in a test before opening connections. Optional dependencies may be
+// missing, and the default constructors count as providers of their values
+// when they are not overridden, see AddDefault. The lazy getters that are
+// not provided require the values they resolve. It also checks that the
+// alternative groups have a selection and that the decorated types are
+// singletons, like Create.
+//
+// The constructors of the ancestors are not validated, validate each
+// container of a hierarchy. It returns a *ValidationError listing all the
+// problems, the missing dependencies as *UnresolvedError.
+func (c *Container) Validate() error {
+	type missing struct {
+		provider string
+		key      Key
+	}
+	errs := []error{}
+	missed := []missing{}
+
+	unlock := c.rlockChain()
+	if err := c.checkAlternatives(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.checkDecorators(); err != nil {
+		errs = append(errs, err)
+	}
+	defaults := map[Key]bool{}
+	for p := c; p != nil; p = p.parent {
+		for _, d := range p.defaults {
+			for _, k := range d.keys {
+				defaults[k] = true
+			}
+		}
+	}
+	provided := func(k Key) bool {
+		_, group := k.(groupKey)
+		return group || defaults[k] || c.provider(k) != nil
+	}
+	check := func(fn interface{}) {
+		for _, k := range requiredKeys(reflect.TypeOf(fn)) {
+			if t, ok := k.(reflect.Type); ok && getterTarget(t) != nil && !provided(k) {
+				// A synthesized getter requires its target when it is called
+				k = baseType(getterTarget(t))
+			}
+			if !provided(k) {
+				missed = append(missed, missing{funcName(fn), k})
+			}
+		}
+	}
+
+	claimed := map[Key]bool{}
+	for _, v := range c.dag.Sort() {
+		if v.Value == nil || claimed[v.Key] {
+			continue
+		}
+		for _, k := range c.outs[v.Key] {
+			claimed[k] = true
+		}
+		check(v.Value)
+	}
+	for _, fns := range c.decorators {
+		for _, fn := range fns {
+			check(fn)
+		}
+	}
+	for _, d := range c.defaults {
+		overridden := false
+		for _, k := range d.keys {
+			if c.provider(k) != nil {
+				overridden = true
+			}
+		}
+		if !overridden {
+			check(d.ctr)
+		}
+	}
+	unlock()
+
+	sort.Slice(missed, func(i, j int) bool {
+		if missed[i].provider != missed[j].provider {
+			return missed[i].provider < missed[j].provider
+		}
+		return keyID(missed[i].key) < keyID(missed[j].key)
+	})
+	for _, m := range missed {
+		errs = append(errs, &UnresolvedError{m.provider, c.notFound(m.key)})
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errs: errs}
+}
+
+// requiredKeys returns the keys of the dependencies of the function that
+// are not optional.
+func requiredKeys(f reflect.Type) []Key {
+	keys := []Key{}
+	for i := 0; i < numArgs(f); i++ {
+		t := f.In(i)
+		if !isIn(t) {
+			keys = append(keys, baseType(t))
+			continue
+		}
+		fields, _ := inFields(t)
+		for _, fd := range fields {
+			if !isOptional(fd) {
+				keys = append(keys, fieldKey(fd))
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,57 @@
+package di
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidate(t *testing.T) {
+	Convey("Create a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.Add(func() *testS1 { return &testS1{} }), ShouldBeNil)
+		c := New(p)
+		called := false
+
+		Convey("a complete graph should be valid without constructing anything", func() {
+			So(c.Add(func(*testS1) *testS2 { called = true; return &testS2{} }), ShouldBeNil)
+			So(c.Add(func(optionalParams, *testS2) *taggedStore { called = true; return nil }), ShouldBeNil)
+			So(c.Add(func(*testS1) *testMemStore { called = true; return nil }), ShouldBeNil)
+			So(c.Validate(), ShouldBeNil)
+			So(called, ShouldBeFalse)
+		})
+
+		Convey("missing dependencies should be listed", func() {
+			So(c.Add(func(*testS3) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(c.Add(func(*testS3, testStore) (*pool, *testMemStore) { return nil, nil }), ShouldBeNil)
+			So(c.Decorate(func(s *testS2, _ *taggedStore) *testS2 { return s }), ShouldBeNil)
+			err := c.Validate()
+			So(err, ShouldHaveSameTypeAs, &ValidationError{})
+			errs := err.(*ValidationError).Errs
+			So(len(errs), ShouldEqual, 4)
+			for _, e := range errs {
+				So(e, ShouldHaveSameTypeAs, &UnresolvedError{})
+			}
+			So(err.Error(), ShouldContainSubstring, "di.testS3 not found")
+			So(err.Error(), ShouldContainSubstring, "di.testStore not found, did you mean di.testMemStore which implements")
+			So(err.Error(), ShouldContainSubstring, "di.taggedStore not found")
+			So(called, ShouldBeFalse)
+		})
+
+		Convey("defaults should provide their values unless overridden", func() {
+			So(c.Add(func(*testS3) *testS2 { return &testS2{} }), ShouldBeNil)
+			So(p.AddDefault(func() *testS3 { return &testS3{} }), ShouldBeNil)
+			So(c.AddDefault(func(*taggedStore) *testS1 { return &testS1{} }), ShouldBeNil)
+			So(c.Validate(), ShouldBeNil)
+			So(c.AddDefault(func(*taggedStore) *pool { return &pool{} }), ShouldBeNil)
+			So(c.Validate(), ShouldNotBeNil)
+		})
+
+		Convey("alternatives without selection should be reported", func() {
+			So(c.AddAlternative("store", "mem", func() testStore { return &testMemStore{} }), ShouldBeNil)
+			So(c.Validate(), ShouldNotBeNil)
+			So(c.Select("store", "mem"), ShouldBeNil)
+			So(c.Validate(), ShouldBeNil)
+		})
+	})
+}
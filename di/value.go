@@ -0,0 +1,51 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AddValue adds an already constructed value to the container, without
+// wrapping it in a constructor. The value is registered in the dependency
+// graph as a constructor without dependencies would be, and is available to
+// the invoked functions and the constructors right away, before Create. It
+// returns an error if the value is nil or an error, or if the container or
+// one of its ancestors already provides a value of its type.
+//
+// The value was constructed by the caller, it is not passed to the value
+// processor of Create nor decorated.
+func (c *Container) AddValue(v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("can't add nil value")
+	}
+	rv := reflect.ValueOf(v)
+	t := rv.Type()
+	if t.Implements(_errType) || baseType(t).Implements(_errType) {
+		return fmt.Errorf("can't add error value %v", v)
+	}
+	unlock := c.rlockChain()
+	p := c.provider(baseType(t))
+	unlock()
+	if p != nil {
+		return fmt.Errorf("type %v is already present", t)
+	}
+
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	ctr := reflect.MakeFunc(
+		reflect.FuncOf(nil, []reflect.Type{t}, false),
+		func([]reflect.Value) []reflect.Value {
+			return []reflect.Value{rv}
+		},
+	)
+	keys, err := c.add(ctr.Interface(), "", "")
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	for _, k := range keys {
+		c.objTable[k] = rv
+	}
+	c.lock.Unlock()
+	return nil
+}
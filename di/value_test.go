@@ -0,0 +1,42 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAddValue(t *testing.T) {
+	Convey("Create a container chained to a parent", t, func() {
+		p := New(nil)
+		So(p.Add(func() int { return 1 }), ShouldBeNil)
+		c := New(p)
+		s1 := &testS1{}
+
+		Convey("a value should be available before create", func() {
+			So(c.AddValue(s1), ShouldBeNil)
+			So(c.Invoke(func(s *testS1) { So(s, ShouldEqual, s1) }, nil), ShouldBeNil)
+			r, ok := c.Registration(reflect.TypeOf(s1))
+			So(ok, ShouldBeTrue)
+			So(r.Constructed, ShouldBeTrue)
+		})
+
+		Convey("constructors should depend on the value", func() {
+			So(c.Add(func(s *testS1) *testS2 { So(s, ShouldEqual, s1); return &testS2{} }), ShouldBeNil)
+			So(c.AddValue(s1), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(*testS2) {}, nil), ShouldBeNil)
+		})
+
+		Convey("the values should be checked", func() {
+			So(c.AddValue(nil), ShouldNotBeNil)
+			So(c.AddValue(errors.New("value")), ShouldNotBeNil)
+			So(c.AddValue(2), ShouldNotBeNil)
+			So(c.AddValue(s1), ShouldBeNil)
+			So(c.AddValue(&testS1{}), ShouldNotBeNil)
+			So(c.Add(func() *testS1 { return s1 }), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// groupKey identifies the values contributed to a value group. A parameter
+// struct field tagged with `group:"..."` depends on the group key and is
+// resolved with a slice of all the contributions, e.g.
+//
+//	type params struct {
+//		di.In
+//		Handlers []http.Handler `group:"handlers"`
+//	}
+type groupKey struct {
+	t     reflect.Type
+	group string
+}
+
+func (k groupKey) String() string {
+	return fmt.Sprintf("%v in group %q", k.t, k.group)
+}
+
+// memberKey identifies a single contribution to a value group.
+type memberKey struct {
+	groupKey
+	index int
+}
+
+func (k memberKey) String() string {
+	return fmt.Sprintf("%v in group %q #%d", k.t, k.group, k.index)
+}
+
+// AddToGroup adds the constructor to the container and contributes the values
+// it produces to the value group. Many constructors can contribute values of
+// the same type to a group, a parameter struct field tagged with the group
+// receives all of them, see In. The fields of a result struct can also be
+// contributed to a group with a `group:"..."` tag, see Out.
+func (c *Container) AddToGroup(group string, ctr interface{}) error {
+	if group == "" {
+		return fmt.Errorf("group of the constructor must not be empty")
+	}
+	c.graphLock.Lock()
+	defer c.graphLock.Unlock()
+	_, err := c.add(ctr, "", group)
+	return err
+}
+
+// member returns the key of a new contribution of the type to the group.
+func (c *Container) member(t reflect.Type, group string) Key {
+	if c.members == nil {
+		c.members = map[groupKey]int{}
+	}
+	k := groupKey{t, group}
+	i := c.members[k]
+	c.members[k] = i + 1
+	return memberKey{k, i}
+}
+
+// groupValues returns the contributions to the group made in the container
+// and its ancestors, ancestors first and in the order of their addition.
+func (c *Container) groupValues(k groupKey) []reflect.Value {
+	vals := []reflect.Value{}
+	if c.parent != nil {
+		vals = c.parent.groupValues(k)
+	}
+	c.graphLock.RLock()
+	defer c.graphLock.RUnlock()
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for i := 0; i < c.members[k]; i++ {
+		if v, ok := c.objTable[memberKey{k, i}]; ok {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// groupSlice returns a slice of type t holding the contributions to the group.
+func (c *Container) groupSlice(t reflect.Type, k groupKey) reflect.Value {
+	vals := c.groupValues(k)
+	s := reflect.MakeSlice(t, 0, len(vals))
+	return reflect.Append(s, vals...)
+}
@@ -0,0 +1,108 @@
+package di
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type poolGroup struct {
+	In
+	Pools []*pool `group:"pools"`
+}
+
+func TestValueGroups(t *testing.T) {
+	Convey("Create a container with a value group", t, func() {
+		c := New(nil)
+		var pools []*pool
+		So(c.Add(func(p poolGroup) *testS2 {
+			pools = p.Pools
+			return &testS2{}
+		}), ShouldBeNil)
+		So(c.AddToGroup("pools", func() *pool { return &pool{"a"} }), ShouldBeNil)
+		So(c.AddToGroup("pools", func(*testS1) (*pool, error) { return &pool{"b"}, nil }), ShouldBeNil)
+		So(c.Add(func() (*testS1, error) { return &testS1{}, nil }), ShouldBeNil)
+
+		Convey("bad groups should be rejected", func() {
+			So(c.AddToGroup("", func() *pool { return &pool{} }), ShouldBeError)
+			So(c.Add(func(struct {
+				In
+				P *pool `group:"pools"`
+			}) *testS3 {
+				return nil
+			}), ShouldBeError)
+			So(c.Add(func() struct {
+				Out
+				P *pool `name:"a" group:"pools"`
+			} {
+				return struct {
+					Out
+					P *pool `name:"a" group:"pools"`
+				}{}
+			}), ShouldBeError)
+			So(c.AddToGroup("pools", func(*testS2) *pool { return nil }), ShouldBeError)
+		})
+
+		Convey("the consumer should receive all the contributions", func() {
+			So(c.Add(func() struct {
+				Out
+				P *pool `group:"pools"`
+				S *testS3
+			} {
+				return struct {
+					Out
+					P *pool `group:"pools"`
+					S *testS3
+				}{P: &pool{"c"}, S: &testS3{}}
+			}), ShouldBeNil)
+			So(c.Create(nil), ShouldBeNil)
+			names := []string{}
+			for _, p := range pools {
+				names = append(names, p.name)
+			}
+			So(names, ShouldResemble, []string{"a", "b", "c"})
+		})
+
+		Convey("the interceptors should apply to the type of the members", func() {
+			c.Intercept(Restrict(reflect.TypeOf(&pool{}), "github.com/anuvu/cube/http"))
+			So(c.Create(nil), ShouldBeError)
+			So(c.Invoke(func(poolGroup) {}, nil), ShouldBeError)
+		})
+
+		Convey("the contributions of the parent should come first", func() {
+			So(c.Create(nil), ShouldBeNil)
+			cc := New(c)
+			So(cc.AddToGroup("pools", func() *pool { return &pool{"d"} }), ShouldBeNil)
+			So(cc.Create(nil), ShouldBeNil)
+			var names []string
+			So(cc.Invoke(func(p poolGroup) {
+				for _, p := range p.Pools {
+					names = append(names, p.name)
+				}
+			}, nil), ShouldBeNil)
+			So(names, ShouldResemble, []string{"a", "b", "d"})
+			So(cc.Snapshot().Invoke(func(p poolGroup) {
+				So(p.Pools, ShouldHaveLength, 3)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("empty groups should resolve to an empty slice", func() {
+			So(c.Create(nil), ShouldBeNil)
+			So(c.Invoke(func(p struct {
+				In
+				Pools []*pool `group:"backup"`
+			}) {
+				So(p.Pools, ShouldBeEmpty)
+			}, nil), ShouldBeNil)
+		})
+
+		Convey("the group should be exported", func() {
+			buf := &bytes.Buffer{}
+			So(c.WriteDOT(buf, DOTOptions{}), ShouldBeNil)
+			So(buf.String(), ShouldContainSubstring, `"github.com/anuvu/cube/di.pool{pools}" [label="[]di.pool group \"pools\""];`)
+			So(buf.String(), ShouldContainSubstring, `"github.com/anuvu/cube/di.pool{pools}" -> "github.com/anuvu/cube/di.pool{pools}#1";`)
+		})
+	})
+}
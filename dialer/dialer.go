@@ -0,0 +1,236 @@
+// Package dialer provides a dialer component that tracks the outbound
+// connections opened by the components. The dialer is stopped after the
+// components depending on it, when the server stops it waits for their
+// connections to close and reports the connections still open, who opened
+// them, where to and since when. The connections still open at the deadline
+// are forcefully closed unless configured otherwise. Components use it in
+// place of a net.Dialer, e.g.
+//
+//	transport := &http.Transport{DialContext: d.Owner("billing").DialContext}
+package dialer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Dialer opens tracked outbound connections.
+type Dialer interface {
+	// Dial connects to the address on the named network.
+	Dial(network, address string) (net.Conn, error)
+
+	// DialContext connects to the address on the named network using the
+	// context.
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+
+	// Owner returns a dialer attributing the connections it opens to the
+	// owner, e.g. the name of the component.
+	Owner(owner string) Dialer
+
+	// Open returns the open connections sorted by their opening time.
+	Open() []Conn
+
+	// Handler returns an admin handler that reports the open connections as
+	// JSON.
+	Handler() http.Handler
+}
+
+// Conn describes an open outbound connection.
+type Conn struct {
+	// Owner of the connection, empty if not attributed
+	Owner string `json:"owner"`
+	// Network of the connection, e.g. tcp
+	Network string `json:"network"`
+	// Address dialed
+	Address string `json:"address"`
+	// Opened is the time the connection was opened at
+	Opened time.Time `json:"opened"`
+}
+
+func (c Conn) String() string {
+	owner := c.Owner
+	if owner == "" {
+		owner = "unknown"
+	}
+	return fmt.Sprintf("%s to %s/%s open for %v", owner, c.Network, c.Address, time.Since(c.Opened).Round(time.Millisecond))
+}
+
+// tracked is a connection tracked by the dialer.
+type tracked struct {
+	net.Conn
+	info Conn
+	once sync.Once
+	d    *dialer
+}
+
+func (t *tracked) Close() error {
+	t.once.Do(func() { t.d.remove(t) })
+	return t.Conn.Close()
+}
+
+type dialer struct {
+	config  *configuration
+	lock    sync.Mutex
+	conns   map[*tracked]struct{}
+	changed chan struct{}
+}
+
+// owned attributes the connections of the dialer to the owner.
+type owned struct {
+	*dialer
+	owner string
+}
+
+// configuration defines the configurable parameters of the dialer
+type configuration struct {
+	config.BaseConfig
+	// Dial timeout in milliseconds, no timeout if 0
+	Timeout int `json:"timeout_ms"`
+	// TCP keep-alive period in milliseconds, the net package default if 0
+	KeepAlive int `json:"keep_alive_ms"`
+	// Time to wait at stop for the open connections to close in milliseconds
+	Deadline int `json:"deadline_ms"`
+	// Close the connections still open at the deadline
+	ForceClose bool `json:"force_close"`
+}
+
+// New creates a new dialer.
+func New(ctx component.Context) Dialer {
+	return &dialer{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "dialer"},
+			Timeout:    30000,
+			Deadline:   5000,
+			ForceClose: true,
+		},
+		conns:   map[*tracked]struct{}{},
+		changed: make(chan struct{}),
+	}
+}
+
+func (d *dialer) Config() config.Config {
+	return d.config
+}
+
+func (d *dialer) Configure(ctx component.Context) error {
+	if d.config.Timeout < 0 || d.config.Deadline < 0 {
+		return fmt.Errorf("dialer timeouts must not be negative")
+	}
+	return nil
+}
+
+func (d *dialer) Dial(network, address string) (net.Conn, error) {
+	return d.dial(context.Background(), "", network, address)
+}
+
+func (d *dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.dial(ctx, "", network, address)
+}
+
+func (d *dialer) Owner(owner string) Dialer {
+	return owned{d, owner}
+}
+
+func (o owned) Dial(network, address string) (net.Conn, error) {
+	return o.dial(context.Background(), o.owner, network, address)
+}
+
+func (o owned) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return o.dial(ctx, o.owner, network, address)
+}
+
+func (d *dialer) dial(ctx context.Context, owner, network, address string) (net.Conn, error) {
+	nd := net.Dialer{
+		Timeout:   time.Duration(d.config.Timeout) * time.Millisecond,
+		KeepAlive: time.Duration(d.config.KeepAlive) * time.Millisecond,
+	}
+	c, err := nd.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	t := &tracked{Conn: c, info: Conn{owner, network, address, time.Now()}, d: d}
+	d.lock.Lock()
+	d.conns[t] = struct{}{}
+	d.lock.Unlock()
+	return t, nil
+}
+
+func (d *dialer) remove(t *tracked) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.conns, t)
+	// wake up the waiter in Stop
+	close(d.changed)
+	d.changed = make(chan struct{})
+}
+
+func (d *dialer) Open() []Conn {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	conns := make([]Conn, 0, len(d.conns))
+	for t := range d.conns {
+		conns = append(conns, t.info)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Opened.Before(conns[j].Opened) })
+	return conns
+}
+
+func (d *dialer) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Open())
+	})
+}
+
+// Stop waits for the open connections to close until the deadline, then
+// reports the connections still open and closes them if configured to. The
+// dialer is stopped after the components depending on it, which had the
+// chance to close their connections.
+func (d *dialer) Stop(ctx component.Context) error {
+	open := d.wait(time.Duration(d.config.Deadline) * time.Millisecond)
+	if len(open) == 0 {
+		return nil
+	}
+	for _, t := range open {
+		ctx.Log().Warn().Str("owner", t.info.Owner).Str("network", t.info.Network).
+			Str("address", t.info.Address).Str("open_for", time.Since(t.info.Opened).String()).
+			Msg("outbound connection still open at stop")
+		if d.config.ForceClose {
+			t.Close()
+		}
+	}
+	return fmt.Errorf("%d outbound connections still open at stop, oldest %v", len(open), open[0].info)
+}
+
+// wait waits until the connections are closed or the timeout expires, and
+// returns the connections that are still open sorted by their opening time.
+func (d *dialer) wait(timeout time.Duration) []*tracked {
+	deadline := time.After(timeout)
+	for {
+		d.lock.Lock()
+		changed := d.changed
+		open := make([]*tracked, 0, len(d.conns))
+		for t := range d.conns {
+			open = append(open, t)
+		}
+		d.lock.Unlock()
+		if len(open) == 0 {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			sort.Slice(open, func(i, j int) bool { return open[i].info.Opened.Before(open[j].info.Opened) })
+			return open
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package dialer
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDialer(t *testing.T) {
+	Convey("After we create a dialer and a server", t, func() {
+		ctx := component.RootContext(zlog.New("dialer.test"))
+		d := New(ctx).(*dialer)
+		So(d.Config().Key(), ShouldEqual, "dialer")
+		d.config.Deadline = 20
+		So(d.Configure(ctx), ShouldBeNil)
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer l.Close()
+		accepted := make(chan net.Conn, 2)
+		go func() {
+			for {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				accepted <- c
+			}
+		}()
+		addr := l.Addr().String()
+
+		Convey("the open connections should be tracked", func() {
+			c1, err := d.Owner("billing").Dial("tcp", addr)
+			So(err, ShouldBeNil)
+			c2, err := d.DialContext(context.Background(), "tcp", addr)
+			So(err, ShouldBeNil)
+			open := d.Open()
+			So(open, ShouldHaveLength, 2)
+			So(open[0].Owner, ShouldEqual, "billing")
+			So(open[0].Address, ShouldEqual, addr)
+			So(open[1].Owner, ShouldBeEmpty)
+
+			w := httptest.NewRecorder()
+			d.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/dialer", nil))
+			conns := []Conn{}
+			So(json.NewDecoder(w.Body).Decode(&conns), ShouldBeNil)
+			So(conns, ShouldHaveLength, 2)
+
+			So(c1.Close(), ShouldBeNil)
+			c1.Close()
+			So(d.Open(), ShouldHaveLength, 1)
+			So(c2.Close(), ShouldBeNil)
+			So(d.Stop(ctx), ShouldBeNil)
+		})
+
+		Convey("the connections still open at stop should be reported and closed", func() {
+			_, err := d.Owner("billing").Dial("tcp", addr)
+			So(err, ShouldBeNil)
+			err = d.Stop(ctx)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "billing to tcp/"+addr)
+			So(d.Open(), ShouldBeEmpty)
+
+			// The peer sees the connection closed
+			peer := <-accepted
+			_, err = ioutil.ReadAll(peer)
+			So(err, ShouldBeNil)
+			peer.Close()
+		})
+
+		Convey("the connections closed before the deadline should not be reported", func() {
+			c, err := d.Dial("tcp", addr)
+			So(err, ShouldBeNil)
+			d.config.Deadline = 5000
+			go c.Close()
+			So(d.Stop(ctx), ShouldBeNil)
+		})
+
+		Convey("the connections should be left open if not forcefully closed", func() {
+			d.config.ForceClose = false
+			c, err := d.Dial("tcp", addr)
+			So(err, ShouldBeNil)
+			defer c.Close()
+			So(d.Stop(ctx), ShouldNotBeNil)
+			So(d.Open(), ShouldHaveLength, 1)
+		})
+	})
+}
@@ -0,0 +1,203 @@
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Record types
+const (
+	typeA     = 1
+	typeCNAME = 5
+	typeTXT   = 16
+	typeAAAA  = 28
+	typeSRV   = 33
+	typeANY   = 255
+
+	classIN = 1
+)
+
+// Response codes
+const (
+	rcodeOK       = 0
+	rcodeFormat   = 1
+	rcodeNXDomain = 3
+	rcodeNotImpl  = 4
+)
+
+const headerLen = 12
+
+var types = map[string]uint16{
+	"A":     typeA,
+	"AAAA":  typeAAAA,
+	"CNAME": typeCNAME,
+	"TXT":   typeTXT,
+	"SRV":   typeSRV,
+}
+
+var errFormat = errors.New("malformed query")
+
+// rr is a resource record with its data in the wire format.
+type rr struct {
+	typ  uint16
+	data []byte
+}
+
+// question is the question of a query.
+type question struct {
+	name  string
+	typ   uint16
+	class uint16
+	raw   []byte
+}
+
+// canonical returns the lower case, fully qualified name.
+func canonical(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// encodeRecord returns the record of the type with its value in the wire
+// format, the value of SRV records is "priority weight port target".
+func encodeRecord(typ, value string) (rr, error) {
+	t, ok := types[strings.ToUpper(typ)]
+	if !ok {
+		return rr{}, fmt.Errorf("unsupported record type %q", typ)
+	}
+	r := rr{typ: t}
+	switch t {
+	case typeA, typeAAAA:
+		ip := net.ParseIP(value)
+		if ip == nil || (t == typeA) != (ip.To4() != nil) {
+			return rr{}, fmt.Errorf("invalid %s record %q", typ, value)
+		}
+		if t == typeA {
+			r.data = ip.To4()
+		} else {
+			r.data = ip.To16()
+		}
+	case typeCNAME:
+		data, err := encodeName(nil, value)
+		if err != nil {
+			return rr{}, err
+		}
+		r.data = data
+	case typeTXT:
+		for len(value) > 255 {
+			r.data = append(append(r.data, 255), value[:255]...)
+			value = value[255:]
+		}
+		r.data = append(append(r.data, byte(len(value))), value...)
+	case typeSRV:
+		f := strings.Fields(value)
+		if len(f) != 4 {
+			return rr{}, fmt.Errorf("invalid SRV record %q, expected priority weight port target", value)
+		}
+		r.data = make([]byte, 6)
+		for i := 0; i < 3; i++ {
+			n, err := strconv.ParseUint(f[i], 10, 16)
+			if err != nil {
+				return rr{}, fmt.Errorf("invalid SRV record %q: %v", value, err)
+			}
+			binary.BigEndian.PutUint16(r.data[2*i:], uint16(n))
+		}
+		data, err := encodeName(r.data, f[3])
+		if err != nil {
+			return rr{}, err
+		}
+		r.data = data
+	}
+	return r, nil
+}
+
+// encodeName appends the name in the wire format, without compression.
+func encodeName(b []byte, name string) ([]byte, error) {
+	name = canonical(name)
+	if len(name) > 255 {
+		return nil, fmt.Errorf("name %q is too long", name)
+	}
+	if name == "." {
+		return append(b, 0), nil
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if l == "" || len(l) > 63 {
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+		b = append(append(b, byte(len(l))), l...)
+	}
+	return append(b, 0), nil
+}
+
+// parseQuery returns the id, the flags and the question of the query.
+// Queries with more than one question are rejected, the other sections are
+// ignored.
+func parseQuery(msg []byte) (uint16, uint16, question, error) {
+	q := question{}
+	if len(msg) < headerLen {
+		return 0, 0, q, errFormat
+	}
+	id := binary.BigEndian.Uint16(msg)
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if binary.BigEndian.Uint16(msg[4:]) != 1 {
+		return id, flags, q, errFormat
+	}
+	labels := []string{}
+	i := headerLen
+	for {
+		if i >= len(msg) {
+			return id, flags, q, errFormat
+		}
+		n := int(msg[i])
+		i++
+		if n == 0 {
+			break
+		}
+		if n > 63 || i+n > len(msg) {
+			// Compression is not expected in the question
+			return id, flags, q, errFormat
+		}
+		labels = append(labels, string(msg[i:i+n]))
+		i += n
+	}
+	if i+4 > len(msg) {
+		return id, flags, q, errFormat
+	}
+	q.name = canonical(strings.Join(labels, "."))
+	q.typ = binary.BigEndian.Uint16(msg[i:])
+	q.class = binary.BigEndian.Uint16(msg[i+2:])
+	q.raw = msg[headerLen : i+4]
+	return id, flags, q, nil
+}
+
+// response returns the response to the query with the answers, echoing the
+// question if any. The answers are all named after the question.
+func response(id, flags uint16, q question, rcode int, answers []rr, ttl uint32) []byte {
+	b := make([]byte, headerLen, 512)
+	binary.BigEndian.PutUint16(b, id)
+	// QR and AA set, opcode and RD copied from the query
+	binary.BigEndian.PutUint16(b[2:], 0x8000|0x0400|flags&0x7900|uint16(rcode))
+	if q.raw == nil {
+		return b
+	}
+	binary.BigEndian.PutUint16(b[4:], 1)
+	binary.BigEndian.PutUint16(b[6:], uint16(len(answers)))
+	b = append(b, q.raw...)
+	for _, a := range answers {
+		var hdr [12]byte
+		// Pointer to the name of the question
+		binary.BigEndian.PutUint16(hdr[0:], 0xc000|headerLen)
+		binary.BigEndian.PutUint16(hdr[2:], a.typ)
+		binary.BigEndian.PutUint16(hdr[4:], classIN)
+		binary.BigEndian.PutUint32(hdr[6:], ttl)
+		binary.BigEndian.PutUint16(hdr[10:], uint16(len(a.data)))
+		b = append(append(b, hdr[:]...), a.data...)
+	}
+	return b
+}
@@ -0,0 +1,80 @@
+package dns
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func query(id uint16, flags uint16, name string, typ uint16) []byte {
+	b := make([]byte, headerLen)
+	binary.BigEndian.PutUint16(b, id)
+	binary.BigEndian.PutUint16(b[2:], flags)
+	binary.BigEndian.PutUint16(b[4:], 1)
+	b, _ = encodeName(b, name)
+	var tail [4]byte
+	binary.BigEndian.PutUint16(tail[:], typ)
+	binary.BigEndian.PutUint16(tail[2:], classIN)
+	return append(b, tail[:]...)
+}
+
+func rcode(resp []byte) int {
+	return int(binary.BigEndian.Uint16(resp[2:]) & 0xf)
+}
+
+func TestMessages(t *testing.T) {
+	Convey("After we create a DNS server", t, func() {
+		ctx := component.RootContext(zlog.New("dns.test"))
+		s := New(ctx).(*server)
+		So(s.Configure(ctx), ShouldBeNil)
+
+		Convey("queries should be parsed", func() {
+			id, flags, q, err := parseQuery(query(7, 0x0100, "Db.Local", typeA))
+			So(err, ShouldBeNil)
+			So(id, ShouldEqual, 7)
+			So(flags, ShouldEqual, 0x0100)
+			So(q.name, ShouldEqual, "db.local.")
+			So(q.typ, ShouldEqual, typeA)
+		})
+
+		Convey("malformed queries should be rejected", func() {
+			So(s.answer([]byte{1, 2, 3}), ShouldBeNil)
+			msg := query(7, 0, "db.local", typeA)
+			So(rcode(s.answer(msg[:headerLen+3])), ShouldEqual, rcodeFormat)
+			So(s.answer(query(7, 0x8000, "db.local", typeA)), ShouldBeNil)
+			So(rcode(s.answer(query(7, 0x1000, "db.local", typeA))), ShouldEqual, rcodeNotImpl)
+		})
+
+		Convey("unknown names should not exist", func() {
+			resp := s.answer(query(7, 0x0100, "db.local", typeA))
+			So(rcode(resp), ShouldEqual, rcodeNXDomain)
+			So(binary.BigEndian.Uint16(resp), ShouldEqual, 7)
+			// Response, authoritative, recursion desired
+			So(binary.BigEndian.Uint16(resp[2:])&0xff00, ShouldEqual, 0x8500)
+		})
+
+		Convey("large responses should be truncated", func() {
+			values := []string{}
+			for i := 0; i < 3; i++ {
+				values = append(values, strings.Repeat("x", 250))
+			}
+			So(s.Set("big.local", "TXT", values...), ShouldBeNil)
+			resp := s.answer(query(7, 0, "big.local", typeTXT))
+			So(rcode(resp), ShouldEqual, rcodeOK)
+			So(resp[2]&0x02, ShouldEqual, 0x02)
+			So(binary.BigEndian.Uint16(resp[6:]), ShouldEqual, 0)
+		})
+
+		Convey("names should be validated", func() {
+			_, err := encodeName(nil, strings.Repeat("a", 64)+".local")
+			So(err, ShouldNotBeNil)
+			b, err := encodeName(nil, ".")
+			So(err, ShouldBeNil)
+			So(b, ShouldResemble, []byte{0})
+		})
+	})
+}
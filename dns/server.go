@@ -0,0 +1,235 @@
+// Package dns provides a small DNS responder component serving fixed records
+// over UDP. It is meant for the integration tests of components depending on
+// name resolution and for development environments faking service discovery,
+// not as a production name server, e.g.
+//
+//	"dns": {
+//		"address": "127.0.0.1:5353",
+//		"records": [
+//			{"name": "db.local", "type": "A", "value": "10.0.0.1"},
+//			{"name": "_http._tcp.api.local", "type": "SRV", "value": "0 5 8080 api.local"}
+//		]
+//	}
+//
+// A, AAAA, CNAME, TXT and SRV records are supported. CNAME records are not
+// chased and the responses are never larger than 512 bytes, larger responses
+// are truncated without answers.
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+const maxUDPSize = 512
+
+// Server is a DNS responder serving fixed records.
+type Server interface {
+	// Addr returns the address the server is bound to, nil until the server
+	// is started.
+	Addr() net.Addr
+
+	// Set replaces the records of the name and type with the values, no
+	// values removes them. It returns an error if a value is invalid. The
+	// records set before the server is configured are replaced by the
+	// configured ones.
+	Set(name, typ string, values ...string) error
+}
+
+// Record is a record served by the server.
+type Record struct {
+	// Name of the record, e.g. db.local
+	Name string `json:"name"`
+	// Type of the record, A, AAAA, CNAME, TXT or SRV
+	Type string `json:"type"`
+	// Value of the record, "priority weight port target" for SRV records
+	Value string `json:"value"`
+}
+
+type server struct {
+	config  *configuration
+	lock    sync.RWMutex
+	zone    map[string][]rr
+	conn    net.PacketConn
+	stopped int32
+}
+
+// configuration defines the configurable parameters of the DNS server
+type configuration struct {
+	config.BaseConfig
+	// UDP address to listen on, an ephemeral port on localhost by default
+	Address string `json:"address"`
+	// TTL of the records in seconds
+	TTL int `json:"ttl"`
+	// Records served
+	Records []Record `json:"records"`
+}
+
+// New creates a new DNS server.
+func New(ctx component.Context) Server {
+	return &server{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "dns"},
+			Address:    "127.0.0.1:0",
+			TTL:        60,
+		},
+		zone: map[string][]rr{},
+	}
+}
+
+func (s *server) Config() config.Config {
+	return s.config
+}
+
+func (s *server) Configure(ctx component.Context) error {
+	if s.config.TTL < 0 {
+		s.config.TTL = 0
+	}
+	zone := map[string][]rr{}
+	for _, r := range s.config.Records {
+		rec, err := encodeRecord(r.Type, r.Value)
+		if err != nil {
+			return err
+		}
+		if _, err := encodeName(nil, r.Name); err != nil {
+			return err
+		}
+		name := canonical(r.Name)
+		zone[name] = append(zone[name], rec)
+	}
+	s.lock.Lock()
+	s.zone = zone
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *server) Start(ctx component.Context) error {
+	conn, err := net.ListenPacket("udp", s.config.Address)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.conn = conn
+	s.lock.Unlock()
+	ctx.Log().Info().Str("addr", conn.LocalAddr().String()).Msg("dns server listening")
+	component.Go(ctx, func(ctx component.Context) error {
+		return s.serve(conn)
+	})
+	return nil
+}
+
+func (s *server) Stop(ctx component.Context) error {
+	s.lock.RLock()
+	conn := s.conn
+	s.lock.RUnlock()
+	if conn == nil {
+		return nil
+	}
+	atomic.StoreInt32(&s.stopped, 1)
+	return conn.Close()
+}
+
+func (s *server) Addr() net.Addr {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+func (s *server) Set(name, typ string, values ...string) error {
+	t, ok := types[strings.ToUpper(typ)]
+	if !ok {
+		return fmt.Errorf("unsupported record type %q", typ)
+	}
+	if _, err := encodeName(nil, name); err != nil {
+		return err
+	}
+	recs := []rr{}
+	for _, v := range values {
+		r, err := encodeRecord(typ, v)
+		if err != nil {
+			return err
+		}
+		recs = append(recs, r)
+	}
+
+	name = canonical(name)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, r := range s.zone[name] {
+		if r.typ != t {
+			recs = append(recs, r)
+		}
+	}
+	if len(recs) == 0 {
+		delete(s.zone, name)
+		return nil
+	}
+	s.zone[name] = recs
+	return nil
+}
+
+// serve answers the queries until the server is stopped.
+func (s *server) serve(conn net.PacketConn) error {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if atomic.LoadInt32(&s.stopped) != 0 {
+				return nil
+			}
+			return err
+		}
+		if resp := s.answer(buf[:n]); resp != nil {
+			conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// answer returns the response to the query, nil if it is not worth a
+// response.
+func (s *server) answer(msg []byte) []byte {
+	id, flags, q, err := parseQuery(msg)
+	switch {
+	case err != nil && len(msg) < headerLen:
+		return nil
+	case flags&0x8000 != 0:
+		// Not a query
+		return nil
+	case err != nil:
+		return response(id, flags, question{}, rcodeFormat, nil, 0)
+	case flags&0x7800 != 0:
+		// Only standard queries are supported
+		return response(id, flags, q, rcodeNotImpl, nil, 0)
+	}
+
+	s.lock.RLock()
+	recs, ok := s.zone[q.name]
+	s.lock.RUnlock()
+	if !ok {
+		return response(id, flags, q, rcodeNXDomain, nil, 0)
+	}
+	answers := []rr{}
+	if q.class == classIN || q.class == typeANY {
+		for _, r := range recs {
+			if r.typ == q.typ || q.typ == typeANY || r.typ == typeCNAME {
+				answers = append(answers, r)
+			}
+		}
+	}
+	resp := response(id, flags, q, rcodeOK, answers, uint32(s.config.TTL))
+	if len(resp) > maxUDPSize {
+		// Set TC so that the client knows the answers are missing
+		resp = response(id, flags, q, rcodeOK, nil, 0)
+		resp[2] |= 0x02
+	}
+	return resp
+}
@@ -0,0 +1,99 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServer(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"dns.test", "--config.mem", `{"dns": {}}`}
+
+	Convey("After we start a DNS server in a group", t, func() {
+		g := component.New("dns")
+		So(g.Add(New), ShouldBeNil)
+		So(g.Create(), ShouldBeNil)
+		var s *server
+		So(g.Invoke(func(srv Server) { s = srv.(*server) }), ShouldBeNil)
+		So(s.Addr(), ShouldBeNil)
+		So(g.Configure(), ShouldBeNil)
+		So(g.Start(), ShouldBeNil)
+		defer g.Stop()
+		So(s.Addr(), ShouldNotBeNil)
+		So(s.Set("db.local", "a", "10.0.0.1", "10.0.0.2"), ShouldBeNil)
+		So(s.Set("db.local", "AAAA", "fd00::1"), ShouldBeNil)
+		So(s.Set("alias.local", "CNAME", "db.local"), ShouldBeNil)
+		So(s.Set("info.local", "TXT", "v=1", strings.Repeat("x", 300)), ShouldBeNil)
+		So(s.Set("_http._tcp.api.local", "SRV", "0 5 8080 api.local"), ShouldBeNil)
+
+		addr := s.Addr().String()
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return net.Dial("udp", addr)
+			},
+		}
+		ctx := context.Background()
+
+		Convey("the addresses should be resolved", func() {
+			addrs, err := r.LookupHost(ctx, "DB.local.")
+			So(err, ShouldBeNil)
+			So(addrs, ShouldHaveLength, 3)
+			So(addrs, ShouldContain, "10.0.0.2")
+			So(addrs, ShouldContain, "fd00::1")
+		})
+
+		Convey("the other record types should be resolved", func() {
+			cname, err := r.LookupCNAME(ctx, "alias.local.")
+			So(err, ShouldBeNil)
+			So(cname, ShouldEqual, "db.local.")
+
+			txt, err := r.LookupTXT(ctx, "info.local.")
+			So(err, ShouldBeNil)
+			So(txt, ShouldHaveLength, 2)
+			So(strings.Join(txt, ""), ShouldContainSubstring, strings.Repeat("x", 300))
+
+			_, srvs, err := r.LookupSRV(ctx, "http", "tcp", "api.local.")
+			So(err, ShouldBeNil)
+			So(srvs, ShouldHaveLength, 1)
+			So(srvs[0].Target, ShouldEqual, "api.local.")
+			So(srvs[0].Port, ShouldEqual, 8080)
+			So(srvs[0].Weight, ShouldEqual, 5)
+		})
+
+		Convey("the removed and unknown names should not be resolved", func() {
+			So(s.Set("db.local", "A"), ShouldBeNil)
+			So(s.Set("db.local", "AAAA"), ShouldBeNil)
+			_, err := r.LookupHost(ctx, "db.local.")
+			So(err, ShouldNotBeNil)
+			So(err.(*net.DNSError).IsNotFound, ShouldBeTrue)
+		})
+
+		Convey("invalid records should be rejected", func() {
+			So(s.Set("db.local", "MX", "mail.local"), ShouldNotBeNil)
+			So(s.Set("db.local", "A", "fd00::1"), ShouldNotBeNil)
+			So(s.Set("db.local", "AAAA", "10.0.0.1"), ShouldNotBeNil)
+			So(s.Set("db.local", "SRV", "0 5 api.local"), ShouldNotBeNil)
+			So(s.Set("db..local", "A", "10.0.0.1"), ShouldNotBeNil)
+		})
+	})
+
+	Convey("The configured records should be served", t, func() {
+		ctx := component.RootContext(zlog.New("dns.test"))
+		s := New(ctx).(*server)
+		s.config.Records = []Record{{Name: "db.local", Type: "A", Value: "10.0.0.1"}}
+		So(s.Configure(ctx), ShouldBeNil)
+		So(s.zone["db.local."], ShouldHaveLength, 1)
+
+		s.config.Records = []Record{{Name: "db.local", Type: "A", Value: "db"}}
+		So(s.Configure(ctx), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,154 @@
+// Package exitcode defines the error categories of the cube framework. Each
+// category maps to a distinct process exit code so that orchestrators and
+// scripts can distinguish failure causes programmatically.
+package exitcode
+
+import (
+	"fmt"
+)
+
+// Category classifies the cause of a failure.
+type Category int
+
+const (
+	// Unknown is the category of errors that are not categorized.
+	Unknown Category = iota
+
+	// Config is the category of configuration errors.
+	Config
+
+	// Dependency is the category of errors while creating components and
+	// resolving their dependencies.
+	Dependency
+
+	// Start is the category of errors while starting components.
+	Start
+
+	// Stop is the category of errors while stopping components.
+	Stop
+
+	// StopTimeout is the category of components failing to stop in time.
+	StopTimeout
+
+	// Panic is the category of recovered panics.
+	Panic
+
+	// SelfCheck is the category of failed self-checks of components.
+	SelfCheck
+)
+
+var categories = map[Category]struct {
+	name string
+	code int
+}{
+	Unknown:     {"unknown", 1},
+	Config:      {"config", 2},
+	Dependency:  {"dependency", 3},
+	Start:       {"start", 4},
+	Stop:        {"stop", 5},
+	StopTimeout: {"stop_timeout", 6},
+	Panic:       {"panic", 7},
+	SelfCheck:   {"self_check", 8},
+}
+
+// String returns the name of the category used in log fields.
+func (c Category) String() string {
+	if v, ok := categories[c]; ok {
+		return v.name
+	}
+	return categories[Unknown].name
+}
+
+// ExitCode returns the process exit code for the category.
+func (c Category) ExitCode() int {
+	if v, ok := categories[c]; ok {
+		return v.code
+	}
+	return categories[Unknown].code
+}
+
+// Error is an error with a category.
+type Error struct {
+	Category Category
+	Err      error
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Category, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns err wrapped with the category. It returns nil if err is nil.
+func New(c Category, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{c, err}
+}
+
+// ConfigError returns err categorized as a configuration error.
+func ConfigError(err error) error {
+	return New(Config, err)
+}
+
+// DependencyError returns err categorized as a dependency error.
+func DependencyError(err error) error {
+	return New(Dependency, err)
+}
+
+// StartError returns err categorized as a start error.
+func StartError(err error) error {
+	return New(Start, err)
+}
+
+// StopError returns err categorized as a stop error.
+func StopError(err error) error {
+	return New(Stop, err)
+}
+
+// StopTimeoutError returns err categorized as a stop timeout.
+func StopTimeoutError(err error) error {
+	return New(StopTimeout, err)
+}
+
+// SelfCheckError returns err categorized as a failed self-check.
+func SelfCheckError(err error) error {
+	return New(SelfCheck, err)
+}
+
+// PanicError returns an error for a recovered panic value.
+func PanicError(v interface{}) error {
+	if err, ok := v.(error); ok {
+		return New(Panic, err)
+	}
+	return New(Panic, fmt.Errorf("%v", v))
+}
+
+// CategoryOf returns the category of the first categorized error in the chain
+// of wrapped errors, or Unknown if there is none.
+func CategoryOf(err error) Category {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			return e.Category
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return Unknown
+}
+
+// ExitCode returns the process exit code for err, 0 if err is nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return CategoryOf(err).ExitCode()
+}
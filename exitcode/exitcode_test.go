@@ -0,0 +1,58 @@
+package exitcode
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type wrapper struct {
+	err error
+}
+
+func (w *wrapper) Error() string { return "wrapped: " + w.err.Error() }
+func (w *wrapper) Unwrap() error { return w.err }
+
+func TestErrors(t *testing.T) {
+	Convey("Categorized errors", t, func() {
+		base := fmt.Errorf("base")
+
+		Convey("nil errors should not be categorized", func() {
+			So(New(Config, nil), ShouldBeNil)
+			So(ExitCode(nil), ShouldEqual, 0)
+			So(CategoryOf(nil), ShouldEqual, Unknown)
+		})
+
+		Convey("each category should have a distinct exit code", func() {
+			codes := map[int]bool{0: true}
+			for c := Unknown; c <= SelfCheck; c++ {
+				So(codes[c.ExitCode()], ShouldBeFalse)
+				codes[c.ExitCode()] = true
+			}
+			So(Category(100).ExitCode(), ShouldEqual, Unknown.ExitCode())
+			So(Category(100).String(), ShouldEqual, "unknown")
+		})
+
+		Convey("constructors should categorize the errors", func() {
+			So(CategoryOf(ConfigError(base)), ShouldEqual, Config)
+			So(CategoryOf(DependencyError(base)), ShouldEqual, Dependency)
+			So(CategoryOf(StartError(base)), ShouldEqual, Start)
+			So(CategoryOf(StopError(base)), ShouldEqual, Stop)
+			So(CategoryOf(StopTimeoutError(base)), ShouldEqual, StopTimeout)
+			So(CategoryOf(SelfCheckError(base)), ShouldEqual, SelfCheck)
+			So(CategoryOf(PanicError("boom")), ShouldEqual, Panic)
+			So(CategoryOf(PanicError(base)), ShouldEqual, Panic)
+			So(CategoryOf(base), ShouldEqual, Unknown)
+			So(ExitCode(base), ShouldEqual, 1)
+		})
+
+		Convey("wrapped errors should keep their category", func() {
+			err := &wrapper{StartError(base)}
+			So(CategoryOf(err), ShouldEqual, Start)
+			So(ExitCode(err), ShouldEqual, Start.ExitCode())
+			So(err.Error(), ShouldEqual, "wrapped: start error: base")
+			So(StartError(base).(*Error).Unwrap(), ShouldEqual, base)
+		})
+	})
+}
@@ -0,0 +1,97 @@
+// Package fx eases the migration of applications written with uber-go/dig and
+// uber-go/fx to cube. It mirrors the fx option functions so that existing
+// provider bundles can be installed into a cube component group with few
+// changes.
+//
+// dig style constructors are plain functions whose parameters are the
+// dependencies and whose results are the provided types, optionally followed
+// by an error. They are registered with the group as they are. Parameter and
+// result structs embedding dig.In or dig.Out, named values and value groups
+// are not supported.
+package fx
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/anuvu/cube/component"
+)
+
+// Option is a bundle of constructors, values and invocations, similar to
+// fx.Option.
+type Option interface {
+	apply(g component.Group) error
+}
+
+type optionFunc func(g component.Group) error
+
+func (f optionFunc) apply(g component.Group) error {
+	return f(g)
+}
+
+// Provide registers the constructors with the group, like fx.Provide.
+func Provide(ctrs ...interface{}) Option {
+	return optionFunc(func(g component.Group) error {
+		for _, ctr := range ctrs {
+			if err := g.Add(ctr); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Supply provides the values as they are, like fx.Supply.
+func Supply(values ...interface{}) Option {
+	return optionFunc(func(g component.Group) error {
+		for _, v := range values {
+			if v == nil {
+				return fmt.Errorf("can't supply an untyped nil")
+			}
+			val := reflect.ValueOf(v)
+			ft := reflect.FuncOf(nil, []reflect.Type{val.Type()}, false)
+			ctr := reflect.MakeFunc(ft, func([]reflect.Value) []reflect.Value {
+				return []reflect.Value{val}
+			})
+			if err := g.Add(ctr.Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Invoke registers the functions to be invoked as soon as the group is
+// created, like fx.Invoke.
+func Invoke(fns ...interface{}) Option {
+	return optionFunc(func(g component.Group) error {
+		r, ok := g.(component.Registry)
+		if !ok {
+			return fmt.Errorf("group %T does not support invocations", g)
+		}
+		for _, f := range fns {
+			if err := r.AddInvoke(f); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Options combines the options into one, like fx.Options.
+func Options(opts ...Option) Option {
+	return optionFunc(func(g component.Group) error {
+		for _, opt := range opts {
+			if err := opt.apply(g); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Install installs the options into the group. It returns the first error
+// encountered.
+func Install(g component.Group, opts ...Option) error {
+	return Options(opts...).apply(g)
+}
@@ -0,0 +1,67 @@
+package fx
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type config struct {
+	addr string
+}
+
+type server struct {
+	addr string
+}
+
+func newServer(c *config) (*server, error) {
+	if c.addr == "" {
+		return nil, errors.New("no address")
+	}
+	return &server{c.addr}, nil
+}
+
+func TestInstall(t *testing.T) {
+	oldArgs := os.Args
+	os.Args = []string{"fx.test"}
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we install an fx style bundle", t, func() {
+		grp := component.New("fx")
+		invoked := ""
+		bundle := Options(
+			Provide(newServer),
+			Invoke(func(s *server) { invoked = s.addr }),
+		)
+
+		Convey("the group should create the provided components and run the invocations", func() {
+			So(Install(grp, bundle, Supply(&config{":8080"})), ShouldBeNil)
+			So(grp.Create(), ShouldBeNil)
+			So(invoked, ShouldEqual, ":8080")
+			So(grp.Invoke(func(s *server) {
+				So(s.addr, ShouldEqual, ":8080")
+			}), ShouldBeNil)
+		})
+
+		Convey("constructor errors should fail the create", func() {
+			So(Install(grp, bundle, Supply(&config{})), ShouldBeNil)
+			So(grp.Create(), ShouldBeError)
+			So(invoked, ShouldBeEmpty)
+		})
+
+		Convey("invocation errors should fail the create", func() {
+			So(Install(grp, Supply(&config{":80"}), Invoke(func(*config) error { return errors.New("failed") })), ShouldBeNil)
+			So(grp.Create(), ShouldBeError)
+		})
+
+		Convey("bad options should be rejected", func() {
+			So(Install(grp, Provide(10)), ShouldBeError)
+			So(Install(grp, Supply(nil)), ShouldBeError)
+			So(Install(grp, Invoke("invoke")), ShouldBeError)
+			So(Install(grp, Supply(&config{}), Supply(&config{})), ShouldBeError)
+		})
+	})
+}
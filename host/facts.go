@@ -0,0 +1,310 @@
+// Package host provides a component that gathers the facts of the host the
+// server runs on: hostname, IP addresses and, on cloud instances, the instance
+// id, region and zone read from the metadata service. Components depending on
+// consistent host information, e.g. for discovery registration, inject the
+// Host and read the facts once the server is started.
+package host
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/zlog"
+)
+
+const (
+	// CloudAWS reads the facts from the EC2 instance metadata service.
+	CloudAWS = "aws"
+	// CloudGCP reads the facts from the GCE metadata server.
+	CloudGCP = "gcp"
+)
+
+var metadataURLs = map[string]string{
+	CloudAWS: "http://169.254.169.254",
+	CloudGCP: "http://metadata.google.internal",
+}
+
+// Facts are the facts of the host.
+type Facts struct {
+	// Hostname of the host
+	Hostname string `json:"hostname"`
+	// IPs are the addresses of the up, non loopback interfaces
+	IPs []string `json:"ips"`
+	// Cloud is the cloud provider, empty if not on a cloud instance
+	Cloud string `json:"cloud,omitempty"`
+	// InstanceID is the id of the cloud instance
+	InstanceID string `json:"instance_id,omitempty"`
+	// Region of the host
+	Region string `json:"region,omitempty"`
+	// Zone of the host
+	Zone string `json:"zone,omitempty"`
+}
+
+// Labels returns the non empty hostname, region and zone of the host, to be
+// used as labels of the metrics or the registrations of the server.
+func (f Facts) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range map[string]string{"host": f.Hostname, "region": f.Region, "zone": f.Zone} {
+		if v != "" {
+			labels[k] = v
+		}
+	}
+	return labels
+}
+
+// Host provides the facts of the host.
+type Host interface {
+	// Facts returns the facts gathered when the server started, the zero
+	// facts before.
+	Facts() Facts
+
+	// Logger returns a logger adding the labels of the host to the events
+	// logged with l once the facts are gathered.
+	Logger(l zlog.Logger) zlog.Logger
+}
+
+type host struct {
+	config   *configuration
+	lock     sync.RWMutex
+	facts    Facts
+	gathered bool
+}
+
+// configuration defines the configurable parameters of the host facts
+type configuration struct {
+	config.BaseConfig
+	// Cloud provider to read the instance metadata from, aws or gcp
+	Cloud string `json:"cloud"`
+	// Base URL of the metadata service, defaults to the one of the provider
+	MetadataURL string `json:"metadata_url"`
+	// Timeout of the metadata requests in milliseconds
+	Timeout int `json:"timeout_ms"`
+	// Region overrides the region of the host
+	Region string `json:"region"`
+	// Zone overrides the zone of the host
+	Zone string `json:"zone"`
+}
+
+// New creates a new host facts component.
+func New(ctx component.Context) Host {
+	return &host{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "host"},
+			Timeout:    1000,
+		},
+	}
+}
+
+func (h *host) Config() config.Config {
+	return h.config
+}
+
+func (h *host) Configure(ctx component.Context) error {
+	if h.config.Timeout <= 0 {
+		h.config.Timeout = 1000
+	}
+	if h.config.Cloud == "" {
+		return nil
+	}
+	if _, ok := metadataURLs[h.config.Cloud]; !ok {
+		return fmt.Errorf("unknown cloud provider %q, expected %s or %s", h.config.Cloud, CloudAWS, CloudGCP)
+	}
+	if h.config.MetadataURL == "" {
+		h.config.MetadataURL = metadataURLs[h.config.Cloud]
+	}
+	return nil
+}
+
+// Start gathers the facts. Failing to read the instance metadata is an error,
+// the server is not expected to run with incomplete facts.
+func (h *host) Start(ctx component.Context) error {
+	f := Facts{}
+	var err error
+	if f.Hostname, err = os.Hostname(); err != nil {
+		return err
+	}
+	if f.IPs, err = addresses(); err != nil {
+		return err
+	}
+	if h.config.Cloud != "" {
+		f.Cloud = h.config.Cloud
+		m := &metadata{strings.TrimSuffix(h.config.MetadataURL, "/"), &http.Client{}}
+		mctx, cancel := context.WithTimeout(ctx.Ctx(), time.Duration(h.config.Timeout)*time.Millisecond)
+		defer cancel()
+		if h.config.Cloud == CloudAWS {
+			err = m.aws(mctx, &f)
+		} else {
+			err = m.gcp(mctx, &f)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the %s instance metadata: %v", h.config.Cloud, err)
+		}
+	}
+	if h.config.Region != "" {
+		f.Region = h.config.Region
+	}
+	if h.config.Zone != "" {
+		f.Zone = h.config.Zone
+	}
+
+	h.lock.Lock()
+	h.facts = f
+	h.gathered = true
+	h.lock.Unlock()
+	ctx.Log().Info().Str("hostname", f.Hostname).Str("ips", strings.Join(f.IPs, ",")).
+		Str("cloud", f.Cloud).Str("instance_id", f.InstanceID).
+		Str("region", f.Region).Str("zone", f.Zone).Msg("gathered host facts")
+	return nil
+}
+
+func (h *host) Facts() Facts {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	f := h.facts
+	f.IPs = append([]string(nil), h.facts.IPs...)
+	return f
+}
+
+func (h *host) labels() map[string]string {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	if !h.gathered {
+		return nil
+	}
+	return h.facts.Labels()
+}
+
+func (h *host) Logger(l zlog.Logger) zlog.Logger {
+	return &logger{Logger: l, h: h}
+}
+
+// logger adds the labels of the host to the events of the wrapped logger.
+type logger struct {
+	zlog.Logger
+	h *host
+}
+
+func (l *logger) Debug() zlog.Event { return l.with(l.Logger.Debug()) }
+func (l *logger) Info() zlog.Event  { return l.with(l.Logger.Info()) }
+func (l *logger) Warn() zlog.Event  { return l.with(l.Logger.Warn()) }
+func (l *logger) Error() zlog.Event { return l.with(l.Logger.Error()) }
+
+func (l *logger) with(e zlog.Event) zlog.Event {
+	labels := l.h.labels()
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e = e.Str(k, labels[k])
+	}
+	return e
+}
+
+// addresses returns the sorted addresses of the up, non loopback interfaces.
+func addresses() ([]string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	ips := []string{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			if n, ok := a.(*net.IPNet); ok && !n.IP.IsLinkLocalUnicast() {
+				ips = append(ips, n.IP.String())
+			}
+		}
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// metadata reads the facts from the instance metadata service.
+type metadata struct {
+	url    string
+	client *http.Client
+}
+
+// aws reads the facts using a session token when the service provides one
+// (IMDSv2), else without.
+func (m *metadata) aws(ctx context.Context, f *Facts) error {
+	hdr := http.Header{}
+	if token, err := m.do(ctx, http.MethodPut, "/latest/api/token",
+		http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"}}); err == nil {
+		hdr.Set("X-Aws-Ec2-Metadata-Token", token)
+	}
+	var err error
+	if f.InstanceID, err = m.do(ctx, http.MethodGet, "/latest/meta-data/instance-id", hdr); err != nil {
+		return err
+	}
+	if f.Zone, err = m.do(ctx, http.MethodGet, "/latest/meta-data/placement/availability-zone", hdr); err != nil {
+		return err
+	}
+	if f.Region, err = m.do(ctx, http.MethodGet, "/latest/meta-data/placement/region", hdr); err != nil {
+		// Older instances have no region entry, e.g. us-east-1a is in us-east-1
+		f.Region = strings.TrimRight(f.Zone, "abcdefghijklmnopqrstuvwxyz")
+	}
+	return nil
+}
+
+// gcp reads the facts, the zone is given as projects/<id>/zones/<zone> and
+// the region is the zone without its suffix, e.g. us-central1-a is in
+// us-central1.
+func (m *metadata) gcp(ctx context.Context, f *Facts) error {
+	hdr := http.Header{"Metadata-Flavor": {"Google"}}
+	var err error
+	if f.InstanceID, err = m.do(ctx, http.MethodGet, "/computeMetadata/v1/instance/id", hdr); err != nil {
+		return err
+	}
+	zone, err := m.do(ctx, http.MethodGet, "/computeMetadata/v1/instance/zone", hdr)
+	if err != nil {
+		return err
+	}
+	f.Zone = zone[strings.LastIndex(zone, "/")+1:]
+	if i := strings.LastIndex(f.Zone, "-"); i > 0 {
+		f.Region = f.Zone[:i]
+	}
+	return nil
+}
+
+func (m *metadata) do(ctx context.Context, method, path string, hdr http.Header) (string, error) {
+	req, err := http.NewRequest(method, m.url+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
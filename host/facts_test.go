@@ -0,0 +1,132 @@
+package host
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// capture records the string fields of the logged events.
+type capture map[string]string
+
+type captureEvent struct {
+	c capture
+}
+
+func (c capture) Debug() zlog.Event { return captureEvent{c} }
+func (c capture) Info() zlog.Event  { return captureEvent{c} }
+func (c capture) Warn() zlog.Event  { return captureEvent{c} }
+func (c capture) Error() zlog.Event { return captureEvent{c} }
+
+func (e captureEvent) Str(k, v string) zlog.Event     { e.c[k] = v; return e }
+func (e captureEvent) Int(k string, v int) zlog.Event { return e }
+func (e captureEvent) Error(err error) zlog.Event     { return e }
+func (e captureEvent) Msg(m string)                   {}
+
+func TestFacts(t *testing.T) {
+	Convey("After we create a host facts component", t, func() {
+		ctx := component.RootContext(zlog.New("host.test"))
+		h := New(ctx).(*host)
+		So(h.Config().Key(), ShouldEqual, "host")
+		hostname, _ := os.Hostname()
+
+		Convey("the local facts should be gathered at start", func() {
+			h.config.Zone = "rack-1"
+			So(h.Configure(ctx), ShouldBeNil)
+			So(h.Facts(), ShouldResemble, Facts{})
+			So(h.Start(ctx), ShouldBeNil)
+			f := h.Facts()
+			So(f.Hostname, ShouldEqual, hostname)
+			So(f.IPs, ShouldNotBeNil)
+			So(f.Cloud, ShouldBeEmpty)
+			So(f.Labels(), ShouldResemble, map[string]string{"host": hostname, "zone": "rack-1"})
+		})
+
+		Convey("the aws metadata should be read with a session token", func() {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/latest/api/token" {
+					w.Write([]byte("secret"))
+					return
+				}
+				if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				switch r.URL.Path {
+				case "/latest/meta-data/instance-id":
+					w.Write([]byte("i-0123"))
+				case "/latest/meta-data/placement/availability-zone":
+					w.Write([]byte("us-east-1a"))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer ts.Close()
+			h.config.Cloud = CloudAWS
+			h.config.MetadataURL = ts.URL + "/"
+			So(h.Configure(ctx), ShouldBeNil)
+			So(h.Start(ctx), ShouldBeNil)
+			f := h.Facts()
+			So(f.Cloud, ShouldEqual, CloudAWS)
+			So(f.InstanceID, ShouldEqual, "i-0123")
+			So(f.Zone, ShouldEqual, "us-east-1a")
+			So(f.Region, ShouldEqual, "us-east-1")
+		})
+
+		Convey("the gcp metadata should be read", func() {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Metadata-Flavor") != "Google" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				switch r.URL.Path {
+				case "/computeMetadata/v1/instance/id":
+					w.Write([]byte("4567\n"))
+				case "/computeMetadata/v1/instance/zone":
+					w.Write([]byte("projects/12/zones/europe-west1-b"))
+				}
+			}))
+			defer ts.Close()
+			h.config.Cloud = CloudGCP
+			h.config.MetadataURL = ts.URL
+			h.config.Region = "eu"
+			So(h.Configure(ctx), ShouldBeNil)
+			So(h.Start(ctx), ShouldBeNil)
+			f := h.Facts()
+			So(f.InstanceID, ShouldEqual, "4567")
+			So(f.Zone, ShouldEqual, "europe-west1-b")
+			So(f.Region, ShouldEqual, "eu")
+		})
+
+		Convey("the start should fail if the metadata cannot be read", func() {
+			ts := httptest.NewServer(http.NotFoundHandler())
+			defer ts.Close()
+			h.config.Cloud = CloudGCP
+			h.config.MetadataURL = ts.URL
+			So(h.Configure(ctx), ShouldBeNil)
+			So(h.Start(ctx), ShouldNotBeNil)
+		})
+
+		Convey("an unknown cloud provider should not be configured", func() {
+			h.config.Cloud = "azure"
+			So(h.Configure(ctx), ShouldNotBeNil)
+		})
+
+		Convey("the logger should add the labels once the facts are gathered", func() {
+			c := capture{}
+			l := h.Logger(c)
+			l.Info().Msg("before")
+			So(c, ShouldBeEmpty)
+			h.config.Region = "local"
+			So(h.Configure(ctx), ShouldBeNil)
+			So(h.Start(ctx), ShouldBeNil)
+			l.Warn().Str("k", "v").Msg("after")
+			So(c, ShouldResemble, capture{"host": hostname, "region": "local", "k": "v"})
+		})
+	})
+}
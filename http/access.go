@@ -0,0 +1,168 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// AccessControl restricts the access to the handlers by the IP address of
+// the client, with the allow and deny lists of the "http_access"
+// configuration, e.g.
+//
+//	"http_access": {
+//		"allow": ["10.0.0.0/8"],
+//		"deny": ["10.1.2.0/24"],
+//		"trusted_proxies": ["10.0.0.1"]
+//	}
+//
+// A client is denied if its address is in the deny list, or if the allow
+// list is not empty and its address is not in it. The denied requests get a
+// 403 response.
+//
+// The address of the client is the address of the peer, or of the
+// connection with the PROXY protocol, see the proxy_protocol field of the
+// "http" configuration. If the peer is a trusted proxy, the address of the
+// client is the last address of the X-Forwarded-For header that is not a
+// trusted proxy.
+type AccessControl interface {
+	// Middleware returns a handler that calls h for the allowed clients.
+	Middleware(h http.Handler) http.Handler
+
+	// ClientIP returns the IP address of the client of the request, nil if
+	// it can't be parsed.
+	ClientIP(r *http.Request) net.IP
+}
+
+type accessControl struct {
+	config  *accessConfig
+	ctx     component.Context
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	proxies []*net.IPNet
+}
+
+// accessConfig defines the access lists in CIDR notation, or as single
+// addresses
+type accessConfig struct {
+	config.BaseConfig
+	// Networks of the allowed clients, all if empty
+	Allow []string `json:"allow"`
+	// Networks of the denied clients
+	Deny []string `json:"deny"`
+	// Networks of the proxies trusted to set the X-Forwarded-For header
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// NewAccessControl creates a new access control middleware.
+func NewAccessControl(ctx component.Context) AccessControl {
+	return &accessControl{
+		config: &accessConfig{BaseConfig: config.BaseConfig{ConfigKey: "http_access"}},
+		ctx:    ctx,
+	}
+}
+
+func (a *accessControl) Config() config.Config {
+	return a.config
+}
+
+func (a *accessControl) Configure(ctx component.Context) error {
+	var err error
+	if a.allow, err = parseNetworks(a.config.Allow); err != nil {
+		return err
+	}
+	if a.deny, err = parseNetworks(a.config.Deny); err != nil {
+		return err
+	}
+	a.proxies, err = parseNetworks(a.config.TrustedProxies)
+	return err
+}
+
+// parseNetworks parses the networks in CIDR notation, a single address is
+// a network of one address.
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, s := range cidrs {
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *accessControl) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !contains(a.proxies, ip) {
+		return ip
+	}
+	// Walk the proxies back from the peer to the first untrusted address
+	hops := []string{}
+	for _, h := range r.Header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(h, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !contains(a.proxies, ip) {
+			break
+		}
+	}
+	return ip
+}
+
+// allowed returns true if the access lists allow the address.
+func (a *accessControl) allowed(ip net.IP) bool {
+	if ip == nil {
+		return len(a.allow) == 0 && len(a.deny) == 0
+	}
+	if contains(a.deny, ip) {
+		return false
+	}
+	return len(a.allow) == 0 || contains(a.allow, ip)
+}
+
+func (a *accessControl) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := a.ClientIP(r)
+		if !a.allowed(ip) {
+			a.ctx.Log().Warn().Str("client", fmt.Sprint(ip)).Str("path", r.URL.Path).Msg("access denied")
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
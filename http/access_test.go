@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAccessControl(t *testing.T) {
+	Convey("After we create an access control middleware", t, func() {
+		ctx := component.RootContext(zlog.New("access.test"))
+		a := NewAccessControl(ctx).(*accessControl)
+		So(a.Config().Key(), ShouldEqual, "http_access")
+		a.config.Allow = []string{"10.0.0.0/8", "2001:db8::/32"}
+		a.config.Deny = []string{"10.1.2.0/24"}
+		a.config.TrustedProxies = []string{"10.0.0.1", "10.0.0.2"}
+		So(a.Configure(ctx), ShouldBeNil)
+		h := a.Middleware(testHandler{})
+		serve := func(remote string, forwarded ...string) int {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = remote
+			for _, f := range forwarded {
+				r.Header.Add("X-Forwarded-For", f)
+			}
+			h.ServeHTTP(w, r)
+			return w.Code
+		}
+
+		Convey("the clients should be filtered by their address", func() {
+			So(serve("10.2.0.1:1234"), ShouldEqual, http.StatusOK)
+			So(serve("[2001:db8::1]:1234"), ShouldEqual, http.StatusOK)
+			So(serve("10.1.2.3:1234"), ShouldEqual, http.StatusForbidden)
+			So(serve("192.0.2.1:1234"), ShouldEqual, http.StatusForbidden)
+			So(serve("garbage"), ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("the client address should be forwarded by trusted proxies only", func() {
+			So(serve("10.0.0.1:1234", "192.0.2.1, 10.2.0.1"), ShouldEqual, http.StatusOK)
+			So(serve("10.0.0.1:1234", "10.2.0.1, 10.1.2.3", "10.0.0.2"), ShouldEqual, http.StatusForbidden)
+			So(serve("10.3.0.1:1234", "10.1.2.3"), ShouldEqual, http.StatusOK)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			r.Header.Set("X-Forwarded-For", "10.0.0.2")
+			So(a.ClientIP(r).String(), ShouldEqual, "10.0.0.2")
+			r.Header.Set("X-Forwarded-For", "junk, 10.0.0.2")
+			So(a.ClientIP(r).String(), ShouldEqual, "10.0.0.2")
+		})
+
+		Convey("invalid networks should fail the configuration", func() {
+			a.config.Deny = []string{"10.0.0.0/33"}
+			So(a.Configure(ctx), ShouldNotBeNil)
+			a.config.Deny = nil
+			a.config.TrustedProxies = []string{"proxy"}
+			So(a.Configure(ctx), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/exitcode"
+)
+
+// HandlerFunc is an HTTP handler returning an error, rendered by the error
+// renderer.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorRenderer renders the errors returned by the handlers as user-facing
+// HTTP responses. The errors are mapped to a status, a stable code and a
+// message once, and the mapping is reused by all the handlers. The response
+// is a JSON object, e.g.
+//
+//	{"code": "not_found", "message": "the order does not exist"}
+//
+// The messages are translated to the language of the Accept-Language header
+// of the request with the messages of the "http_errors" configuration, e.g.
+//
+//	"http_errors": {"messages": {"fr": {"not_found": "la commande n'existe pas"}}}
+//
+// Errors that are not mapped are rendered as internal errors, without their
+// text, and are logged.
+type ErrorRenderer interface {
+	// Map maps the errors matching the target to the status, the code and
+	// the default message. The target is either an error value, matching
+	// the errors equal to it, a nil pointer to an error type, e.g.
+	// (*NotFoundError)(nil), matching the errors of this type, or a
+	// category of the cube exitcode package. The errors wrapped with an
+	// Unwrap() error method are matched too, the outermost error matching a
+	// target wins and the targets are tried in the order they were mapped.
+	Map(target interface{}, status int, code, message string) error
+
+	// Render writes the response of the error.
+	Render(w http.ResponseWriter, r *http.Request, err error)
+
+	// Handler returns a handler calling h and rendering its error, if any.
+	// The handler must not write the response if it returns an error.
+	Handler(h HandlerFunc) http.Handler
+}
+
+// ErrorResponse is the body of the response of an error.
+type ErrorResponse struct {
+	// Code identifies the error for the clients
+	Code string `json:"code"`
+	// Message describes the error to the user
+	Message string `json:"message"`
+}
+
+type errorRule struct {
+	value    error
+	typ      reflect.Type
+	category exitcode.Category
+	status   int
+	code     string
+	message  string
+}
+
+// matches returns true if the error matches the target of the rule.
+func (rule *errorRule) matches(err error) bool {
+	switch {
+	case rule.value != nil:
+		return err == rule.value
+	case rule.typ != nil:
+		return reflect.TypeOf(err) == rule.typ
+	}
+	e, ok := err.(*exitcode.Error)
+	return ok && e.Category == rule.category
+}
+
+type errorRenderer struct {
+	config *errorsConfig
+	ctx    component.Context
+	lock   sync.RWMutex
+	rules  []*errorRule
+}
+
+// errorsConfig defines the translations of the error messages
+type errorsConfig struct {
+	config.BaseConfig
+	// Messages by language and code, e.g. {"fr": {"not_found": "..."}}
+	Messages map[string]map[string]string `json:"messages"`
+}
+
+var internalError = ErrorResponse{Code: "internal", Message: "internal server error"}
+
+// NewErrorRenderer creates a new error renderer.
+func NewErrorRenderer(ctx component.Context) ErrorRenderer {
+	return &errorRenderer{
+		config: &errorsConfig{
+			BaseConfig: config.BaseConfig{ConfigKey: "http_errors"},
+			Messages:   map[string]map[string]string{},
+		},
+		ctx: ctx,
+	}
+}
+
+func (e *errorRenderer) Config() config.Config {
+	return e.config
+}
+
+func (e *errorRenderer) Configure(ctx component.Context) error {
+	messages := map[string]map[string]string{}
+	for lang, m := range e.config.Messages {
+		messages[strings.ToLower(lang)] = m
+	}
+	e.config.Messages = messages
+	return nil
+}
+
+func (e *errorRenderer) Map(target interface{}, status int, code, message string) error {
+	if status < 400 || status > 599 {
+		return fmt.Errorf("status %d of error %s is not an error status", status, code)
+	}
+	rule := &errorRule{status: status, code: code, message: message}
+	switch t := target.(type) {
+	case exitcode.Category:
+		rule.category = t
+	case error:
+		if v := reflect.ValueOf(t); v.Kind() == reflect.Ptr && v.IsNil() {
+			rule.typ = v.Type()
+		} else {
+			rule.value = t
+		}
+	default:
+		return fmt.Errorf("can't map errors to %T, the target must be an error or a category", target)
+	}
+	e.lock.Lock()
+	e.rules = append(e.rules, rule)
+	e.lock.Unlock()
+	return nil
+}
+
+// resolve returns the status and the response of the error.
+func (e *errorRenderer) resolve(err error) (int, ErrorResponse, bool) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+	for err != nil {
+		for _, rule := range e.rules {
+			if rule.matches(err) {
+				return rule.status, ErrorResponse{rule.code, rule.message}, true
+			}
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		err = u.Unwrap()
+	}
+	return http.StatusInternalServerError, internalError, false
+}
+
+// translate returns the message of the code in the preferred language of the
+// Accept-Language header, the default message if there is no translation.
+func (e *errorRenderer) translate(accept, code, message string) string {
+	for _, part := range strings.Split(accept, ",") {
+		lang := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+		for lang != "" {
+			if m, ok := e.config.Messages[lang][code]; ok {
+				return m
+			}
+			// Fall back from the region to the language, e.g. fr-ca to fr
+			i := strings.LastIndex(lang, "-")
+			if i < 0 {
+				break
+			}
+			lang = lang[:i]
+		}
+	}
+	return message
+}
+
+func (e *errorRenderer) Render(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp, mapped := e.resolve(err)
+	if !mapped {
+		e.ctx.Log().Error().Str("method", r.Method).Str("path", r.URL.Path).Error(err).Msg("request failed")
+	}
+	resp.Message = e.translate(r.Header.Get("Accept-Language"), resp.Code, resp.Message)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (e *errorRenderer) Handler(h HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			e.Render(w, r, err)
+		}
+	})
+}
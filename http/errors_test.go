@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/exitcode"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type notFoundError struct {
+	id string
+}
+
+func (e *notFoundError) Error() string { return "order " + e.id + " not found" }
+
+type wrappedError struct {
+	err error
+}
+
+func (e *wrappedError) Error() string { return "wrapped: " + e.err.Error() }
+func (e *wrappedError) Unwrap() error { return e.err }
+
+var errConflict = fmt.Errorf("conflict")
+
+func TestErrorRenderer(t *testing.T) {
+	Convey("After we create an error renderer", t, func() {
+		ctx := component.RootContext(zlog.New("errors.test"))
+		e := NewErrorRenderer(ctx).(*errorRenderer)
+		So(e.Config().Key(), ShouldEqual, "http_errors")
+		e.config.Messages = map[string]map[string]string{"FR": {"not_found": "la commande n'existe pas"}}
+		So(e.Configure(ctx), ShouldBeNil)
+		So(e.Map((*notFoundError)(nil), http.StatusNotFound, "not_found", "the order does not exist"), ShouldBeNil)
+		So(e.Map(errConflict, http.StatusConflict, "conflict", "the order was modified"), ShouldBeNil)
+		So(e.Map(exitcode.Config, http.StatusServiceUnavailable, "unavailable", "try again later"), ShouldBeNil)
+
+		render := func(err error, lang string) (int, ErrorResponse) {
+			h := e.Handler(func(w http.ResponseWriter, r *http.Request) error { return err })
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/orders/1", nil)
+			r.Header.Set("Accept-Language", lang)
+			h.ServeHTTP(w, r)
+			resp := ErrorResponse{}
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			return w.Code, resp
+		}
+
+		Convey("mapped errors should be rendered", func() {
+			status, resp := render(&notFoundError{"1"}, "")
+			So(status, ShouldEqual, http.StatusNotFound)
+			So(resp, ShouldResemble, ErrorResponse{"not_found", "the order does not exist"})
+
+			status, resp = render(&wrappedError{errConflict}, "")
+			So(status, ShouldEqual, http.StatusConflict)
+			So(resp.Code, ShouldEqual, "conflict")
+
+			status, resp = render(&wrappedError{exitcode.ConfigError(errConflict)}, "")
+			So(status, ShouldEqual, http.StatusServiceUnavailable)
+		})
+
+		Convey("the messages should be translated", func() {
+			_, resp := render(&notFoundError{"1"}, "de;q=0.9, fr-CA;q=0.8")
+			So(resp.Message, ShouldEqual, "la commande n'existe pas")
+			_, resp = render(errConflict, "fr")
+			So(resp.Message, ShouldEqual, "the order was modified")
+		})
+
+		Convey("other errors should be internal errors", func() {
+			status, resp := render(fmt.Errorf("secret"), "")
+			So(status, ShouldEqual, http.StatusInternalServerError)
+			So(resp, ShouldResemble, internalError)
+		})
+
+		Convey("successful handlers should not be rendered", func() {
+			h := e.Handler(func(w http.ResponseWriter, r *http.Request) error {
+				w.WriteHeader(http.StatusAccepted)
+				return nil
+			})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			So(w.Code, ShouldEqual, http.StatusAccepted)
+		})
+
+		Convey("bad mappings should be rejected", func() {
+			So(e.Map(errConflict, http.StatusOK, "ok", ""), ShouldBeError)
+			So(e.Map("conflict", http.StatusConflict, "conflict", ""), ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,158 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// SecurityHeaders sets the standard security headers on the responses, with
+// defaults passing the baseline security scans:
+//
+//	Strict-Transport-Security: max-age=31536000; includeSubDomains
+//	X-Content-Type-Options: nosniff
+//	X-Frame-Options: DENY
+//	Content-Security-Policy: default-src 'self'
+//	Referrer-Policy: strict-origin-when-cross-origin
+//
+// The headers are set before the handler is called, which can still change
+// them. They are configured by the "http_security" configuration, including
+// the overrides of the routes by path prefix, e.g.
+//
+//	"http_security": {
+//		"content_security_policy": "default-src 'self'; img-src *",
+//		"routes": {"/embed/": {"X-Frame-Options": ""}}
+//	}
+//
+// where an empty value removes the header.
+type SecurityHeaders interface {
+	// Middleware returns a handler that sets the security headers and calls
+	// h. The overrides replace the configured headers for the handler, or
+	// remove them if their value is empty, and are replaced in turn by the
+	// configured overrides of the routes.
+	Middleware(h http.Handler, overrides ...SecurityHeader) http.Handler
+}
+
+// SecurityHeader is a header overriding a security header, see
+// SecurityHeaders.
+type SecurityHeader struct {
+	Name  string
+	Value string
+}
+
+type securityHeaders struct {
+	config *securityConfig
+}
+
+// securityConfig defines the security headers of the responses
+type securityConfig struct {
+	config.BaseConfig
+	// Max age of the HSTS policy in milliseconds, no
+	// Strict-Transport-Security header if 0
+	HSTSMaxAge int64 `json:"hsts_max_age_ms"`
+	// Apply the HSTS policy to the sub-domains
+	HSTSSubdomains bool `json:"hsts_include_subdomains"`
+	// Allow the preloading of the HSTS policy by the browsers
+	HSTSPreload bool `json:"hsts_preload"`
+	// X-Frame-Options header, DENY or SAMEORIGIN, none if empty
+	FrameOptions string `json:"frame_options"`
+	// Content-Security-Policy header, none if empty
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	// Referrer-Policy header, none if empty
+	ReferrerPolicy string `json:"referrer_policy"`
+	// Headers overridden by path prefix, the longest prefix wins
+	Routes map[string]map[string]string `json:"routes"`
+}
+
+// NewSecurityHeaders creates a new security headers middleware.
+func NewSecurityHeaders(ctx component.Context) SecurityHeaders {
+	return &securityHeaders{
+		config: &securityConfig{
+			BaseConfig:            config.BaseConfig{ConfigKey: "http_security"},
+			HSTSMaxAge:            365 * 24 * 3600 * 1000,
+			HSTSSubdomains:        true,
+			FrameOptions:          "DENY",
+			ContentSecurityPolicy: "default-src 'self'",
+			ReferrerPolicy:        "strict-origin-when-cross-origin",
+			Routes:                map[string]map[string]string{},
+		},
+	}
+}
+
+func (s *securityHeaders) Config() config.Config {
+	return s.config
+}
+
+func (s *securityHeaders) Configure(ctx component.Context) error {
+	c := s.config
+	if c.HSTSMaxAge < 0 {
+		return fmt.Errorf("hsts max age must not be negative")
+	}
+	c.FrameOptions = strings.ToUpper(c.FrameOptions)
+	switch c.FrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("unknown frame options %q, must be DENY or SAMEORIGIN", c.FrameOptions)
+	}
+	for prefix := range c.Routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("security headers route %q must start with /", prefix)
+		}
+	}
+	return nil
+}
+
+// headers returns the configured headers, without the overrides.
+func (s *securityHeaders) headers() map[string]string {
+	c := s.config
+	h := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         c.FrameOptions,
+		"Content-Security-Policy": c.ContentSecurityPolicy,
+		"Referrer-Policy":         c.ReferrerPolicy,
+	}
+	if c.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", c.HSTSMaxAge/1000)
+		if c.HSTSSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if c.HSTSPreload {
+			hsts += "; preload"
+		}
+		h["Strict-Transport-Security"] = hsts
+	}
+	return h
+}
+
+// route returns the configured overrides of the longest prefix of the path.
+func (s *securityHeaders) route(path string) map[string]string {
+	var headers map[string]string
+	longest := -1
+	for prefix, h := range s.config.Routes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+			headers, longest = h, len(prefix)
+		}
+	}
+	return headers
+}
+
+func (s *securityHeaders) Middleware(h http.Handler, overrides ...SecurityHeader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := s.headers()
+		for _, o := range overrides {
+			headers[http.CanonicalHeaderKey(o.Name)] = o.Value
+		}
+		for name, v := range s.route(r.URL.Path) {
+			headers[http.CanonicalHeaderKey(name)] = v
+		}
+		for name, v := range headers {
+			if v != "" {
+				w.Header().Set(name, v)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	Convey("After we create the security headers middleware", t, func() {
+		ctx := component.RootContext(zlog.New("security.test"))
+		s := NewSecurityHeaders(ctx).(*securityHeaders)
+		So(s.Config().Key(), ShouldEqual, "http_security")
+		serve := func(h http.Handler, path string) http.Header {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			return w.Header()
+		}
+
+		Convey("the default headers should be set", func() {
+			So(s.Configure(ctx), ShouldBeNil)
+			h := serve(s.Middleware(testHandler{}), "/")
+			So(h.Get("Strict-Transport-Security"), ShouldEqual, "max-age=31536000; includeSubDomains")
+			So(h.Get("X-Content-Type-Options"), ShouldEqual, "nosniff")
+			So(h.Get("X-Frame-Options"), ShouldEqual, "DENY")
+			So(h.Get("Content-Security-Policy"), ShouldEqual, "default-src 'self'")
+			So(h.Get("Referrer-Policy"), ShouldEqual, "strict-origin-when-cross-origin")
+		})
+
+		Convey("the headers should be configured and overridden per route", func() {
+			s.config.HSTSMaxAge = 0
+			s.config.FrameOptions = "sameorigin"
+			s.config.Routes = map[string]map[string]string{
+				"/embed/":       {"x-frame-options": ""},
+				"/embed/admin/": {"X-Frame-Options": "DENY"},
+			}
+			So(s.Configure(ctx), ShouldBeNil)
+			m := s.Middleware(testHandler{}, SecurityHeader{"content-security-policy", "default-src *"}, SecurityHeader{"Referrer-Policy", ""})
+			h := serve(m, "/")
+			So(h, ShouldNotContainKey, "Strict-Transport-Security")
+			So(h, ShouldNotContainKey, "Referrer-Policy")
+			So(h.Get("X-Frame-Options"), ShouldEqual, "SAMEORIGIN")
+			So(h.Get("Content-Security-Policy"), ShouldEqual, "default-src *")
+			So(serve(m, "/embed/video"), ShouldNotContainKey, "X-Frame-Options")
+			So(serve(m, "/embed/admin/users").Get("X-Frame-Options"), ShouldEqual, "DENY")
+		})
+
+		Convey("invalid configurations should fail", func() {
+			s.config.FrameOptions = "ALLOW"
+			So(s.Configure(ctx), ShouldNotBeNil)
+			s.config.FrameOptions = "DENY"
+			s.config.Routes = map[string]map[string]string{"embed": {}}
+			So(s.Configure(ctx), ShouldNotBeNil)
+			s.config.Routes = nil
+			s.config.HSTSMaxAge = -1
+			So(s.Configure(ctx), ShouldNotBeNil)
+		})
+	})
+}
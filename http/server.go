@@ -1,13 +1,17 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/anuvu/cube/component"
 	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/proxyproto"
 )
 
 // Server is the object through which people can register HTTP servers.
@@ -15,11 +19,28 @@ type Server interface {
 	Register(string, http.Handler)
 }
 
+// BoundAddr provides the address the HTTP server is actually bound to. It
+// differs from the configured address when the port is 0, in which case the
+// server listens on an ephemeral port.
+type BoundAddr interface {
+	// Addr returns the bound address, nil until the server is started.
+	Addr() net.Addr
+
+	// Handler returns an admin handler that reports the bound address.
+	Handler() http.Handler
+}
+
 type server struct {
 	config  *configuration
 	mux     *http.ServeMux
 	server  http.Server
 	running int32
+	lock    sync.RWMutex
+	addr    net.Addr
+	// networks of the load balancers sending PROXY headers
+	proxyNets []*net.IPNet
+	// unix socket to listen on instead of the port
+	socket *socket
 }
 
 // configuration defines the configurable parameters of http server
@@ -27,13 +48,35 @@ type configuration struct {
 	config.BaseConfig
 	// Listen port
 	Port int `json:"port"`
+	// Expect a PROXY protocol header on the connections, when the server is
+	// behind an L4 load balancer
+	ProxyProtocol bool `json:"proxy_protocol"`
+	// Networks of the load balancers sending the PROXY header in CIDR
+	// notation, all if empty
+	ProxyNetworks []string `json:"proxy_networks"`
+	// Path of a unix socket to listen on instead of the port
+	Socket string `json:"socket"`
+	// Permissions of the socket in octal, e.g. "0660", as per the umask if
+	// empty
+	SocketMode string `json:"socket_mode"`
+	// Owner of the socket, a user name or id, the user of the process if
+	// empty
+	SocketOwner string `json:"socket_owner"`
+	// Group of the socket, a group name or id, the group of the process if
+	// empty
+	SocketGroup string `json:"socket_group"`
+	// Permissions in octal of the directory of the socket, which is created
+	// if it does not exist, e.g. "0750", not created if empty
+	SocketDirMode string `json:"socket_dir_mode"`
 }
 
+// proxyTimeout bounds the time to receive the PROXY header of a connection.
+const proxyTimeout = 5 * time.Second
+
 // New creates a new HTTP server
 func New(ctx component.Context) Server {
 	cfg := &configuration{
-		config.BaseConfig{ConfigKey: "http"},
-		0,
+		BaseConfig: config.BaseConfig{ConfigKey: "http"},
 	}
 	return &server{
 		config: cfg,
@@ -41,6 +84,17 @@ func New(ctx component.Context) Server {
 	}
 }
 
+// boundAddr is kept apart from the server so that the lifecycle hooks of the
+// server are not registered twice.
+type boundAddr struct {
+	s *server
+}
+
+// NewBoundAddr provides the address the server is bound to.
+func NewBoundAddr(s Server) BoundAddr {
+	return boundAddr{s.(*server)}
+}
+
 func (s *server) Register(url string, h http.Handler) {
 	s.mux.Handle(url, h)
 }
@@ -50,16 +104,35 @@ func (s *server) Config() config.Config {
 }
 
 func (s *server) Configure(ctx component.Context) error {
-	return nil
+	nets, err := parseNetworks(s.config.ProxyNetworks)
+	if err != nil {
+		return err
+	}
+	s.proxyNets = nets
+	s.socket, err = parseSocket(s.config)
+	return err
 }
 
 func (s *server) Start(ctx component.Context) error {
 	addr := fmt.Sprintf("localhost:%d", s.config.Port)
 	s.server = http.Server{Addr: addr, Handler: s.mux}
-	l, err := net.Listen("tcp", addr)
+	var l net.Listener
+	var err error
+	if s.socket != nil {
+		l, err = s.socket.listen()
+	} else {
+		l, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		return err
 	}
+	if s.config.ProxyProtocol {
+		l = proxyproto.NewListener(l, proxyTimeout, s.proxyNets...)
+	}
+	s.lock.Lock()
+	s.addr = l.Addr()
+	s.lock.Unlock()
+	ctx.Log().Info().Str("addr", l.Addr().String()).Msg("http server listening")
 	atomic.AddInt32(&s.running, 1)
 	go func() {
 		if err := s.server.Serve(l); err != nil {
@@ -71,9 +144,31 @@ func (s *server) Start(ctx component.Context) error {
 
 func (s *server) Stop(ctx component.Context) error {
 	atomic.AddInt32(&s.running, -1)
-	return s.server.Close()
+	err := s.server.Close()
+	if s.socket != nil {
+		s.socket.close()
+	}
+	return err
 }
 
 func (s *server) IsHealthy(ctx component.Context) bool {
 	return atomic.LoadInt32(&s.running) > 0
 }
+
+func (b boundAddr) Addr() net.Addr {
+	b.s.lock.RLock()
+	defer b.s.lock.RUnlock()
+	return b.s.addr
+}
+
+func (b boundAddr) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr := b.Addr()
+		if addr == nil {
+			http.Error(w, "server is not started", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"addr": addr.String()})
+	})
+}
@@ -1,9 +1,12 @@
 package http
 
 import (
+	"bufio"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/anuvu/zlog"
@@ -13,8 +16,7 @@ import (
 )
 
 const (
-	port = 8989
-	msg  = "hello"
+	msg = "hello"
 )
 
 type testHandler struct{}
@@ -35,21 +37,27 @@ func TestHTTPServer(t *testing.T) {
 		So(s.(component.StopHook), ShouldNotBeNil)
 		So(s.(component.HealthHook), ShouldNotBeNil)
 		srv := s.(*server)
-		cfg := srv.Config().(*configuration)
-		cfg.Port = port
+		addr := NewBoundAddr(s)
+		So(addr.Addr(), ShouldBeNil)
 		So(srv.Configure(ctx), ShouldBeNil)
 		So(srv.Start(ctx), ShouldBeNil)
+		So(addr.Addr(), ShouldNotBeNil)
 
 		s.Register("/foo", testHandler{})
 
 		// Write client to test the server
 		So(srv.IsHealthy(ctx), ShouldBeTrue)
-		resp, err := http.Get(fmt.Sprintf("http://localhost:%d/foo", port))
+		resp, err := http.Get(fmt.Sprintf("http://%s/foo", addr.Addr()))
 		So(err, ShouldBeNil)
 		bytes, err := ioutil.ReadAll(resp.Body)
 		So(err, ShouldBeNil)
 		So(string(bytes), ShouldEqual, string(msg))
 
+		// The admin handler should report the bound address
+		rec := httptest.NewRecorder()
+		addr.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/addr", nil))
+		So(rec.Body.String(), ShouldEqual, fmt.Sprintf("{\"addr\":%q}\n", addr.Addr()))
+
 		// Stop the group
 		So(srv.Stop(ctx), ShouldBeNil)
 		So(srv.IsHealthy(ctx), ShouldBeFalse)
@@ -64,5 +72,38 @@ func TestBadPort(t *testing.T) {
 		cfg.Port = -1
 		So(s.Configure(ctx), ShouldBeNil)
 		So(s.Start(ctx), ShouldNotBeNil)
+
+		// The bound address should not be reported before the start
+		rec := httptest.NewRecorder()
+		NewBoundAddr(s).Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/addr", nil))
+		So(rec.Code, ShouldEqual, http.StatusServiceUnavailable)
+	})
+}
+
+func TestProxyProtocol(t *testing.T) {
+	Convey("http server behind a load balancer", t, func() {
+		ctx := component.RootContext(zlog.New("http.test"))
+		s := New(ctx).(*server)
+		s.config.ProxyProtocol = true
+		s.config.ProxyNetworks = []string{"127.0.0.1"}
+		So(s.Configure(ctx), ShouldBeNil)
+		So(s.Start(ctx), ShouldBeNil)
+		defer s.Stop(ctx)
+		s.Register("/client", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.RemoteAddr))
+		}))
+
+		// The client address should be the one of the PROXY header
+		c, err := net.Dial("tcp", NewBoundAddr(s).Addr().String())
+		So(err, ShouldBeNil)
+		defer c.Close()
+		fmt.Fprint(c, "PROXY TCP4 192.0.2.1 127.0.0.1 5000 80\r\nGET /client HTTP/1.0\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		So(string(body), ShouldEqual, "192.0.2.1:5000")
+
+		s.config.ProxyNetworks = []string{"not a network"}
+		So(s.Configure(ctx), ShouldNotBeNil)
 	})
 }
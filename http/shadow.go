@@ -0,0 +1,184 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ShadowResult captures the response of a request served by the primary
+// handler or by the shadow upstream.
+type ShadowResult struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	Err    error
+}
+
+// DiffFunc is called with the results of the primary handler and the shadow
+// upstream for every mirrored request.
+type DiffFunc func(req *http.Request, primary, shadow *ShadowResult)
+
+// Shadow mirrors a percentage of requests to a secondary upstream while
+// serving them from the primary handler. Mirrored requests are sent
+// asynchronously and their responses are discarded after being passed to the
+// diff hook. Requests are dropped from mirroring when too many mirrored
+// requests are in flight.
+type Shadow struct {
+	upstream *url.URL
+	percent  float64
+	client   *http.Client
+	diff     DiffFunc
+	maxBody  int64
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	lock     sync.Mutex
+	rnd      *rand.Rand
+}
+
+// NewShadow returns a shadow middleware that mirrors percent (0-100) of the
+// requests to the upstream base URL using the client. If client is nil a
+// client with a 10 second timeout is used. The diff hook is optional.
+func NewShadow(upstream string, percent float64, client *http.Client, diff DiffFunc) (*Shadow, error) {
+	u, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Shadow{
+		upstream: u,
+		percent:  percent,
+		client:   client,
+		diff:     diff,
+		maxBody:  1 << 20,
+		slots:    make(chan struct{}, 64),
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Middleware returns a handler that serves the requests using h and mirrors
+// the sampled requests to the shadow upstream.
+func (s *Shadow) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.sample() {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		// Buffer the body so that it can be replayed to the shadow upstream.
+		var body []byte
+		if r.Body != nil {
+			orig := r.Body
+			b, err := ioutil.ReadAll(io.LimitReader(orig, s.maxBody+1))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if int64(len(b)) > s.maxBody {
+				// Too large to mirror, serve the request from the primary only.
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(b), orig), orig}
+				h.ServeHTTP(w, r)
+				return
+			}
+			orig.Close()
+			body = b
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		var primary *ShadowResult
+		if s.diff != nil {
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			h.ServeHTTP(rec, r)
+			header := http.Header{}
+			for k, v := range w.Header() {
+				header[k] = v
+			}
+			primary = &ShadowResult{Status: rec.status, Header: header, Body: rec.body.Bytes()}
+		} else {
+			h.ServeHTTP(w, r)
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			// Too many mirrored requests in flight, drop this one.
+			return
+		}
+		req := r
+		s.wg.Add(1)
+		go func() {
+			defer func() {
+				<-s.slots
+				s.wg.Done()
+			}()
+			res := s.mirror(req, body)
+			if s.diff != nil {
+				s.diff(req, primary, res)
+			}
+		}()
+	})
+}
+
+// Wait waits for the mirrored requests in flight to complete.
+func (s *Shadow) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Shadow) sample() bool {
+	if s.percent <= 0 {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.rnd.Float64()*100 < s.percent
+}
+
+func (s *Shadow) mirror(r *http.Request, body []byte) *ShadowResult {
+	u := *s.upstream
+	u.Path = strings.TrimSuffix(u.Path, "/") + r.URL.Path
+	u.RawQuery = r.URL.RawQuery
+	req, err := http.NewRequest(r.Method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return &ShadowResult{Err: err}
+	}
+	for k, v := range r.Header {
+		req.Header[k] = v
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &ShadowResult{Err: err}
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	return &ShadowResult{Status: resp.StatusCode, Header: resp.Header, Body: b, Err: err}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recorder records the status and the body written to a response writer.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestShadow(t *testing.T) {
+	Convey("After we create a shadow middleware", t, func() {
+		var lock sync.Mutex
+		mirrored := []string{}
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			lock.Lock()
+			mirrored = append(mirrored, r.Method+" "+r.URL.String()+" "+string(b))
+			lock.Unlock()
+			w.Write([]byte("shadow"))
+		}))
+		defer upstream.Close()
+
+		primary := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			w.Write(append([]byte("primary "), b...))
+		})
+
+		Convey("all requests should be mirrored at 100 percent", func() {
+			results := [][2]*ShadowResult{}
+			s, err := NewShadow(upstream.URL+"/v2/", 100, nil, func(r *http.Request, p, sh *ShadowResult) {
+				lock.Lock()
+				results = append(results, [2]*ShadowResult{p, sh})
+				lock.Unlock()
+			})
+			So(err, ShouldBeNil)
+			h := s.Middleware(primary)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("POST", "/foo?a=1", strings.NewReader("body")))
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(w.Body.String(), ShouldEqual, "primary body")
+			s.Wait()
+
+			So(mirrored, ShouldResemble, []string{"POST /v2/foo?a=1 body"})
+			So(len(results), ShouldEqual, 1)
+			So(results[0][0].Status, ShouldEqual, http.StatusCreated)
+			So(string(results[0][0].Body), ShouldEqual, "primary body")
+			So(results[0][1].Err, ShouldBeNil)
+			So(string(results[0][1].Body), ShouldEqual, "shadow")
+		})
+
+		Convey("no requests should be mirrored at 0 percent", func() {
+			s, err := NewShadow(upstream.URL, 0, nil, nil)
+			So(err, ShouldBeNil)
+			w := httptest.NewRecorder()
+			s.Middleware(primary).ServeHTTP(w, httptest.NewRequest("GET", "/foo", nil))
+			s.Wait()
+			So(w.Code, ShouldEqual, http.StatusCreated)
+			So(mirrored, ShouldBeEmpty)
+		})
+
+		Convey("large bodies should not be mirrored", func() {
+			s, err := NewShadow(upstream.URL, 100, nil, nil)
+			So(err, ShouldBeNil)
+			s.maxBody = 2
+			w := httptest.NewRecorder()
+			s.Middleware(primary).ServeHTTP(w, httptest.NewRequest("PUT", "/foo", strings.NewReader("large")))
+			s.Wait()
+			So(w.Body.String(), ShouldEqual, "primary large")
+			So(mirrored, ShouldBeEmpty)
+		})
+
+		Convey("mirroring errors should be reported to the diff hook", func() {
+			var res *ShadowResult
+			s, err := NewShadow("http://127.0.0.1:1", 100, nil, func(r *http.Request, p, sh *ShadowResult) { res = sh })
+			So(err, ShouldBeNil)
+			s.Middleware(primary).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo", nil))
+			s.Wait()
+			So(res.Err, ShouldNotBeNil)
+		})
+
+		Convey("bad upstream should fail", func() {
+			_, err := NewShadow("http://[::1", 100, nil, nil)
+			So(err, ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+)
+
+// socket is the unix socket the server listens on, with the permissions
+// and the ownership of the "http" configuration, e.g.
+//
+//	"http": {
+//		"socket": "/run/app/http.sock",
+//		"socket_mode": "0660",
+//		"socket_group": "sidecar",
+//		"socket_dir_mode": "0750"
+//	}
+//
+// so that the clients running with other users can connect to it.
+type socket struct {
+	path string
+	// mode of the socket, the umask applies if 0
+	mode os.FileMode
+	// owner and group of the socket, unchanged if -1
+	uid int
+	gid int
+	// mode of the directory created for the socket, none created if 0
+	dirMode os.FileMode
+	// directory created by listen, removed by close
+	created string
+}
+
+// parseSocket returns the socket of the configuration, nil if the server
+// listens on a port.
+func parseSocket(c *configuration) (*socket, error) {
+	if c.Socket == "" {
+		return nil, nil
+	}
+	s := &socket{path: c.Socket, uid: -1, gid: -1}
+	var err error
+	if s.mode, err = parseMode(c.SocketMode); err != nil {
+		return nil, err
+	}
+	if s.dirMode, err = parseMode(c.SocketDirMode); err != nil {
+		return nil, err
+	}
+	if c.SocketOwner != "" {
+		if s.uid, err = lookupID(c.SocketOwner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if c.SocketGroup != "" {
+		if s.gid, err = lookupID(c.SocketGroup, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseMode parses a file mode in octal, 0 if empty.
+func parseMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || m > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q, must be octal permissions", s)
+	}
+	return os.FileMode(m), nil
+}
+
+// lookupID returns the numeric id, or the id of the name found by lookup.
+func lookupID(s string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		return id, nil
+	}
+	id, err := lookup(s)
+	if err != nil {
+		return -1, err
+	}
+	return strconv.Atoi(id)
+}
+
+// listen creates the directory of the socket if configured, removes a stale
+// socket and listens on the socket with the configured permissions.
+func (s *socket) listen() (net.Listener, error) {
+	dir := filepath.Dir(s.path)
+	if s.dirMode != 0 {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if err := os.MkdirAll(dir, s.dirMode); err != nil {
+				return nil, err
+			}
+			// MkdirAll applies the umask
+			if err := os.Chmod(dir, s.dirMode); err != nil {
+				return nil, err
+			}
+			s.created = dir
+		}
+	}
+	// A socket left by a crashed process prevents the listen
+	if fi, err := os.Lstat(s.path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(s.path); err != nil {
+			return nil, err
+		}
+	}
+	l, err := net.Listen("unix", s.path)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	if err := s.setup(); err != nil {
+		l.Close()
+		s.close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// setup applies the permissions and the ownership to the socket and to the
+// directory created for it.
+func (s *socket) setup() error {
+	if s.mode != 0 {
+		if err := os.Chmod(s.path, s.mode); err != nil {
+			return err
+		}
+	}
+	if s.uid == -1 && s.gid == -1 {
+		return nil
+	}
+	if err := os.Chown(s.path, s.uid, s.gid); err != nil {
+		return err
+	}
+	if s.created != "" {
+		return os.Chown(s.created, s.uid, s.gid)
+	}
+	return nil
+}
+
+// close removes the socket, and its directory if it was created by listen.
+func (s *socket) close() {
+	os.Remove(s.path)
+	if s.created != "" {
+		os.Remove(s.created)
+		s.created = ""
+	}
+}
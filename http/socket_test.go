@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/anuvu/zlog"
+
+	"github.com/anuvu/cube/component"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnixSocket(t *testing.T) {
+	Convey("http server listening on a unix socket", t, func() {
+		tmp, err := ioutil.TempDir("", "socket")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(tmp)
+		dir := filepath.Join(tmp, "run")
+		path := filepath.Join(dir, "http.sock")
+
+		ctx := component.RootContext(zlog.New("http.test"))
+		s := New(ctx).(*server)
+		s.config.Socket = path
+		s.config.SocketMode = "0660"
+		s.config.SocketGroup = strconv.Itoa(os.Getgid())
+		s.config.SocketDirMode = "0750"
+		So(s.Configure(ctx), ShouldBeNil)
+		So(s.Start(ctx), ShouldBeNil)
+		s.Register("/foo", testHandler{})
+
+		// The socket and its directory should have the configured permissions
+		fi, err := os.Stat(path)
+		So(err, ShouldBeNil)
+		So(fi.Mode()&os.ModeSocket, ShouldNotEqual, 0)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0660))
+		fi, err = os.Stat(dir)
+		So(err, ShouldBeNil)
+		So(fi.Mode().Perm(), ShouldEqual, os.FileMode(0750))
+
+		client := &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return net.Dial("unix", path)
+			},
+		}}
+		resp, err := client.Get("http://unix/foo")
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		So(string(body), ShouldEqual, msg)
+
+		// The socket and the created directory should be removed
+		So(s.Stop(ctx), ShouldBeNil)
+		_, err = os.Stat(dir)
+		So(os.IsNotExist(err), ShouldBeTrue)
+
+		Convey("a stale socket should be replaced", func() {
+			So(os.MkdirAll(dir, 0700), ShouldBeNil)
+			l, err := net.Listen("unix", path)
+			So(err, ShouldBeNil)
+			// Keep the socket file once the listener is closed
+			l.(*net.UnixListener).SetUnlinkOnClose(false)
+			l.Close()
+			s := New(ctx).(*server)
+			s.config.Socket = path
+			So(s.Configure(ctx), ShouldBeNil)
+			So(s.Start(ctx), ShouldBeNil)
+			So(s.Stop(ctx), ShouldBeNil)
+			_, err = os.Stat(path)
+			So(os.IsNotExist(err), ShouldBeTrue)
+			// The directory existed before the start
+			_, err = os.Stat(dir)
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("invalid socket settings should fail the configuration", t, func() {
+		ctx := component.RootContext(zlog.New("http.test"))
+		s := New(ctx).(*server)
+		s.config.Socket = "/tmp/http.sock"
+		s.config.SocketMode = "rw-rw----"
+		So(s.Configure(ctx), ShouldNotBeNil)
+		s.config.SocketMode = ""
+		s.config.SocketOwner = "no-such-user-for-cube"
+		So(s.Configure(ctx), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,195 @@
+// Package maintenance provides a component that puts the server in maintenance
+// mode without stopping its components. In maintenance mode the server reports
+// itself not ready and the HTTP middleware rejects requests with 503.
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Gate toggles the maintenance mode of the server.
+type Gate interface {
+	// Enable puts the server in maintenance mode.
+	Enable(reason string)
+
+	// Disable takes the server out of maintenance mode.
+	Disable()
+
+	// Enabled returns true and the reason if the server is in maintenance mode.
+	Enabled() (bool, string)
+
+	// Middleware returns a handler that rejects the requests with 503 and a
+	// Retry-After header while in maintenance mode, else calls h.
+	Middleware(h http.Handler) http.Handler
+
+	// Handler returns an admin handler that reports the maintenance mode on
+	// GET, enables it on POST and disables it on DELETE.
+	Handler() http.Handler
+}
+
+type gate struct {
+	config   *configuration
+	ctx      component.Context
+	lock     sync.RWMutex
+	enabled  bool
+	reason   string
+	sentinel bool
+}
+
+// configuration defines the configurable parameters of the maintenance gate
+type configuration struct {
+	config.BaseConfig
+	// Enable maintenance mode while this file exists
+	Sentinel string `json:"sentinel"`
+	// Interval to check for the sentinel file in milliseconds
+	Interval int `json:"interval_ms"`
+	// Retry-After header value in seconds
+	RetryAfter int `json:"retry_after"`
+}
+
+// New creates a new maintenance gate.
+func New(ctx component.Context) Gate {
+	return &gate{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "maintenance"},
+			Interval:   1000,
+			RetryAfter: 30,
+		},
+		ctx: ctx,
+	}
+}
+
+func (g *gate) Config() config.Config {
+	return g.config
+}
+
+func (g *gate) Configure(ctx component.Context) error {
+	if g.config.Interval <= 0 {
+		g.config.Interval = 1000
+	}
+	return nil
+}
+
+func (g *gate) Start(ctx component.Context) error {
+	if g.config.Sentinel == "" {
+		return nil
+	}
+	g.checkSentinel()
+	component.Go(ctx, func(ctx component.Context) error {
+		t := time.NewTicker(time.Duration(g.config.Interval) * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Ctx().Done():
+				return nil
+			case <-t.C:
+				g.checkSentinel()
+			}
+		}
+	})
+	return nil
+}
+
+// IsReady returns false in maintenance mode.
+func (g *gate) IsReady(ctx component.Context) bool {
+	enabled, _ := g.Enabled()
+	return !enabled
+}
+
+// checkSentinel toggles the maintenance mode when the sentinel file appears
+// or disappears. Maintenance mode enabled by other means is left alone.
+func (g *gate) checkSentinel() {
+	_, err := os.Stat(g.config.Sentinel)
+	exists := err == nil
+
+	g.lock.RLock()
+	changed := exists != g.sentinel
+	g.lock.RUnlock()
+	if !changed {
+		return
+	}
+
+	if exists {
+		g.Enable("sentinel " + g.config.Sentinel)
+		g.lock.Lock()
+		g.sentinel = true
+		g.lock.Unlock()
+	} else {
+		g.lock.Lock()
+		g.sentinel = false
+		g.lock.Unlock()
+		g.Disable()
+	}
+}
+
+func (g *gate) Enable(reason string) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.enabled {
+		return
+	}
+	g.enabled = true
+	g.reason = reason
+	g.ctx.Log().Warn().Str("reason", reason).Msg("*** server entered maintenance mode ***")
+}
+
+func (g *gate) Disable() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if !g.enabled {
+		return
+	}
+	g.enabled = false
+	g.reason = ""
+	g.ctx.Log().Warn().Msg("*** server left maintenance mode ***")
+}
+
+func (g *gate) Enabled() (bool, string) {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.enabled, g.reason
+}
+
+func (g *gate) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enabled, _ := g.Enabled(); enabled {
+			w.Header().Set("Retry-After", strconv.Itoa(g.config.RetryAfter))
+			http.Error(w, "server is in maintenance mode", http.StatusServiceUnavailable)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (g *gate) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			reason := r.URL.Query().Get("reason")
+			if reason == "" {
+				reason = "admin"
+			}
+			g.Enable(reason)
+		case http.MethodDelete:
+			g.Disable()
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		enabled, reason := g.Enabled()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Enabled bool   `json:"enabled"`
+			Reason  string `json:"reason"`
+		}{enabled, reason})
+	})
+}
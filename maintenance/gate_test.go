@@ -0,0 +1,98 @@
+package maintenance
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGate(t *testing.T) {
+	Convey("After we create a maintenance gate", t, func() {
+		ctx := component.RootContext(zlog.New("maintenance.test"))
+		g := New(ctx).(*gate)
+		So(g.Config().Key(), ShouldEqual, "maintenance")
+		So(g.Configure(ctx), ShouldBeNil)
+		ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+		h := g.Middleware(ok)
+
+		Convey("requests should be served when disabled", func() {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			So(w.Code, ShouldEqual, http.StatusOK)
+			So(g.IsReady(ctx), ShouldBeTrue)
+		})
+
+		Convey("requests should be rejected when enabled", func() {
+			g.Enable("test")
+			g.Enable("again")
+			enabled, reason := g.Enabled()
+			So(enabled, ShouldBeTrue)
+			So(reason, ShouldEqual, "test")
+			So(g.IsReady(ctx), ShouldBeFalse)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+			So(w.Header().Get("Retry-After"), ShouldEqual, "30")
+
+			g.Disable()
+			g.Disable()
+			So(g.IsReady(ctx), ShouldBeTrue)
+		})
+
+		Convey("the admin handler should toggle the mode", func() {
+			admin := g.Handler()
+			w := httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest("POST", "/maintenance?reason=deploy", nil))
+			So(w.Body.String(), ShouldEqual, "{\"enabled\":true,\"reason\":\"deploy\"}\n")
+
+			w = httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest("GET", "/maintenance", nil))
+			So(w.Body.String(), ShouldContainSubstring, `"enabled":true`)
+
+			w = httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest("DELETE", "/maintenance", nil))
+			So(w.Body.String(), ShouldContainSubstring, `"enabled":false`)
+
+			w = httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest("POST", "/maintenance", nil))
+			So(w.Body.String(), ShouldContainSubstring, `"reason":"admin"`)
+
+			w = httptest.NewRecorder()
+			admin.ServeHTTP(w, httptest.NewRequest("PUT", "/maintenance", nil))
+			So(w.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		})
+
+		Convey("the sentinel file should toggle the mode", func() {
+			dir, err := ioutil.TempDir("", "maintenance")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			g.config.Sentinel = filepath.Join(dir, "down")
+			g.config.Interval = 1
+
+			So(g.Start(ctx), ShouldBeNil)
+			So(g.IsReady(ctx), ShouldBeTrue)
+			So(ioutil.WriteFile(g.config.Sentinel, nil, 0644), ShouldBeNil)
+			for g.IsReady(ctx) {
+				time.Sleep(time.Millisecond)
+			}
+			So(os.Remove(g.config.Sentinel), ShouldBeNil)
+			for !g.IsReady(ctx) {
+				time.Sleep(time.Millisecond)
+			}
+			ctx.(interface{ Shutdown() }).Shutdown()
+		})
+
+		Convey("no sentinel should not start a watcher", func() {
+			So(g.Start(ctx), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,185 @@
+// Package manifest assembles a server from a declarative manifest listing the
+// modules to enable and their settings. A binary registers all the modules it
+// ships in a registry, and each deployment enables the ones it needs without
+// recompiling, e.g.
+//
+//	{
+//		"modules": [
+//			{"name": "http"},
+//			{"name": "probes", "group": "probes", "settings": {"verbose": true}},
+//			{"name": "notify", "enabled": false}
+//		]
+//	}
+//
+// Manifests are JSON documents, YAML is not supported.
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/anuvu/cube/component"
+)
+
+// ManifestEnv is the environment variable giving the path of the manifest
+// read by Init when no path is given.
+const ManifestEnv = "CUBE_MANIFEST"
+
+// Module installs a bundle of components into the group. The settings are the
+// raw JSON settings of the module in the manifest, nil if there are none.
+type Module func(g component.Group, settings json.RawMessage) error
+
+// Manifest lists the modules to install.
+type Manifest struct {
+	Modules []Entry `json:"modules"`
+}
+
+// Entry enables a module in the manifest.
+type Entry struct {
+	// Name of the module in the registry
+	Name string `json:"name"`
+	// Enabled toggles the module, modules are enabled by default
+	Enabled *bool `json:"enabled"`
+	// Group is the name of the sub-group the module is installed into, the
+	// module is installed into the server group if empty
+	Group string `json:"group"`
+	// Settings of the module
+	Settings json.RawMessage `json:"settings"`
+}
+
+// IsEnabled checks if the module is enabled.
+func (e *Entry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// Read reads the manifest from r.
+func Read(r io.Reader) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return m, nil
+}
+
+// ReadFile reads the manifest from the file.
+func ReadFile(name string) (*Manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Registry is a registry of the known modules.
+type Registry struct {
+	lock    sync.RWMutex
+	modules map[string]Module
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{modules: map[string]Module{}}
+}
+
+// Register registers the module under the name. It returns an error if the
+// name is already registered.
+func (r *Registry) Register(name string, m Module) error {
+	if name == "" || m == nil {
+		return fmt.Errorf("module must have a name and an install function")
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.modules[name]; ok {
+		return fmt.Errorf("module %s is already registered", name)
+	}
+	r.modules[name] = m
+	return nil
+}
+
+// Modules returns the sorted names of the registered modules.
+func (r *Registry) Modules() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.names()
+}
+
+// Install installs the enabled modules of the manifest into the group in the
+// order of the manifest. The manifest is checked before any module is
+// installed, it is an error to enable an unknown module or to enable a
+// module twice.
+func (r *Registry) Install(g component.Group, m *Manifest) error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	enabled := map[string]bool{}
+	for _, e := range m.Modules {
+		if !e.IsEnabled() {
+			continue
+		}
+		if _, ok := r.modules[e.Name]; !ok {
+			return fmt.Errorf("unknown module %q, known modules are %s", e.Name, strings.Join(r.names(), ", "))
+		}
+		if enabled[e.Name] {
+			return fmt.Errorf("module %s is enabled twice", e.Name)
+		}
+		enabled[e.Name] = true
+	}
+
+	for _, e := range m.Modules {
+		if !e.IsEnabled() {
+			continue
+		}
+		grp := g
+		if e.Group != "" {
+			s, ok := g.(component.Supervisor)
+			if !ok {
+				return fmt.Errorf("module %s: group %T does not support sub-groups", e.Name, g)
+			}
+			var err error
+			if grp, err = s.NewE(e.Group); err != nil {
+				return fmt.Errorf("module %s: %v", e.Name, err)
+			}
+		}
+		if err := r.modules[e.Name](grp, e.Settings); err != nil {
+			return fmt.Errorf("module %s: %v", e.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.modules))
+	for n := range r.modules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Init returns a server initialization function, see cube.ServerInit, that
+// installs the modules of the manifest file into the server group. The path
+// of the manifest is read from the CUBE_MANIFEST environment variable if
+// empty, e.g.
+//
+//	cube.Main(manifest.Init(registry, ""))
+func Init(r *Registry, path string) func(g component.Group) error {
+	return func(g component.Group) error {
+		name := path
+		if name == "" {
+			name = os.Getenv(ManifestEnv)
+		}
+		if name == "" {
+			return fmt.Errorf("no manifest given, set %s", ManifestEnv)
+		}
+		m, err := ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return r.Install(g, m)
+	}
+}
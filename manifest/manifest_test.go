@@ -0,0 +1,85 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type moduleA struct{ verbose bool }
+
+type moduleB struct{}
+
+func TestManifest(t *testing.T) {
+	Convey("After we register modules", t, func() {
+		r := NewRegistry()
+		var a *moduleA
+		So(r.Register("a", func(g component.Group, settings json.RawMessage) error {
+			s := struct {
+				Verbose bool `json:"verbose"`
+			}{}
+			if settings != nil {
+				if err := json.Unmarshal(settings, &s); err != nil {
+					return err
+				}
+			}
+			a = &moduleA{s.Verbose}
+			return g.Add(func() *moduleA { return a })
+		}), ShouldBeNil)
+		So(r.Register("b", func(g component.Group, settings json.RawMessage) error {
+			return g.Add(func() *moduleB { return &moduleB{} })
+		}), ShouldBeNil)
+		So(r.Register("fails", func(g component.Group, settings json.RawMessage) error {
+			return fmt.Errorf("oops")
+		}), ShouldBeNil)
+		So(r.Register("a", nil), ShouldBeError)
+		So(r.Register("a", func(component.Group, json.RawMessage) error { return nil }), ShouldBeError)
+		So(r.Modules(), ShouldResemble, []string{"a", "b", "fails"})
+		grp := component.New("srv")
+
+		Convey("the enabled modules of the manifest should be installed", func() {
+			m, err := Read(strings.NewReader(`{"modules": [
+				{"name": "a", "group": "sub", "settings": {"verbose": true}},
+				{"name": "b", "enabled": false},
+				{"name": "fails", "enabled": false}
+			]}`))
+			So(err, ShouldBeNil)
+			So(r.Install(grp, m), ShouldBeNil)
+			So(grp.Create(), ShouldBeNil)
+			So(a.verbose, ShouldBeTrue)
+			So(grp.Invoke(func(*moduleB) {}), ShouldBeError)
+			So(grp.(component.Inspector).GraphDOT(), ShouldNotContainSubstring, "moduleA")
+		})
+
+		Convey("bad manifests should be rejected", func() {
+			_, err := Read(strings.NewReader(`{"modules": {}}`))
+			So(err, ShouldBeError)
+			So(r.Install(grp, &Manifest{Modules: []Entry{{Name: "c"}}}), ShouldBeError, `unknown module "c", known modules are a, b, fails`)
+			So(r.Install(grp, &Manifest{Modules: []Entry{{Name: "b"}, {Name: "b"}}}), ShouldBeError)
+			So(r.Install(grp, &Manifest{Modules: []Entry{{Name: "fails"}}}), ShouldBeError, "module fails: oops")
+		})
+
+		Convey("the manifest file should be read by Init", func() {
+			f, err := ioutil.TempFile("", "manifest")
+			So(err, ShouldBeNil)
+			defer os.Remove(f.Name())
+			f.WriteString(`{"modules": [{"name": "b"}]}`)
+			f.Close()
+
+			So(Init(r, f.Name())(grp), ShouldBeNil)
+			So(grp.Create(), ShouldBeNil)
+			So(grp.Invoke(func(*moduleB) {}), ShouldBeNil)
+
+			old := os.Getenv(ManifestEnv)
+			defer os.Setenv(ManifestEnv, old)
+			os.Setenv(ManifestEnv, "")
+			So(Init(r, "")(component.New("other")), ShouldBeError)
+		})
+	})
+}
@@ -0,0 +1,294 @@
+// Package notify provides a component that posts JSON notifications of the
+// lifecycle events of the server to a webhook, e.g. a Slack incoming webhook.
+// Start completion and failures, health transitions and shutdowns are
+// notified with a templated message, rate limited and retried on failure.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Notifier posts notifications to the webhook.
+type Notifier interface {
+	// Notify posts a notification of the event if its type is selected in
+	// the configuration. Components can notify events of their own.
+	Notify(e component.Event)
+}
+
+// DefaultTemplate is the default template of the notification messages.
+const DefaultTemplate = `[{{.Group}}] {{.Type}}{{with .Component}} {{.}}{{end}}` +
+	`{{if eq .Type "health_changed"}} healthy={{.Healthy}}{{end}}{{with .Error}}: {{.}}{{end}}`
+
+// DefaultEvents are the types of the events notified if none are configured.
+// The other events, e.g. the panics of the goroutines of the components, are
+// only notified if configured.
+var DefaultEvents = []component.EventType{
+	component.EventStarted,
+	component.EventStartFailed,
+	component.EventHealthChanged,
+	component.EventStopping,
+	component.EventStopped,
+}
+
+type notifier struct {
+	config *configuration
+	ctx    component.Context
+	tmpl   *template.Template
+	client *http.Client
+	lock   sync.Mutex
+	sent   []time.Time
+	// queue of the notifications, nil once the server is stopped
+	queue   chan []byte
+	done    chan struct{}
+	started bool
+}
+
+// configuration defines the configurable parameters of the notifier.
+type configuration struct {
+	config.BaseConfig
+	// URL of the webhook, notifications are disabled if empty
+	URL string `json:"url"`
+	// Format of the payload, "json" or "slack"
+	Format string `json:"format"`
+	// Types of the events to notify, the lifecycle and failure events of
+	// DefaultEvents if empty
+	Events []component.EventType `json:"events"`
+	// Template of the message using the fields of the payload
+	Template string `json:"template"`
+	// Maximum number of notifications per minute, unlimited if 0
+	Rate int `json:"rate_per_minute"`
+	// Number of retries of a failed notification
+	Retries int `json:"retries"`
+	// Delay before the first retry in milliseconds, doubled on each retry
+	RetryDelay int `json:"retry_delay_ms"`
+	// Timeout of a notification in milliseconds
+	Timeout int `json:"timeout_ms"`
+}
+
+// payload is the JSON notification of an event in the "json" format.
+type payload struct {
+	Type      component.EventType `json:"type"`
+	Group     string              `json:"group"`
+	Component string              `json:"component,omitempty"`
+	Healthy   bool                `json:"healthy"`
+	Error     string              `json:"error,omitempty"`
+	Time      time.Time           `json:"time"`
+	RunID     string              `json:"run_id,omitempty"`
+	Text      string              `json:"text"`
+}
+
+// New creates a new webhook notifier.
+func New(ctx component.Context) Notifier {
+	return &notifier{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "notify"},
+			Format:     "json",
+			Template:   DefaultTemplate,
+			Rate:       30,
+			Retries:    3,
+			RetryDelay: 1000,
+			Timeout:    5000,
+		},
+		ctx:   ctx,
+		queue: make(chan []byte, 64),
+		done:  make(chan struct{}),
+	}
+}
+
+func (n *notifier) Config() config.Config {
+	return n.config
+}
+
+func (n *notifier) Configure(ctx component.Context) error {
+	if n.config.Format != "json" && n.config.Format != "slack" {
+		return fmt.Errorf("unknown notification format %q", n.config.Format)
+	}
+	tmpl, err := template.New("notify").Parse(n.config.Template)
+	if err != nil {
+		return err
+	}
+	n.tmpl = tmpl
+	n.client = &http.Client{Timeout: time.Duration(n.config.Timeout) * time.Millisecond}
+	return nil
+}
+
+// Start starts posting the notifications in the background. Notifications
+// are never posted by the caller of Notify, the notifications made before the
+// start are queued and the notifications made after the stop are dropped.
+func (n *notifier) Start(ctx component.Context) error {
+	if n.config.URL == "" {
+		return nil
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.started || n.queue == nil {
+		return nil
+	}
+	n.started = true
+	go n.run(n.queue, n.done)
+	return nil
+}
+
+// OnEvent notifies the lifecycle events of the server.
+func (n *notifier) OnEvent(ctx component.Context, e component.Event) {
+	n.Notify(e)
+}
+
+func (n *notifier) Notify(e component.Event) {
+	if n.config.URL == "" || n.tmpl == nil {
+		return
+	}
+	if n.selected(e.Type) {
+		n.enqueue(e)
+	}
+	if e.Type == component.EventStopped {
+		// The server is done, flush the queue before the process exits
+		n.flush()
+	}
+}
+
+// enqueue queues the notification of the event.
+func (n *notifier) enqueue(e component.Event) {
+	body, err := n.payload(e)
+	if err != nil {
+		n.ctx.Log().Error().Error(err).Msg("failed to render the notification")
+		return
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.queue == nil {
+		return
+	}
+	if !n.allow(time.Now()) {
+		n.ctx.Log().Warn().Str("event", string(e.Type)).Msg("notification rate exceeded")
+		return
+	}
+	select {
+	case n.queue <- body:
+	default:
+		n.ctx.Log().Warn().Str("event", string(e.Type)).Msg("notification queue is full")
+	}
+}
+
+// flush closes the queue and waits for the queued notifications to be posted
+// if the notifier is started.
+func (n *notifier) flush() {
+	n.lock.Lock()
+	if n.queue != nil {
+		close(n.queue)
+		n.queue = nil
+	}
+	started, done := n.started, n.done
+	n.lock.Unlock()
+	if started {
+		<-done
+	}
+}
+
+// run posts the queued notifications until the queue is closed.
+func (n *notifier) run(queue chan []byte, done chan struct{}) {
+	defer close(done)
+	for body := range queue {
+		n.post(body)
+	}
+}
+
+// selected checks if the event type is selected in the configuration.
+func (n *notifier) selected(t component.EventType) bool {
+	events := n.config.Events
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	for _, s := range events {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// allow checks if a notification can be sent at the time without exceeding
+// the rate, and records it if so.
+func (n *notifier) allow(now time.Time) bool {
+	if n.config.Rate <= 0 {
+		return true
+	}
+	i := 0
+	for i < len(n.sent) && now.Sub(n.sent[i]) >= time.Minute {
+		i++
+	}
+	n.sent = n.sent[i:]
+	if len(n.sent) >= n.config.Rate {
+		return false
+	}
+	n.sent = append(n.sent, now)
+	return true
+}
+
+// payload renders the notification of the event.
+func (n *notifier) payload(e component.Event) ([]byte, error) {
+	p := payload{
+		Type:      e.Type,
+		Group:     e.Group,
+		Component: e.Component,
+		Healthy:   e.Healthy,
+		Time:      e.Time,
+		RunID:     e.RunID,
+	}
+	if e.Err != nil {
+		p.Error = e.Err.Error()
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	if p.RunID == "" {
+		p.RunID = component.RunID(n.ctx)
+	}
+	buf := &bytes.Buffer{}
+	if err := n.tmpl.Execute(buf, p); err != nil {
+		return nil, err
+	}
+	p.Text = buf.String()
+	if n.config.Format == "slack" {
+		return json.Marshal(map[string]string{"text": p.Text})
+	}
+	return json.Marshal(p)
+}
+
+// post posts the notification to the webhook, retrying on failures.
+func (n *notifier) post(body []byte) {
+	delay := time.Duration(n.config.RetryDelay) * time.Millisecond
+	var err error
+	for i := 0; i <= n.config.Retries; i++ {
+		if i > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = n.send(body); err == nil {
+			return
+		}
+	}
+	n.ctx.Log().Error().Error(err).Msg("failed to post the notification")
+}
+
+func (n *notifier) send(body []byte) error {
+	resp, err := n.client.Post(n.config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
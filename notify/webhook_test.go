@@ -0,0 +1,160 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type hook struct {
+	lock     sync.Mutex
+	bodies   []map[string]interface{}
+	failures int
+}
+
+func (h *hook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failures > 0 {
+		h.failures--
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	body := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&body)
+	h.bodies = append(h.bodies, body)
+}
+
+func (h *hook) texts() []interface{} {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	texts := []interface{}{}
+	for _, b := range h.bodies {
+		texts = append(texts, b["text"])
+	}
+	return texts
+}
+
+func TestNotifier(t *testing.T) {
+	Convey("After we create a notifier", t, func() {
+		h := &hook{}
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+		ctx := component.RootContext(zlog.New("notify.test"))
+		n := New(ctx).(*notifier)
+		So(n.Config().Key(), ShouldEqual, "notify")
+		n.config.URL = srv.URL
+		n.config.RetryDelay = 1
+
+		Convey("bad configuration should be rejected", func() {
+			n.config.Format = "xml"
+			So(n.Configure(ctx), ShouldBeError)
+			n.config.Format = "json"
+			n.config.Template = "{{.Type"
+			So(n.Configure(ctx), ShouldBeError)
+		})
+
+		Convey("the lifecycle events should be posted", func() {
+			So(n.Configure(ctx), ShouldBeNil)
+			n.OnEvent(ctx, component.Event{Type: component.EventStartFailed, Group: "srv/http", Component: "*http.server", Err: fmt.Errorf("bind failed")})
+			// Notifications before the start are queued
+			So(h.texts(), ShouldBeEmpty)
+			So(n.Start(ctx), ShouldBeNil)
+			n.OnEvent(ctx, component.Event{Type: component.EventHealthChanged, Group: "srv"})
+			n.OnEvent(ctx, component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.texts(), ShouldResemble, []interface{}{
+				"[srv/http] start_failed *http.server: bind failed",
+				"[srv] health_changed healthy=false",
+				"[srv] stopped",
+			})
+			So(h.bodies[0]["error"], ShouldEqual, "bind failed")
+			So(h.bodies[0]["component"], ShouldEqual, "*http.server")
+			So(h.bodies[0]["run_id"], ShouldEqual, component.RunID(ctx))
+
+			// Notifications after the stop are dropped
+			n.Notify(component.Event{Type: component.EventStarted, Group: "srv"})
+			So(h.texts(), ShouldHaveLength, 3)
+		})
+
+		Convey("only the lifecycle and failure events should be posted by default", func() {
+			So(n.Configure(ctx), ShouldBeNil)
+			So(n.Start(ctx), ShouldBeNil)
+			n.OnEvent(ctx, component.Event{Type: component.EventConfigFetched, Group: "srv", Key: "http"})
+			n.OnEvent(ctx, component.Event{Type: component.EventStarted, Group: "srv"})
+			n.OnEvent(ctx, component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.texts(), ShouldResemble, []interface{}{"[srv] started", "[srv] stopped"})
+		})
+
+		Convey("the panics should only be posted if selected, in the background", func() {
+			release := make(chan struct{})
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-release
+				h.ServeHTTP(w, r)
+			}))
+			defer slow.Close()
+			n.config.URL = slow.URL
+			So(n.Configure(ctx), ShouldBeNil)
+			So(n.Start(ctx), ShouldBeNil)
+			n.OnEvent(ctx, component.Event{Type: component.EventPanicked, Group: "srv"})
+			n.config.Events = []component.EventType{component.EventPanicked}
+			notified := make(chan struct{})
+			go func() {
+				n.OnEvent(ctx, component.Event{Type: component.EventPanicked, Group: "srv", Err: fmt.Errorf("panic: boom")})
+				close(notified)
+			}()
+			returned := false
+			select {
+			case <-notified:
+				returned = true
+			case <-time.After(time.Second):
+			}
+			So(returned, ShouldBeTrue)
+			So(h.texts(), ShouldBeEmpty)
+			close(release)
+			n.OnEvent(ctx, component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.texts(), ShouldResemble, []interface{}{"[srv] panicked: panic: boom"})
+		})
+
+		Convey("the events should be filtered and rate limited", func() {
+			n.config.Events = []component.EventType{component.EventStarted}
+			n.config.Rate = 1
+			n.config.Format = "slack"
+			So(n.Configure(ctx), ShouldBeNil)
+			So(n.Start(ctx), ShouldBeNil)
+			n.Notify(component.Event{Type: component.EventStopping, Group: "srv"})
+			n.Notify(component.Event{Type: component.EventStarted, Group: "srv"})
+			n.Notify(component.Event{Type: component.EventStarted, Group: "srv"})
+			n.Notify(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.texts(), ShouldResemble, []interface{}{"[srv] started"})
+			So(h.bodies[0], ShouldHaveLength, 1)
+		})
+
+		Convey("failed notifications should be retried", func() {
+			So(n.Configure(ctx), ShouldBeNil)
+			So(n.Start(ctx), ShouldBeNil)
+			// The first notification fails all its attempts, the second
+			// one succeeds on its third attempt
+			h.failures = 6
+			n.Notify(component.Event{Type: component.EventStarted, Group: "srv"})
+			n.Notify(component.Event{Type: component.EventStopping, Group: "srv"})
+			n.Notify(component.Event{Type: component.EventStopped, Group: "srv"})
+			So(h.texts(), ShouldResemble, []interface{}{"[srv] stopping", "[srv] stopped"})
+		})
+
+		Convey("notifications should be disabled without a URL", func() {
+			n.config.URL = ""
+			So(n.Configure(ctx), ShouldBeNil)
+			So(n.Start(ctx), ShouldBeNil)
+			n.Notify(component.Event{Type: component.EventStarted, Group: "srv"})
+			So(h.texts(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,214 @@
+// Package overload provides a component protecting the server from
+// overload. The HTTP middleware bounds the number of requests in flight and
+// of the requests queued for a slot, and sheds the other ones with a fast 503
+// and a Retry-After header, instead of letting the latency and the memory of
+// the process grow until it collapses. While it sheds load the server
+// reports itself not ready, and it recovers automatically once the load
+// decreases.
+package overload
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Stats are the counters of the overload protection.
+type Stats struct {
+	// InFlight is the number of requests being served
+	InFlight int64 `json:"in_flight"`
+	// Queued is the number of requests waiting for a slot
+	Queued int64 `json:"queued"`
+	// Served is the number of requests served since the start
+	Served int64 `json:"served"`
+	// Shed is the number of requests rejected since the start
+	Shed int64 `json:"shed"`
+	// Overloaded is true while the server sheds load
+	Overloaded bool `json:"overloaded"`
+}
+
+// Protector sheds the requests exceeding the capacity of the server.
+type Protector interface {
+	// Middleware returns a handler that calls h if a slot is available
+	// before the queue timeout, else rejects the request with 503 and a
+	// Retry-After header.
+	Middleware(h http.Handler) http.Handler
+
+	// Stats returns the counters of the overload protection.
+	Stats() Stats
+
+	// Handler returns an admin handler that reports the counters.
+	Handler() http.Handler
+}
+
+type protector struct {
+	config     *configuration
+	ctx        component.Context
+	slots      chan struct{}
+	inFlight   int64
+	queued     int64
+	served     int64
+	shed       int64
+	lock       sync.Mutex
+	lastShed   time.Time
+	overloaded bool
+}
+
+// configuration defines the capacity of the server
+type configuration struct {
+	config.BaseConfig
+	// Maximum number of requests in flight
+	MaxInFlight int `json:"max_in_flight"`
+	// Maximum number of requests waiting for a slot
+	MaxQueue int `json:"max_queue"`
+	// Maximum time a request waits for a slot in milliseconds
+	QueueTimeout int `json:"queue_timeout_ms"`
+	// Time without shedding after which the server recovers in
+	// milliseconds
+	Recovery int `json:"recovery_ms"`
+	// Retry-After header value in seconds
+	RetryAfter int `json:"retry_after"`
+}
+
+// New creates a new overload protection.
+func New(ctx component.Context) Protector {
+	return &protector{
+		config: &configuration{
+			BaseConfig:   config.BaseConfig{ConfigKey: "overload"},
+			MaxInFlight:  1000,
+			MaxQueue:     1000,
+			QueueTimeout: 100,
+			Recovery:     5000,
+			RetryAfter:   1,
+		},
+		ctx: ctx,
+	}
+}
+
+func (p *protector) Config() config.Config {
+	return p.config
+}
+
+func (p *protector) Configure(ctx component.Context) error {
+	c := p.config
+	if c.MaxInFlight <= 0 || c.MaxQueue < 0 || c.QueueTimeout < 0 || c.Recovery <= 0 {
+		return fmt.Errorf("overload max in flight and recovery must be positive, max queue and queue timeout must not be negative")
+	}
+	p.slots = make(chan struct{}, c.MaxInFlight)
+	return nil
+}
+
+func (p *protector) Start(ctx component.Context) error {
+	recovery := time.Duration(p.config.Recovery) * time.Millisecond
+	component.Go(ctx, func(ctx component.Context) error {
+		t := time.NewTicker(recovery / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Ctx().Done():
+				return nil
+			case <-t.C:
+				p.recover()
+			}
+		}
+	})
+	return nil
+}
+
+// IsReady returns false while the server sheds load.
+func (p *protector) IsReady(ctx component.Context) bool {
+	p.recover()
+	return !p.Stats().Overloaded
+}
+
+// recover leaves the overload state once no request was shed for the
+// recovery time.
+func (p *protector) recover() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	recovery := time.Duration(p.config.Recovery) * time.Millisecond
+	if p.overloaded && time.Since(p.lastShed) >= recovery {
+		p.overloaded = false
+		p.ctx.Log().Warn().Int("shed", int(atomic.LoadInt64(&p.shed))).Msg("*** server recovered from overload ***")
+	}
+}
+
+// reject sheds the request and enters the overload state.
+func (p *protector) reject(w http.ResponseWriter) {
+	atomic.AddInt64(&p.shed, 1)
+	p.lock.Lock()
+	p.lastShed = time.Now()
+	if !p.overloaded {
+		p.overloaded = true
+		p.ctx.Log().Warn().Msg("*** server overloaded, shedding load ***")
+	}
+	p.lock.Unlock()
+	w.Header().Set("Retry-After", strconv.Itoa(p.config.RetryAfter))
+	http.Error(w, "server is overloaded", http.StatusServiceUnavailable)
+}
+
+// acquire takes a slot, waiting in the queue if there is room, and returns
+// false if no slot was available in time.
+func (p *protector) acquire() bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if atomic.AddInt64(&p.queued, 1) > int64(p.config.MaxQueue) {
+		atomic.AddInt64(&p.queued, -1)
+		return false
+	}
+	defer atomic.AddInt64(&p.queued, -1)
+	t := time.NewTimer(time.Duration(p.config.QueueTimeout) * time.Millisecond)
+	defer t.Stop()
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+func (p *protector) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !p.acquire() {
+			p.reject(w)
+			return
+		}
+		atomic.AddInt64(&p.inFlight, 1)
+		defer func() {
+			atomic.AddInt64(&p.inFlight, -1)
+			atomic.AddInt64(&p.served, 1)
+			<-p.slots
+		}()
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (p *protector) Stats() Stats {
+	p.lock.Lock()
+	overloaded := p.overloaded
+	p.lock.Unlock()
+	return Stats{
+		InFlight:   atomic.LoadInt64(&p.inFlight),
+		Queued:     atomic.LoadInt64(&p.queued),
+		Served:     atomic.LoadInt64(&p.served),
+		Shed:       atomic.LoadInt64(&p.shed),
+		Overloaded: overloaded,
+	}
+}
+
+func (p *protector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.Stats())
+	})
+}
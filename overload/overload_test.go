@@ -0,0 +1,106 @@
+package overload
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProtector(t *testing.T) {
+	Convey("After we create an overload protection", t, func() {
+		ctx := component.RootContext(zlog.New("overload.test"))
+		p := New(ctx).(*protector)
+		So(p.Config().Key(), ShouldEqual, "overload")
+		p.config.MaxInFlight = 2
+		p.config.MaxQueue = 1
+		p.config.QueueTimeout = 50
+		p.config.Recovery = 100
+		So(p.Configure(ctx), ShouldBeNil)
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 10)
+		h := p.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			started <- struct{}{}
+			<-release
+		}))
+		serve := func() *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			return w
+		}
+
+		Convey("requests beyond the capacity should be shed", func() {
+			wg := sync.WaitGroup{}
+			codes := make(chan int, 3)
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					codes <- serve().Code
+				}()
+			}
+			<-started
+			<-started
+			for p.Stats().Queued != 1 {
+				time.Sleep(time.Millisecond)
+			}
+			So(p.Stats().InFlight, ShouldEqual, 2)
+
+			// The queue is full and the queued request times out
+			w := serve()
+			So(w.Code, ShouldEqual, http.StatusServiceUnavailable)
+			So(w.Header().Get("Retry-After"), ShouldEqual, "1")
+			So(p.IsReady(ctx), ShouldBeFalse)
+			So(<-codes, ShouldEqual, http.StatusServiceUnavailable)
+
+			close(release)
+			wg.Wait()
+			stats := p.Stats()
+			So(stats.Served, ShouldEqual, 2)
+			So(stats.Shed, ShouldEqual, 2)
+			So(stats.InFlight, ShouldEqual, 0)
+
+			// The server recovers once it stops shedding
+			time.Sleep(100 * time.Millisecond)
+			So(p.IsReady(ctx), ShouldBeTrue)
+			So(serve().Code, ShouldEqual, http.StatusOK)
+		})
+
+		Convey("queued requests should be served when a slot frees up", func() {
+			p.config.QueueTimeout = 5000
+			codes := make(chan int, 3)
+			for i := 0; i < 3; i++ {
+				go func() { codes <- serve().Code }()
+			}
+			<-started
+			<-started
+			for p.Stats().Queued != 1 {
+				time.Sleep(time.Millisecond)
+			}
+			close(release)
+			for i := 0; i < 3; i++ {
+				So(<-codes, ShouldEqual, http.StatusOK)
+			}
+			So(p.IsReady(ctx), ShouldBeTrue)
+		})
+
+		Convey("the admin handler should report the counters", func() {
+			close(release)
+			serve()
+			w := httptest.NewRecorder()
+			p.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/overload", nil))
+			So(w.Body.String(), ShouldEqual, "{\"in_flight\":0,\"queued\":0,\"served\":1,\"shed\":0,\"overloaded\":false}\n")
+		})
+
+		Convey("invalid configurations should fail", func() {
+			p.config.MaxInFlight = 0
+			So(p.Configure(ctx), ShouldNotBeNil)
+		})
+	})
+}
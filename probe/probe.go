@@ -0,0 +1,207 @@
+// Package probe provides a component that runs external health probes
+// declared in the configuration. TCP connect, HTTP GET and script probes are
+// run on an interval and their results are folded into the health of the
+// group the component is added to, so that operational checks do not require
+// writing Go components.
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Prober runs the configured probes.
+type Prober interface {
+	// Results returns the last result of each probe by name. A nil result
+	// means the probe succeeded.
+	Results() map[string]error
+}
+
+// Probe declares an external health probe.
+type Probe struct {
+	// Name of the probe
+	Name string `json:"name"`
+	// Type of the probe, one of "tcp", "http" or "exec"
+	Type string `json:"type"`
+	// Address to connect to for tcp probes, URL to get for http probes
+	Target string `json:"target"`
+	// Command and arguments to run for exec probes
+	Command []string `json:"command"`
+	// Interval between the probes in milliseconds
+	Interval int `json:"interval_ms"`
+	// Timeout of a probe in milliseconds
+	Timeout int `json:"timeout_ms"`
+}
+
+type prober struct {
+	config  *configuration
+	lock    sync.RWMutex
+	results map[string]error
+}
+
+// configuration defines the configurable parameters of the prober.
+type configuration struct {
+	config.BaseConfig
+	Probes []Probe `json:"probes"`
+}
+
+// New creates a new prober.
+func New(ctx component.Context) Prober {
+	return &prober{
+		config:  &configuration{BaseConfig: config.BaseConfig{ConfigKey: "probes"}},
+		results: map[string]error{},
+	}
+}
+
+func (p *prober) Config() config.Config {
+	return p.config
+}
+
+func (p *prober) Configure(ctx component.Context) error {
+	names := map[string]bool{}
+	for i := range p.config.Probes {
+		pr := &p.config.Probes[i]
+		if pr.Name == "" || names[pr.Name] {
+			return fmt.Errorf("probe %d must have a unique name", i)
+		}
+		names[pr.Name] = true
+		switch pr.Type {
+		case "tcp", "http":
+			if pr.Target == "" {
+				return fmt.Errorf("probe %s requires a target", pr.Name)
+			}
+		case "exec":
+			if len(pr.Command) == 0 {
+				return fmt.Errorf("probe %s requires a command", pr.Name)
+			}
+		default:
+			return fmt.Errorf("probe %s has unknown type %q", pr.Name, pr.Type)
+		}
+		if pr.Interval <= 0 {
+			pr.Interval = 10000
+		}
+		if pr.Timeout <= 0 {
+			pr.Timeout = 5000
+		}
+	}
+	return nil
+}
+
+func (p *prober) Start(ctx component.Context) error {
+	for _, pr := range p.config.Probes {
+		pr := pr
+		// Run each probe once before starting so that the health reflects
+		// the probes as soon as the server is started.
+		p.run(ctx, pr)
+		component.Go(ctx, func(ctx component.Context) error {
+			t := time.NewTicker(time.Duration(pr.Interval) * time.Millisecond)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Ctx().Done():
+					return nil
+				case <-t.C:
+					p.run(ctx, pr)
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// SelfCheck runs each probe once, so that the self-check of the server
+// verifies that the services it depends on are reachable.
+func (p *prober) SelfCheck(ctx component.Context) error {
+	failed := []string{}
+	for _, pr := range p.config.Probes {
+		pctx, cancel := context.WithTimeout(ctx.Ctx(), time.Duration(pr.Timeout)*time.Millisecond)
+		err := check(pctx, pr)
+		cancel()
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", pr.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("probes failed: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// IsHealthy returns false if any probe failed.
+func (p *prober) IsHealthy(ctx component.Context) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	for _, err := range p.results {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *prober) Results() map[string]error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	results := make(map[string]error, len(p.results))
+	for n, err := range p.results {
+		results[n] = err
+	}
+	return results
+}
+
+// run runs the probe and records its result.
+func (p *prober) run(ctx component.Context, pr Probe) {
+	pctx, cancel := context.WithTimeout(ctx.Ctx(), time.Duration(pr.Timeout)*time.Millisecond)
+	defer cancel()
+	err := check(pctx, pr)
+
+	p.lock.Lock()
+	prev, seen := p.results[pr.Name]
+	p.results[pr.Name] = err
+	p.lock.Unlock()
+
+	if err != nil && (!seen || prev == nil) {
+		ctx.Log().Warn().Str("probe", pr.Name).Error(err).Msg("probe failed")
+	} else if err == nil && prev != nil {
+		ctx.Log().Info().Str("probe", pr.Name).Msg("probe recovered")
+	}
+}
+
+// check runs the probe once.
+func check(ctx context.Context, pr Probe) error {
+	switch pr.Type {
+	case "tcp":
+		d := net.Dialer{}
+		conn, err := d.DialContext(ctx, "tcp", pr.Target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case "http":
+		req, err := http.NewRequest(http.MethodGet, pr.Target, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 399 {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return nil
+	case "exec":
+		return exec.CommandContext(ctx, pr.Command[0], pr.Command[1:]...).Run()
+	}
+	return fmt.Errorf("unknown probe type %q", pr.Type)
+}
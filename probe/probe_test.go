@@ -0,0 +1,79 @@
+package probe
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProber(t *testing.T) {
+	Convey("After we create a prober", t, func() {
+		ctx := component.RootContext(zlog.New("probe.test"))
+		p := New(ctx).(*prober)
+		So(p.Config().Key(), ShouldEqual, "probes")
+
+		Convey("bad probes should be rejected", func() {
+			p.config.Probes = []Probe{{Name: "a", Type: "tcp"}}
+			So(p.Configure(ctx), ShouldBeError)
+			p.config.Probes = []Probe{{Name: "a", Type: "exec"}}
+			So(p.Configure(ctx), ShouldBeError)
+			p.config.Probes = []Probe{{Name: "a", Type: "icmp"}}
+			So(p.Configure(ctx), ShouldBeError)
+			p.config.Probes = []Probe{{Type: "tcp", Target: "x"}}
+			So(p.Configure(ctx), ShouldBeError)
+			p.config.Probes = []Probe{{Name: "a", Type: "exec", Command: []string{"true"}}, {Name: "a", Type: "exec", Command: []string{"true"}}}
+			So(p.Configure(ctx), ShouldBeError)
+		})
+
+		Convey("the probes should be folded into the health", func() {
+			ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			defer ok.Close()
+			bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer bad.Close()
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			So(err, ShouldBeNil)
+			addr := l.Addr().String()
+			l.Close()
+
+			p.config.Probes = []Probe{
+				{Name: "http", Type: "http", Target: ok.URL},
+				{Name: "tcp", Type: "tcp", Target: ok.Listener.Addr().String()},
+			}
+			So(p.Configure(ctx), ShouldBeNil)
+			So(p.Start(ctx), ShouldBeNil)
+			So(p.IsHealthy(ctx), ShouldBeTrue)
+			So(p.Results(), ShouldResemble, map[string]error{"http": nil, "tcp": nil})
+
+			p.run(ctx, Probe{Name: "bad", Type: "http", Target: bad.URL, Timeout: 1000})
+			So(p.IsHealthy(ctx), ShouldBeFalse)
+			So(p.Results()["bad"], ShouldBeError)
+			p.run(ctx, Probe{Name: "bad", Type: "tcp", Target: addr, Timeout: 1000})
+			So(p.Results()["bad"], ShouldBeError)
+			p.run(ctx, Probe{Name: "bad", Type: "exec", Command: []string{"false"}, Timeout: 1000})
+			So(p.Results()["bad"], ShouldBeError)
+			p.run(ctx, Probe{Name: "bad", Type: "exec", Command: []string{"true"}, Timeout: 1000})
+			So(p.IsHealthy(ctx), ShouldBeTrue)
+			ctx.(interface{ Shutdown() }).Shutdown()
+		})
+
+		Convey("the self-check should run the probes once", func() {
+			p.config.Probes = []Probe{{Name: "exec", Type: "exec", Command: []string{"true"}}}
+			So(p.Configure(ctx), ShouldBeNil)
+			So(p.SelfCheck(ctx), ShouldBeNil)
+			So(p.Results(), ShouldBeEmpty)
+
+			p.config.Probes = append(p.config.Probes, Probe{Name: "bad", Type: "exec", Command: []string{"false"}})
+			So(p.Configure(ctx), ShouldBeNil)
+			err := p.SelfCheck(ctx)
+			So(err, ShouldBeError)
+			So(err.Error(), ShouldStartWith, "probes failed: bad: ")
+		})
+	})
+}
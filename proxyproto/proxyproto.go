@@ -0,0 +1,240 @@
+// Package proxyproto implements the receiving side of the PROXY protocol,
+// versions 1 and 2, used by the L4 load balancers to pass the address of the
+// client to the servers behind them. A listener accepting the connections of
+// a load balancer is wrapped with NewListener, e.g.
+//
+//	l, err := net.Listen("tcp", addr)
+//	l = proxyproto.NewListener(l, 5*time.Second)
+//
+// and the RemoteAddr of its connections is the address of the client sent in
+// the PROXY header, instead of the address of the load balancer.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxV1Header is the maximum length of a version 1 header, including the
+// final CRLF.
+const maxV1Header = 107
+
+// v2Signature starts the version 2 headers.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ErrNoHeader is returned by the reads of a connection that does not start
+// with a PROXY header.
+var ErrNoHeader = errors.New("proxyproto: missing PROXY protocol header")
+
+type listener struct {
+	net.Listener
+	timeout time.Duration
+	trusted []*net.IPNet
+}
+
+// NewListener returns a listener whose connections start with a PROXY
+// header. The header is read by the first Read or RemoteAddr call on the
+// connection, within the timeout if it is not 0, and a connection without a
+// valid header fails its reads. If trusted networks are given, only the
+// connections from these networks, e.g. the load balancers, are expected to
+// send a header, the other ones are passed through unchanged.
+func NewListener(l net.Listener, timeout time.Duration, trusted ...*net.IPNet) net.Listener {
+	return &listener{l, timeout, trusted}
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.isTrusted(c.RemoteAddr()) {
+		return c, nil
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c), timeout: l.timeout}, nil
+}
+
+// isTrusted returns true if the address belongs to a trusted network, or if
+// all the networks are trusted.
+func (l *listener) isTrusted(addr net.Addr) bool {
+	if len(l.trusted) == 0 {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, n := range l.trusted {
+		if n.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// conn is a connection starting with a PROXY header.
+type conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	once    sync.Once
+	remote  net.Addr
+	local   net.Addr
+	err     error
+}
+
+// init reads the header of the connection once.
+func (c *conn) init() {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer c.Conn.SetReadDeadline(time.Time{})
+		}
+		c.remote, c.local, c.err = readHeader(c.r)
+	})
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.init()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the address of the client sent in the header, the
+// address of the peer if the header does not carry addresses.
+func (c *conn) RemoteAddr() net.Addr {
+	c.init()
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address sent in the header, the local
+// address if the header does not carry addresses.
+func (c *conn) LocalAddr() net.Addr {
+	c.init()
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readHeader reads a version 1 or 2 header and returns the source and the
+// destination addresses, nil if the header does not carry addresses, e.g.
+// for the health checks of the load balancer.
+func readHeader(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch b[0] {
+	case 'P':
+		return readV1(r)
+	case v2Signature[0]:
+		return readV2(r)
+	}
+	return nil, nil, ErrNoHeader
+}
+
+func readV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	line := make([]byte, 0, maxV1Header)
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) == maxV1Header {
+			return nil, nil, errors.New("proxyproto: version 1 header too long")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if fields[0] != "PROXY" || len(fields) < 2 {
+		return nil, nil, ErrNoHeader
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, nil, fmt.Errorf("proxyproto: unknown protocol %q", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, nil, fmt.Errorf("proxyproto: invalid version 1 header %q", line)
+	}
+	src, err := tcpAddr(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := tcpAddr(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+func tcpAddr(ip, port string) (*net.TCPAddr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil {
+		return nil, fmt.Errorf("proxyproto: invalid address %q", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("proxyproto: invalid port %q", port)
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	hdr := make([]byte, 16)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(hdr[:12], v2Signature) {
+		return nil, nil, ErrNoHeader
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, nil, fmt.Errorf("proxyproto: unknown version %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, err
+	}
+	switch hdr[12] & 0xf {
+	case 0:
+		// LOCAL command, e.g. the health checks of the load balancer
+		return nil, nil, nil
+	case 1:
+	default:
+		return nil, nil, fmt.Errorf("proxyproto: unknown command %d", hdr[12]&0xf)
+	}
+
+	size := 0
+	switch hdr[13] >> 4 {
+	case 1:
+		size = net.IPv4len
+	case 2:
+		size = net.IPv6len
+	default:
+		// Unix sockets and unspecified families carry no IP address
+		return nil, nil, nil
+	}
+	if len(body) < 2*size+4 {
+		return nil, nil, errors.New("proxyproto: version 2 header too short")
+	}
+	src := &net.TCPAddr{IP: net.IP(body[:size]), Port: int(binary.BigEndian.Uint16(body[2*size:]))}
+	dst := &net.TCPAddr{IP: net.IP(body[size : 2*size]), Port: int(binary.BigEndian.Uint16(body[2*size+2:]))}
+	return src, dst, nil
+}
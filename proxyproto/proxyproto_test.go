@@ -0,0 +1,95 @@
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// serve accepts a connection on the listener and returns its remote
+// address and its data once the client sent the data and closed.
+func serve(l net.Listener, data []byte) (string, string, error) {
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			return
+		}
+		c.Write(data)
+		c.Close()
+	}()
+	c, err := l.Accept()
+	if err != nil {
+		return "", "", err
+	}
+	defer c.Close()
+	b, err := ioutil.ReadAll(c)
+	return c.RemoteAddr().String(), string(b), err
+}
+
+func v2Header(cmd, fam byte, addrs []byte) []byte {
+	b := append([]byte{}, v2Signature...)
+	b = append(b, 0x20|cmd, fam, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(addrs)))
+	return append(b, addrs...)
+}
+
+func TestListener(t *testing.T) {
+	Convey("After we wrap a listener", t, func() {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		l := NewListener(tcp, time.Second)
+		defer l.Close()
+
+		Convey("version 1 headers should set the remote address", func() {
+			remote, data, err := serve(l, []byte("PROXY TCP4 192.0.2.1 198.51.100.1 5000 80\r\nhello"))
+			So(err, ShouldBeNil)
+			So(remote, ShouldEqual, "192.0.2.1:5000")
+			So(data, ShouldEqual, "hello")
+
+			remote, _, err = serve(l, []byte("PROXY TCP6 2001:db8::1 2001:db8::2 5000 80\r\n"))
+			So(err, ShouldBeNil)
+			So(remote, ShouldEqual, "[2001:db8::1]:5000")
+
+			remote, data, err = serve(l, []byte("PROXY UNKNOWN\r\nhello"))
+			So(err, ShouldBeNil)
+			So(remote, ShouldStartWith, "127.0.0.1:")
+			So(data, ShouldEqual, "hello")
+		})
+
+		Convey("version 2 headers should set the remote address", func() {
+			addrs := []byte{192, 0, 2, 1, 198, 51, 100, 1, 0x13, 0x88, 0, 80, 0xaa, 0xbb}
+			remote, data, err := serve(l, append(v2Header(1, 0x11, addrs), "hello"...))
+			So(err, ShouldBeNil)
+			So(remote, ShouldEqual, "192.0.2.1:5000")
+			So(data, ShouldEqual, "hello")
+
+			remote, data, err = serve(l, append(v2Header(0, 0, nil), "hello"...))
+			So(err, ShouldBeNil)
+			So(remote, ShouldStartWith, "127.0.0.1:")
+			So(data, ShouldEqual, "hello")
+		})
+
+		Convey("connections without a valid header should fail", func() {
+			_, _, err := serve(l, []byte("GET / HTTP/1.1\r\n\r\n"))
+			So(err, ShouldEqual, ErrNoHeader)
+			_, _, err = serve(l, []byte("PROXY TCP4 192.0.2.1\r\n"))
+			So(err, ShouldNotBeNil)
+			_, _, err = serve(l, append([]byte("PROXY TCP4 "), bytes.Repeat([]byte("1"), 120)...))
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("untrusted peers should be passed through", func() {
+			_, n, _ := net.ParseCIDR("10.0.0.0/8")
+			l := NewListener(tcp, time.Second, n)
+			remote, data, err := serve(l, []byte("GET /"))
+			So(err, ShouldBeNil)
+			So(remote, ShouldStartWith, "127.0.0.1:")
+			So(data, ShouldEqual, "GET /")
+		})
+	})
+}
@@ -0,0 +1,260 @@
+// Package record provides a component that records the lifecycle events,
+// the configuration fetches and the signal deliveries of the server with
+// their timestamps to a file, and a reader to replay and inspect the
+// recordings. It helps debugging boot ordering issues that are hard to
+// reproduce outside of the field.
+//
+// The recording is enabled by the "record" configuration, e.g.
+//
+//	"record": {"file": "/var/log/server.rec"}
+//
+// The entries are written one JSON object per line as they occur, so that a
+// recording survives a crash of the server.
+package record
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+	"github.com/anuvu/cube/signal"
+)
+
+// EventSignal is the type of the entries recording a signal delivery.
+const EventSignal component.EventType = "signal"
+
+// Recorder records the events of the server.
+type Recorder interface {
+	// Record records the event. Components can record events of their own.
+	Record(e component.Event)
+}
+
+// Entry is a recorded event.
+type Entry struct {
+	// Time of the event
+	Time time.Time `json:"time"`
+	// Type of the event, a lifecycle event type or EventSignal
+	Type component.EventType `json:"type"`
+	// Group the event relates to
+	Group string `json:"group,omitempty"`
+	// Component the event relates to
+	Component string `json:"component,omitempty"`
+	// Key is the configuration key of config events
+	Key string `json:"key,omitempty"`
+	// Signal delivered for signal events
+	Signal string `json:"signal,omitempty"`
+	// Healthy is the health of the server for health events
+	Healthy bool `json:"healthy,omitempty"`
+	// Error of the event, if any
+	Error string `json:"error,omitempty"`
+	// RunID is the identifier of the run of the server
+	RunID string `json:"run_id,omitempty"`
+}
+
+// Event returns the lifecycle event of the entry, e.g. to replay it to an
+// event hook.
+func (e Entry) Event() component.Event {
+	ev := component.Event{
+		Type:      e.Type,
+		Group:     e.Group,
+		Component: e.Component,
+		Key:       e.Key,
+		Healthy:   e.Healthy,
+		Time:      e.Time,
+		RunID:     e.RunID,
+	}
+	if e.Error != "" {
+		ev.Err = errors.New(e.Error)
+	}
+	return ev
+}
+
+// String formats the entry on a single line.
+func (e Entry) String() string {
+	parts := []string{e.Time.Format("15:04:05.000000"), string(e.Type)}
+	if e.Group != "" {
+		parts = append(parts, "["+e.Group+"]")
+	}
+	if e.Component != "" {
+		parts = append(parts, e.Component)
+	}
+	if e.Key != "" {
+		parts = append(parts, "key="+e.Key)
+	}
+	if e.Signal != "" {
+		parts = append(parts, "signal="+e.Signal)
+	}
+	if e.Type == component.EventHealthChanged {
+		parts = append(parts, fmt.Sprintf("healthy=%v", e.Healthy))
+	}
+	s := strings.Join(parts, " ")
+	if e.Error != "" {
+		s += ": " + e.Error
+	}
+	return s
+}
+
+type recorder struct {
+	config     *configuration
+	ctx        component.Context
+	lock       sync.Mutex
+	configured bool
+	pending    []Entry
+	out        io.WriteCloser
+}
+
+// configuration defines the configurable parameters of the recorder.
+type configuration struct {
+	config.BaseConfig
+	// File to record to, the recording is disabled if empty
+	File string `json:"file"`
+}
+
+// New creates a new recorder. The recorder observes the signals delivered to
+// the router if it implements signal.Observer.
+func New(ctx component.Context, router signal.Router) Recorder {
+	r := &recorder{
+		config: &configuration{BaseConfig: config.BaseConfig{ConfigKey: "record"}},
+		ctx:    ctx,
+	}
+	if obs, ok := router.(signal.Observer); ok {
+		obs.Observe(func(sig os.Signal) {
+			r.add(Entry{Time: time.Now(), Type: EventSignal, Signal: sig.String(), RunID: component.RunID(ctx)})
+		})
+	}
+	return r
+}
+
+func (r *recorder) Config() config.Config {
+	return r.config
+}
+
+// Configure opens the recording file and writes the events recorded before
+// the configuration, e.g. the configuration fetches of the components
+// configured first.
+func (r *recorder) Configure(ctx component.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	pending := r.pending
+	r.pending = nil
+	r.configured = true
+	if r.config.File == "" {
+		return nil
+	}
+	f, err := os.OpenFile(r.config.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	r.out = f
+	for _, e := range pending {
+		r.write(e)
+	}
+	return nil
+}
+
+// OnEvent records the lifecycle events. The recording is closed once the
+// server is stopped.
+func (r *recorder) OnEvent(ctx component.Context, e component.Event) {
+	r.Record(e)
+	if e.Type == component.EventStopped {
+		r.close()
+	}
+}
+
+func (r *recorder) Record(e component.Event) {
+	entry := Entry{
+		Time:      e.Time,
+		Type:      e.Type,
+		Group:     e.Group,
+		Component: e.Component,
+		Key:       e.Key,
+		Healthy:   e.Healthy,
+		RunID:     e.RunID,
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if entry.RunID == "" {
+		entry.RunID = component.RunID(r.ctx)
+	}
+	if e.Err != nil {
+		entry.Error = e.Err.Error()
+	}
+	r.add(entry)
+}
+
+// add records the entry, or keeps it until the recorder is configured.
+func (r *recorder) add(e Entry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.configured {
+		r.pending = append(r.pending, e)
+	} else if r.out != nil {
+		r.write(e)
+	}
+}
+
+func (r *recorder) write(e Entry) {
+	b, err := json.Marshal(e)
+	if err == nil {
+		_, err = r.out.Write(append(b, '\n'))
+	}
+	if err != nil {
+		r.ctx.Log().Warn().Error(err).Msg("failed to record event")
+	}
+}
+
+func (r *recorder) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.out != nil {
+		r.out.Close()
+		r.out = nil
+	}
+}
+
+// Replay calls fn with the entries recorded in r in the order they were
+// recorded. It stops at the first error returned by fn.
+func Replay(r io.Reader, fn func(Entry) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		e := Entry{}
+		if err := dec.Decode(&e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Read returns the entries recorded in r. The entries read before an error,
+// e.g. an entry truncated by a crash of the server, are returned with the
+// error.
+func Read(r io.Reader) ([]Entry, error) {
+	entries := []Entry{}
+	err := Replay(r, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	return entries, err
+}
+
+// ReadFile returns the entries recorded in the file.
+func ReadFile(name string) ([]Entry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
@@ -0,0 +1,93 @@
+package record
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/signal"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRecorder(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+
+	Convey("After we create a group with a recorder", t, func() {
+		dir, err := ioutil.TempDir("", "record")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		file := filepath.Join(dir, "server.rec")
+
+		grp := component.New("srv")
+		So(grp.Add(signal.New), ShouldBeNil)
+		So(grp.Add(New), ShouldBeNil)
+		So(grp.Create(), ShouldBeNil)
+
+		Convey("the events should be recorded and read back", func() {
+			os.Args = []string{"record.test", "--config.mem", fmt.Sprintf(`{"record": {"file": %q}}`, file)}
+			So(grp.Configure(), ShouldBeNil)
+			So(grp.Start(), ShouldBeNil)
+
+			delivered := make(chan struct{}, 1)
+			runID := ""
+			So(grp.Invoke(func(ctx component.Context, router signal.Router, r Recorder) {
+				runID = component.RunID(ctx)
+				router.Handle(syscall.SIGUSR1, func(os.Signal) { delivered <- struct{}{} })
+				r.Record(component.Event{Type: "custom", Group: "srv", Err: fmt.Errorf("oops")})
+			}), ShouldBeNil)
+			So(syscall.Kill(os.Getpid(), syscall.SIGUSR1), ShouldBeNil)
+			select {
+			case <-delivered:
+			case <-time.After(5 * time.Second):
+			}
+			So(grp.Stop(), ShouldBeNil)
+
+			entries, err := ReadFile(file)
+			So(err, ShouldBeNil)
+			types := []component.EventType{}
+			for _, e := range entries {
+				types = append(types, e.Type)
+			}
+			So(types, ShouldResemble, []component.EventType{
+				component.EventConfigFetched, component.EventStarted, "custom",
+				EventSignal, component.EventStopping, component.EventStopped,
+			})
+			So(entries[0].Component, ShouldEqual, "*record.recorder")
+			So(entries[0].Key, ShouldEqual, "record")
+			So(entries[3].Signal, ShouldEqual, syscall.SIGUSR1.String())
+			So(entries[2].Event().Err, ShouldBeError, "oops")
+			So(entries[2].String(), ShouldEndWith, "custom [srv]: oops")
+			So(entries[1].Time.After(entries[0].Time), ShouldBeTrue)
+			for _, e := range entries {
+				So(e.RunID, ShouldEqual, runID)
+			}
+			So(entries[1].Event().RunID, ShouldEqual, runID)
+		})
+
+		Convey("nothing should be recorded without a file", func() {
+			os.Args = []string{"record.test", "--config.mem", `{"record": {}}`}
+			So(grp.Configure(), ShouldBeNil)
+			So(grp.Start(), ShouldBeNil)
+			So(grp.Stop(), ShouldBeNil)
+			files, err := ioutil.ReadDir(dir)
+			So(err, ShouldBeNil)
+			So(files, ShouldBeEmpty)
+		})
+	})
+
+	Convey("Truncated recordings should return the entries read", t, func() {
+		rec := `{"time":"2018-01-02T03:04:05Z","type":"started","group":"srv"}` + "\n" +
+			`{"time":"2018-01-02T03:04:06Z","type":"health_changed","group":"srv","he`
+		entries, err := Read(strings.NewReader(rec))
+		So(err, ShouldBeError)
+		So(entries, ShouldHaveLength, 1)
+		So(entries[0].String(), ShouldEqual, "03:04:05.000000 started [srv]")
+	})
+}
@@ -0,0 +1,320 @@
+// Package session provides a session middleware for the web applications
+// built on cube. The session of a request is identified by a cookie, or by a
+// header for API clients, and its values are kept in a pluggable store, e.g.
+//
+//	g.Add(session.New)
+//	g.AddDefault(session.NewMemoryStore)
+//	g.Invoke(func(s http.Server, m session.Manager) {
+//		s.Register("/", m.Middleware(handler))
+//	})
+//
+// and the handlers use the session of the request:
+//
+//	s := session.FromRequest(r)
+//	s.Set("user", user)
+//
+// The session is saved when the response is written, only if it was
+// modified, and its TTL starts over when it is saved. The identifier of the
+// session changes periodically and when the handler calls Rotate, e.g. after
+// a login, to prevent session fixation.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/cube/config"
+)
+
+// Manager loads and saves the sessions of the requests.
+type Manager interface {
+	// Middleware returns a handler that loads the session of the request,
+	// calls h and saves the session when the response is written.
+	Middleware(h http.Handler) http.Handler
+}
+
+// Session is the session of a request. It must not be modified once the
+// response is written.
+type Session struct {
+	lock      sync.Mutex
+	id        string
+	data      Data
+	isNew     bool
+	dirty     bool
+	rotate    bool
+	destroyed bool
+}
+
+type sessionKey struct{}
+
+// FromRequest returns the session of the request, nil if the request is not
+// served by the middleware of a manager.
+func FromRequest(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey{}).(*Session)
+	return s
+}
+
+// ID returns the identifier of the session, empty until a new session is
+// saved.
+func (s *Session) ID() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.isNew {
+		return ""
+	}
+	return s.id
+}
+
+// IsNew returns true if the request had no valid session.
+func (s *Session) IsNew() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.isNew
+}
+
+// Get returns the value of the key and whether it is set.
+func (s *Session) Get(key string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	v, ok := s.data.Values[key]
+	return v, ok
+}
+
+// Set sets the value of the key.
+func (s *Session) Set(key, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.data.Values[key] = value
+	s.dirty = true
+}
+
+// Delete removes the key.
+func (s *Session) Delete(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.data.Values[key]; ok {
+		delete(s.data.Values, key)
+		s.dirty = true
+	}
+}
+
+// Rotate changes the identifier of the session when it is saved, e.g. after
+// the user logs in.
+func (s *Session) Rotate() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rotate = true
+}
+
+// Destroy removes the session from the store and from the client, e.g.
+// when the user logs out.
+func (s *Session) Destroy() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.destroyed = true
+}
+
+type manager struct {
+	config *configuration
+	ctx    component.Context
+	store  Store
+}
+
+// configuration defines the configurable parameters of the sessions
+type configuration struct {
+	config.BaseConfig
+	// Name of the session cookie
+	Cookie string `json:"cookie"`
+	// Header carrying the session token instead of the cookie, e.g.
+	// X-Session-Token for API clients
+	Header string `json:"header"`
+	// Time to live of a session since it was last saved in milliseconds
+	TTL int `json:"ttl_ms"`
+	// Age after which the identifier of a session is changed in
+	// milliseconds, never if 0
+	Rotate int `json:"rotate_ms"`
+	// SameSite attribute of the cookie, lax, strict or none
+	SameSite string `json:"same_site"`
+	// Send the cookie over HTTPS only
+	Secure bool `json:"secure"`
+	// Path of the cookie
+	Path string `json:"path"`
+	// Domain of the cookie, the host of the request if empty
+	Domain string `json:"domain"`
+}
+
+// New creates a new session manager storing the sessions in the store.
+func New(ctx component.Context, store Store) Manager {
+	return &manager{
+		config: &configuration{
+			BaseConfig: config.BaseConfig{ConfigKey: "session"},
+			Cookie:     "session",
+			TTL:        24 * 3600 * 1000,
+			Rotate:     15 * 60 * 1000,
+			SameSite:   "lax",
+			Secure:     true,
+			Path:       "/",
+		},
+		ctx:   ctx,
+		store: store,
+	}
+}
+
+func (m *manager) Config() config.Config {
+	return m.config
+}
+
+func (m *manager) Configure(ctx component.Context) error {
+	c := m.config
+	if c.TTL <= 0 || c.Rotate < 0 {
+		return fmt.Errorf("session ttl must be positive and rotation must not be negative")
+	}
+	if c.Cookie == "" && c.Header == "" {
+		return fmt.Errorf("session requires a cookie or a header")
+	}
+	c.SameSite = strings.ToLower(c.SameSite)
+	switch c.SameSite {
+	case "", "lax", "strict":
+	case "none":
+		if !c.Secure {
+			return fmt.Errorf("session cookie with same_site none must be secure")
+		}
+	default:
+		return fmt.Errorf("unknown same_site %q of the session cookie", c.SameSite)
+	}
+	return nil
+}
+
+func (m *manager) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := m.load(r)
+		cw := &committer{ResponseWriter: w, commit: func() { m.commit(w, s) }}
+		h.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+		cw.once.Do(cw.commit)
+	})
+}
+
+// load returns the session of the request, a new session if the request has
+// no valid session.
+func (m *manager) load(r *http.Request) *Session {
+	token := ""
+	if m.config.Header != "" {
+		token = r.Header.Get(m.config.Header)
+	} else if c, err := r.Cookie(m.config.Cookie); err == nil {
+		token = c.Value
+	}
+	if token != "" {
+		d, err := m.store.Load(token)
+		if err == nil {
+			if d.Values == nil {
+				d.Values = map[string]string{}
+			}
+			return &Session{id: token, data: d}
+		}
+		if err != ErrNotFound {
+			m.ctx.Log().Error().Error(err).Msg("loading session failed")
+		}
+	}
+	now := time.Now()
+	return &Session{
+		id:    newID(),
+		data:  Data{Values: map[string]string{}, Created: now, Rotated: now},
+		isNew: true,
+	}
+}
+
+// commit saves the session and sends its token to the client.
+func (m *manager) commit(w http.ResponseWriter, s *Session) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.destroyed {
+		if !s.isNew {
+			if err := m.store.Delete(s.id); err != nil {
+				m.ctx.Log().Error().Error(err).Msg("deleting session failed")
+			}
+		}
+		m.send(w, "", -1)
+		return
+	}
+
+	rotate := m.config.Rotate
+	if !s.isNew && (s.rotate || rotate > 0 && time.Since(s.data.Rotated) > time.Duration(rotate)*time.Millisecond) {
+		if err := m.store.Delete(s.id); err != nil {
+			m.ctx.Log().Error().Error(err).Msg("deleting session failed")
+		}
+		s.id = newID()
+		s.data.Rotated = time.Now()
+		s.dirty = true
+	}
+	if !s.dirty {
+		return
+	}
+	ttl := time.Duration(m.config.TTL) * time.Millisecond
+	if err := m.store.Save(s.id, s.data, ttl); err != nil {
+		m.ctx.Log().Error().Error(err).Msg("saving session failed")
+		return
+	}
+	s.isNew = false
+	s.dirty = false
+	m.send(w, s.id, int(ttl/time.Second))
+}
+
+// send sends the token of the session to the client, a negative max age
+// removes the cookie.
+func (m *manager) send(w http.ResponseWriter, token string, maxAge int) {
+	if m.config.Header != "" {
+		if token != "" {
+			w.Header().Set(m.config.Header, token)
+		}
+		return
+	}
+	c := &http.Cookie{
+		Name:     m.config.Cookie,
+		Value:    token,
+		Path:     m.config.Path,
+		Domain:   m.config.Domain,
+		MaxAge:   maxAge,
+		Secure:   m.config.Secure,
+		HttpOnly: true,
+	}
+	v := c.String()
+	if ss := m.config.SameSite; ss != "" {
+		// The SameSite field of http.Cookie requires go1.11
+		v += "; SameSite=" + strings.ToUpper(ss[:1]) + ss[1:]
+	}
+	w.Header().Add("Set-Cookie", v)
+}
+
+// newID returns a new random session identifier.
+func newID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("session: reading random bytes failed: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// committer commits the session before the response is written, so that the
+// cookie is part of the headers.
+type committer struct {
+	http.ResponseWriter
+	once   sync.Once
+	commit func()
+}
+
+func (c *committer) WriteHeader(status int) {
+	c.once.Do(c.commit)
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *committer) Write(b []byte) (int, error) {
+	c.once.Do(c.commit)
+	return c.ResponseWriter.Write(b)
+}
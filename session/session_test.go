@@ -0,0 +1,159 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anuvu/cube/component"
+	"github.com/anuvu/zlog"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSession(t *testing.T) {
+	Convey("After we create a session manager", t, func() {
+		ctx := component.RootContext(zlog.New("session.test"))
+		store := NewMemoryStore()
+		m := New(ctx, store).(*manager)
+		So(m.Config().Key(), ShouldEqual, "session")
+		So(m.Configure(ctx), ShouldBeNil)
+
+		var handler func(s *Session, w http.ResponseWriter)
+		h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(FromRequest(r), w)
+		}))
+		serve := func(token string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "/", nil)
+			if token != "" {
+				if m.config.Header != "" {
+					r.Header.Set(m.config.Header, token)
+				} else {
+					r.AddCookie(&http.Cookie{Name: "session", Value: token})
+				}
+			}
+			h.ServeHTTP(w, r)
+			return w
+		}
+		cookie := func(w *httptest.ResponseRecorder) string {
+			return w.Header().Get("Set-Cookie")
+		}
+		token := func(w *httptest.ResponseRecorder) string {
+			c := cookie(w)
+			return strings.TrimPrefix(strings.SplitN(c, ";", 2)[0], "session=")
+		}
+
+		Convey("sessions should only be saved when modified", func() {
+			handler = func(s *Session, w http.ResponseWriter) {
+				So(s.IsNew(), ShouldBeTrue)
+				So(s.ID(), ShouldBeEmpty)
+			}
+			So(cookie(serve("")), ShouldBeEmpty)
+
+			handler = func(s *Session, w http.ResponseWriter) {
+				s.Set("user", "alice")
+				w.Write([]byte("hello"))
+			}
+			w := serve("")
+			So(cookie(w), ShouldContainSubstring, "Max-Age=86400")
+			So(cookie(w), ShouldContainSubstring, "HttpOnly")
+			So(cookie(w), ShouldContainSubstring, "Secure")
+			So(cookie(w), ShouldEndWith, "; SameSite=Lax")
+			id := token(w)
+			So(len(id), ShouldEqual, 43)
+
+			handler = func(s *Session, w http.ResponseWriter) {
+				So(s.IsNew(), ShouldBeFalse)
+				So(s.ID(), ShouldEqual, id)
+				v, ok := s.Get("user")
+				So(ok, ShouldBeTrue)
+				So(v, ShouldEqual, "alice")
+			}
+			So(cookie(serve(id)), ShouldBeEmpty)
+
+			Convey("unknown tokens should start new sessions", func() {
+				handler = func(s *Session, w http.ResponseWriter) {
+					So(s.IsNew(), ShouldBeTrue)
+					_, ok := s.Get("user")
+					So(ok, ShouldBeFalse)
+				}
+				serve("forged")
+			})
+
+			Convey("rotated sessions should change their identifier", func() {
+				handler = func(s *Session, w http.ResponseWriter) {
+					s.Rotate()
+					w.WriteHeader(http.StatusNoContent)
+				}
+				w := serve(id)
+				So(w.Code, ShouldEqual, http.StatusNoContent)
+				nid := token(w)
+				So(nid, ShouldNotBeEmpty)
+				So(nid, ShouldNotEqual, id)
+				_, err := store.Load(id)
+				So(err, ShouldEqual, ErrNotFound)
+				d, err := store.Load(nid)
+				So(err, ShouldBeNil)
+				So(d.Values["user"], ShouldEqual, "alice")
+			})
+
+			Convey("old sessions should be rotated", func() {
+				d, _ := store.Load(id)
+				d.Rotated = time.Now().Add(-time.Hour)
+				store.Save(id, d, time.Hour)
+				handler = func(s *Session, w http.ResponseWriter) {}
+				nid := token(serve(id))
+				So(nid, ShouldNotEqual, id)
+				d, err := store.Load(nid)
+				So(err, ShouldBeNil)
+				So(time.Since(d.Rotated), ShouldBeLessThan, time.Minute)
+			})
+
+			Convey("destroyed sessions should be removed", func() {
+				handler = func(s *Session, w http.ResponseWriter) {
+					s.Destroy()
+				}
+				w := serve(id)
+				So(cookie(w), ShouldStartWith, "session=;")
+				So(cookie(w), ShouldContainSubstring, "Max-Age=0")
+				_, err := store.Load(id)
+				So(err, ShouldEqual, ErrNotFound)
+			})
+		})
+
+		Convey("sessions should be carried by the header if configured", func() {
+			m.config.Header = "X-Session-Token"
+			So(m.Configure(ctx), ShouldBeNil)
+			handler = func(s *Session, w http.ResponseWriter) {
+				s.Set("user", "alice")
+			}
+			w := serve("")
+			So(cookie(w), ShouldBeEmpty)
+			id := w.Header().Get("X-Session-Token")
+			So(id, ShouldNotBeEmpty)
+
+			handler = func(s *Session, w http.ResponseWriter) {
+				So(s.ID(), ShouldEqual, id)
+				s.Delete("user")
+			}
+			So(serve(id).Header().Get("X-Session-Token"), ShouldEqual, id)
+		})
+
+		Convey("invalid configurations should fail", func() {
+			m.config.SameSite = "None"
+			m.config.Secure = false
+			So(m.Configure(ctx), ShouldNotBeNil)
+			m.config.SameSite = "sometimes"
+			So(m.Configure(ctx), ShouldNotBeNil)
+			m.config.SameSite = "strict"
+			m.config.TTL = 0
+			So(m.Configure(ctx), ShouldNotBeNil)
+		})
+
+		Convey("requests not served by the middleware should have no session", func() {
+			So(FromRequest(httptest.NewRequest("GET", "/", nil)), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,101 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by the stores when a session does not exist or
+// expired.
+var ErrNotFound = errors.New("session not found")
+
+// Data is the stored state of a session.
+type Data struct {
+	// Values of the session
+	Values map[string]string `json:"values"`
+	// Created is the time the session was created at
+	Created time.Time `json:"created"`
+	// Rotated is the time the identifier of the session was last changed
+	Rotated time.Time `json:"rotated"`
+}
+
+// Store stores the sessions, e.g. in memory, in Redis or in a database.
+// Applications provide their own store to the group of the manager to
+// replace the memory store, e.g.
+//
+//	g.Add(func(db *sql.DB) session.Store { return &dbStore{db} })
+type Store interface {
+	// Load returns the data of the session, ErrNotFound if it does not
+	// exist or expired.
+	Load(id string) (Data, error)
+
+	// Save stores the data of the session until the TTL expires.
+	Save(id string, d Data, ttl time.Duration) error
+
+	// Delete removes the session, it does not fail if the session does not
+	// exist.
+	Delete(id string) error
+}
+
+// memoryStore is a Store keeping the sessions in memory, the expired
+// sessions are removed at most once a minute when sessions are saved.
+type memoryStore struct {
+	lock     sync.Mutex
+	sessions map[string]memorySession
+	swept    time.Time
+}
+
+type memorySession struct {
+	data    Data
+	expires time.Time
+}
+
+// NewMemoryStore returns a store keeping the sessions in memory, suitable
+// for a single instance of a server. The sessions are lost on restart.
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: map[string]memorySession{}, swept: time.Now()}
+}
+
+func (m *memoryStore) Load(id string) (Data, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || !time.Now().Before(s.expires) {
+		return Data{}, ErrNotFound
+	}
+	return copyData(s.data), nil
+}
+
+func (m *memoryStore) Save(id string, d Data, ttl time.Duration) error {
+	now := time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.sessions[id] = memorySession{copyData(d), now.Add(ttl)}
+	if now.Sub(m.swept) > time.Minute {
+		for id, s := range m.sessions {
+			if !now.Before(s.expires) {
+				delete(m.sessions, id)
+			}
+		}
+		m.swept = now
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+// copyData returns a copy of the data that does not share its values.
+func copyData(d Data) Data {
+	values := make(map[string]string, len(d.Values))
+	for k, v := range d.Values {
+		values[k] = v
+	}
+	d.Values = values
+	return d
+}
@@ -0,0 +1,50 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMemoryStore(t *testing.T) {
+	Convey("After we create a memory store", t, func() {
+		s := NewMemoryStore()
+
+		Convey("missing sessions should not be found", func() {
+			_, err := s.Load("missing")
+			So(err, ShouldEqual, ErrNotFound)
+			So(s.Delete("missing"), ShouldBeNil)
+		})
+
+		Convey("saved sessions should be loaded until they expire", func() {
+			d := Data{Values: map[string]string{"user": "alice"}, Created: time.Now()}
+			So(s.Save("a", d, time.Hour), ShouldBeNil)
+			So(s.Save("b", d, -time.Second), ShouldBeNil)
+			d.Values["user"] = "bob"
+
+			l, err := s.Load("a")
+			So(err, ShouldBeNil)
+			So(l.Values, ShouldResemble, map[string]string{"user": "alice"})
+			l.Values["user"] = "carol"
+			l, _ = s.Load("a")
+			So(l.Values["user"], ShouldEqual, "alice")
+
+			_, err = s.Load("b")
+			So(err, ShouldEqual, ErrNotFound)
+
+			So(s.Delete("a"), ShouldBeNil)
+			_, err = s.Load("a")
+			So(err, ShouldEqual, ErrNotFound)
+		})
+
+		Convey("expired sessions should be swept when sessions are saved", func() {
+			m := s.(*memoryStore)
+			So(s.Save("old", Data{}, -time.Second), ShouldBeNil)
+			m.swept = time.Now().Add(-2 * time.Minute)
+			So(s.Save("new", Data{}, time.Hour), ShouldBeNil)
+			So(m.sessions, ShouldContainKey, "new")
+			So(m.sessions, ShouldNotContainKey, "old")
+		})
+	})
+}
@@ -29,10 +29,19 @@ type Router interface {
 	IsIgnored(sig os.Signal) bool
 }
 
+// Observer is implemented by the routers that let handlers observe the
+// signals, the router returned by New implements it.
+type Observer interface {
+	// Observe registers a handler called with every signal delivered to the
+	// router before it is routed, e.g. to trace the signal deliveries.
+	Observe(h Handler)
+}
+
 type router struct {
 	signalCh   chan os.Signal
 	signals    map[os.Signal]Handler
 	ignSignals map[os.Signal]struct{}
+	observers  []Handler
 	running    bool
 	lock       *sync.RWMutex
 }
@@ -40,7 +49,8 @@ type router struct {
 // New returns a signal router.
 func New() Router {
 	r := &router{
-		signalCh:   make(chan os.Signal),
+		// signal.Notify drops the signals it can't send without blocking
+		signalCh:   make(chan os.Signal, 1),
 		signals:    make(map[os.Signal]Handler),
 		ignSignals: make(map[os.Signal]struct{}),
 		running:    false,
@@ -86,9 +96,15 @@ func (s *router) IsIgnored(sig os.Signal) bool {
 	return ok
 }
 
+func (s *router) Observe(h Handler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.observers = append(s.observers, h)
+}
+
 // StartRouter starts the signal router and listens for registered signals.
 func (s *router) Start(ctx component.Context) error {
-	go func() {
+	component.Go(ctx, func(ctx component.Context) error {
 		defer func() {
 			s.lock.Lock()
 			defer s.lock.Unlock()
@@ -99,18 +115,21 @@ func (s *router) Start(ctx component.Context) error {
 			select {
 			case <-ctx.Ctx().Done():
 				// We are done exit.
-				return
+				return nil
 			case sig := <-s.signalCh:
 				func() {
 					s.lock.RLock()
 					defer s.lock.RUnlock()
+					for _, o := range s.observers {
+						o(sig)
+					}
 					if h, ok := s.signals[sig]; ok {
 						h(sig)
 					}
 				}()
 			}
 		}
-	}()
+	})
 	s.lock.Lock()
 	s.running = true
 	s.lock.Unlock()
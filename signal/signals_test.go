@@ -83,6 +83,17 @@ func TestSignals(t *testing.T) {
 					So(sh.Sig(0), ShouldEqual, syscall.SIGINT)
 				})
 
+				Convey("Should be able to observe the signals", func() {
+					obs := &sigH{[]os.Signal{}, &sync.RWMutex{}}
+					s.(Observer).Observe(obs.handle)
+					s.(*router).signalCh <- syscall.SIGHUP
+					s.(*router).signalCh <- syscall.SIGINT
+
+					time.Sleep(100 * time.Millisecond)
+					So(obs.Len(), ShouldEqual, 2)
+					So(sh.Len(), ShouldEqual, 1)
+				})
+
 				Convey("Should be able to stop the component", func() {
 					So(grp.IsHealthy(), ShouldBeTrue)
 					grp.Invoke(func(sf component.Shutdown) {